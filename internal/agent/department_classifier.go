@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/eliasbui/ccl-magic/internal/department"
+)
+
+//go:embed templates/classify.md
+var classifyPrompt []byte
+
+// classifyTimeout bounds a model classification; past it the coordinator
+// falls back to keyword classification
+const classifyTimeout = 10 * time.Second
+
+// modelClassifier classifies requests by asking a language model for a
+// JSON classification
+type modelClassifier struct {
+	// generate returns the model's reply to a prompt
+	generate func(ctx context.Context, prompt string) (string, error)
+	timeout  time.Duration
+}
+
+// newModelClassifier returns a classifier backed by the given model, meant
+// to be the small model
+func newModelClassifier(model Model) *modelClassifier {
+	var maxOutput int64 = 200
+	if model.CatwalkCfg.CanReason {
+		maxOutput = model.CatwalkCfg.DefaultMaxTokens
+	}
+
+	agent := fantasy.NewAgent(model.Model,
+		fantasy.WithSystemPrompt(string(classifyPrompt)+"\n /no_think"),
+		fantasy.WithMaxOutputTokens(maxOutput),
+	)
+
+	return &modelClassifier{
+		generate: func(ctx context.Context, prompt string) (string, error) {
+			resp, err := agent.Generate(ctx, fantasy.AgentCall{
+				Prompt: fmt.Sprintf("Classify the following request:\n\n%s\n <think>\n\n</think>", prompt),
+			})
+			if err != nil {
+				return "", err
+			}
+			return resp.Response.Content.Text(), nil
+		},
+		timeout: classifyTimeout,
+	}
+}
+
+// Classify implements department.Classifier
+func (c *modelClassifier) Classify(ctx context.Context, prompt string) (department.Classification, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	reply, err := c.generate(ctx, prompt)
+	if err != nil {
+		return department.Classification{}, fmt.Errorf("failed to classify request: %w", err)
+	}
+	return parseClassification(reply)
+}
+
+// parseClassification extracts the JSON classification from a model reply,
+// skipping any thinking or code fences around it
+func parseClassification(reply string) (department.Classification, error) {
+	if idx := strings.Index(reply, "</think>"); idx >= 0 {
+		reply = reply[idx+len("</think>"):]
+	}
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return department.Classification{}, fmt.Errorf("no classification in model reply %q", reply)
+	}
+
+	var classification department.Classification
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &classification); err != nil {
+		return department.Classification{}, fmt.Errorf("invalid classification in model reply: %w", err)
+	}
+	classification.Type = strings.ToLower(strings.TrimSpace(classification.Type))
+	classification.Priority = department.Priority(strings.ToLower(strings.TrimSpace(string(classification.Priority))))
+	for i, skill := range classification.Skills {
+		classification.Skills[i] = strings.ToLower(strings.TrimSpace(skill))
+	}
+	if err := classification.Validate(); err != nil {
+		return department.Classification{}, fmt.Errorf("invalid classification in model reply: %w", err)
+	}
+	return classification, nil
+}
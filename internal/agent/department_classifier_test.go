@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/config"
+	"github.com/eliasbui/ccl-magic/internal/department"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClassification(t *testing.T) {
+	t.Parallel()
+
+	classification, err := parseClassification("<think>hmm</think>\n```json\n{\"type\": \"Feature_Development\", \"priority\": \"low\", \"skills\": [\"Go\"]}\n```")
+	require.NoError(t, err)
+	require.Equal(t, department.Classification{
+		Type:     department.TaskTypeFeatureDevelopment,
+		Priority: department.PriorityLow,
+		Skills:   []string{"go"},
+	}, classification)
+
+	_, err = parseClassification("I think this is a bug fix")
+	require.ErrorContains(t, err, "no classification")
+	_, err = parseClassification(`{"type": "bug_fix", "priority": "whenever"}`)
+	require.ErrorContains(t, err, "unknown priority")
+}
+
+func TestDepartmentCoordinatorClassify(t *testing.T) {
+	t.Parallel()
+
+	dc := newTestDepartmentCoordinator(t, &department.DepartmentConfig{Enabled: true})
+	prompt := "I want to feature-freeze the bug tracker"
+
+	// The model reads past the keywords
+	dc.classifier = &modelClassifier{
+		generate: func(context.Context, string) (string, error) {
+			return `{"type": "general", "priority": "medium", "skills": []}`, nil
+		},
+	}
+	classification := dc.classify(t.Context(), prompt)
+	require.Equal(t, department.TaskTypeGeneral, classification.Type)
+
+	// Model errors and timeouts fall back to keywords
+	dc.classifier = &modelClassifier{
+		generate: func(context.Context, string) (string, error) {
+			return "", errors.New("model unavailable")
+		},
+	}
+	classification = dc.classify(t.Context(), prompt)
+	require.Equal(t, department.TaskTypeBugFix, classification.Type)
+
+	dc.classifier = &modelClassifier{
+		generate: func(ctx context.Context, _ string) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		},
+		timeout: 10 * time.Millisecond,
+	}
+	classification = dc.classify(t.Context(), prompt)
+	require.Equal(t, department.TaskTypeBugFix, classification.Type)
+	require.Equal(t, department.PriorityMedium, classification.Priority)
+
+	// The keyword fallback knows the configured skill aliases
+	dc.classifier = nil
+	dc.config.Department.TaskRouting.SkillAliases = map[string][]string{"terraform": {"tf"}}
+	classification = dc.classify(t.Context(), "Fix the tf state")
+	require.Equal(t, []string{"terraform"}, classification.Skills)
+}
+
+func TestDepartmentCoordinatorKeepsGivenClassifier(t *testing.T) {
+	t.Parallel()
+
+	classifier := department.KeywordClassifier{}
+	dc := &DepartmentCoordinator{
+		config: &config.Config{Department: &department.DepartmentConfig{Enabled: true}},
+	}
+	WithClassifier(classifier)(dc)
+
+	require.NoError(t, dc.initializeDepartmentManager(t.Context()))
+	t.Cleanup(func() { require.NoError(t, dc.departmentManager.Stop()) })
+	require.Equal(t, classifier, dc.classifier)
+}
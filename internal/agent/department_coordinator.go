@@ -2,10 +2,13 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"charm.land/fantasy"
 	"github.com/eliasbui/ccl-magic/internal/agent/prompt"
@@ -20,12 +23,50 @@ import (
 	"github.com/eliasbui/ccl-magic/internal/session"
 )
 
+// defaultTaskTimeout is used when neither the task nor the department config
+// sets a timeout
+const defaultTaskTimeout = 30 * time.Minute
+
 // DepartmentCoordinator extends the base coordinator to support department management
 type DepartmentCoordinator struct {
 	*coordinator // Embedded base coordinator
 
 	departmentManager *department.Manager
 	config           *config.Config
+
+	// classifier decides a request's task type, priority and skills. Keyword
+	// classification is used when it is nil or fails.
+	classifier department.Classifier
+
+	// runTask runs a task's prompt for its member. Nil runs it through the
+	// base coordinator.
+	runTask func(ctx context.Context, sessionID, prompt string, attachments ...message.Attachment) (*fantasy.AgentResult, error)
+}
+
+// taskRetryError reports that a task's attempt failed and the task was
+// routed again, to be run after backoff
+type taskRetryError struct {
+	backoff time.Duration
+	cause   error
+}
+
+func (e *taskRetryError) Error() string {
+	return fmt.Sprintf("attempt failed, retrying in %s: %v", e.backoff, e.cause)
+}
+
+func (e *taskRetryError) Unwrap() error {
+	return e.cause
+}
+
+// DepartmentCoordinatorOption configures a DepartmentCoordinator
+type DepartmentCoordinatorOption func(*DepartmentCoordinator)
+
+// WithClassifier makes the coordinator classify requests with classifier
+// instead of the small model
+func WithClassifier(classifier department.Classifier) DepartmentCoordinatorOption {
+	return func(dc *DepartmentCoordinator) {
+		dc.classifier = classifier
+	}
 }
 
 // NewDepartmentCoordinator creates a new coordinator with department management capabilities
@@ -37,6 +78,7 @@ func NewDepartmentCoordinator(
 	permissions permission.Service,
 	history history.Service,
 	lspClients *csync.Map[string, *lsp.Client],
+	opts ...DepartmentCoordinatorOption,
 ) (Coordinator, error) {
 	// Create base coordinator
 	baseCoord := &coordinator{
@@ -53,6 +95,9 @@ func NewDepartmentCoordinator(
 		coordinator: baseCoord,
 		config:      cfg,
 	}
+	for _, opt := range opts {
+		opt(deptCoord)
+	}
 
 	// Initialize department manager if enabled
 	if cfg.Department != nil && cfg.Department.Enabled {
@@ -78,6 +123,16 @@ func (dc *DepartmentCoordinator) initializeDepartmentManager(ctx context.Context
 	}
 	dc.departmentManager = deptManager
 
+	// Classify requests with the small model when one is configured and no
+	// classifier was given
+	if dc.classifier == nil {
+		if _, small, err := dc.buildAgentModels(ctx); err == nil {
+			dc.classifier = newModelClassifier(small)
+		} else {
+			slog.Info("Classifying department requests by keyword", "reason", err)
+		}
+	}
+
 	// Start department manager
 	if err := deptManager.Start(ctx); err != nil {
 		return fmt.Errorf("failed to start department manager: %w", err)
@@ -87,6 +142,7 @@ func (dc *DepartmentCoordinator) initializeDepartmentManager(ctx context.Context
 	go dc.handleDepartmentEvents(ctx)
 	go dc.handleMemberEvents(ctx)
 	go dc.handleTaskEvents(ctx)
+	go dc.handleScalingEvents(ctx)
 
 	slog.Info("Department coordinator initialized", "departments_enabled", true)
 
@@ -131,49 +187,114 @@ func (dc *DepartmentCoordinator) Run(ctx context.Context, sessionID, prompt stri
 
 // runWithDepartmentRouting routes the request through the department system
 func (dc *DepartmentCoordinator) runWithDepartmentRouting(ctx context.Context, sessionID, prompt string, attachments ...message.Attachment) (*fantasy.AgentResult, error) {
+	if err := dc.checkPrompt(prompt); err != nil {
+		return nil, err
+	}
+
 	// Create a task from the user request
+	task := dc.requestTask(ctx, sessionID, prompt, attachments)
+
+	// Run a workflow when one is defined for this task type
+	if workflow, ok := dc.departmentManager.WorkflowForTaskType(task.Type); ok {
+		run, err := dc.departmentManager.StartWorkflow(ctx, workflow.ID, task)
+		if err == nil {
+			return dc.waitForWorkflowRun(ctx, sessionID, run.ID, prompt, attachments...)
+		}
+		slog.Warn("Failed to start workflow, routing as a single task", "workflow_id", workflow.ID, "error", err)
+	}
+
+	// Create task through department manager
+	createdTask, err := dc.departmentManager.CreateTask(ctx, task)
+	if errors.Is(err, department.ErrQueueFull) {
+		// Running the request anyway would defeat the limit; the caller
+		// should back off and retry
+		return nil, err
+	}
+	if err != nil {
+		slog.Warn("Failed to create department task, falling back to base coordinator", "error", err)
+		return dc.coordinator.Run(ctx, sessionID, prompt, attachments...)
+	}
+
+	// Wait for task assignment and execution. The member reads the
+	// attachments from the task, where large ones may have been spilled.
+	return dc.waitForTaskCompletion(ctx, sessionID, createdTask.ID, prompt)
+}
+
+// requestTask classifies a user request and builds the task routing it
+func (dc *DepartmentCoordinator) requestTask(ctx context.Context, sessionID, prompt string, attachments []message.Attachment) *department.Task {
+	classification := dc.classify(ctx, prompt)
 	task := &department.Task{
 		Title:          extractTaskTitle(prompt),
 		Description:    prompt,
-		Type:          determineTaskType(prompt),
-		Priority:       determineTaskPriority(prompt),
+		Type:           classification.Type,
+		Priority:       classification.Priority,
 		RequestedBy:    "user",
+		SessionID:      sessionID,
+		AffinityKey:    sessionID, // Follow-ups stay with the same member
 		DepartmentID:   "", // Will be determined by task router
 		Attachments:    convertAttachments(attachments),
-		RequiredSkills: extractRequiredSkills(prompt),
+		RequiredSkills: classification.Skills,
 	}
+	if dc.config != nil && dc.config.Department != nil && dc.config.Department.DefaultRetryPolicy != nil {
+		policy := *dc.config.Department.DefaultRetryPolicy
+		task.RetryPolicy = &policy
+	}
+	return task
+}
 
-	// Create task through department manager
-	createdTask, err := dc.departmentManager.CreateTask(ctx, task)
-	if err != nil {
-		slog.Warn("Failed to create department task, falling back to base coordinator", "error", err)
-		return dc.coordinator.Run(ctx, sessionID, prompt, attachments...)
+// PreviewRequest reports which department and member a request would be
+// routed to, without creating a task or running anything
+func (dc *DepartmentCoordinator) PreviewRequest(ctx context.Context, prompt string) (*department.RoutePreview, error) {
+	if dc.departmentManager == nil {
+		return nil, fmt.Errorf("department management is not enabled")
+	}
+	if err := dc.checkPrompt(prompt); err != nil {
+		return nil, err
 	}
 
-	// Wait for task assignment and execution
-	return dc.waitForTaskCompletion(ctx, sessionID, createdTask.ID, prompt, attachments...)
+	return dc.departmentManager.PreviewRoute(dc.requestTask(ctx, "", prompt, nil))
+}
+
+// checkPrompt rejects prompts routing cannot handle
+func (dc *DepartmentCoordinator) checkPrompt(prompt string) error {
+	// An empty prompt gives routing nothing to work with
+	if strings.TrimSpace(prompt) == "" {
+		return fmt.Errorf("invalid department request: %w", ErrEmptyPrompt)
+	}
+	// A prompt no member can take would only fail once it runs
+	if err := dc.departmentManager.CheckPromptSize(prompt); err != nil {
+		return fmt.Errorf("invalid department request: %w", err)
+	}
+	return nil
 }
 
 // waitForTaskCompletion waits for a department task to be completed and returns the result
 func (dc *DepartmentCoordinator) waitForTaskCompletion(ctx context.Context, sessionID, taskID, prompt string, attachments ...message.Attachment) (*fantasy.AgentResult, error) {
-	// Subscribe to task events
-	taskEvents := dc.departmentManager.SubscribeToTaskEvents(ctx)
-	defer close(taskEvents)
+	// Bound both waiting for assignment and execution by the task timeout
+	timeout := defaultTaskTimeout
+	if task, err := dc.departmentManager.GetTask(taskID); err == nil {
+		timeout = dc.taskTimeout(task)
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Subscribe to task events. The broker owns the channel; cancelling the
+	// subscription context when we return is what unsubscribes.
+	subCtx, unsubscribe := context.WithCancel(ctx)
+	defer unsubscribe()
+	taskEvents := dc.departmentManager.SubscribeToTaskEvents(subCtx)
 
 	// Poll for task completion
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
-	timeout := time.NewTimer(30 * time.Minute) // 30 minute timeout
-	defer timeout.Stop()
-
 	for {
 		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-
-		case <-timeout.C:
-			return nil, fmt.Errorf("task %s timed out", taskID)
+		case <-runCtx.Done():
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, dc.failTimedOutTask(ctx, taskID, timeout)
 
 		case <-ticker.C:
 			task, err := dc.departmentManager.GetTask(taskID)
@@ -188,18 +309,41 @@ func (dc *DepartmentCoordinator) waitForTaskCompletion(ctx context.Context, sess
 			case department.TaskStatusFailed:
 				return nil, fmt.Errorf("task %s failed: %s", taskID, task.Results["error"])
 
+			case department.TaskStatusCancelled:
+				return nil, fmt.Errorf("task %s: %w", taskID, department.ErrTaskCancelled)
+
 			case department.TaskStatusAssigned:
 				// Task is assigned, execute it through the appropriate member
 				if task.AssignedMember != "" {
-					return dc.executeTaskForMember(ctx, sessionID, task, prompt, attachments...)
+					result, err := dc.executeTaskForMember(runCtx, sessionID, task, prompt, attachments...)
+					if err != nil && ctx.Err() == nil && runCtx.Err() != nil {
+						return nil, fmt.Errorf("task %s timed out after %s", taskID, timeout)
+					}
+					var retry *taskRetryError
+					if errors.As(err, &retry) {
+						// Wait out the backoff, then run the task again once
+						// it is assigned
+						slog.Info("Task attempt failed, retrying", "task_id", taskID, "backoff", retry.backoff, "error", retry.cause)
+						select {
+						case <-time.After(retry.backoff):
+						case <-runCtx.Done():
+						}
+						continue
+					}
+					return result, err
 				}
 
 			default:
 				// Continue waiting
 			}
 
-		case event := <-taskEvents:
-			if event.Payload.ID == taskID {
+		case event, ok := <-taskEvents:
+			if !ok {
+				// The broker shut down; keep polling
+				taskEvents = nil
+				continue
+			}
+			if event.Payload != nil && event.Payload.ID == taskID {
 				switch event.Type {
 				case pubsub.UpdatedEvent:
 					if event.Payload.Status == department.TaskStatusCompleted {
@@ -208,12 +352,85 @@ func (dc *DepartmentCoordinator) waitForTaskCompletion(ctx context.Context, sess
 					if event.Payload.Status == department.TaskStatusFailed {
 						return nil, fmt.Errorf("task failed: %s", event.Payload.Results["error"])
 					}
+					if event.Payload.Status == department.TaskStatusCancelled {
+						return nil, fmt.Errorf("task %s: %w", taskID, department.ErrTaskCancelled)
+					}
 				}
 			}
 		}
 	}
 }
 
+// waitForWorkflowRun executes the steps of a workflow run as they are assigned
+// and returns the aggregated step results once the run finishes
+func (dc *DepartmentCoordinator) waitForWorkflowRun(ctx context.Context, sessionID, runID, prompt string, attachments ...message.Attachment) (*fantasy.AgentResult, error) {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	runTimeout := defaultTaskTimeout
+	if run, err := dc.departmentManager.GetWorkflowRun(runID); err == nil {
+		if task, err := dc.departmentManager.GetTask(run.TaskID); err == nil {
+			runTimeout = dc.taskTimeout(task)
+		}
+	}
+	timeout := time.NewTimer(runTimeout)
+	defer timeout.Stop()
+
+	for {
+		run, err := dc.departmentManager.GetWorkflowRun(runID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch run.Status {
+		case department.TaskStatusCompleted:
+			task, err := dc.departmentManager.GetTask(run.TaskID)
+			if err != nil {
+				return nil, err
+			}
+			return dc.createResultFromTask(task), nil
+
+		case department.TaskStatusFailed:
+			return nil, fmt.Errorf("workflow run %s failed: %s", runID, run.Error)
+		}
+
+		// Execute every step that has been assigned to a member
+		executed := false
+		for _, taskID := range run.StepTasks {
+			task, err := dc.departmentManager.GetTask(taskID)
+			if err != nil || task.Status != department.TaskStatusAssigned || task.AssignedMember == "" {
+				continue
+			}
+
+			stepPrompt := fmt.Sprintf("%s\n\nWorkflow step: %s\n%s", prompt, task.Title, task.Description)
+			if _, err := dc.executeTaskForMember(ctx, sessionID, task, stepPrompt, attachments...); err != nil {
+				slog.Warn("Workflow step failed", "run_id", runID, "task_id", task.ID, "error", err)
+			}
+			executed = true
+		}
+		if executed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, dc.abortWorkflowRun(ctx, runID, ctx.Err())
+		case <-timeout.C:
+			return nil, dc.abortWorkflowRun(ctx, runID, fmt.Errorf("workflow run %s timed out", runID))
+		case <-ticker.C:
+		}
+	}
+}
+
+// abortWorkflowRun fails a run the coordinator stopped waiting for, so its
+// steps do not keep holding member capacity, and returns the cause
+func (dc *DepartmentCoordinator) abortWorkflowRun(ctx context.Context, runID string, cause error) error {
+	if err := dc.departmentManager.AbortWorkflowRun(context.WithoutCancel(ctx), runID, cause); err != nil {
+		slog.Warn("Failed to abort workflow run", "run_id", runID, "error", err)
+	}
+	return cause
+}
+
 // executeTaskForMember executes a task using a specific department member
 func (dc *DepartmentCoordinator) executeTaskForMember(ctx context.Context, sessionID string, task *department.Task, prompt string, attachments ...message.Attachment) (*fantasy.AgentResult, error) {
 	// Get the member assigned to the task
@@ -222,28 +439,56 @@ func (dc *DepartmentCoordinator) executeTaskForMember(ctx context.Context, sessi
 		return nil, fmt.Errorf("failed to get assigned member: %w", err)
 	}
 
+	// Attachments carried by the task replace the ones passed in, fetching
+	// spilled content only now that a member runs the task
+	if len(task.Attachments) > 0 {
+		attachments, err = dc.taskAttachments(ctx, task)
+		if err != nil {
+			err = fmt.Errorf("failed to load attachments of task %s: %w", task.ID, err)
+			if updateErr := dc.departmentManager.UpdateTaskStatus(ctx, task.ID, department.TaskStatusFailed, map[string]interface{}{"error": err.Error()}); updateErr != nil {
+				slog.Warn("Failed to update task status", "error", updateErr)
+			}
+			return nil, err
+		}
+	}
+
 	// Update task status to in progress
 	if err := dc.departmentManager.UpdateTaskStatus(ctx, task.ID, department.TaskStatusInProgress, nil); err != nil {
 		slog.Warn("Failed to update task status", "error", err)
 	}
 
 	// Execute the task using the base coordinator
-	result, err := dc.coordinator.Run(ctx, sessionID, prompt, attachments...)
+	run := dc.runTask
+	if run == nil {
+		run = dc.coordinator.Run
+	}
+	progressCtx, stopProgress := context.WithCancel(ctx)
+	go dc.reportMessageProgress(progressCtx, sessionID, task.ID)
+	result, err := run(ctx, sessionID, prompt, attachments...)
+	stopProgress()
 	if err != nil {
-		// Mark task as failed
-		updateErr := dc.departmentManager.UpdateTaskStatus(ctx, task.ID, department.TaskStatusFailed, map[string]interface{}{
+		// Retry the task when its policy allows, otherwise mark it failed
+		results := map[string]interface{}{
 			"error": err.Error(),
-		})
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			results["timeout"] = dc.taskTimeout(task).String()
+		}
+		retryable := ctx.Err() == nil && isRetryableTaskError(err)
+		backoff, retrying, updateErr := dc.departmentManager.FailTaskAttempt(context.WithoutCancel(ctx), task.ID, results, retryable)
 		if updateErr != nil {
-			slog.Warn("Failed to update task status to failed", "error", updateErr)
+			slog.Warn("Failed to record failed task attempt", "error", updateErr)
+		}
+		if retrying {
+			return nil, &taskRetryError{backoff: backoff, cause: err}
 		}
 		return nil, err
 	}
 
 	// Mark task as completed with results
 	taskResults := map[string]interface{}{
-		"response":    result.Content,
-		"tool_calls":  result.ToolCalls,
+		"response":    result.Response.Content.Text(),
+		"tool_calls":  result.Response.Content.ToolCalls(),
 		"member_id":   member.ID,
 		"member_role": string(member.Role),
 		"execution_time": time.Now().Format(time.RFC3339),
@@ -256,18 +501,70 @@ func (dc *DepartmentCoordinator) executeTaskForMember(ctx context.Context, sessi
 	return result, nil
 }
 
+// isRetryableTaskError reports whether a failed attempt may succeed when
+// tried again. Denied permissions and rejected prompts fail the same way
+// every time.
+func isRetryableTaskError(err error) bool {
+	switch {
+	case errors.Is(err, permission.ErrorPermissionDenied),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, ErrEmptyPrompt),
+		errors.Is(err, department.ErrPromptTooLarge):
+		return false
+	}
+	return true
+}
+
+// taskTimeout returns the task's own timeout, falling back to the configured
+// default and then to defaultTaskTimeout
+func (dc *DepartmentCoordinator) taskTimeout(task *department.Task) time.Duration {
+	if task.Timeout > 0 {
+		return task.Timeout
+	}
+	if dc.config != nil && dc.config.Department != nil && dc.config.Department.DefaultTaskTimeout > 0 {
+		return dc.config.Department.DefaultTaskTimeout
+	}
+	return defaultTaskTimeout
+}
+
+// failTimedOutTask marks a task that ran out of time as failed, which frees
+// its member, and returns the timeout error
+func (dc *DepartmentCoordinator) failTimedOutTask(ctx context.Context, taskID string, timeout time.Duration) error {
+	timeoutErr := fmt.Errorf("task %s timed out after %s", taskID, timeout)
+
+	task, err := dc.departmentManager.GetTask(taskID)
+	if err != nil {
+		return timeoutErr
+	}
+	switch task.Status {
+	case department.TaskStatusCompleted, department.TaskStatusFailed, department.TaskStatusCancelled:
+		return timeoutErr
+	}
+
+	if err := dc.departmentManager.UpdateTaskStatus(ctx, taskID, department.TaskStatusFailed, map[string]interface{}{
+		"error":   timeoutErr.Error(),
+		"timeout": timeout.String(),
+	}); err != nil {
+		slog.Warn("Failed to mark timed out task as failed", "task_id", taskID, "error", err)
+	}
+	return timeoutErr
+}
+
 // createResultFromTask creates a fantasy.AgentResult from a completed task
 func (dc *DepartmentCoordinator) createResultFromTask(task *department.Task) *fantasy.AgentResult {
 	content, _ := task.Results["response"].(string)
 
-	var toolCalls []fantasy.ToolCall
-	if calls, ok := task.Results["tool_calls"].([]fantasy.ToolCall); ok {
-		toolCalls = calls
+	responseContent := fantasy.ResponseContent{fantasy.TextContent{Text: content}}
+	if calls, ok := task.Results["tool_calls"].([]fantasy.ToolCallContent); ok {
+		for _, call := range calls {
+			responseContent = append(responseContent, call)
+		}
 	}
 
 	return &fantasy.AgentResult{
-		Content:   content,
-		ToolCalls: toolCalls,
+		Response: fantasy.Response{
+			Content: responseContent,
+		},
 	}
 }
 
@@ -325,6 +622,35 @@ func (dc *DepartmentCoordinator) handleTaskEvents(ctx context.Context) {
 	}
 }
 
+// handleScalingEvents surfaces members added or removed by the auto-scaler
+func (dc *DepartmentCoordinator) handleScalingEvents(ctx context.Context) {
+	if dc.departmentManager == nil {
+		return
+	}
+
+	events := dc.departmentManager.SubscribeToScalingEvents(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				// Auto-scaling is disabled or the manager stopped
+				return
+			}
+			scaling := event.Payload
+			slog.Info("Department scaled",
+				"department_id", scaling.DepartmentID,
+				"action", scaling.Action,
+				"member_id", scaling.MemberID,
+				"role", string(scaling.Role),
+				"reason", scaling.Reason,
+				"members", scaling.MembersAfter)
+		}
+	}
+}
+
 // processDepartmentEvent processes department events
 func (dc *DepartmentCoordinator) processDepartmentEvent(event pubsub.Event[*department.Department]) {
 	dept := event.Payload
@@ -403,128 +729,111 @@ func (dc *DepartmentCoordinator) CreateCustomerRequest(ctx context.Context, titl
 }
 
 // GetDepartmentStatus returns the status of all departments
-func (dc *DepartmentCoordinator) GetDepartmentStatus() (map[string]interface{}, error) {
+func (dc *DepartmentCoordinator) GetDepartmentStatus() (*department.DepartmentStatusReport, error) {
 	if dc.departmentManager == nil {
 		return nil, fmt.Errorf("department management is not enabled")
 	}
 
-	status := make(map[string]interface{})
+	return dc.departmentManager.StatusReport(), nil
+}
 
-	// Get department statistics
-	departments := dc.departmentManager.ListDepartments()
-	for _, dept := range departments {
-		stats, err := dc.departmentManager.GetDepartmentStats(dept.ID)
-		if err != nil {
-			continue
+// classify classifies a request with the configured classifier, falling
+// back to keyword classification if it fails or times out. Keywords match
+// the configured skill aliases as well as the default ones.
+func (dc *DepartmentCoordinator) classify(ctx context.Context, prompt string) department.Classification {
+	if dc.classifier != nil {
+		classification, err := dc.classifier.Classify(ctx, prompt)
+		if err == nil {
+			return classification
 		}
-		status[dept.ID] = map[string]interface{}{
-			"name":       dept.Name,
-			"type":       string(dept.Type),
-			"stats":      stats,
-			"auto_scale": dept.AutoScale,
-		}
-	}
-
-	// Get member information
-	members := dc.departmentManager.ListMembers("")
-	status["members"] = map[string]interface{}{
-		"total":   len(members),
-		"online":  countMembersByStatus(members, department.MemberStatusOnline),
-		"busy":    countMembersByStatus(members, department.MemberStatusBusy),
-		"offline": countMembersByStatus(members, department.MemberStatusOffline),
+		slog.Warn("Failed to classify request, falling back to keywords", "error", err)
 	}
 
-	// Get task information
-	tasks := dc.departmentManager.ListTasks("", "")
-	status["tasks"] = map[string]interface{}{
-		"total":     len(tasks),
-		"queued":    countTasksByStatus(tasks, department.TaskStatusQueued),
-		"active":    countTasksByStatus(tasks, department.TaskStatusInProgress),
-		"completed": countTasksByStatus(tasks, department.TaskStatusCompleted),
-		"failed":    countTasksByStatus(tasks, department.TaskStatusFailed),
-	}
-
-	return status, nil
+	classifier := department.NewKeywordClassifier(dc.config.Department.TaskRouting.SkillAliases)
+	classification, _ := classifier.Classify(ctx, prompt)
+	return classification
 }
 
 // Helper functions
 
-func extractTaskTitle(prompt string) string {
-	// Simple title extraction - in a real implementation, this would be more sophisticated
-	lines := strings.Split(prompt, "\n")
-	if len(lines) > 0 && len(strings.TrimSpace(lines[0])) < 100 {
-		return strings.TrimSpace(lines[0])
-	}
+// Task titles are the first line of the prompt when it is shorter than
+// maxTitleLength, and otherwise cut to at most truncatedTitleLength
+const (
+	maxTitleLength       = 100
+	truncatedTitleLength = 50
+)
 
-	// Truncate prompt if too long
-	if len(prompt) > 50 {
-		return prompt[:47] + "..."
+// extractTaskTitle derives a task title from the first line of prose in a
+// prompt, skipping blank lines and code blocks and stripping markdown header
+// and quote markers. Questions are kept whole; other long lines are cut at a
+// word boundary.
+func extractTaskTitle(prompt string) string {
+	line := firstProseLine(prompt)
+	if strings.HasSuffix(line, "?") || utf8.RuneCountInString(line) < maxTitleLength {
+		return line
 	}
-	return prompt
+	return truncateOnWord(line, truncatedTitleLength)
 }
 
-func determineTaskType(prompt string) string {
-	prompt = strings.ToLower(prompt)
-
-	if strings.Contains(prompt, "bug") || strings.Contains(prompt, "fix") {
-		return "bug_fix"
-	}
-	if strings.Contains(prompt, "feature") || strings.Contains(prompt, "implement") {
-		return "feature_development"
-	}
-	if strings.Contains(prompt, "test") || strings.Contains(prompt, "qa") {
-		return "testing"
-	}
-	if strings.Contains(prompt, "deploy") || strings.Contains(prompt, "release") {
-		return "deployment"
-	}
-	if strings.Contains(prompt, "security") || strings.Contains(prompt, "vulnerability") {
-		return "security"
+// firstProseLine returns the first non-empty line outside code blocks, with
+// markdown header and quote markers removed. A prompt that is only code
+// yields its first line of code.
+func firstProseLine(prompt string) string {
+	inCode := false
+	firstCode := ""
+	for _, line := range strings.Split(prompt, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			if firstCode == "" {
+				firstCode = line
+			}
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimLeft(line, "#> \t"))
+		if line != "" {
+			return line
+		}
 	}
-
-	return "general"
+	return firstCode
 }
 
-func determineTaskPriority(prompt string) department.Priority {
-	prompt = strings.ToLower(prompt)
-
-	if strings.Contains(prompt, "urgent") || strings.Contains(prompt, "critical") || strings.Contains(prompt, "asap") {
-		return department.PriorityCritical
-	}
-	if strings.Contains(prompt, "high") || strings.Contains(prompt, "important") {
-		return department.PriorityHigh
+// truncateOnWord shortens s to at most limit runes, ellipsis included,
+// cutting at the last word boundary that fits
+func truncateOnWord(s string, limit int) string {
+	const ellipsis = "..."
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
 	}
-	if strings.Contains(prompt, "low") || strings.Contains(prompt, "minor") {
-		return department.PriorityLow
-	}
-
-	return department.PriorityMedium
-}
 
-func extractRequiredSkills(prompt string) []string {
-	prompt = strings.ToLower(prompt)
-	var skills []string
-
-	skillKeywords := map[string][]string{
-		"go":         {"golang", "go "},
-		"javascript": {"javascript", "js ", "node"},
-		"python":     {"python", "py "},
-		"docker":     {"docker", "container"},
-		"kubernetes": {"kubernetes", "k8s"},
-		"security":   {"security", "vulnerability", "penetration"},
-		"testing":    {"test", "testing", "qa"},
+	cut := string(runes[:limit-len(ellipsis)])
+	if idx := strings.LastIndexFunc(cut, unicode.IsSpace); idx > 0 {
+		cut = cut[:idx]
 	}
+	return strings.TrimRight(cut, " \t,;:-") + ellipsis
+}
 
-	for skill, keywords := range skillKeywords {
-		for _, keyword := range keywords {
-			if strings.Contains(prompt, keyword) {
-				skills = append(skills, skill)
-				break
-			}
+// taskAttachments converts a task's attachments back to message
+// attachments, reading spilled content from the department's blob store
+func (dc *DepartmentCoordinator) taskAttachments(ctx context.Context, task *department.Task) ([]message.Attachment, error) {
+	attachments := make([]message.Attachment, 0, len(task.Attachments))
+	for _, att := range task.Attachments {
+		content, err := dc.departmentManager.AttachmentContent(ctx, att)
+		if err != nil {
+			return nil, err
 		}
+		attachments = append(attachments, message.Attachment{
+			FilePath: att.Path,
+			FileName: att.Name,
+			MimeType: att.Type,
+			Content:  content,
+		})
 	}
-
-	return skills
+	return attachments, nil
 }
 
 func convertAttachments(attachments []message.Attachment) []department.TaskAttachment {
@@ -533,9 +842,10 @@ func convertAttachments(attachments []message.Attachment) []department.TaskAttac
 	for i, att := range attachments {
 		taskAtt := department.TaskAttachment{
 			ID:        fmt.Sprintf("att-%d", i),
-			Name:      att.Name,
-			Type:      att.ContentType,
+			Name:      att.FileName,
+			Type:      att.MimeType,
 			Size:      int64(len(att.Content)),
+			Path:      att.FilePath,
 			Content:   att.Content,
 			CreatedAt: time.Now(),
 		}
@@ -544,23 +854,3 @@ func convertAttachments(attachments []message.Attachment) []department.TaskAttac
 
 	return taskAttachments
 }
-
-func countMembersByStatus(members []*department.Member, status department.MemberStatus) int {
-	count := 0
-	for _, member := range members {
-		if member.Status == status {
-			count++
-		}
-	}
-	return count
-}
-
-func countTasksByStatus(tasks []*department.Task, status department.TaskStatus) int {
-	count := 0
-	for _, task := range tasks {
-		if task.Status == status {
-			count++
-		}
-	}
-	return count
-}
\ No newline at end of file
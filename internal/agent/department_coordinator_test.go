@@ -0,0 +1,354 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/eliasbui/ccl-magic/internal/config"
+	"github.com/eliasbui/ccl-magic/internal/department"
+	"github.com/eliasbui/ccl-magic/internal/message"
+	"github.com/eliasbui/ccl-magic/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDepartmentCoordinator(t *testing.T, deptCfg *department.DepartmentConfig) *DepartmentCoordinator {
+	t.Helper()
+
+	manager, err := department.NewManager(t.Context(), deptCfg)
+	require.NoError(t, err)
+
+	return &DepartmentCoordinator{
+		departmentManager: manager,
+		config:            &config.Config{Department: deptCfg},
+	}
+}
+
+func TestDepartmentCoordinatorTaskTimeout(t *testing.T) {
+	t.Parallel()
+
+	dc := newTestDepartmentCoordinator(t, &department.DepartmentConfig{
+		Enabled:            true,
+		DefaultTaskTimeout: time.Hour,
+	})
+	manager := dc.GetDepartmentManager()
+
+	require.Equal(t, time.Hour, dc.taskTimeout(&department.Task{}))
+	require.Equal(t, time.Minute, dc.taskTimeout(&department.Task{Timeout: time.Minute}))
+	dc.config.Department.DefaultTaskTimeout = 0
+	require.Equal(t, defaultTaskTimeout, dc.taskTimeout(&department.Task{}))
+
+	// The task is assigned but nothing executes it before the timeout
+	member := &department.Member{
+		ID:            "dev-1",
+		Role:          department.RoleDeveloper,
+		DepartmentID:  "dept-dev",
+		MaxConcurrent: 1,
+	}
+	require.NoError(t, manager.RegisterMember(t.Context(), member))
+	task, err := manager.CreateTask(t.Context(), &department.Task{
+		ID:           "slow",
+		DepartmentID: "dept-dev",
+		Timeout:      50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.NoError(t, manager.UpdateTaskStatus(t.Context(), task.ID, department.TaskStatusInProgress, nil))
+	require.Equal(t, member.ID, task.AssignedMember)
+
+	_, err = dc.waitForTaskCompletion(t.Context(), "session", task.ID, "prompt")
+	require.ErrorContains(t, err, "timed out after 50ms")
+
+	task, err = manager.GetTask(task.ID)
+	require.NoError(t, err)
+	require.Equal(t, department.TaskStatusFailed, task.Status)
+	require.Equal(t, "50ms", task.Results["timeout"])
+	require.Empty(t, member.CurrentTasks)
+	require.Equal(t, department.MemberStatusOnline, member.Status)
+}
+
+func TestDepartmentCoordinatorWaitUnderEventLoad(t *testing.T) {
+	t.Parallel()
+
+	dc := newTestDepartmentCoordinator(t, &department.DepartmentConfig{Enabled: true})
+	manager := dc.GetDepartmentManager()
+
+	// With no members the task stays queued until completed below
+	task, err := manager.CreateTask(t.Context(), &department.Task{ID: "watched", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+
+	type outcome struct {
+		result *fantasy.AgentResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := dc.waitForTaskCompletion(t.Context(), "session", task.ID, "prompt")
+		done <- outcome{result, err}
+	}()
+
+	// Flood the broker with unrelated task events while the coordinator waits
+	// Each flooder stops at its first error, checked once they are done
+	errs := make(chan error, 8)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			for j := range 100 {
+				_, err := manager.CreateTask(t.Context(), &department.Task{
+					ID:           fmt.Sprintf("noise-%d-%d", i, j),
+					DepartmentID: "dept-qa",
+				})
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, manager.UpdateTaskStatus(t.Context(), task.ID, department.TaskStatusCompleted, map[string]any{
+		"response": "all done",
+	}))
+
+	select {
+	case out := <-done:
+		require.NoError(t, out.err)
+		require.Equal(t, "all done", out.result.Response.Content.Text())
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitForTaskCompletion did not return")
+	}
+}
+
+func TestDepartmentCoordinatorRetriesFailedTask(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name     string
+		failures int
+		err      error
+		wantErr  string
+		attempts int
+	}{
+		{name: "succeeds on second attempt", failures: 1, err: errors.New("member dropped"), attempts: 2},
+		{name: "exhausts retries", failures: 3, err: errors.New("member dropped"), wantErr: "member dropped", attempts: 3},
+		{name: "permission denied is not retried", failures: 3, err: permission.ErrorPermissionDenied, wantErr: "permission denied", attempts: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dc := newTestDepartmentCoordinator(t, &department.DepartmentConfig{Enabled: true})
+			manager := dc.GetDepartmentManager()
+			for _, id := range []string{"dev-1", "dev-2"} {
+				require.NoError(t, manager.RegisterMember(t.Context(), &department.Member{
+					ID:            id,
+					Role:          department.RoleDeveloper,
+					DepartmentID:  "dept-dev",
+					MaxConcurrent: 1,
+				}))
+			}
+
+			var (
+				mu      sync.Mutex
+				members []string
+			)
+			dc.runTask = func(ctx context.Context, sessionID, prompt string, _ ...message.Attachment) (*fantasy.AgentResult, error) {
+				task, err := manager.GetTask("flaky")
+				require.NoError(t, err)
+
+				mu.Lock()
+				defer mu.Unlock()
+				members = append(members, task.AssignedMember)
+				if len(members) <= tt.failures {
+					return nil, tt.err
+				}
+				return &fantasy.AgentResult{Response: fantasy.Response{
+					Content: fantasy.ResponseContent{fantasy.TextContent{Text: "done"}},
+				}}, nil
+			}
+
+			task, err := manager.CreateTask(t.Context(), &department.Task{
+				ID:           "flaky",
+				DepartmentID: "dept-dev",
+				RetryPolicy:  &department.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+			})
+			require.NoError(t, err)
+
+			result, err := dc.waitForTaskCompletion(t.Context(), "session", task.ID, "prompt")
+			task, getErr := manager.GetTask(task.ID)
+			require.NoError(t, getErr)
+			require.Equal(t, tt.attempts, task.Attempts)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				require.Equal(t, department.TaskStatusFailed, task.Status)
+				require.Equal(t, tt.attempts, task.Results["attempt"])
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "done", result.Response.Content.Text())
+
+			// The retry ran on the other member
+			require.Len(t, members, 2)
+			require.NotEqual(t, members[0], members[1])
+		})
+	}
+}
+
+func TestDepartmentCoordinatorRejectsEmptyPrompt(t *testing.T) {
+	t.Parallel()
+
+	dc := newTestDepartmentCoordinator(t, &department.DepartmentConfig{Enabled: true})
+
+	for _, prompt := range []string{"", "  \n\t "} {
+		_, err := dc.runWithDepartmentRouting(t.Context(), "session-1", prompt)
+		require.ErrorIs(t, err, ErrEmptyPrompt)
+	}
+	require.Empty(t, dc.GetDepartmentManager().ListTasks("", ""))
+}
+
+func TestDepartmentCoordinatorRejectsOversizedPrompt(t *testing.T) {
+	t.Parallel()
+
+	dc := newTestDepartmentCoordinator(t, &department.DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: department.TaskRoutingConfig{MaxPromptTokens: 100},
+	})
+
+	// About 250 tokens against a limit of 100
+	prompt := strings.Repeat("fix the build ", 70)
+	_, err := dc.runWithDepartmentRouting(t.Context(), "session-1", prompt)
+	require.ErrorIs(t, err, department.ErrPromptTooLarge)
+	require.ErrorContains(t, err, "limit is 100")
+	require.Empty(t, dc.GetDepartmentManager().ListTasks("", ""))
+}
+
+func TestDepartmentCoordinatorSurfacesFullQueue(t *testing.T) {
+	t.Parallel()
+
+	dc := newTestDepartmentCoordinator(t, &department.DepartmentConfig{
+		Enabled:        true,
+		MaxQueuedTasks: 1,
+		TaskRouting:    department.TaskRoutingConfig{DefaultDepartment: "dept-dev"},
+	})
+	manager := dc.GetDepartmentManager()
+
+	// Nothing can run, so every department's queue is at its limit
+	for _, dept := range manager.ListDepartments() {
+		_, err := manager.CreateTask(t.Context(), &department.Task{DepartmentID: dept.ID})
+		require.NoError(t, err)
+	}
+
+	_, err := dc.runWithDepartmentRouting(t.Context(), "session-1", "fix the build")
+	require.ErrorIs(t, err, department.ErrQueueFull)
+}
+
+func TestExtractTaskTitle(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		prompt   string
+		expected string
+	}{
+		{"first line", "Add retries\nThe client gives up too early.", "Add retries"},
+		{"leading whitespace", "\n\n   \n  Add retries  \nmore", "Add retries"},
+		{"markdown header", "## Add retries to the client\n\nDetails", "Add retries to the client"},
+		{"quote marker", "> Add retries\n> to the client", "Add retries"},
+		{"code fence first", "```go\nfunc main() {}\n```\nWhy does this not compile?", "Why does this not compile?"},
+		{"only code", "```\nmake build\n```", "make build"},
+		{
+			"long single line",
+			"Implement the new authentication middleware for the gateway so every request carries a verified identity token downstream",
+			"Implement the new authentication middleware...",
+		},
+		{
+			"long question",
+			"Can you explain why the authentication middleware rejects every request that carries a refresh token in the header?",
+			"Can you explain why the authentication middleware rejects every request that carries a refresh token in the header?",
+		},
+		{"long word", strings.Repeat("x", 120), strings.Repeat("x", 47) + "..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			title := extractTaskTitle(tt.prompt)
+			require.Equal(t, tt.expected, title)
+			if !strings.HasSuffix(tt.expected, "?") {
+				require.LessOrEqual(t, len([]rune(title)), maxTitleLength)
+			}
+		})
+	}
+}
+
+func TestDepartmentCoordinatorLoadsSpilledAttachments(t *testing.T) {
+	t.Parallel()
+
+	dc := newTestDepartmentCoordinator(t, &department.DepartmentConfig{
+		Enabled:                  true,
+		AttachmentSpillThreshold: 4,
+		AttachmentDir:            t.TempDir(),
+		TaskRouting:              department.TaskRoutingConfig{DefaultDepartment: "dept-dev"},
+	})
+	manager := dc.GetDepartmentManager()
+	require.NoError(t, manager.RegisterMember(t.Context(), &department.Member{
+		ID:            "dev-1",
+		Role:          department.RoleDeveloper,
+		DepartmentID:  "dept-dev",
+		MaxConcurrent: 1,
+	}))
+
+	var received []message.Attachment
+	dc.runTask = func(ctx context.Context, sessionID, prompt string, attachments ...message.Attachment) (*fantasy.AgentResult, error) {
+		received = attachments
+		return &fantasy.AgentResult{}, nil
+	}
+
+	attachment := message.Attachment{FilePath: "/tmp/trace.log", FileName: "trace.log", MimeType: "text/plain", Content: []byte("stack trace")}
+	_, err := dc.runWithDepartmentRouting(t.Context(), "session-1", "fix the crash", attachment)
+	require.NoError(t, err)
+
+	// The task only kept a reference, but the member got the full content
+	tasks := manager.ListTasks("", "")
+	require.Len(t, tasks, 1)
+	require.Nil(t, tasks[0].Attachments[0].Content)
+	require.Equal(t, int64(len(attachment.Content)), tasks[0].Attachments[0].Size)
+	require.Equal(t, []message.Attachment{attachment}, received)
+}
+
+func TestDepartmentCoordinatorPreviewRequest(t *testing.T) {
+	t.Parallel()
+
+	dc := newTestDepartmentCoordinator(t, &department.DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: department.TaskRoutingConfig{DefaultDepartment: "dept-dev"},
+	})
+	manager := dc.GetDepartmentManager()
+	require.NoError(t, manager.RegisterMember(t.Context(), &department.Member{
+		ID:            "dev-1",
+		Role:          department.RoleDeveloper,
+		DepartmentID:  "dept-dev",
+		MaxConcurrent: 1,
+	}))
+
+	preview, err := dc.PreviewRequest(t.Context(), "fix the crash")
+	require.NoError(t, err)
+	require.Equal(t, "dept-dev", preview.DepartmentID)
+	require.Equal(t, "dev-1", preview.MemberID)
+
+	// Nothing was created or assigned
+	require.Empty(t, manager.ListTasks("", ""))
+	member, err := manager.GetMember("dev-1")
+	require.NoError(t, err)
+	require.Empty(t, member.CurrentTasks)
+
+	_, err = dc.PreviewRequest(t.Context(), " ")
+	require.ErrorIs(t, err, ErrEmptyPrompt)
+}
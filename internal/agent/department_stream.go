@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"context"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/eliasbui/ccl-magic/internal/department"
+	"github.com/eliasbui/ccl-magic/internal/message"
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+)
+
+// agentEventBuffer is how many events RunStream holds for a slow reader
+const agentEventBuffer = 64
+
+// AgentEventType identifies what an AgentEvent carries
+type AgentEventType string
+
+const (
+	// AgentEventProgress carries a progress update from the member running
+	// one of the request's tasks
+	AgentEventProgress AgentEventType = "progress"
+	// AgentEventResult carries the final result; it is the last event
+	AgentEventResult AgentEventType = "result"
+	// AgentEventError carries the error the request failed with; it is the
+	// last event
+	AgentEventError AgentEventType = "error"
+)
+
+// AgentEvent is one update streamed by RunStream
+type AgentEvent struct {
+	Type     AgentEventType
+	Progress *department.TaskProgress
+	Result   *fantasy.AgentResult
+	Err      error
+}
+
+// RunStream runs a request like Run but streams progress reported for the
+// session's tasks as it happens. The channel ends with a result or error
+// event and is then closed. Prompts routing cannot handle are rejected
+// before anything runs.
+func (dc *DepartmentCoordinator) RunStream(ctx context.Context, sessionID, prompt string, attachments ...message.Attachment) (<-chan AgentEvent, error) {
+	var progress <-chan pubsub.Event[*department.TaskProgress]
+	subCtx, unsubscribe := context.WithCancel(ctx)
+	if dc.departmentManager != nil {
+		if err := dc.checkPrompt(prompt); err != nil {
+			unsubscribe()
+			return nil, err
+		}
+		// Subscribe before the request starts so no update is missed
+		progress = dc.departmentManager.SubscribeToTaskProgress(subCtx)
+	}
+
+	done := make(chan AgentEvent, 1)
+	go func() {
+		result, err := dc.Run(ctx, sessionID, prompt, attachments...)
+		if err != nil {
+			done <- AgentEvent{Type: AgentEventError, Err: err}
+			return
+		}
+		done <- AgentEvent{Type: AgentEventResult, Result: result}
+	}()
+
+	events := make(chan AgentEvent, agentEventBuffer)
+	go func() {
+		defer close(events)
+		defer unsubscribe()
+
+		send := func(event AgentEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		forward := func(event pubsub.Event[*department.TaskProgress]) bool {
+			if event.Payload == nil || !dc.isSessionTask(event.Payload.TaskID, sessionID) {
+				return true
+			}
+			return send(AgentEvent{Type: AgentEventProgress, Progress: event.Payload})
+		}
+
+		for {
+			select {
+			case event, ok := <-progress:
+				if !ok {
+					progress = nil
+					continue
+				}
+				if !forward(event) {
+					return
+				}
+			case final := <-done:
+				// Updates reported before the request finished come first
+				for drained := false; !drained; {
+					select {
+					case event, ok := <-progress:
+						if !ok || !forward(event) {
+							drained = true
+						}
+					default:
+						drained = true
+					}
+				}
+				send(final)
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// isSessionTask reports whether the task was requested from the session
+func (dc *DepartmentCoordinator) isSessionTask(taskID, sessionID string) bool {
+	task, err := dc.departmentManager.GetTask(taskID)
+	return err == nil && task.SessionID == sessionID
+}
+
+// reportMessageProgress reports the text and reasoning the session's
+// assistant messages gain as progress on the task, until ctx ends. Messages
+// are published whole on every update, so only what was added since the
+// last update is reported.
+func (dc *DepartmentCoordinator) reportMessageProgress(ctx context.Context, sessionID, taskID string) {
+	if dc.coordinator == nil || dc.messages == nil {
+		return
+	}
+
+	type seen struct{ content, reasoning string }
+	last := make(map[string]seen)
+	for event := range dc.messages.Subscribe(ctx) {
+		msg := event.Payload
+		if msg.SessionID != sessionID || msg.Role != message.Assistant {
+			continue
+		}
+
+		current := seen{content: msg.Content().Text, reasoning: msg.ReasoningContent().Thinking}
+		previous := last[msg.ID]
+		last[msg.ID] = current
+		update := department.TaskProgress{
+			Content:   addedText(previous.content, current.content),
+			Reasoning: addedText(previous.reasoning, current.reasoning),
+		}
+		if update.Content == "" && update.Reasoning == "" {
+			continue
+		}
+		// The task may already have finished; later updates are dropped
+		_ = dc.departmentManager.ReportTaskProgress(taskID, update)
+	}
+}
+
+// addedText returns what current adds to previous, or all of current when
+// it does not extend previous
+func addedText(previous, current string) string {
+	if added, ok := strings.CutPrefix(current, previous); ok {
+		return added
+	}
+	return current
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/eliasbui/ccl-magic/internal/department"
+	"github.com/eliasbui/ccl-magic/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDepartmentCoordinatorRunStream(t *testing.T) {
+	t.Parallel()
+
+	dc := newTestDepartmentCoordinator(t, &department.DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: department.TaskRoutingConfig{DefaultDepartment: "dept-dev"},
+	})
+	manager := dc.GetDepartmentManager()
+	require.NoError(t, manager.RegisterMember(t.Context(), &department.Member{
+		ID:            "dev-1",
+		Role:          department.RoleDeveloper,
+		DepartmentID:  "dept-dev",
+		MaxConcurrent: 1,
+	}))
+
+	dc.runTask = func(ctx context.Context, sessionID, prompt string, _ ...message.Attachment) (*fantasy.AgentResult, error) {
+		tasks := manager.ListTasks("dept-dev", department.TaskStatusInProgress)
+		require.Len(t, tasks, 1)
+		for _, chunk := range []string{"thinking", " harder"} {
+			require.NoError(t, manager.ReportTaskProgress(tasks[0].ID, department.TaskProgress{Content: chunk}))
+		}
+		return &fantasy.AgentResult{Response: fantasy.Response{
+			Content: fantasy.ResponseContent{fantasy.TextContent{Text: "done"}},
+		}}, nil
+	}
+
+	events, err := dc.RunStream(t.Context(), "session", "hello there")
+	require.NoError(t, err)
+
+	var got []AgentEvent
+	timeout := time.After(10 * time.Second)
+	for open := true; open; {
+		select {
+		case event, ok := <-events:
+			if ok {
+				got = append(got, event)
+			}
+			open = ok
+		case <-timeout:
+			t.Fatal("stream did not finish")
+		}
+	}
+
+	require.Len(t, got, 3)
+	require.Equal(t, AgentEventProgress, got[0].Type)
+	require.Equal(t, "thinking", got[0].Progress.Content)
+	require.Equal(t, " harder", got[1].Progress.Content)
+	require.Equal(t, AgentEventResult, got[2].Type)
+	require.Equal(t, "done", got[2].Result.Response.Content.Text())
+}
+
+func TestDepartmentCoordinatorRunStreamRejectsEmptyPrompt(t *testing.T) {
+	t.Parallel()
+
+	dc := newTestDepartmentCoordinator(t, &department.DepartmentConfig{Enabled: true})
+	_, err := dc.RunStream(t.Context(), "session", "  ")
+	require.ErrorIs(t, err, ErrEmptyPrompt)
+}
+
+func TestAddedText(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, " world", addedText("hello", "hello world"))
+	require.Equal(t, "", addedText("hello", "hello"))
+	require.Equal(t, "rewritten", addedText("hello", "rewritten"))
+}
@@ -54,6 +54,12 @@ func Load(workingDir, dataDir string, debug bool) (*Config, error) {
 
 	cfg.setDefaults(workingDir, dataDir)
 
+	if cfg.Department != nil {
+		if err := cfg.Department.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid department config: %w", err)
+		}
+	}
+
 	if debug {
 		cfg.Options.Debug = true
 	}
@@ -0,0 +1,54 @@
+package department
+
+import "time"
+
+// affinity records the member that last handled a task with an affinity
+// key, and until when related tasks prefer it
+type affinity struct {
+	memberID  string
+	expiresAt time.Time
+}
+
+// affinityMember returns the candidate that last handled a task with the
+// same affinity key, if its mapping has not expired. The caller must hold
+// the manager lock; a read lock will do, as expired mappings are left for
+// recordAffinity to sweep.
+func (tr *TaskRouter) affinityMember(task *Task, candidates []*Member) *Member {
+	if task.AffinityKey == "" || tr.config.AffinityTTL <= 0 {
+		return nil
+	}
+
+	entry, ok := tr.affinities[task.AffinityKey]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil
+	}
+	for _, member := range candidates {
+		if member.ID == entry.memberID {
+			return member
+		}
+	}
+	return nil
+}
+
+// recordAffinity maps the task's affinity key to member for AffinityTTL.
+// Expired mappings are swept at most once per TTL so keys that are never
+// used again do not pile up. The caller must hold the manager lock.
+func (tr *TaskRouter) recordAffinity(task *Task, member *Member) {
+	if task.AffinityKey == "" || tr.config.AffinityTTL <= 0 {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(tr.affinitiesSwept) >= tr.config.AffinityTTL {
+		for key, entry := range tr.affinities {
+			if now.After(entry.expiresAt) {
+				delete(tr.affinities, key)
+			}
+		}
+		tr.affinitiesSwept = now
+	}
+	tr.affinities[task.AffinityKey] = affinity{
+		memberID:  member.ID,
+		expiresAt: now.Add(tr.config.AffinityTTL),
+	}
+}
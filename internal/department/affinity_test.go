@@ -0,0 +1,71 @@
+package department
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskRouterAffinity(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{AffinityTTL: time.Hour},
+	})
+	require.NoError(t, err)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+	registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 2)
+
+	first, err := m.CreateTask(ctx, &Task{ID: "first", DepartmentID: "dept-dev", AffinityKey: "session-1"})
+	require.NoError(t, err)
+	sticky := first.AssignedMember
+	require.NotEmpty(t, sticky)
+
+	// Load-based routing would pick the idle member, but the follow-up
+	// stays with the member that has the session's context
+	followUp, err := m.CreateTask(ctx, &Task{ID: "follow-up", DepartmentID: "dept-dev", AffinityKey: "session-1"})
+	require.NoError(t, err)
+	require.Equal(t, sticky, followUp.AssignedMember)
+	require.Contains(t, followUp.RoutingDecision.MemberReason, `affinity key "session-1"`)
+
+	// A full member is passed over and the key moves to the new member
+	third, err := m.CreateTask(ctx, &Task{ID: "third", DepartmentID: "dept-dev", AffinityKey: "session-1"})
+	require.NoError(t, err)
+	require.NotEqual(t, sticky, third.AssignedMember)
+	require.NotEmpty(t, third.AssignedMember)
+
+	m.mu.RLock()
+	require.Equal(t, third.AssignedMember, m.taskRouter.affinities["session-1"].memberID)
+	m.mu.RUnlock()
+}
+
+func TestTaskRouterAffinityExpires(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{AffinityTTL: time.Hour},
+	})
+	require.NoError(t, err)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 3)
+	registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 3)
+
+	first, err := m.CreateTask(ctx, &Task{ID: "first", DepartmentID: "dept-dev", AffinityKey: "session-1"})
+	require.NoError(t, err)
+
+	m.mu.Lock()
+	entry := m.taskRouter.affinities["session-1"]
+	entry.expiresAt = time.Now().Add(-time.Second)
+	m.taskRouter.affinities["session-1"] = entry
+	m.mu.Unlock()
+
+	// With the mapping expired, load-based routing picks the idle member
+	second, err := m.CreateTask(ctx, &Task{ID: "second", DepartmentID: "dept-dev", AffinityKey: "session-1"})
+	require.NoError(t, err)
+	require.NotEqual(t, first.AssignedMember, second.AssignedMember)
+}
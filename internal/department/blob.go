@@ -0,0 +1,166 @@
+package department
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// BlobStore keeps attachment content that is too large to hold in memory for
+// the life of a task
+type BlobStore interface {
+	// Put stores data under key and returns the URL it can be read back from
+	Put(ctx context.Context, key string, data []byte) (string, error)
+	// Get returns the data stored at a URL returned by Put
+	Get(ctx context.Context, url string) ([]byte, error)
+	// Delete removes the data stored at a URL returned by Put. Deleting data
+	// that is already gone is not an error.
+	Delete(ctx context.Context, url string) error
+}
+
+// WithBlobStore makes the manager spill attachment content larger than
+// AttachmentSpillThreshold to store instead of the default file store
+func WithBlobStore(store BlobStore) ManagerOption {
+	return func(m *Manager) {
+		m.blobs = store
+	}
+}
+
+// defaultAttachmentDir is where spilled attachments go without an
+// AttachmentDir
+var defaultAttachmentDir = filepath.Join(os.TempDir(), "ccl-magic-attachments")
+
+// FileBlobStore stores each blob as a file in a directory and refers to it
+// by file URL
+type FileBlobStore struct {
+	dir string
+}
+
+// NewFileBlobStore creates a blob store that writes to dir, creating it when
+// the first blob is stored
+func NewFileBlobStore(dir string) *FileBlobStore {
+	return &FileBlobStore{dir: dir}
+}
+
+// Put writes data to a file named after key
+func (s *FileBlobStore) Put(ctx context.Context, key string, data []byte) (string, error) {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create blob dir: %w", err)
+	}
+	path := filepath.Join(s.dir, url.PathEscape(key))
+	if err := writeFileAtomic(path, data); err != nil {
+		return "", fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(), nil
+}
+
+// Get reads the file behind a URL returned by Put. URLs outside the store's
+// directory are rejected.
+func (s *FileBlobStore) Get(ctx context.Context, rawURL string) ([]byte, error) {
+	path, err := s.path(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	return data, nil
+}
+
+// Delete removes the file behind a URL returned by Put. URLs outside the
+// store's directory are rejected.
+func (s *FileBlobStore) Delete(ctx context.Context, rawURL string) error {
+	path, err := s.path(rawURL)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	return nil
+}
+
+// path returns the file behind a blob URL, checking that it is inside the
+// store's directory
+func (s *FileBlobStore) path(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "file" {
+		return "", fmt.Errorf("not a file blob URL: %q", rawURL)
+	}
+	dir, err := filepath.Abs(s.dir)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.FromSlash(u.Path)
+	if rel, err := filepath.Rel(dir, path); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("blob %q is outside %s", rawURL, s.dir)
+	}
+	return path, nil
+}
+
+// spillAttachments returns a copy of task's attachments with the size of
+// every attachment filled in and the content of those larger than
+// AttachmentSpillThreshold moved to the blob store, leaving a URL in its
+// place. The task itself is left alone, so it can be submitted again if it
+// is rejected. It does I/O, so it runs without the manager lock.
+func (m *Manager) spillAttachments(ctx context.Context, task *Task) ([]TaskAttachment, error) {
+	threshold := m.config.AttachmentSpillThreshold
+	attachments := slices.Clone(task.Attachments)
+	for i := range attachments {
+		att := &attachments[i]
+		if att.Content != nil {
+			att.Size = int64(len(att.Content))
+		}
+		if m.blobs == nil || threshold <= 0 || att.Size <= threshold || att.Content == nil {
+			continue
+		}
+
+		blobURL, err := m.blobs.Put(ctx, fmt.Sprintf("%s-%d", task.ID, i), att.Content)
+		if err != nil {
+			m.deleteAttachments(ctx, attachments[:i])
+			return nil, fmt.Errorf("failed to store attachment %s: %w", att.Name, err)
+		}
+		att.URL = blobURL
+		att.Spilled = true
+		att.Content = nil
+	}
+	return attachments, nil
+}
+
+// deleteAttachments removes the blobs spilled for attachments of a task that
+// was rejected or removed. It does I/O, so callers holding the manager lock
+// run it in a goroutine.
+func (m *Manager) deleteAttachments(ctx context.Context, attachments []TaskAttachment) {
+	for _, att := range attachments {
+		if !att.Spilled || m.blobs == nil {
+			continue
+		}
+		if err := m.blobs.Delete(ctx, att.URL); err != nil {
+			slog.Warn("Failed to delete spilled attachment", "url", att.URL, "error", err)
+		}
+	}
+}
+
+// AttachmentContent returns the content of a task attachment, reading it
+// from the blob store when it was spilled there
+func (m *Manager) AttachmentContent(ctx context.Context, att TaskAttachment) ([]byte, error) {
+	if att.Content != nil || att.URL == "" {
+		return att.Content, nil
+	}
+	if m.blobs == nil {
+		return nil, fmt.Errorf("attachment %s is stored at %s but no blob store is configured", att.Name, att.URL)
+	}
+	return m.blobs.Get(ctx, att.URL)
+}
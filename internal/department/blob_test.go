@@ -0,0 +1,112 @@
+package department
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerSpillsLargeAttachments(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	dir := t.TempDir()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:                  true,
+		AttachmentSpillThreshold: 8,
+		AttachmentDir:            dir,
+	})
+	require.NoError(t, err)
+
+	large := bytes.Repeat([]byte("x"), 64)
+	task, err := m.CreateTask(ctx, &Task{
+		DepartmentID: "dept-dev",
+		Attachments: []TaskAttachment{
+			{ID: "small", Name: "small.txt", Content: []byte("tiny")},
+			{ID: "large", Name: "large.bin", Content: large},
+		},
+	})
+	require.NoError(t, err)
+
+	// Small content stays in memory; large content is replaced by a URL but
+	// keeps its size
+	small, spilled := task.Attachments[0], task.Attachments[1]
+	require.Equal(t, int64(4), small.Size)
+	require.Empty(t, small.URL)
+	require.Nil(t, spilled.Content)
+	require.Equal(t, int64(64), spilled.Size)
+	require.Contains(t, spilled.URL, "file://")
+
+	for att, want := range map[*TaskAttachment][]byte{&small: []byte("tiny"), &spilled: large} {
+		content, err := m.AttachmentContent(ctx, *att)
+		require.NoError(t, err)
+		require.Equal(t, want, content)
+	}
+
+	_, err = NewFileBlobStore(dir).Get(ctx, "file:///etc/passwd")
+	require.ErrorContains(t, err, "is outside")
+}
+
+func TestManagerDeletesSpilledAttachments(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	dir := t.TempDir()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:                  true,
+		AttachmentSpillThreshold: 8,
+		AttachmentDir:            dir,
+	})
+	require.NoError(t, err)
+
+	blobs := func() []os.DirEntry {
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		require.NoError(t, err)
+		return entries
+	}
+	large := bytes.Repeat([]byte("x"), 64)
+
+	// A rejected task spills nothing and keeps its content
+	rejected := &Task{DepartmentID: "dept-missing", Attachments: []TaskAttachment{{ID: "large", Content: large}}}
+	_, err = m.CreateTask(ctx, rejected)
+	require.ErrorContains(t, err, "does not exist")
+	_, err = m.StartWorkflow(ctx, "missing-flow", rejected)
+	require.ErrorContains(t, err, "does not exist")
+	require.Empty(t, blobs())
+	require.Equal(t, large, rejected.Attachments[0].Content)
+
+	task, err := m.CreateTask(ctx, &Task{DepartmentID: "dept-dev", Attachments: []TaskAttachment{{ID: "large", Content: large}}})
+	require.NoError(t, err)
+	require.True(t, task.Attachments[0].Spilled)
+	require.Len(t, blobs(), 1)
+
+	// Attachments that only refer to content elsewhere are not the store's
+	// to delete
+	external := filepath.Join(dir, "external")
+	require.NoError(t, os.WriteFile(external, []byte("keep"), 0o600))
+	_, err = m.CreateTask(ctx, &Task{DepartmentID: "dept-dev", Attachments: []TaskAttachment{{ID: "ref", URL: "file://" + filepath.ToSlash(external)}}})
+	require.NoError(t, err)
+
+	// Importing state without the tasks removes their blobs
+	doc, err := m.ExportState()
+	require.NoError(t, err)
+	doc.Tasks = nil
+	require.NoError(t, m.ImportState(doc))
+	require.Eventually(t, func() bool {
+		entries := blobs()
+		return len(entries) == 1 && entries[0].Name() == "external"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	store := NewFileBlobStore(dir)
+	require.NoError(t, store.Delete(ctx, task.Attachments[0].URL))
+	require.ErrorContains(t, store.Delete(ctx, "file:///etc/passwd"), "is outside")
+}
@@ -0,0 +1,151 @@
+package department
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Task types a request can be classified as. Workflows are looked up by
+// task type.
+const (
+	TaskTypeBugFix             = "bug_fix"
+	TaskTypeFeatureDevelopment = "feature_development"
+	TaskTypeTesting            = "testing"
+	TaskTypeDeployment         = "deployment"
+	TaskTypeSecurity           = "security"
+	TaskTypeGeneral            = "general"
+)
+
+// taskTypes lists every task type a classifier may return
+var taskTypes = []string{
+	TaskTypeBugFix, TaskTypeFeatureDevelopment, TaskTypeTesting,
+	TaskTypeDeployment, TaskTypeSecurity, TaskTypeGeneral,
+}
+
+// Classification is what a classifier decided about a request
+type Classification struct {
+	Type     string   `json:"type"`
+	Priority Priority `json:"priority"`
+	Skills   []string `json:"skills"`
+}
+
+// Validate checks that the classification names a known task type and
+// priority
+func (c Classification) Validate() error {
+	if !slices.Contains(taskTypes, c.Type) {
+		return fmt.Errorf("unknown task type %q", c.Type)
+	}
+	if c.Priority.Rank() == 0 {
+		return fmt.Errorf("unknown priority %q", c.Priority)
+	}
+	return nil
+}
+
+// Classifier decides the task type, priority and required skills of a
+// request from its prompt
+type Classifier interface {
+	Classify(ctx context.Context, prompt string) (Classification, error)
+}
+
+// KeywordClassifier classifies requests by the keywords they contain. It
+// never fails, which makes it the fallback for smarter classifiers. The zero
+// value only knows the default skill aliases.
+type KeywordClassifier struct {
+	skills *skillMatcher
+}
+
+// NewKeywordClassifier creates a keyword classifier that also recognizes
+// skills by the given aliases, such as TaskRoutingConfig.SkillAliases
+func NewKeywordClassifier(skillAliases map[string][]string) KeywordClassifier {
+	return KeywordClassifier{skills: newSkillMatcher(skillAliases)}
+}
+
+// Classify implements Classifier
+func (c KeywordClassifier) Classify(_ context.Context, prompt string) (Classification, error) {
+	skills := c.skills
+	if skills == nil {
+		skills = keywordSkillMatcher
+	}
+
+	prompt = strings.ToLower(prompt)
+	return Classification{
+		Type:     keywordTaskType(prompt),
+		Priority: keywordPriority(prompt),
+		Skills:   keywordSkills(prompt, skills),
+	}, nil
+}
+
+func keywordTaskType(prompt string) string {
+	if strings.Contains(prompt, "bug") || strings.Contains(prompt, "fix") {
+		return TaskTypeBugFix
+	}
+	if strings.Contains(prompt, "feature") || strings.Contains(prompt, "implement") {
+		return TaskTypeFeatureDevelopment
+	}
+	if strings.Contains(prompt, "test") || strings.Contains(prompt, "qa") {
+		return TaskTypeTesting
+	}
+	if strings.Contains(prompt, "deploy") || strings.Contains(prompt, "release") {
+		return TaskTypeDeployment
+	}
+	if strings.Contains(prompt, "security") || strings.Contains(prompt, "vulnerability") {
+		return TaskTypeSecurity
+	}
+
+	return TaskTypeGeneral
+}
+
+func keywordPriority(prompt string) Priority {
+	if strings.Contains(prompt, "urgent") || strings.Contains(prompt, "critical") || strings.Contains(prompt, "asap") {
+		return PriorityCritical
+	}
+	if strings.Contains(prompt, "high") || strings.Contains(prompt, "important") {
+		return PriorityHigh
+	}
+	if strings.Contains(prompt, "low") || strings.Contains(prompt, "minor") {
+		return PriorityLow
+	}
+
+	return PriorityMedium
+}
+
+// keywordSkillMatcher recognizes skills mentioned in a prompt by their
+// default names and aliases
+var keywordSkillMatcher = newSkillMatcher(nil)
+
+// keywordSkills returns the known skills a prompt mentions. Words are also
+// tried without a plural or verb ending, so "containers" and "tests" count.
+func keywordSkills(prompt string, matcher *skillMatcher) []string {
+	var skills []string
+	for _, word := range skillWords(prompt) {
+		for _, stem := range wordStems(word) {
+			skill, known := matcher.lookup(stem)
+			if !known {
+				continue
+			}
+			if !slices.Contains(skills, skill) {
+				skills = append(skills, skill)
+			}
+			break
+		}
+	}
+	slices.Sort(skills)
+	return skills
+}
+
+// wordStems returns a lowercased word followed by the forms left after
+// stripping a simple English plural or verb ending from it
+func wordStems(word string) []string {
+	stems := []string{word}
+	for _, suffix := range []struct{ from, to string }{
+		{"ies", "y"}, {"es", ""}, {"s", ""}, {"ing", ""}, {"ed", ""},
+	} {
+		// Short stems are left alone so "goes" and "going" are not "go"
+		if stem, ok := strings.CutSuffix(word, suffix.from); ok && len(stem) >= 3 {
+			stems = append(stems, stem+suffix.to)
+		}
+	}
+	return stems
+}
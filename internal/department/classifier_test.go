@@ -0,0 +1,71 @@
+package department
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeywordClassifier(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		prompt   string
+		expected Classification
+	}{
+		{"Fix the login bug, it's urgent", Classification{Type: TaskTypeBugFix, Priority: PriorityCritical}},
+		{"Implement dark mode in the node app", Classification{Type: TaskTypeFeatureDevelopment, Priority: PriorityMedium, Skills: []string{"javascript"}}},
+		{"Deploy the docker image to k8s", Classification{Type: TaskTypeDeployment, Priority: PriorityMedium, Skills: []string{"docker", "kubernetes"}}},
+		{"Write a summary of the meeting", Classification{Type: TaskTypeGeneral, Priority: PriorityMedium}},
+	}
+	for _, tt := range tests {
+		classification, err := KeywordClassifier{}.Classify(t.Context(), tt.prompt)
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, classification, tt.prompt)
+		require.NoError(t, classification.Validate())
+	}
+}
+
+func TestKeywordClassifierSkillForms(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		prompt string
+		skills []string
+	}{
+		{"Restart the containers", []string{"docker"}},
+		{"Add tests for the parser", []string{"testing"}},
+		{"Tested it on staging", []string{"testing"}},
+		{"Audit the vulnerabilities", []string{"security"}},
+		{"Move the nodes to k8s", []string{"javascript", "kubernetes"}},
+		{"The build goes green when going slow", nil},
+	}
+	for _, tt := range tests {
+		classification, err := KeywordClassifier{}.Classify(t.Context(), tt.prompt)
+		require.NoError(t, err)
+		require.Equal(t, tt.skills, classification.Skills, tt.prompt)
+	}
+}
+
+func TestKeywordClassifierSkillAliases(t *testing.T) {
+	t.Parallel()
+
+	prompt := "Update the tf modules"
+
+	classification, err := KeywordClassifier{}.Classify(t.Context(), prompt)
+	require.NoError(t, err)
+	require.Empty(t, classification.Skills)
+
+	classifier := NewKeywordClassifier(map[string][]string{"terraform": {"tf", "module"}})
+	classification, err = classifier.Classify(t.Context(), prompt)
+	require.NoError(t, err)
+	require.Equal(t, []string{"terraform"}, classification.Skills)
+}
+
+func TestClassificationValidate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, Classification{Type: TaskTypeSecurity, Priority: PriorityHigh}.Validate())
+	require.ErrorContains(t, Classification{Type: "refactor", Priority: PriorityHigh}.Validate(), "unknown task type")
+	require.ErrorContains(t, Classification{Type: TaskTypeSecurity, Priority: "asap"}.Validate(), "unknown priority")
+}
@@ -0,0 +1,240 @@
+package department
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// coldStartReason is the scaling reason of members started for a department
+// scaled to zero
+const coldStartReason = "cold_start"
+
+// defaultColdStartTimeout bounds the wait for a cold-started member to pass
+// its first health check when no ColdStartTimeout is configured
+const defaultColdStartTimeout = 2 * time.Minute
+
+// coldStartPollInterval is how often a cold-started member is probed while
+// waiting for it to become healthy
+const coldStartPollInterval = 250 * time.Millisecond
+
+// RequestColdStart starts a member for a department scaled to zero. It
+// returns immediately; the member is registered once it passes a health
+// check, and the department's queued tasks are then routed to it. A cold
+// start held back by MaxConcurrentLaunches stays under way and resumes once a
+// launch slot frees up. It returns false if no cold start was begun, for
+// example because one is already running for the department or no member
+// launcher is configured.
+func (as *AutoScaler) RequestColdStart(dept Department) bool {
+	if as.manager.launcher == nil {
+		return false
+	}
+
+	as.coldStartMu.Lock()
+	defer as.coldStartMu.Unlock()
+
+	if as.ctx.Err() != nil || as.coldStarting[dept.ID] {
+		return false
+	}
+	as.coldStarting[dept.ID] = true
+
+	as.goColdStart(&dept)
+	return true
+}
+
+// goColdStart runs a cold start in the background so that Stop waits for it.
+// The caller must hold as.mu or as.coldStartMu and have checked that the
+// scaler has not stopped.
+func (as *AutoScaler) goColdStart(dept *Department) {
+	as.coldStarts.Add(1)
+	go func() {
+		defer as.coldStarts.Done()
+		as.coldStart(dept)
+	}()
+}
+
+// coldStart launches a member, waits for it to become healthy, registers it
+// and routes the department's queued tasks
+func (as *AutoScaler) coldStart(dept *Department) {
+	deferred := false
+	defer func() {
+		if deferred {
+			return
+		}
+		as.coldStartMu.Lock()
+		delete(as.coldStarting, dept.ID)
+		as.coldStartMu.Unlock()
+	}()
+
+	as.mu.Lock()
+	if as.ctx.Err() != nil {
+		as.mu.Unlock()
+		return
+	}
+	if !as.beginLaunch() {
+		// Resumed by endLaunch, so the task that triggered it is not left
+		// waiting with nothing to retry it
+		as.deferredColdStarts[dept.ID] = dept
+		deferred = true
+		as.mu.Unlock()
+		slog.Info("Cold start deferred at max concurrent launches", "department", dept.ID)
+		return
+	}
+	var member *Member
+	defer func() {
+		as.mu.Lock()
+		as.endLaunch()
+		if member != nil {
+			delete(as.launchingMembers, member.ID)
+		}
+		as.mu.Unlock()
+	}()
+	if role := as.determineRoleToAdd(dept); role != "" {
+		member = as.newScaledMember(dept, role, coldStartReason)
+	}
+	as.mu.Unlock()
+	if member == nil {
+		slog.Info("Cannot determine role to cold start", "department", dept.ID)
+		return
+	}
+
+	slog.Info("Cold starting member for department scaled to zero",
+		"department", dept.ID,
+		"member_id", member.ID,
+		"role", string(member.Role))
+
+	if err := as.launchMember(member); err != nil {
+		slog.Error("Failed to launch cold-started member",
+			"department", dept.ID,
+			"error", err)
+		return
+	}
+	if err := as.waitUntilHealthy(member); err != nil {
+		slog.Error("Cold-started member did not become healthy",
+			"department", dept.ID,
+			"member_id", member.ID,
+			"error", err)
+		as.terminateMember(member.ID)
+		return
+	}
+
+	// Checked under the scaler lock so a member is never registered once
+	// Stop has gone past it
+	as.mu.Lock()
+	if as.ctx.Err() != nil {
+		as.mu.Unlock()
+		slog.Info("Auto-scaler stopped before cold-started member was registered",
+			"department", dept.ID,
+			"member_id", member.ID)
+		as.terminateMember(member.ID)
+		return
+	}
+	before := len(as.manager.ListMembers(dept.ID))
+	err := as.manager.RegisterMember(context.Background(), member)
+	as.mu.Unlock()
+	if err != nil {
+		slog.Error("Failed to register cold-started member",
+			"department", dept.ID,
+			"member_id", member.ID,
+			"error", err)
+		as.terminateMember(member.ID)
+		return
+	}
+
+	as.manager.mu.Lock()
+	routed := as.manager.rerouteQueuedTasks(as.ctx, dept.ID)
+	if routed > 0 {
+		as.manager.persist()
+	}
+	as.manager.mu.Unlock()
+
+	slog.Info("Cold-started member ready",
+		"department", dept.ID,
+		"member_id", member.ID,
+		"routed_tasks", routed)
+
+	now := time.Now()
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.lastScaleTime[dept.ID] = now
+	as.lastScaleAction[dept.ID] = scaleUp
+	as.recordScalingEvent(ScalingEvent{
+		DepartmentID:  dept.ID,
+		Action:        scaleUp,
+		MemberID:      member.ID,
+		Role:          member.Role,
+		Reason:        coldStartReason,
+		MembersBefore: before,
+		MembersAfter:  len(as.manager.ListMembers(dept.ID)),
+		Timestamp:     now,
+	})
+}
+
+// waitUntilHealthy probes a member until it passes a health check or the
+// cold start timeout runs out
+func (as *AutoScaler) waitUntilHealthy(member *Member) error {
+	checker := as.manager.healthChecker
+	if checker == nil {
+		checker = NewHealthChecker(as.manager.config.HealthCheck, as.manager)
+		defer checker.Stop()
+	}
+
+	timeout := as.config.ColdStartTimeout
+	if timeout <= 0 {
+		timeout = defaultColdStartTimeout
+	}
+	ctx, cancel := context.WithTimeout(as.ctx, timeout)
+	defer cancel()
+
+	// Probe a copy so the probes don't race with updates to the member
+	as.manager.mu.RLock()
+	member = member.clone()
+	as.manager.mu.RUnlock()
+
+	ticker := time.NewTicker(coldStartPollInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		healthy, _, err := checker.probeMember(ctx, member)
+		if healthy {
+			return nil
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("not healthy after %s: %w", timeout, errors.Join(ctx.Err(), lastErr))
+		case <-ticker.C:
+		}
+	}
+}
+
+// coldStartFor asks the auto-scaler to start a member when a task arrives
+// for a department that has scaled to zero. It reports whether a cold start
+// is under way, in which case the task stays queued until the member is
+// ready. The caller must hold the manager lock.
+func (tr *TaskRouter) coldStartFor(task *Task) bool {
+	scaler := tr.manager.scaler
+	dept, exists := tr.manager.departments[task.DepartmentID]
+	if scaler == nil || !exists || !dept.ScaleToZero || dept.Disabled {
+		return false
+	}
+	for _, member := range tr.manager.listMembers(dept.ID) {
+		if isAvailable(member) {
+			return false
+		}
+	}
+
+	if scaler.RequestColdStart(*dept) {
+		return true
+	}
+
+	// A cold start already under way will route the task too
+	scaler.coldStartMu.Lock()
+	defer scaler.coldStartMu.Unlock()
+	return scaler.coldStarting[dept.ID]
+}
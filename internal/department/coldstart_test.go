@@ -0,0 +1,209 @@
+package department
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// healthyLauncher launches members that all answer health checks from the
+// same test server
+type healthyLauncher struct {
+	mu         sync.Mutex
+	endpoint   string
+	launched   []MemberSpec
+	terminated []string
+}
+
+func (l *healthyLauncher) Launch(ctx context.Context, spec MemberSpec) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.launched = append(l.launched, spec)
+	return l.endpoint, nil
+}
+
+func (l *healthyLauncher) Terminate(ctx context.Context, memberID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.terminated = append(l.terminated, memberID)
+	return nil
+}
+
+func TestTaskRouterColdStartsDepartmentScaledToZero(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	launcher := &healthyLauncher{endpoint: server.URL}
+	m, err := NewManager(t.Context(), &DepartmentConfig{
+		Enabled: true,
+		AutoScaling: AutoScalingConfig{
+			Enabled:          true,
+			CheckInterval:    time.Hour,
+			ColdStartTimeout: 5 * time.Second,
+		},
+		HealthCheck: HealthCheckConfig{Timeout: time.Second},
+	}, WithMemberLauncher(launcher))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, m.Stop()) })
+
+	m.mu.Lock()
+	security := m.departments["dept-security"]
+	security.ScaleToZero = true
+	security.MinMembers = 0
+	m.mu.Unlock()
+
+	task, err := m.CreateTask(t.Context(), &Task{ID: "scan", DepartmentID: "dept-security"})
+	require.NoError(t, err)
+
+	// The scaling event is recorded once the queued task has been routed
+	require.Eventually(t, func() bool {
+		return len(m.scaler.GetScalingHistory(0)) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	events := m.scaler.GetScalingHistory(0)
+	require.Len(t, events, 1)
+	require.Equal(t, coldStartReason, events[0].Reason)
+
+	stored, err := m.GetTask(task.ID)
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusAssigned, stored.Status)
+
+	members := m.ListMembers("dept-security")
+	require.Len(t, members, 1)
+	require.Equal(t, members[0].ID, stored.AssignedMember)
+	require.Equal(t, server.URL, members[0].Endpoint)
+	require.Len(t, launcher.launched, 1)
+
+	// Once idle, the last member can be removed, lead or not
+	require.NoError(t, m.UpdateTaskStatus(t.Context(), task.ID, TaskStatusCompleted, nil))
+	dept, err := m.GetDepartment("dept-security")
+	require.NoError(t, err)
+	candidate := m.scaler.findScaleDownCandidate(dept)
+	require.NotNil(t, candidate)
+	require.Equal(t, members[0].ID, candidate.ID)
+}
+
+func TestTaskRouterResumesColdStartDeferredAtLaunchCap(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	m, err := NewManager(t.Context(), &DepartmentConfig{
+		Enabled: true,
+		AutoScaling: AutoScalingConfig{
+			Enabled:               true,
+			CheckInterval:         time.Hour,
+			ColdStartTimeout:      5 * time.Second,
+			MaxConcurrentLaunches: 1,
+		},
+		HealthCheck: HealthCheckConfig{Timeout: time.Second},
+	}, WithMemberLauncher(&healthyLauncher{endpoint: server.URL}))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, m.Stop()) })
+
+	m.mu.Lock()
+	security := m.departments["dept-security"]
+	security.ScaleToZero = true
+	security.MinMembers = 0
+	m.mu.Unlock()
+
+	// Another launch holds the only slot
+	m.scaler.mu.Lock()
+	require.True(t, m.scaler.beginLaunch())
+	m.scaler.mu.Unlock()
+
+	task, err := m.CreateTask(t.Context(), &Task{ID: "scan", DepartmentID: "dept-security"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		m.scaler.mu.Lock()
+		defer m.scaler.mu.Unlock()
+		return m.scaler.deferredColdStarts["dept-security"] != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	stored, err := m.GetTask(task.ID)
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, stored.Status)
+
+	// Freeing the slot resumes the cold start, and the task is routed
+	m.scaler.mu.Lock()
+	m.scaler.endLaunch()
+	m.scaler.mu.Unlock()
+
+	require.Eventually(t, func() bool {
+		stored, err := m.GetTask(task.ID)
+		return err == nil && stored.Status == TaskStatusAssigned
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Len(t, m.ListMembers("dept-security"), 1)
+}
+
+func TestTaskRouterColdStartRequiresScaleToZero(t *testing.T) {
+	t.Parallel()
+
+	launcher := &healthyLauncher{endpoint: "http://127.0.0.1:1"}
+	m, err := NewManager(t.Context(), &DepartmentConfig{
+		Enabled:     true,
+		AutoScaling: AutoScalingConfig{Enabled: true, CheckInterval: time.Hour},
+	}, WithMemberLauncher(launcher))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, m.Stop()) })
+
+	task, err := m.CreateTask(t.Context(), &Task{ID: "scan", DepartmentID: "dept-security"})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, task.Status)
+	require.Empty(t, launcher.launched)
+}
+
+func TestAutoScalerStopWaitsForColdStarts(t *testing.T) {
+	t.Parallel()
+
+	// The member never becomes healthy, so the cold start is still waiting
+	// when the scaler stops
+	launcher := &healthyLauncher{endpoint: "http://127.0.0.1:1"}
+	m, err := NewManager(t.Context(), &DepartmentConfig{
+		Enabled: true,
+		AutoScaling: AutoScalingConfig{
+			Enabled:          true,
+			CheckInterval:    time.Hour,
+			ColdStartTimeout: time.Minute,
+		},
+		HealthCheck: HealthCheckConfig{Timeout: time.Second},
+	}, WithMemberLauncher(launcher))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, m.Stop()) })
+
+	m.mu.Lock()
+	security := m.departments["dept-security"]
+	security.ScaleToZero = true
+	security.MinMembers = 0
+	m.mu.Unlock()
+
+	_, err = m.CreateTask(t.Context(), &Task{ID: "scan", DepartmentID: "dept-security"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		launcher.mu.Lock()
+		defer launcher.mu.Unlock()
+		return len(launcher.launched) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	m.scaler.Stop()
+
+	// The cold start finished before Stop returned and cleaned up after itself
+	launcher.mu.Lock()
+	require.Equal(t, []string{launcher.launched[0].ID}, launcher.terminated)
+	launcher.mu.Unlock()
+	require.Empty(t, m.ListMembers("dept-security"))
+	require.False(t, m.scaler.RequestColdStart(*security))
+}
@@ -0,0 +1,223 @@
+package department
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Validate checks the configuration for contradictory or out-of-range
+// settings: member bounds, references to unknown departments and roles,
+// negative durations and limits, and scaling thresholds. Known departments
+// are the configured and the default ones. Every problem found is reported,
+// one per line.
+func (c *DepartmentConfig) Validate() error {
+	var problems []error
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	// Member bounds
+	for _, id := range slices.Sorted(maps.Keys(c.Departments)) {
+		dept := c.Departments[id]
+		if dept.MinMembers < 0 || dept.MaxMembers < 0 {
+			add("department %s: member bounds must not be negative", id)
+		} else if dept.MaxMembers > 0 && dept.MinMembers > dept.MaxMembers {
+			add("department %s: min members %d exceeds max members %d", id, dept.MinMembers, dept.MaxMembers)
+		}
+		if dept.MaxQueuedTasks < 0 {
+			add("department %s: max queued tasks must not be negative", id)
+		}
+	}
+
+	// Referenced departments and roles
+	departments := c.departmentIDs()
+	checkDepartment := func(field, id string) {
+		if id != "" && !slices.Contains(departments, id) {
+			add("%s: unknown department %q", field, id)
+		}
+	}
+	checkRoles := func(field string, roles []string) {
+		for _, role := range roles {
+			if !c.isKnownRole(role) {
+				add("%s: unknown role %q", field, role)
+			}
+		}
+	}
+	checkDepartment("task routing default department", c.TaskRouting.DefaultDepartment)
+	for _, id := range slices.Sorted(maps.Keys(c.TaskRouting.DepartmentRules)) {
+		checkDepartment("task routing department rules", id)
+	}
+	for i, rule := range c.AutoScaling.ScheduledRules {
+		checkDepartment(fmt.Sprintf("scheduled rule %d", i), rule.DepartmentID)
+	}
+	if role := c.TaskRouting.DefaultRole; role != "" {
+		checkRoles("task routing default role", []string{role})
+	}
+	checkRoles("task routing role rules", slices.Sorted(maps.Keys(c.TaskRouting.RoleRules)))
+	checkRoles("auto scaling role scaling", slices.Sorted(maps.Keys(c.AutoScaling.RoleScaling)))
+	checkRoles("auto scaling capacity per member", slices.Sorted(maps.Keys(c.AutoScaling.CapacityPerMember)))
+	checkRoles("health check role specific checks", slices.Sorted(maps.Keys(c.HealthCheck.RoleSpecificChecks)))
+	checkRoles("health check commands", slices.Sorted(maps.Keys(c.HealthCheck.Commands)))
+	checkRoles("role permissions", slices.Sorted(maps.Keys(c.Roles.Permissions)))
+	checkRoles("reporting role reports", c.Reporting.RoleReports)
+	for _, name := range slices.Sorted(maps.Keys(c.Roles.RoleDefinitions)) {
+		checkRoles(fmt.Sprintf("role %s can assign to", name), c.Roles.RoleDefinitions[name].CanAssignTo)
+	}
+
+	// Durations, limits and thresholds
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"default task timeout", c.DefaultTaskTimeout},
+		{"member heartbeat timeout", c.MemberHeartbeatTimeout},
+		{"auto scaling cooldown period", c.AutoScaling.CooldownPeriod},
+		{"auto scaling scale up cooldown", c.AutoScaling.ScaleUpCooldown},
+		{"auto scaling scale down cooldown", c.AutoScaling.ScaleDownCooldown},
+		{"auto scaling drain timeout", c.AutoScaling.DrainTimeout},
+		{"auto scaling cold start timeout", c.AutoScaling.ColdStartTimeout},
+		{"auto scaling max queue wait", c.AutoScaling.MaxQueueWait},
+		{"auto scaling queue wait target", c.AutoScaling.QueueWaitP95Target},
+		{"health check timeout", c.HealthCheck.Timeout},
+		{"health check idle conn timeout", c.HealthCheck.IdleConnTimeout},
+		{"reporting interval", c.Reporting.ReportInterval},
+	} {
+		if d.value < 0 {
+			add("%s must not be negative", d.name)
+		}
+	}
+	if c.MaxQueuedTasks < 0 {
+		add("max queued tasks must not be negative")
+	}
+	if c.AttachmentSpillThreshold < 0 {
+		add("attachment spill threshold must not be negative")
+	}
+	if c.AutoScaling.MaxMembersPerDept < 0 || c.AutoScaling.ScalingHistorySize < 0 {
+		add("auto scaling limits must not be negative")
+	}
+	if c.HealthCheck.UnhealthyThreshold < 0 || c.HealthCheck.HealthyThreshold < 0 || c.HealthCheck.RetryCount < 0 {
+		add("health check thresholds must not be negative")
+	}
+	if c.AutoScaling.Enabled {
+		if c.AutoScaling.CheckInterval <= 0 {
+			add("auto scaling check interval must be positive")
+		}
+		// Thresholds left at zero are unset, for scaling on queue waits only
+		up, down := c.AutoScaling.ScaleUpThreshold, c.AutoScaling.ScaleDownThreshold
+		if (up != 0 || down != 0) && down >= up {
+			add("auto scaling scale down threshold %v must be below scale up threshold %v",
+				c.AutoScaling.ScaleDownThreshold, c.AutoScaling.ScaleUpThreshold)
+		}
+	}
+	if c.HealthCheck.Enabled && c.HealthCheck.CheckInterval <= 0 {
+		add("health check interval must be positive")
+	}
+	for _, metric := range c.Reporting.Metrics {
+		if !slices.Contains(reportMetrics, metric) {
+			add("reporting: unknown metric %q", metric)
+		}
+	}
+	for _, format := range c.Reporting.ExportFormats {
+		if !slices.Contains(reportFormats, format) {
+			add("reporting: unknown export format %q", format)
+		}
+	}
+	if c.Reporting.Enabled && c.Reporting.ReportInterval > 0 && c.Reporting.ReportDir == "" {
+		add("reporting: report dir is required for periodic reports")
+	}
+	if c.Notifications.RateLimit < 0 {
+		add("notification rate limit must not be negative")
+	}
+	for _, event := range c.Notifications.Events {
+		if !slices.Contains(notificationEvents, event) {
+			add("notifications: unknown event %q", event)
+		}
+	}
+
+	if err := c.TaskRouting.Validate(); err != nil {
+		add("task routing: %w", err)
+	}
+	if err := c.AutoScaling.Validate(); err != nil {
+		add("auto scaling: %w", err)
+	}
+	if c.DefaultRetryPolicy != nil {
+		if err := c.DefaultRetryPolicy.Validate(); err != nil {
+			add("default retry policy: %w", err)
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+// departmentIDs returns the IDs of the configured and default departments
+func (c *DepartmentConfig) departmentIDs() []string {
+	ids := slices.Collect(maps.Keys(c.Departments))
+	for _, dept := range defaultDepartments() {
+		ids = append(ids, dept.ID)
+	}
+	return ids
+}
+
+// isKnownRole reports whether role is a built-in role or one defined in the
+// role configuration
+func (c *DepartmentConfig) isKnownRole(role string) bool {
+	if slices.Contains(memberRoles, MemberRole(role)) {
+		return true
+	}
+	_, defined := c.Roles.RoleDefinitions[role]
+	return defined
+}
+
+// roleDefinition returns the configured definition of role, if any
+func (c *DepartmentConfig) roleDefinition(role string) (RoleDefinition, bool) {
+	def, ok := c.Roles.RoleDefinitions[role]
+	return def, ok
+}
+
+// isLeadRole reports whether role leads others. A role definition decides
+// for the roles it covers; other roles fall back to the built-in leads.
+func (c *DepartmentConfig) isLeadRole(role MemberRole) bool {
+	if def, ok := c.roleDefinition(string(role)); ok {
+		return def.LeadRole
+	}
+	return isLeadRole(role)
+}
+
+// roles returns the built-in roles followed by the roles only defined in the
+// role configuration, sorted
+func (c *DepartmentConfig) roles() []MemberRole {
+	roles := slices.Clone(memberRoles)
+	for _, name := range slices.Sorted(maps.Keys(c.Roles.RoleDefinitions)) {
+		if !slices.Contains(roles, MemberRole(name)) {
+			roles = append(roles, MemberRole(name))
+		}
+	}
+	return roles
+}
+
+// canRoleHandle reports whether role is permitted to handle tasks of
+// taskType. Roles without permissions, and tasks without a type, are not
+// restricted.
+func (c *DepartmentConfig) canRoleHandle(role MemberRole, taskType string) bool {
+	permitted := c.Roles.Permissions[string(role)]
+	if len(permitted) == 0 || taskType == "" {
+		return true
+	}
+	return slices.ContainsFunc(permitted, func(t string) bool {
+		return strings.EqualFold(t, taskType)
+	})
+}
+
+// canAssignTo reports whether a lead in leadRole may hand tasks to members
+// in role. A definition without CanAssignTo allows every role.
+func (c *DepartmentConfig) canAssignTo(leadRole, role MemberRole) bool {
+	def, ok := c.roleDefinition(string(leadRole))
+	if !ok || len(def.CanAssignTo) == 0 {
+		return true
+	}
+	return slices.Contains(def.CanAssignTo, string(role))
+}
@@ -0,0 +1,82 @@
+package department
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDepartmentConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, (&DepartmentConfig{Enabled: true}).Validate())
+	require.NoError(t, (&DepartmentConfig{
+		Enabled: true,
+		TaskRouting: TaskRoutingConfig{
+			DefaultDepartment: "dept-dev",
+			RoleRules:         map[string][]string{"sre": {"incident"}},
+		},
+		AutoScaling: AutoScalingConfig{
+			Enabled:            true,
+			CheckInterval:      time.Minute,
+			ScaleUpThreshold:   0.8,
+			ScaleDownThreshold: 0.2,
+		},
+		Roles: RoleConfig{RoleDefinitions: map[string]RoleDefinition{"sre": {Name: "SRE"}}},
+	}).Validate())
+
+	err := (&DepartmentConfig{
+		Enabled: true,
+		Departments: map[string]Department{
+			"dept-data": {MinMembers: 5, MaxMembers: 2},
+		},
+		TaskRouting: TaskRoutingConfig{
+			DefaultDepartment: "dept-missing",
+			RoleRules:         map[string][]string{"wizard": {"magic"}},
+		},
+		AutoScaling: AutoScalingConfig{
+			Enabled:            true,
+			ScaleUpThreshold:   0.3,
+			ScaleDownThreshold: 0.6,
+			DrainTimeout:       -time.Second,
+		},
+		HealthCheck:   HealthCheckConfig{Enabled: true},
+		Notifications: NotificationConfig{Events: []string{"task_failed", "task_exploded"}},
+		Reporting:     ReportingConfig{Enabled: true, ReportInterval: time.Hour, Metrics: []string{"happiness"}},
+		Roles: RoleConfig{
+			RoleDefinitions: map[string]RoleDefinition{"lead_dev": {CanAssignTo: []string{"intern"}}},
+			Permissions:     map[string][]string{"wizard": {"spell"}},
+		},
+	}).Validate()
+	require.Error(t, err)
+
+	// Every problem is listed, one per line
+	problems := strings.Split(err.Error(), "\n")
+	require.Equal(t, []string{
+		"department dept-data: min members 5 exceeds max members 2",
+		`task routing default department: unknown department "dept-missing"`,
+		`task routing role rules: unknown role "wizard"`,
+		`role permissions: unknown role "wizard"`,
+		`role lead_dev can assign to: unknown role "intern"`,
+		"auto scaling drain timeout must not be negative",
+		"auto scaling check interval must be positive",
+		"auto scaling scale down threshold 0.6 must be below scale up threshold 0.3",
+		"health check interval must be positive",
+		`reporting: unknown metric "happiness"`,
+		"reporting: report dir is required for periodic reports",
+		`notifications: unknown event "task_exploded"`,
+	}, problems)
+}
+
+func TestNewManagerRejectsInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewManager(context.Background(), &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{DefaultDepartment: "dept-missing"},
+	})
+	require.ErrorContains(t, err, `invalid department config: task routing default department: unknown department "dept-missing"`)
+}
@@ -0,0 +1,65 @@
+package department
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// taskCost is the estimated cost of running task on member
+func taskCost(task *Task, member *Member) float64 {
+	return member.CostPerTask * taskWeight(task)
+}
+
+// costScore rates a member for cost-based routing, higher being better. It
+// blends the member's cost, relative to the priciest candidate, with its
+// load. Cheaper members score higher, except for critical tasks, which
+// escalate to pricier members and score leads above everyone else.
+func (tr *TaskRouter) costScore(task *Task, member *Member, maxCost float64) float64 {
+	cost := 0.0
+	if maxCost > 0 {
+		cost = member.CostPerTask / maxCost
+	}
+	critical := effectivePriority(task) == PriorityCritical
+	if critical {
+		cost = 1 - cost
+	}
+	load := 1 - tr.manager.remainingUnits(member)/memberCapacity(member)
+
+	blend := tr.config.CostLoadBlend
+	score := 1 - ((1-blend)*cost + blend*load)
+	if critical && member.IsLead {
+		score++
+	}
+	return score
+}
+
+// maxCandidateCost returns the highest cost per task among candidates
+func maxCandidateCost(candidates []*Member) float64 {
+	maxCost := 0.0
+	for _, member := range candidates {
+		maxCost = max(maxCost, member.CostPerTask)
+	}
+	return maxCost
+}
+
+// selectByCost selects the member with the best cost score. Ties go to the
+// lowest member ID.
+func (tr *TaskRouter) selectByCost(task *Task, candidates []*Member) (*Member, error) {
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no candidates available")
+	}
+
+	slices.SortFunc(candidates, func(a, b *Member) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	maxCost := maxCandidateCost(candidates)
+	selected, bestScore := candidates[0], tr.costScore(task, candidates[0], maxCost)
+	for _, member := range candidates[1:] {
+		if score := tr.costScore(task, member, maxCost); score > bestScore {
+			selected, bestScore = member, score
+		}
+	}
+	return selected, nil
+}
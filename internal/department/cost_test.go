@@ -0,0 +1,89 @@
+package department
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newCostRoutingManager(t *testing.T, blend float64) *Manager {
+	t.Helper()
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled: true,
+		TaskRouting: TaskRoutingConfig{
+			Strategy:      RoutingCostBased,
+			CostLoadBlend: blend,
+		},
+	})
+	require.NoError(t, err)
+
+	for _, member := range []*Member{
+		{ID: "cheap", Role: RoleDeveloper, CostPerTask: 1},
+		{ID: "mid", Role: RoleDeveloper, CostPerTask: 2},
+		{ID: "lead", Role: RoleLeadDev, CostPerTask: 5},
+	} {
+		member.Name = member.ID
+		member.DepartmentID = "dept-dev"
+		member.MaxConcurrent = 4
+		require.NoError(t, m.RegisterMember(ctx, member))
+	}
+	return m
+}
+
+func TestTaskRouterCostBased(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newCostRoutingManager(t, 0)
+
+	// Without load in the blend the cheapest member takes everything it can
+	for i := range 4 {
+		task, err := m.CreateTask(ctx, &Task{ID: fmt.Sprintf("task-%d", i), DepartmentID: "dept-dev", Priority: PriorityLow})
+		require.NoError(t, err)
+		require.Equal(t, "cheap", task.AssignedMember)
+		require.Equal(t, 1.0, task.EstimatedCost)
+	}
+	task, err := m.CreateTask(ctx, &Task{ID: "task-4", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, "mid", task.AssignedMember)
+	require.Contains(t, task.RoutingDecision.MemberReason, "low cost")
+
+	// Critical tasks escalate to the lead
+	critical, err := m.CreateTask(ctx, &Task{ID: "critical", DepartmentID: "dept-dev", Priority: PriorityCritical, Weight: 2})
+	require.NoError(t, err)
+	require.Equal(t, "lead", critical.AssignedMember)
+	require.Equal(t, 10.0, critical.EstimatedCost)
+
+	stats, err := m.GetDepartmentStats("dept-dev")
+	require.NoError(t, err)
+	require.Equal(t, 16.0, stats.EstimatedCost)
+}
+
+func TestTaskRouterCostBlendsLoad(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newCostRoutingManager(t, 0.5)
+
+	// Load offsets the small cost difference, so the two cheaper members
+	// alternate and the lead is left alone
+	var assigned []string
+	for i := range 4 {
+		task, err := m.CreateTask(ctx, &Task{ID: fmt.Sprintf("task-%d", i), DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+		assigned = append(assigned, task.AssignedMember)
+	}
+	require.Equal(t, []string{"cheap", "mid", "cheap", "mid"}, assigned)
+}
+
+func TestTaskRoutingConfigValidateCostLoadBlend(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, TaskRoutingConfig{Strategy: RoutingCostBased, CostLoadBlend: 0.3}.Validate())
+	err := TaskRoutingConfig{CostLoadBlend: 1.5}.Validate()
+	require.ErrorContains(t, err, "cost load blend must be between 0 and 1")
+}
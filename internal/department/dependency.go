@@ -0,0 +1,376 @@
+package department
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+)
+
+// resultBlockedOn is the task result listing the unfinished dependencies a
+// blocked task waits on
+const resultBlockedOn = "blocked_on"
+
+// dependencyFailedReason is the failure reason of tasks failed because a task
+// they depend on failed
+const dependencyFailedReason = "dependency_failed"
+
+// effectivePriority is the priority a task is scheduled at: its own, or the
+// priority it inherited from a more urgent task waiting on it
+func effectivePriority(task *Task) Priority {
+	if task.InheritedPriority.Rank() > task.Priority.Rank() {
+		return task.InheritedPriority
+	}
+	return task.Priority
+}
+
+// validateDependencies checks that a new task's dependencies exist, have not
+// failed or been cancelled, and do not lead back to the task. A task replacing one with the
+// same ID could otherwise close a cycle and block every task in it forever.
+// The caller must hold the manager lock.
+func (m *Manager) validateDependencies(task *Task) error {
+	for _, dep := range task.Dependencies {
+		if dep == task.ID {
+			return fmt.Errorf("task %s cannot depend on itself", task.ID)
+		}
+		depTask, exists := m.tasks[dep]
+		if !exists {
+			return fmt.Errorf("task %s depends on unknown task %s", task.ID, dep)
+		}
+		if depTask.Status == TaskStatusFailed || depTask.Status == TaskStatusCancelled {
+			return fmt.Errorf("task %s depends on %s task %s", task.ID, depTask.Status, dep)
+		}
+	}
+
+	if cycle := m.dependencyCycle(task); cycle != nil {
+		return fmt.Errorf("task %s would create a dependency cycle: %s", task.ID, strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+// dependencyCycle returns the path from the task through existing tasks'
+// dependencies back to the task, or nil if there is none. The caller must
+// hold the manager lock.
+func (m *Manager) dependencyCycle(task *Task) []string {
+	visited := make(map[string]bool)
+
+	var visit func(id string, path []string) []string
+	visit = func(id string, path []string) []string {
+		path = append(path, id)
+		if id == task.ID {
+			return path
+		}
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+
+		if depTask, exists := m.tasks[id]; exists {
+			for _, dep := range depTask.Dependencies {
+				if cycle := visit(dep, path); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		return nil
+	}
+
+	for _, dep := range task.Dependencies {
+		if cycle := visit(dep, []string{task.ID}); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// findTaskCycle returns the ID of a task that is part of a dependency cycle
+// among tasks, or an empty string if there is none
+func findTaskCycle(tasks map[string]*Task) string {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(tasks))
+
+	var visit func(id string) string
+	visit = func(id string) string {
+		switch state[id] {
+		case visiting:
+			return id
+		case visited:
+			return ""
+		}
+		state[id] = visiting
+		if task, exists := tasks[id]; exists {
+			for _, dep := range task.Dependencies {
+				if cycle := visit(dep); cycle != "" {
+					return cycle
+				}
+			}
+		}
+		state[id] = visited
+		return ""
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(tasks)) {
+		if cycle := visit(id); cycle != "" {
+			return cycle
+		}
+	}
+	return ""
+}
+
+// unfinishedDependencies returns how many of a new task's dependencies have
+// not completed yet. The caller must hold the manager lock.
+func (m *Manager) unfinishedDependencies(task *Task) int {
+	unfinished := 0
+	for _, dep := range task.Dependencies {
+		if depTask := m.tasks[dep]; depTask != nil && depTask.Status != TaskStatusCompleted {
+			unfinished++
+		}
+	}
+	return unfinished
+}
+
+// waitForDependencies indexes a blocked task under each dependency it still
+// waits on. The caller must hold the manager lock.
+func (m *Manager) waitForDependencies(task *Task) {
+	for _, dep := range task.Dependencies {
+		if depTask := m.tasks[dep]; depTask != nil && depTask.Status != TaskStatusCompleted {
+			m.dependents[dep] = append(m.dependents[dep], task.ID)
+		}
+	}
+}
+
+// rebuildDependents indexes the blocked tasks restored from persisted state.
+// Workflow steps are left out; their workflow unblocks them.
+func (m *Manager) rebuildDependents() {
+	m.dependents = make(map[string][]string)
+	for _, task := range m.tasks {
+		if task.Status == TaskStatusBlocked && task.Metadata[metadataWorkflowRun] == "" {
+			m.waitForDependencies(task)
+		}
+	}
+}
+
+// resolveDependencies reacts to a task finishing: tasks that were only
+// waiting on it are routed, and inherited priorities are recomputed if it
+// took part in inheritance. The caller must hold the manager lock.
+func (m *Manager) resolveDependencies(ctx context.Context, done *Task) {
+	m.unblockDependents(ctx, done)
+
+	// Priority flows from a task to its dependencies, so finishing changes
+	// inherited priorities only for a task that passed one on or held one
+	if len(done.Dependencies) > 0 || done.InheritedPriority != "" {
+		m.inheritPriorities()
+	}
+}
+
+// unblockDependents routes the blocked tasks whose dependencies have all
+// completed now that the given task is done. Only completion unblocks: when
+// the task failed or was cancelled, the tasks waiting on it fail or are
+// cancelled in turn. The caller must hold the manager lock.
+func (m *Manager) unblockDependents(ctx context.Context, done *Task) {
+	waiting := m.dependents[done.ID]
+	delete(m.dependents, done.ID)
+
+	for _, id := range waiting {
+		task, exists := m.tasks[id]
+		if !exists || task.Status != TaskStatusBlocked {
+			continue
+		}
+		switch done.Status {
+		case TaskStatusFailed:
+			slog.Info("Failing task whose dependency failed", "task_id", id, "dependency", done.ID)
+			if err := m.updateTaskStatus(ctx, id, TaskStatusFailed, map[string]interface{}{
+				"error":          fmt.Sprintf("dependency %s failed", done.ID),
+				"failure_reason": dependencyFailedReason,
+			}); err != nil {
+				slog.Warn("Failed to fail dependent task", "task_id", id, "error", err)
+			}
+			continue
+		case TaskStatusCancelled:
+			m.cancelTask(ctx, task, fmt.Sprintf("dependency %s was cancelled", done.ID))
+			continue
+		}
+
+		ready := true
+		for _, dep := range task.Dependencies {
+			if depTask := m.tasks[dep]; depTask != nil && depTask.Status != TaskStatusCompleted {
+				ready = false
+				break
+			}
+		}
+		if !ready {
+			if m.updateBlockedOn(task) {
+				m.taskEvents.Publish(pubsub.UpdatedEvent, task)
+			}
+			continue
+		}
+
+		task.Status = TaskStatusQueued
+		task.UpdatedAt = time.Now()
+		m.indexTask(task)
+		delete(task.Results, resultBlockedOn)
+		if err := m.taskRouter.routeTask(ctx, task); err != nil {
+			slog.Warn("Failed to route unblocked task", "task_id", task.ID, "error", err)
+		}
+		m.taskEvents.Publish(pubsub.UpdatedEvent, task)
+	}
+}
+
+// blockedOn returns the dependencies of a task that have not completed yet.
+// The caller must hold the manager lock.
+func (m *Manager) blockedOn(task *Task) []string {
+	var waiting []string
+	for _, dep := range task.Dependencies {
+		if depTask := m.tasks[dep]; depTask == nil || depTask.Status != TaskStatusCompleted {
+			waiting = append(waiting, dep)
+		}
+	}
+	return waiting
+}
+
+// updateBlockedOn records the dependencies a blocked task waits on in its
+// results and reports whether they changed. The caller must hold the
+// manager lock.
+func (m *Manager) updateBlockedOn(task *Task) bool {
+	waiting := m.blockedOn(task)
+	if current, ok := task.Results[resultBlockedOn].([]string); ok && slices.Equal(current, waiting) {
+		return false
+	}
+	if task.Results == nil {
+		task.Results = make(map[string]interface{})
+	}
+	task.Results[resultBlockedOn] = waiting
+	return true
+}
+
+// GetBlockedTasks returns copies of the blocked tasks, longest blocked
+// first, with the dependencies each waits on in Results["blocked_on"]
+func (m *Manager) GetBlockedTasks() []*Task {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var blocked []*Task
+	for _, task := range m.tasks {
+		if task.Status != TaskStatusBlocked {
+			continue
+		}
+		c := task.clone()
+		if c.Results == nil {
+			c.Results = make(map[string]interface{})
+		}
+		c.Results[resultBlockedOn] = m.blockedOn(task)
+		blocked = append(blocked, c)
+	}
+	slices.SortFunc(blocked, func(a, b *Task) int {
+		if c := a.UpdatedAt.Compare(b.UpdatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return blocked
+}
+
+// blockedMonitor periodically warns about tasks blocked for too long
+func (m *Manager) blockedMonitor(ctx context.Context) {
+	ticker := time.NewTicker(max(m.config.TaskRouting.MaxBlockedTime/2, minQueueWaitCheckInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.checkBlockedTasks(time.Now())
+		}
+	}
+}
+
+// checkBlockedTasks logs a warning for every task that has been blocked
+// longer than MaxBlockedTime, once per task, and returns how many it warned
+// about
+func (m *Manager) checkBlockedTasks(now time.Time) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	limit := m.config.TaskRouting.MaxBlockedTime
+	warned := 0
+	for id, task := range m.tasks {
+		if task.Status != TaskStatusBlocked {
+			delete(m.blockedAlerts, id)
+			continue
+		}
+		if m.blockedAlerts[id] || now.Sub(task.UpdatedAt) <= limit {
+			continue
+		}
+		m.blockedAlerts[id] = true
+		warned++
+
+		slog.Warn("Task blocked too long",
+			"task_id", id,
+			"department", task.DepartmentID,
+			"blocked_on", m.blockedOn(task),
+			"blocked", now.Sub(task.UpdatedAt),
+			"max_blocked", limit)
+	}
+	return warned
+}
+
+// inheritPriorities recomputes the priority every unfinished task inherits
+// from the unfinished tasks depending on it, directly or through other
+// tasks, so that low-priority prerequisites of urgent work are not starved.
+// Tasks whose inherited priority changes are published. The caller must
+// hold the manager lock.
+func (m *Manager) inheritPriorities() {
+	inherited := make(map[string]Priority)
+
+	var raise func(taskID string, priority Priority, seen map[string]bool)
+	raise = func(taskID string, priority Priority, seen map[string]bool) {
+		task, exists := m.tasks[taskID]
+		if !exists || isTaskDone(task.Status) || seen[taskID] {
+			return
+		}
+		seen[taskID] = true
+		if priority.Rank() > inherited[taskID].Rank() {
+			inherited[taskID] = priority
+		}
+		for _, dep := range task.Dependencies {
+			raise(dep, priority, seen)
+		}
+	}
+
+	for _, task := range m.tasks {
+		if isTaskDone(task.Status) || len(task.Dependencies) == 0 {
+			continue
+		}
+		seen := map[string]bool{task.ID: true}
+		for _, dep := range task.Dependencies {
+			raise(dep, task.Priority, seen)
+		}
+	}
+
+	for id, task := range m.tasks {
+		priority := inherited[id]
+		if priority.Rank() <= task.Priority.Rank() {
+			priority = ""
+		}
+		if priority == task.InheritedPriority {
+			continue
+		}
+		task.InheritedPriority = priority
+		m.taskEvents.Publish(pubsub.UpdatedEvent, task)
+		if priority != "" {
+			slog.Debug("Task inherited priority",
+				"task_id", id,
+				"priority", string(task.Priority),
+				"inherited", string(priority))
+		}
+	}
+}
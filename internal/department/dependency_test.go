@@ -0,0 +1,226 @@
+package department
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerPriorityInheritance(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "sec-1", "dept-security", RoleSecurity, 1)
+
+	_, err := m.CreateTask(ctx, &Task{ID: "busy", DepartmentID: "dept-security", Priority: PriorityLow})
+	require.NoError(t, err)
+	_, err = m.CreateTask(ctx, &Task{ID: "other", DepartmentID: "dept-security", Priority: PriorityLow})
+	require.NoError(t, err)
+	_, err = m.CreateTask(ctx, &Task{ID: "prereq", DepartmentID: "dept-security", Priority: PriorityLow})
+	require.NoError(t, err)
+
+	release, err := m.CreateTask(ctx, &Task{
+		ID:           "release",
+		DepartmentID: "dept-security",
+		Priority:     PriorityCritical,
+		Dependencies: []string{"prereq"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusBlocked, release.Status)
+
+	prereq, err := m.GetTask("prereq")
+	require.NoError(t, err)
+	require.Equal(t, PriorityLow, prereq.Priority)
+	require.Equal(t, PriorityCritical, prereq.InheritedPriority)
+	other, err := m.GetTask("other")
+	require.NoError(t, err)
+	require.Empty(t, other.InheritedPriority)
+
+	// The elevated prerequisite is scheduled ahead of older low-priority work
+	require.NoError(t, m.UpdateTaskStatus(ctx, "busy", TaskStatusCompleted, nil))
+	m.retryQueuedTasks(ctx)
+
+	prereq, err = m.GetTask("prereq")
+	require.NoError(t, err)
+	require.Equal(t, "sec-1", prereq.AssignedMember)
+	other, err = m.GetTask("other")
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, other.Status)
+
+	// Finishing the prerequisite resolves the edge: the inherited priority is
+	// dropped and the critical task is routed
+	require.NoError(t, m.UpdateTaskStatus(ctx, "prereq", TaskStatusCompleted, nil))
+	prereq, err = m.GetTask("prereq")
+	require.NoError(t, err)
+	require.Empty(t, prereq.InheritedPriority)
+	release, err = m.GetTask("release")
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusAssigned, release.Status)
+	require.Equal(t, "sec-1", release.AssignedMember)
+}
+
+func TestManagerPriorityInheritanceReverts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+
+	_, err := m.CreateTask(ctx, &Task{ID: "base", DepartmentID: "dept-dev", Priority: PriorityLow})
+	require.NoError(t, err)
+	_, err = m.CreateTask(ctx, &Task{ID: "middle", DepartmentID: "dept-dev", Priority: PriorityMedium, Dependencies: []string{"base"}})
+	require.NoError(t, err)
+	_, err = m.CreateTask(ctx, &Task{ID: "top", DepartmentID: "dept-dev", Priority: PriorityHigh, Dependencies: []string{"middle"}})
+	require.NoError(t, err)
+
+	// Priority is inherited through the whole chain
+	base, err := m.GetTask("base")
+	require.NoError(t, err)
+	require.Equal(t, PriorityHigh, base.InheritedPriority)
+
+	// Cancelling the urgent dependent reverts to the next one waiting
+	require.NoError(t, m.CancelTask(ctx, "top", "not needed"))
+	base, err = m.GetTask("base")
+	require.NoError(t, err)
+	require.Equal(t, PriorityMedium, base.InheritedPriority)
+	middle, err := m.GetTask("middle")
+	require.NoError(t, err)
+	require.Empty(t, middle.InheritedPriority)
+
+	_, err = m.CreateTask(ctx, &Task{ID: "orphan", DepartmentID: "dept-dev", Dependencies: []string{"missing"}})
+	require.ErrorContains(t, err, "unknown task missing")
+}
+
+func TestManagerRejectsInvalidDependencies(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+
+	// Re-creating task a on top of c, which waits on b and thereby on a,
+	// would close the cycle a -> c -> b -> a
+	_, err := m.CreateTask(ctx, &Task{ID: "a", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	_, err = m.CreateTask(ctx, &Task{ID: "b", DepartmentID: "dept-dev", Dependencies: []string{"a"}})
+	require.NoError(t, err)
+	_, err = m.CreateTask(ctx, &Task{ID: "c", DepartmentID: "dept-dev", Dependencies: []string{"b"}})
+	require.NoError(t, err)
+	_, err = m.CreateTask(ctx, &Task{ID: "a", DepartmentID: "dept-dev", Dependencies: []string{"c"}})
+	require.EqualError(t, err, "task a would create a dependency cycle: a -> c -> b -> a")
+
+	// The original task is kept
+	task, err := m.GetTask("a")
+	require.NoError(t, err)
+	require.Empty(t, task.Dependencies)
+
+	_, err = m.CreateTask(ctx, &Task{ID: "d", DepartmentID: "dept-dev", Dependencies: []string{"missing"}})
+	require.EqualError(t, err, "task d depends on unknown task missing")
+
+	failed, err := m.CreateTask(ctx, &Task{ID: "failed", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, failed.ID, TaskStatusFailed, nil))
+	_, err = m.CreateTask(ctx, &Task{ID: "e", DepartmentID: "dept-dev", Dependencies: []string{"failed"}})
+	require.EqualError(t, err, "task e depends on failed task failed")
+}
+
+func TestManagerBlockedTasks(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{MaxBlockedTime: time.Minute},
+	})
+	require.NoError(t, err)
+
+	for _, id := range []string{"build", "test"} {
+		_, err := m.CreateTask(ctx, &Task{ID: id, DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+	}
+	events := m.SubscribeToTaskEvents(ctx)
+
+	// blockedUpdate returns what the task was blocked on when the last
+	// update for it was published
+	blockedUpdate := func(taskID string) any {
+		t.Helper()
+		var blockedOn any
+		for {
+			select {
+			case event := <-events:
+				if event.Type == pubsub.UpdatedEvent && event.Payload.ID == taskID {
+					blockedOn = event.Payload.Results[resultBlockedOn]
+				}
+			default:
+				require.NotNil(t, blockedOn, "no update was published for %s", taskID)
+				return blockedOn
+			}
+		}
+	}
+
+	release, err := m.CreateTask(ctx, &Task{ID: "release", DepartmentID: "dept-dev", Dependencies: []string{"build", "test"}})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusBlocked, release.Status)
+	require.Equal(t, []string{"build", "test"}, blockedUpdate("release"))
+
+	// Still blocked, now on one dependency
+	require.NoError(t, m.UpdateTaskStatus(ctx, "build", TaskStatusCompleted, nil))
+	require.Equal(t, []string{"test"}, blockedUpdate("release"))
+
+	blocked := m.GetBlockedTasks()
+	require.Len(t, blocked, 1)
+	require.Equal(t, "release", blocked[0].ID)
+	require.Equal(t, []string{"test"}, blocked[0].Results[resultBlockedOn])
+
+	// Warned about once
+	require.Zero(t, m.checkBlockedTasks(release.UpdatedAt.Add(time.Minute)))
+	require.Equal(t, 1, m.checkBlockedTasks(release.UpdatedAt.Add(2*time.Minute)))
+	require.Zero(t, m.checkBlockedTasks(release.UpdatedAt.Add(3*time.Minute)))
+
+	require.NoError(t, m.UpdateTaskStatus(ctx, "test", TaskStatusCompleted, nil))
+	require.Empty(t, m.GetBlockedTasks())
+	release, err = m.GetTask("release")
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, release.Status)
+	require.NotContains(t, release.Results, resultBlockedOn)
+}
+
+func TestManagerUnfinishedDependencyEndsDependents(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m := newTestManager(t)
+
+	for _, id := range []string{"build", "lint"} {
+		_, err := m.CreateTask(ctx, &Task{ID: id, DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+	}
+	for _, pair := range [][2]string{{"test", "build"}, {"release", "test"}, {"docs", "lint"}} {
+		_, err := m.CreateTask(ctx, &Task{ID: pair[0], DepartmentID: "dept-dev", Dependencies: []string{pair[1]}})
+		require.NoError(t, err)
+	}
+
+	status := func(id string) TaskStatus {
+		task, err := m.GetTask(id)
+		require.NoError(t, err)
+		return task.Status
+	}
+
+	// A failed dependency fails its dependents, transitively
+	require.NoError(t, m.UpdateTaskStatus(ctx, "build", TaskStatusFailed, nil))
+	require.Equal(t, TaskStatusFailed, status("test"))
+	require.Equal(t, TaskStatusFailed, status("release"))
+	test, err := m.GetTask("test")
+	require.NoError(t, err)
+	require.Equal(t, dependencyFailedReason, test.Results["failure_reason"])
+
+	// A cancelled dependency cancels them
+	require.NoError(t, m.CancelTask(ctx, "lint", "not needed"))
+	require.Equal(t, TaskStatusCancelled, status("docs"))
+
+	// New tasks cannot wait on either
+	_, err = m.CreateTask(ctx, &Task{ID: "deploy", DepartmentID: "dept-dev", Dependencies: []string{"lint"}})
+	require.ErrorContains(t, err, "depends on cancelled task lint")
+}
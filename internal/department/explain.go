@@ -0,0 +1,71 @@
+package department
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExplainRouting describes in plain language why a task is assigned where it
+// is, based on the routing decision recorded when it was placed
+func (m *Manager) ExplainRouting(taskID string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	task, exists := m.tasks[taskID]
+	if !exists {
+		return "", fmt.Errorf("task %s does not exist", taskID)
+	}
+	decision := task.RoutingDecision
+	if decision == nil {
+		return "", fmt.Errorf("task %s has no routing decision (status %s)", taskID, task.Status)
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Task %s", task.ID)
+	if task.Title != "" {
+		fmt.Fprintf(&b, " (%q)", task.Title)
+	}
+	fmt.Fprintf(&b, " was routed to %s", m.describeMember(decision.MemberID))
+	if decision.TeamID != "" {
+		fmt.Fprintf(&b, " on behalf of team %s", decision.TeamID)
+	}
+	fmt.Fprintf(&b, " in department %s.\n", decision.DepartmentID)
+
+	fmt.Fprintf(&b, "Department %s was chosen because %s.\n", decision.DepartmentID, decision.DepartmentReason)
+
+	if decision.Strategy == "" {
+		fmt.Fprintf(&b, "Member %s was chosen because %s.\n", decision.MemberID, decision.MemberReason)
+	} else {
+		fmt.Fprintf(&b, "Member %s was chosen by %s routing because %s.\n", decision.MemberID, decision.Strategy, decision.MemberReason)
+	}
+
+	if len(decision.Candidates) > 0 {
+		b.WriteString("Candidates considered:\n")
+		for _, candidate := range decision.Candidates {
+			fmt.Fprintf(&b, "  - %s: score %.2f", candidate.MemberID, candidate.Score)
+			if candidate.MemberID == decision.MemberID {
+				b.WriteString(" (selected)")
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if task.AssignedMember != decision.MemberID {
+		fmt.Fprintf(&b, "The task has since moved and is currently %s.\n", task.Status)
+	}
+
+	return b.String(), nil
+}
+
+// describeMember returns a member's ID with its name and role when known
+func (m *Manager) describeMember(memberID string) string {
+	member, exists := m.members[memberID]
+	if !exists {
+		return fmt.Sprintf("member %s", memberID)
+	}
+	if member.Name != "" && member.Name != member.ID {
+		return fmt.Sprintf("member %s (%s, %s)", member.ID, member.Name, member.Role)
+	}
+	return fmt.Sprintf("member %s (%s)", member.ID, member.Role)
+}
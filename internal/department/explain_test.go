@@ -0,0 +1,56 @@
+package department
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerExplainRouting(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled: true,
+		TaskRouting: TaskRoutingConfig{
+			Strategy:        RoutingSkillBased,
+			DepartmentRules: map[string][]string{"dept-security": {"CVE"}},
+		},
+	})
+	require.NoError(t, err)
+
+	registerTestMember(t, m, "sec-1", "dept-security", RoleSecurity, 2)
+	expert := &Member{
+		ID:              "sec-2",
+		Name:            "Vulnerability expert",
+		Role:            RoleSecurity,
+		DepartmentID:    "dept-security",
+		MaxConcurrent:   2,
+		Specializations: []string{"cve-triage"},
+	}
+	require.NoError(t, m.RegisterMember(ctx, expert))
+
+	task, err := m.CreateTask(ctx, &Task{
+		ID:             "triage",
+		Title:          "Triage CVE-2024-1234",
+		RequiredSkills: []string{"cve-triage"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, expert.ID, task.AssignedMember)
+
+	explanation, err := m.ExplainRouting(task.ID)
+	require.NoError(t, err)
+	require.Contains(t, explanation, "member sec-2 (Vulnerability expert, security)")
+	require.Contains(t, explanation, `mentions "CVE"`)
+	require.Contains(t, explanation, "by skill-based routing")
+	require.Contains(t, explanation, "sec-2: score 14.00 (selected)")
+
+	// Manual assignments explain themselves too
+	require.NoError(t, m.ForceAssignTask(ctx, task.ID, "sec-1"))
+	explanation, err = m.ExplainRouting(task.ID)
+	require.NoError(t, err)
+	require.Contains(t, explanation, "force-assigned")
+
+	_, err = m.ExplainRouting("missing")
+	require.ErrorContains(t, err, "does not exist")
+}
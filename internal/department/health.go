@@ -2,24 +2,61 @@ package department
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"runtime"
 	"sync"
 	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+)
+
+// Connection pooling defaults for health probes
+const (
+	defaultProbeKeepAlive           = 30 * time.Second
+	defaultProbeMaxIdleConnsPerHost = 2
+	defaultProbeIdleConnTimeout     = 90 * time.Second
+
+	// defaultProbeRetryDelay is the wait before the first probe retry; it
+	// doubles on every further retry
+	defaultProbeRetryDelay = 100 * time.Millisecond
 )
 
+// Shares of unhealthy members at which a department is degraded or critical
+const (
+	defaultDegradedThreshold = 0.25
+	defaultCriticalThreshold = 0.75
+)
+
+// HealthChangedEvent is published on the health event stream whenever a
+// member flips between healthy and unhealthy
+const HealthChangedEvent pubsub.EventType = "health_changed"
+
 // HealthChecker monitors the health of department members
 type HealthChecker struct {
 	config  HealthCheckConfig
 	manager *Manager
 	client  *http.Client
 
+	// Wait before the first retry of a failed probe
+	retryDelay time.Duration
+
+	// Probers by health check type
+	probers map[HealthCheckType]Prober
+
 	// Health tracking
 	healthStatus map[string]*MemberHealth
 	mu           sync.RWMutex
 
+	// Status changes are suspended until this time, for example during a
+	// deploy. Guarded by mu.
+	pausedUntil time.Time
+
+	// Health transitions
+	events *pubsub.Broker[*MemberHealth]
+
 	// Control
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -29,12 +66,18 @@ type HealthChecker struct {
 type MemberHealth struct {
 	MemberID        string    `json:"member_id"`
 	Status          string    `json:"status"`
+	// PreviousStatus is the status before the last health transition
+	PreviousStatus string `json:"previous_status,omitempty"`
 	LastCheck       time.Time `json:"last_check"`
 	ResponseTime    float64   `json:"response_time"`
 	SuccessRate     float64   `json:"success_rate"`
 	FailedChecks    int       `json:"failed_checks"`
 	ConsecutiveFails int      `json:"consecutive_fails"`
+	// ConsecutiveSuccesses counts passed checks since the last failure
+	ConsecutiveSuccesses int `json:"consecutive_successes"`
 	IsHealthy       bool      `json:"is_healthy"`
+	// Retries is how many retries the last check needed
+	Retries int `json:"retries"`
 	LastError       string    `json:"last_error,omitempty"`
 }
 
@@ -42,14 +85,48 @@ type MemberHealth struct {
 func NewHealthChecker(config HealthCheckConfig, manager *Manager) *HealthChecker {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &HealthChecker{
+	h := &HealthChecker{
 		config:       config,
 		manager:      manager,
-		client:       &http.Client{Timeout: config.Timeout},
+		client:       &http.Client{Timeout: config.Timeout, Transport: newProbeTransport(config)},
+		retryDelay:   defaultProbeRetryDelay,
 		healthStatus: make(map[string]*MemberHealth),
+		events:       pubsub.NewBroker[*MemberHealth](),
 		ctx:          ctx,
 		cancel:       cancel,
 	}
+	h.probers = map[HealthCheckType]Prober{
+		HealthCheckHTTP: &httpProber{checker: h},
+		HealthCheckTCP:  &tcpProber{timeout: probeTimeout(config)},
+		HealthCheckExec: &execProber{timeout: probeTimeout(config), commands: config.Commands},
+	}
+	return h
+}
+
+// newProbeTransport builds an HTTP transport that keeps connections to member
+// endpoints alive so frequent probes reuse them
+func newProbeTransport(config HealthCheckConfig) *http.Transport {
+	keepAlive := config.KeepAlive
+	if keepAlive == 0 {
+		keepAlive = defaultProbeKeepAlive
+	}
+	maxIdle := config.MaxIdleConnsPerHost
+	if maxIdle <= 0 {
+		maxIdle = defaultProbeMaxIdleConnsPerHost
+	}
+	idleTimeout := config.IdleConnTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = defaultProbeIdleConnTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   config.Timeout,
+		KeepAlive: keepAlive,
+	}).DialContext
+	transport.MaxIdleConnsPerHost = maxIdle
+	transport.IdleConnTimeout = idleTimeout
+	return transport
 }
 
 // Start begins the health checking process
@@ -75,21 +152,74 @@ func (h *HealthChecker) Start(ctx context.Context) {
 // Stop stops the health checker
 func (h *HealthChecker) Stop() {
 	h.cancel()
+	h.events.Shutdown()
+}
+
+// Pause suspends health-driven member status changes for d, for a known
+// deploy window whose restarts would otherwise mark members unhealthy and
+// move their tasks. Probes keep running and health is still tracked, so once
+// the pause ends the next check acts on members that are still failing.
+func (h *HealthChecker) Pause(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.pausedUntil = time.Now().Add(d)
+	slog.Info("Health-driven status changes paused", "until", h.pausedUntil)
+}
+
+// Resume ends a pause early
+func (h *HealthChecker) Resume() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.pausedUntil.IsZero() {
+		return
+	}
+	h.pausedUntil = time.Time{}
+	slog.Info("Health-driven status changes resumed")
+}
+
+// Paused reports whether health-driven status changes are suspended
+func (h *HealthChecker) Paused() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.pausedAt(time.Now())
+}
+
+// pausedAt reports whether a pause is in effect at now. The caller must hold
+// h.mu.
+func (h *HealthChecker) pausedAt(now time.Time) bool {
+	return now.Before(h.pausedUntil)
+}
+
+// SubscribeToHealthEvents returns a channel of health transitions. Each event
+// carries a snapshot of the member's health with its previous and new status.
+func (h *HealthChecker) SubscribeToHealthEvents(ctx context.Context) <-chan pubsub.Event[*MemberHealth] {
+	return h.events.Subscribe(ctx)
 }
 
 // performHealthCheck checks the health of all registered members
 func (h *HealthChecker) performHealthCheck() {
-	members := h.manager.ListMembers("")
+	h.manager.mu.RLock()
+	members := make([]*Member, 0, len(h.manager.members))
+	for _, member := range h.manager.members {
+		if member.Status != MemberStatusOffline {
+			members = append(members, member)
+		}
+	}
+	h.manager.mu.RUnlock()
+
+	// Cap in-flight checks so large departments don't flood the network
+	sem := make(chan struct{}, h.maxConcurrentChecks())
 
 	var wg sync.WaitGroup
 	for _, member := range members {
-		if member.Status == MemberStatusOffline {
-			continue
-		}
-
 		wg.Add(1)
 		go func(m *Member) {
 			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
 			h.checkMemberHealth(m)
 		}(member)
 	}
@@ -97,12 +227,24 @@ func (h *HealthChecker) performHealthCheck() {
 	wg.Wait()
 }
 
+// maxConcurrentChecks is how many members are probed at once
+func (h *HealthChecker) maxConcurrentChecks() int {
+	if h.config.MaxConcurrentChecks > 0 {
+		return h.config.MaxConcurrentChecks
+	}
+	return runtime.NumCPU() * 4
+}
+
 // checkMemberHealth performs a health check on a single member
 func (h *HealthChecker) checkMemberHealth(member *Member) {
-	start := time.Now()
+	// Probe a copy, since registrations and updates change the member under
+	// the manager lock while the probe runs
+	h.manager.mu.RLock()
+	member = member.clone()
+	h.manager.mu.RUnlock()
 
 	// Perform the actual health check
-	healthy, responseTime, err := h.pingMember(member)
+	healthy, responseTime, retries, err := h.pingMember(h.ctx, member)
 
 	checkTime := time.Now()
 
@@ -111,30 +253,45 @@ func (h *HealthChecker) checkMemberHealth(member *Member) {
 
 	health, exists := h.healthStatus[member.ID]
 	if !exists {
+		// Members are assumed healthy until a check fails
 		health = &MemberHealth{
-			MemberID: member.ID,
+			MemberID:  member.ID,
+			Status:    "healthy",
+			IsHealthy: true,
 		}
 		h.healthStatus[member.ID] = health
 	}
+	previousStatus := health.Status
+	wasHealthy := health.IsHealthy
+	paused := h.pausedAt(checkTime)
 
 	// Update health status
 	health.LastCheck = checkTime
 	health.ResponseTime = responseTime
+	health.Retries = retries
 
 	if healthy {
-		health.FailedChecks = 0
 		health.ConsecutiveFails = 0
-		health.IsHealthy = true
-		health.Status = "healthy"
-		health.LastError = ""
-
-		// Update member status if it was unhealthy
-		if member.Status == MemberStatusUnhealthy {
-			h.manager.UpdateMemberStatus(context.Background(), member.ID, MemberStatusOnline)
+		health.ConsecutiveSuccesses++
+		h.manager.seeMember(member.ID, checkTime)
+
+		// An unhealthy member has to pass several checks in a row before it
+		// recovers, so a borderline member doesn't flap
+		if health.IsHealthy || health.ConsecutiveSuccesses >= h.healthyThreshold() {
+			health.FailedChecks = 0
+			health.IsHealthy = true
+			health.Status = "healthy"
+			health.LastError = ""
+
+			// Update member status if it was unhealthy
+			if member.Status == MemberStatusUnhealthy && !paused {
+				h.manager.UpdateMemberStatus(context.Background(), member.ID, MemberStatusOnline)
+			}
 		}
 	} else {
 		health.FailedChecks++
 		health.ConsecutiveFails++
+		health.ConsecutiveSuccesses = 0
 		health.IsHealthy = false
 		health.Status = "unhealthy"
 
@@ -142,86 +299,148 @@ func (h *HealthChecker) checkMemberHealth(member *Member) {
 			health.LastError = err.Error()
 		}
 
-		// Mark member as unhealthy if threshold is reached
-		if health.ConsecutiveFails >= h.config.UnhealthyThreshold {
+		// Mark member as unhealthy if threshold is reached, unless status
+		// changes are paused
+		if paused {
+			slog.Debug("Health check failed while paused",
+				"member_id", member.ID,
+				"consecutive_failures", health.ConsecutiveFails)
+		} else if health.ConsecutiveFails >= h.config.UnhealthyThreshold {
 			h.manager.UpdateMemberStatus(context.Background(), member.ID, MemberStatusUnhealthy)
 			slog.Warn("Member marked as unhealthy",
 				"member_id", member.ID,
 				"consecutive_failures", health.ConsecutiveFails,
 				"last_error", health.LastError)
+
+			if h.config.ReassignOnUnhealthy {
+				h.reassignMemberTasks(member.ID)
+			}
 		}
 	}
 
 	// Calculate success rate based on recent checks
 	h.calculateSuccessRate(member.ID)
+
+	if health.IsHealthy != wasHealthy {
+		health.PreviousStatus = previousStatus
+		snapshot := *health
+		h.events.Publish(HealthChangedEvent, &snapshot)
+	}
 }
 
-// pingMember sends a health check request to a member
-func (h *HealthChecker) pingMember(member *Member) (bool, float64, error) {
-	start := time.Now()
+// healthyThreshold is how many consecutive passed checks restore an
+// unhealthy member
+func (h *HealthChecker) healthyThreshold() int {
+	return max(h.config.HealthyThreshold, 1)
+}
 
-	// Create health check URL
-	healthURL := fmt.Sprintf("%s/health", member.Endpoint)
+// reassignMemberTasks moves an unhealthy member's tasks to healthy members
+func (h *HealthChecker) reassignMemberTasks(memberID string) {
+	if h.manager.taskRouter == nil {
+		return
+	}
 
-	// Create request
-	req, err := http.NewRequestWithContext(context.Background(), "GET", healthURL, nil)
+	moved, err := h.manager.taskRouter.ReassignMemberTasks(h.ctx, memberID, "member_unhealthy")
 	if err != nil {
-		return false, 0, fmt.Errorf("failed to create request: %w", err)
+		slog.Warn("Failed to reassign some tasks of unhealthy member",
+			"member_id", memberID,
+			"error", err)
 	}
+	if moved > 0 {
+		slog.Info("Reassigned tasks of unhealthy member",
+			"member_id", memberID,
+			"tasks", moved)
+	}
+}
 
-	// Add authentication headers if needed
-	if member.AuthMethod != "" {
-		switch member.AuthMethod {
-		case "bearer":
-			// In a real implementation, you'd get the token from a secure source
-			req.Header.Set("Authorization", "Bearer "+member.ID)
-		case "api-key":
-			req.Header.Set("X-API-Key", member.ID)
+// pingMember probes a member, retrying failed probes up to RetryCount times
+// with exponential backoff capped by the check timeout. It returns the result
+// of the last attempt and how many retries were used.
+func (h *HealthChecker) pingMember(ctx context.Context, member *Member) (bool, float64, int, error) {
+	delay := h.retryDelay
+	for retries := 0; ; retries++ {
+		healthy, responseTime, err := h.probeMember(ctx, member)
+		if healthy || retries >= h.config.RetryCount {
+			return healthy, responseTime, retries, err
 		}
-	}
 
-	// Perform the request
-	resp, err := h.client.Do(req)
-	if err != nil {
-		return false, 0, fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
+		slog.Debug("Health probe failed, retrying",
+			"member_id", member.ID,
+			"attempt", retries+1,
+			"delay", delay,
+			"error", err)
 
-	responseTime := time.Since(start).Seconds()
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false, responseTime, retries, fmt.Errorf("health check cancelled: %w", ctx.Err())
+		case <-timer.C:
+		}
 
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		return false, responseTime, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		delay *= 2
+		if h.config.Timeout > 0 && delay > h.config.Timeout {
+			delay = h.config.Timeout
+		}
 	}
+}
 
-	// Parse response body
-	var healthResp struct {
-		Status  string                 `json:"status"`
-		Metrics map[string]interface{} `json:"metrics,omitempty"`
+// probeMember runs a single health probe against a member, using the prober
+// for its health check type
+func (h *HealthChecker) probeMember(ctx context.Context, member *Member) (bool, float64, error) {
+	checkType := member.HealthCheckType
+	if checkType == "" {
+		checkType = HealthCheckHTTP
+	}
+	prober, exists := h.probers[checkType]
+	if !exists {
+		return false, 0, fmt.Errorf("unknown health check type %q", checkType)
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&healthResp); err != nil {
-		return false, responseTime, fmt.Errorf("failed to decode response: %w", err)
+	start := time.Now()
+	err := prober.Probe(ctx, member)
+	responseTime := time.Since(start).Seconds()
+	if err != nil {
+		return false, responseTime, err
 	}
+	return true, responseTime, nil
+}
 
-	// Check if member reports as healthy
-	if healthResp.Status != "healthy" && healthResp.Status != "ok" {
-		return false, responseTime, fmt.Errorf("member reports status: %s", healthResp.Status)
+// redactToken hides all but the last few characters of a credential so it
+// can be logged
+func redactToken(token string) string {
+	const visible = 4
+	if len(token) <= 2*visible {
+		return "[REDACTED]"
 	}
+	return "[REDACTED]" + token[len(token)-visible:]
+}
 
-	// Apply role-specific health checks
-	if !h.checkRoleSpecificHealth(member, healthResp.Metrics) {
-		return false, responseTime, fmt.Errorf("role-specific health check failed")
+// healthCriteria returns the health criteria for a member: the role-level
+// checks with any non-zero per-member overrides applied on top
+func (h *HealthChecker) healthCriteria(member *Member) (HealthCheck, bool) {
+	criteria, exists := h.config.RoleSpecificChecks[string(member.Role)]
+	if member.HealthCheck == nil {
+		return criteria, exists
 	}
 
-	return true, responseTime, nil
+	if member.HealthCheck.ResponseTime > 0 {
+		criteria.ResponseTime = member.HealthCheck.ResponseTime
+	}
+	if member.HealthCheck.TaskSuccess > 0 {
+		criteria.TaskSuccess = member.HealthCheck.TaskSuccess
+	}
+	if member.HealthCheck.Uptime > 0 {
+		criteria.Uptime = member.HealthCheck.Uptime
+	}
+	return criteria, true
 }
 
 // checkRoleSpecificHealth applies role-specific health criteria
 func (h *HealthChecker) checkRoleSpecificHealth(member *Member, metrics map[string]interface{}) bool {
-	roleChecks, exists := h.config.RoleSpecificChecks[string(member.Role)]
+	roleChecks, exists := h.healthCriteria(member)
 	if !exists {
-		return true // No specific checks for this role
+		return true // No specific checks for this member or role
 	}
 
 	// Check response time
@@ -294,6 +513,55 @@ func (h *HealthChecker) GetAllHealthStatus() map[string]*MemberHealth {
 	return result
 }
 
+// healthStatuses returns a copy of the latest health of each checked member
+func (h *HealthChecker) healthStatuses() map[string]MemberHealth {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	statuses := make(map[string]MemberHealth, len(h.healthStatus))
+	for id, health := range h.healthStatus {
+		statuses[id] = *health
+	}
+	return statuses
+}
+
+// healthSnapshot reports whether each checked member is healthy
+func (h *HealthChecker) healthSnapshot() map[string]bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	snapshot := make(map[string]bool, len(h.healthStatus))
+	for id, health := range h.healthStatus {
+		snapshot[id] = health.IsHealthy
+	}
+	return snapshot
+}
+
+// departmentHealthState maps the share of unhealthy members onto a
+// department health state
+func (h *HealthChecker) departmentHealthState(healthy, unhealthy int) DepartmentHealthState {
+	if unhealthy == 0 {
+		return DepartmentHealthy
+	}
+
+	degraded, critical := h.config.DegradedThreshold, h.config.CriticalThreshold
+	if degraded <= 0 {
+		degraded = defaultDegradedThreshold
+	}
+	if critical <= 0 {
+		critical = defaultCriticalThreshold
+	}
+
+	share := float64(unhealthy) / float64(healthy+unhealthy)
+	switch {
+	case share >= critical:
+		return DepartmentCritical
+	case share >= degraded:
+		return DepartmentDegraded
+	}
+	return DepartmentHealthy
+}
+
 // GetHealthyMembers returns a list of healthy members
 func (h *HealthChecker) GetHealthyMembers() []string {
 	h.mu.RLock()
@@ -320,4 +588,4 @@ func (h *HealthChecker) GetUnhealthyMembers() []string {
 		}
 	}
 	return unhealthy
-}
\ No newline at end of file
+}
\ No newline at end of file
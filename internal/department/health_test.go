@@ -0,0 +1,485 @@
+package department
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCheckerReusesConnections(t *testing.T) {
+	t.Parallel()
+
+	var connections atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok"}` + "\n"))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	h := NewHealthChecker(HealthCheckConfig{Timeout: time.Second}, nil)
+	member := &Member{ID: "dev-1", Role: RoleDeveloper, Endpoint: server.URL}
+
+	for range 5 {
+		healthy, _, _, err := h.pingMember(t.Context(), member)
+		require.NoError(t, err)
+		require.True(t, healthy)
+	}
+
+	require.Equal(t, int32(1), connections.Load())
+}
+
+func TestNewProbeTransportDefaults(t *testing.T) {
+	t.Parallel()
+
+	transport := newProbeTransport(HealthCheckConfig{})
+	require.Equal(t, defaultProbeMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	require.Equal(t, defaultProbeIdleConnTimeout, transport.IdleConnTimeout)
+
+	transport = newProbeTransport(HealthCheckConfig{MaxIdleConnsPerHost: 8, IdleConnTimeout: time.Minute})
+	require.Equal(t, 8, transport.MaxIdleConnsPerHost)
+	require.Equal(t, time.Minute, transport.IdleConnTimeout)
+}
+
+func TestHealthCheckerPerMemberCriteria(t *testing.T) {
+	t.Parallel()
+
+	h := NewHealthChecker(HealthCheckConfig{
+		RoleSpecificChecks: map[string]HealthCheck{
+			string(RoleDeveloper): {ResponseTime: time.Second, TaskSuccess: 0.9},
+		},
+	}, nil)
+
+	strict := &Member{ID: "strict", Role: RoleDeveloper}
+	lenient := &Member{
+		ID:          "lenient",
+		Role:        RoleDeveloper,
+		HealthCheck: &HealthCheck{ResponseTime: 5 * time.Second},
+	}
+
+	// 3s is too slow against the role threshold but fine for the override
+	metrics := map[string]interface{}{"response_time": 3.0, "task_success_rate": 0.95}
+	require.False(t, h.checkRoleSpecificHealth(strict, metrics))
+	require.True(t, h.checkRoleSpecificHealth(lenient, metrics))
+
+	// Criteria the override leaves unset still come from the role
+	metrics = map[string]interface{}{"response_time": 0.5, "task_success_rate": 0.5}
+	require.False(t, h.checkRoleSpecificHealth(lenient, metrics))
+
+	// Overrides apply even when the role has no checks
+	qa := &Member{ID: "qa", Role: RoleQA, HealthCheck: &HealthCheck{ResponseTime: time.Second}}
+	require.False(t, h.checkRoleSpecificHealth(qa, map[string]interface{}{"response_time": 2.0}))
+	require.True(t, h.checkRoleSpecificHealth(&Member{Role: RoleQA}, map[string]interface{}{"response_time": 2.0}))
+}
+
+func TestHealthCheckerRetriesWithBackoff(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	failures := int32(2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	member := &Member{ID: "dev-1", Role: RoleDeveloper, Endpoint: server.URL}
+
+	// Two transient failures are absorbed by three retries
+	h := NewHealthChecker(HealthCheckConfig{Timeout: time.Second, RetryCount: 3}, nil)
+	h.retryDelay = time.Millisecond
+	healthy, _, retries, err := h.pingMember(t.Context(), member)
+	require.NoError(t, err)
+	require.True(t, healthy)
+	require.Equal(t, 2, retries)
+	require.Equal(t, int32(3), requests.Load())
+
+	// Without retries the first failure counts
+	requests.Store(0)
+	h = NewHealthChecker(HealthCheckConfig{Timeout: time.Second}, nil)
+	healthy, _, retries, err = h.pingMember(t.Context(), member)
+	require.ErrorContains(t, err, "unexpected status code: 503")
+	require.False(t, healthy)
+	require.Zero(t, retries)
+	require.Equal(t, int32(1), requests.Load())
+}
+
+func TestHealthCheckerRetryRespectsCancellation(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+
+	h := NewHealthChecker(HealthCheckConfig{Timeout: time.Minute, RetryCount: 5}, nil)
+	h.retryDelay = time.Minute
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	healthy, _, retries, err := h.pingMember(ctx, &Member{ID: "dev-1", Endpoint: server.URL})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.False(t, healthy)
+	require.Zero(t, retries)
+	require.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestHealthCheckerSendsAuthToken(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		authMethod string
+		token      string
+		header     string
+		expected   string
+	}{
+		{name: "bearer", authMethod: "bearer", token: "secret-token", header: "Authorization", expected: "Bearer secret-token"},
+		{name: "api key", authMethod: "api-key", token: "secret-key", header: "X-API-Key", expected: "secret-key"},
+		{name: "no token", authMethod: "bearer", header: "Authorization", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			received := make(chan string, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received <- r.Header.Get(tt.header)
+				_, _ = w.Write([]byte(`{"status":"ok"}`))
+			}))
+			t.Cleanup(server.Close)
+
+			h := NewHealthChecker(HealthCheckConfig{Timeout: time.Second}, nil)
+			member := &Member{ID: "dev-1", Endpoint: server.URL, AuthMethod: tt.authMethod, AuthToken: tt.token}
+
+			healthy, _, _, err := h.pingMember(t.Context(), member)
+			require.NoError(t, err)
+			require.True(t, healthy)
+			require.Equal(t, tt.expected, <-received)
+		})
+	}
+}
+
+func TestRedactToken(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "[REDACTED]", redactToken("short"))
+	require.Equal(t, "[REDACTED]cdef", redactToken("0123456789abcdef"))
+}
+
+func TestHealthCheckerReassignsTasksOfUnhealthyMember(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	dev1 := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", Title: "work", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, dev1.ID, task.AssignedMember)
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+	dev2 := registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 1)
+
+	m.mu.Lock()
+	dev1.Endpoint = server.URL
+	m.mu.Unlock()
+
+	h := NewHealthChecker(HealthCheckConfig{
+		Timeout:             time.Second,
+		UnhealthyThreshold:  1,
+		ReassignOnUnhealthy: true,
+	}, m)
+	h.checkMemberHealth(dev1)
+
+	require.Equal(t, MemberStatusUnhealthy, dev1.Status)
+	require.Empty(t, dev1.CurrentTasks)
+	require.Equal(t, dev2.ID, task.AssignedMember)
+	require.Equal(t, TaskStatusAssigned, task.Status)
+	require.Equal(t, []string{task.ID}, dev2.CurrentTasks)
+}
+
+func TestHealthCheckerFailsPinnedTaskOfUnhealthyMember(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	dev1 := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+	pinned, err := m.CreateTask(ctx, &Task{ID: "deploy", Title: "deploy", DepartmentID: "dept-dev", NoReassign: true})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, pinned.ID, TaskStatusInProgress, nil))
+	free, err := m.CreateTask(ctx, &Task{ID: "review", Title: "review", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, free.ID, TaskStatusInProgress, nil))
+	dev2 := registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 2)
+
+	// Pinned tasks cannot be moved by hand either
+	require.ErrorContains(t, m.taskRouter.ReassignTask(ctx, pinned.ID, "manual"), "pinned")
+
+	m.mu.Lock()
+	dev1.Endpoint = server.URL
+	m.mu.Unlock()
+
+	h := NewHealthChecker(HealthCheckConfig{
+		Timeout:             time.Second,
+		UnhealthyThreshold:  1,
+		ReassignOnUnhealthy: true,
+	}, m)
+	h.checkMemberHealth(dev1)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	require.Empty(t, dev1.CurrentTasks)
+	require.Equal(t, TaskStatusFailed, pinned.Status)
+	require.Equal(t, "member_lost", pinned.Results["failure_reason"])
+	require.Equal(t, dev1.ID, pinned.AssignedMember)
+	require.Equal(t, dev2.ID, free.AssignedMember)
+	require.Equal(t, []string{free.ID}, dev2.CurrentTasks)
+}
+
+func TestReassignMemberTasksSkipsTheMember(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	dev1 := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", Title: "work", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+
+	// dev-1 is the only member, so the task cannot move and stays queued
+	moved, err := m.taskRouter.ReassignMemberTasks(ctx, dev1.ID, "member_unhealthy")
+	require.Error(t, err)
+	require.Zero(t, moved)
+	require.Empty(t, dev1.CurrentTasks)
+	require.Equal(t, TaskStatusQueued, task.Status)
+	require.Empty(t, task.AssignedMember)
+}
+
+func TestHealthCheckerPublishesTransitions(t *testing.T) {
+	t.Parallel()
+
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	m := newTestManager(t)
+	member := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	m.mu.Lock()
+	member.Endpoint = server.URL
+	m.mu.Unlock()
+
+	h := NewHealthChecker(HealthCheckConfig{Timeout: time.Second, UnhealthyThreshold: 2}, m)
+	t.Cleanup(h.Stop)
+	events := h.SubscribeToHealthEvents(t.Context())
+
+	// Two failures flip the member once, then it recovers
+	h.checkMemberHealth(member)
+	h.checkMemberHealth(member)
+	healthy.Store(true)
+	h.checkMemberHealth(member)
+	h.checkMemberHealth(member)
+
+	event := <-events
+	require.Equal(t, HealthChangedEvent, event.Type)
+	require.Equal(t, "healthy", event.Payload.PreviousStatus)
+	require.Equal(t, "unhealthy", event.Payload.Status)
+	require.Equal(t, 1, event.Payload.ConsecutiveFails)
+
+	event = <-events
+	require.Equal(t, "unhealthy", event.Payload.PreviousStatus)
+	require.Equal(t, "healthy", event.Payload.Status)
+	require.Zero(t, event.Payload.ConsecutiveFails)
+	require.Empty(t, events)
+}
+
+func TestManagerHealthEventsWithoutHealthChecks(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+	_, ok := <-m.SubscribeToHealthEvents(t.Context())
+	require.False(t, ok)
+}
+
+func TestHealthCheckerRecoveryHysteresis(t *testing.T) {
+	t.Parallel()
+
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	m := newTestManager(t)
+	member := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	m.mu.Lock()
+	member.Endpoint = server.URL
+	m.mu.Unlock()
+
+	h := NewHealthChecker(HealthCheckConfig{
+		Timeout:            time.Second,
+		UnhealthyThreshold: 1,
+		HealthyThreshold:   3,
+	}, m)
+
+	check := func(pass bool) *MemberHealth {
+		healthy.Store(pass)
+		h.checkMemberHealth(member)
+		health, err := h.GetMemberHealth(member.ID)
+		require.NoError(t, err)
+		return health
+	}
+
+	// Alternating results never bring the member back
+	for range 3 {
+		check(false)
+		require.Equal(t, MemberStatusUnhealthy, member.Status)
+		health := check(true)
+		require.Equal(t, MemberStatusUnhealthy, member.Status)
+		require.False(t, health.IsHealthy)
+		require.Equal(t, 1, health.ConsecutiveSuccesses)
+	}
+
+	// Enough passes in a row restore it
+	check(true)
+	require.Equal(t, MemberStatusUnhealthy, member.Status)
+	health := check(true)
+	require.Equal(t, MemberStatusOnline, member.Status)
+	require.True(t, health.IsHealthy)
+	require.Equal(t, 3, health.ConsecutiveSuccesses)
+}
+
+func TestHealthCheckerBoundsConcurrentChecks(t *testing.T) {
+	t.Parallel()
+
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			seen := peak.Load()
+			if current <= seen || peak.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	m := newTestManager(t)
+	members := 0
+	for _, dept := range m.ListDepartments() {
+		for i := range 3 {
+			member := registerTestMember(t, m, fmt.Sprintf("%s-%d", dept.ID, i), dept.ID, RoleDeveloper, 1)
+			m.mu.Lock()
+			member.Endpoint = server.URL
+			m.mu.Unlock()
+			members++
+		}
+	}
+	require.Greater(t, members, 3)
+
+	h := NewHealthChecker(HealthCheckConfig{Timeout: time.Second, MaxConcurrentChecks: 3}, m)
+	h.performHealthCheck()
+
+	require.Len(t, h.GetHealthyMembers(), members)
+	require.LessOrEqual(t, peak.Load(), int32(3))
+	require.Zero(t, inFlight.Load())
+}
+
+func TestHealthCheckerPauseSuspendsStatusChanges(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled: true,
+		HealthCheck: HealthCheckConfig{
+			Enabled:             true,
+			CheckInterval:       time.Hour,
+			Timeout:             time.Second,
+			UnhealthyThreshold:  2,
+			ReassignOnUnhealthy: true,
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, m.Stop()) })
+
+	dev1 := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", Title: "work", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 1)
+	m.mu.Lock()
+	dev1.Endpoint = server.URL
+	m.mu.Unlock()
+
+	// Probes fail throughout the deploy window, but the member stays put
+	require.NoError(t, m.PauseHealthChecks(time.Hour))
+	require.True(t, m.healthChecker.Paused())
+	for range 3 {
+		m.healthChecker.checkMemberHealth(dev1)
+	}
+	member, err := m.GetMember(dev1.ID)
+	require.NoError(t, err)
+	require.Equal(t, MemberStatusBusy, member.Status)
+	stored, err := m.GetTask(task.ID)
+	require.NoError(t, err)
+	require.Equal(t, dev1.ID, stored.AssignedMember)
+
+	health, err := m.healthChecker.GetMemberHealth(dev1.ID)
+	require.NoError(t, err)
+	require.Equal(t, 3, health.ConsecutiveFails)
+
+	// A member still failing after the window is marked on the next check
+	require.NoError(t, m.ResumeHealthChecks())
+	require.False(t, m.healthChecker.Paused())
+	m.healthChecker.checkMemberHealth(dev1)
+	member, err = m.GetMember(dev1.ID)
+	require.NoError(t, err)
+	require.Equal(t, MemberStatusUnhealthy, member.Status)
+	stored, err = m.GetTask(task.ID)
+	require.NoError(t, err)
+	require.Equal(t, "dev-2", stored.AssignedMember)
+
+	require.Error(t, newTestManager(t).PauseHealthChecks(time.Hour))
+}
@@ -0,0 +1,86 @@
+package department
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+)
+
+// heartbeatExpiredReason is the reassignment reason of tasks taken from
+// members that stopped being seen
+const heartbeatExpiredReason = "member_heartbeat_expired"
+
+// heartbeatMonitor marks members offline once they have gone unseen for
+// longer than the heartbeat timeout
+func (m *Manager) heartbeatMonitor(ctx context.Context) {
+	ticker := time.NewTicker(max(m.config.MemberHeartbeatTimeout/2, minQueueWaitCheckInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.expireSilentMembers(ctx, time.Now())
+		}
+	}
+}
+
+// expireSilentMembers marks every member last seen more than the heartbeat
+// timeout before now offline, moves its tasks to other members and returns
+// how many members expired
+func (m *Manager) expireSilentMembers(ctx context.Context, now time.Time) int {
+	timeout := m.config.MemberHeartbeatTimeout
+	if timeout <= 0 {
+		return 0
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expired := 0
+	for _, member := range m.members {
+		if member.Status == MemberStatusOffline || now.Sub(member.LastSeen) <= timeout {
+			continue
+		}
+
+		slog.Warn("Member heartbeat expired, marking offline",
+			"member_id", member.ID,
+			"department", member.DepartmentID,
+			"last_seen", member.LastSeen)
+		member.Status = MemberStatusOffline
+		expired++
+
+		if m.taskRouter != nil {
+			if _, err := m.taskRouter.reassignMemberTasks(ctx, member, heartbeatExpiredReason); err != nil {
+				slog.Warn("Failed to reassign some tasks of expired member",
+					"member_id", member.ID,
+					"error", err)
+			}
+		}
+		m.memberEvents.Publish(pubsub.UpdatedEvent, member)
+	}
+
+	if expired > 0 {
+		m.persist()
+	}
+	return expired
+}
+
+// seeMember records that a member was seen at the given time
+func (m *Manager) seeMember(memberID string, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.touchMember(memberID, at)
+}
+
+// touchMember records that a member was seen at the given time, unless it
+// was already seen later. The caller must hold the manager lock.
+func (m *Manager) touchMember(memberID string, at time.Time) {
+	if member, exists := m.members[memberID]; exists && at.After(member.LastSeen) {
+		member.LastSeen = at
+	}
+}
@@ -0,0 +1,59 @@
+package department
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerExpiresSilentMembers(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m, err := NewManager(ctx, &DepartmentConfig{Enabled: true, MemberHeartbeatTimeout: time.Minute})
+	require.NoError(t, err)
+	silent := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, silent.ID, task.AssignedMember)
+	registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 1)
+
+	updates := m.SubscribeToMemberEvents(ctx)
+
+	// The clock is driven by hand, starting from when the members joined
+	start, err := m.GetMember("dev-2")
+	require.NoError(t, err)
+	now := start.LastSeen
+
+	// Task activity counts as being seen
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+	require.Zero(t, m.expireSilentMembers(ctx, now.Add(time.Minute)))
+
+	// Only dev-2 keeps sending heartbeats
+	m.seeMember("dev-2", now.Add(90*time.Second))
+	require.Equal(t, 1, m.expireSilentMembers(ctx, now.Add(2*time.Minute)))
+
+	member, err := m.GetMember(silent.ID)
+	require.NoError(t, err)
+	require.Equal(t, MemberStatusOffline, member.Status)
+
+	// Its task moved to the member still alive
+	task, err = m.GetTask(task.ID)
+	require.NoError(t, err)
+	require.Equal(t, "dev-2", task.AssignedMember)
+
+	published := false
+	for !published {
+		select {
+		case event := <-updates:
+			published = event.Payload.ID == silent.ID && event.Payload.Status == MemberStatusOffline
+		default:
+			t.Fatal("no update was published for the expired member")
+		}
+	}
+
+	// Offline members are not expired again
+	require.Zero(t, m.expireSilentMembers(ctx, now.Add(2*time.Minute)))
+}
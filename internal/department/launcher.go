@@ -0,0 +1,224 @@
+package department
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemberSpec describes a member the auto-scaler wants started
+type MemberSpec struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	DepartmentID    string     `json:"department_id"`
+	Role            MemberRole `json:"role"`
+	Specializations []string   `json:"specializations,omitempty"`
+	MaxConcurrent   int        `json:"max_concurrent"`
+}
+
+// MemberLauncher starts and stops the instances behind auto-scaled members
+type MemberLauncher interface {
+	// Launch starts an instance for the member and returns the endpoint it
+	// serves on
+	Launch(ctx context.Context, spec MemberSpec) (endpoint string, err error)
+	// Terminate stops the instance started for the member
+	Terminate(ctx context.Context, memberID string) error
+}
+
+// WithMemberLauncher makes the auto-scaler launch an instance for every
+// member it adds and terminate it when the member is removed
+func WithMemberLauncher(launcher MemberLauncher) ManagerOption {
+	return func(m *Manager) {
+		m.launcher = launcher
+	}
+}
+
+// Environment variables a LocalProcessLauncher passes to each process
+const (
+	EnvMemberID         = "CCL_MAGIC_MEMBER_ID"
+	EnvMemberDepartment = "CCL_MAGIC_MEMBER_DEPARTMENT"
+	EnvMemberRole       = "CCL_MAGIC_MEMBER_ROLE"
+	EnvMemberPort       = "CCL_MAGIC_MEMBER_PORT"
+)
+
+// stoppableLauncher is implemented by launchers that can stop every instance
+// they started at once
+type stoppableLauncher interface {
+	Stop(ctx context.Context) error
+}
+
+// defaultTerminateGrace is how long a terminated process gets to exit after
+// an interrupt before it is killed
+const defaultTerminateGrace = 5 * time.Second
+
+// LocalProcessLauncher runs each member as a subprocess listening on a free
+// local port. The port is passed in the CCL_MAGIC_MEMBER_PORT environment
+// variable and replaces any "{port}" in the command's arguments. Processes
+// that exit on their own are reaped and stop being tracked.
+type LocalProcessLauncher struct {
+	command []string
+	host    string
+
+	mu        sync.Mutex
+	processes map[string]*memberProcess
+}
+
+// memberProcess is a running member process. exited is closed once the
+// process has been waited for.
+type memberProcess struct {
+	cmd    *exec.Cmd
+	exited chan struct{}
+}
+
+// NewLocalProcessLauncher creates a launcher that starts members by running
+// command
+func NewLocalProcessLauncher(command ...string) *LocalProcessLauncher {
+	return &LocalProcessLauncher{
+		command:   command,
+		host:      "127.0.0.1",
+		processes: make(map[string]*memberProcess),
+	}
+}
+
+// Launch starts a process for the member
+func (l *LocalProcessLauncher) Launch(ctx context.Context, spec MemberSpec) (string, error) {
+	if len(l.command) == 0 {
+		return "", errors.New("no member command configured")
+	}
+
+	port, err := freePort(l.host)
+	if err != nil {
+		return "", fmt.Errorf("failed to find a free port: %w", err)
+	}
+
+	args := make([]string, len(l.command)-1)
+	for i, arg := range l.command[1:] {
+		args[i] = strings.ReplaceAll(arg, "{port}", strconv.Itoa(port))
+	}
+
+	// The process outlives the launch request, so it is not bound to ctx
+	cmd := exec.Command(l.command[0], args...)
+	cmd.Env = append(os.Environ(),
+		EnvMemberID+"="+spec.ID,
+		EnvMemberDepartment+"="+spec.DepartmentID,
+		EnvMemberRole+"="+string(spec.Role),
+		EnvMemberPort+"="+strconv.Itoa(port))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, exists := l.processes[spec.ID]; exists {
+		return "", fmt.Errorf("member %s is already running", spec.ID)
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start member process: %w", err)
+	}
+	process := &memberProcess{cmd: cmd, exited: make(chan struct{})}
+	l.processes[spec.ID] = process
+	go l.reap(spec.ID, process)
+
+	slog.Info("Launched member process",
+		"member_id", spec.ID,
+		"pid", cmd.Process.Pid,
+		"port", port)
+
+	return fmt.Sprintf("http://%s", net.JoinHostPort(l.host, strconv.Itoa(port))), nil
+}
+
+// reap waits for a member process to exit and stops tracking it if it exited
+// without being terminated
+func (l *LocalProcessLauncher) reap(memberID string, process *memberProcess) {
+	err := process.cmd.Wait()
+	close(process.exited)
+
+	l.mu.Lock()
+	unexpected := l.processes[memberID] == process
+	if unexpected {
+		delete(l.processes, memberID)
+	}
+	l.mu.Unlock()
+
+	if unexpected {
+		slog.Warn("Member process exited",
+			"member_id", memberID,
+			"pid", process.cmd.Process.Pid,
+			"error", err)
+	}
+}
+
+// Terminate interrupts the member's process and kills it if it does not exit
+// in time or ctx is done first
+func (l *LocalProcessLauncher) Terminate(ctx context.Context, memberID string) error {
+	l.mu.Lock()
+	process, exists := l.processes[memberID]
+	delete(l.processes, memberID)
+	l.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("no process running for member %s", memberID)
+	}
+
+	l.stop(ctx, memberID, process)
+	return nil
+}
+
+// stop interrupts a process that is no longer tracked and kills it if it does
+// not exit in time or ctx is done first
+func (l *LocalProcessLauncher) stop(ctx context.Context, memberID string, process *memberProcess) {
+	cmd := process.cmd
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		_ = cmd.Process.Kill()
+	}
+
+	timer := time.NewTimer(defaultTerminateGrace)
+	defer timer.Stop()
+
+	select {
+	case <-process.exited:
+	case <-timer.C:
+		_ = cmd.Process.Kill()
+		<-process.exited
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		<-process.exited
+	}
+
+	slog.Info("Terminated member process", "member_id", memberID, "pid", cmd.Process.Pid)
+}
+
+// Stop terminates every process the launcher is still tracking
+func (l *LocalProcessLauncher) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	processes := l.processes
+	l.processes = make(map[string]*memberProcess)
+	l.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for memberID, process := range processes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.stop(ctx, memberID, process)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// freePort asks the OS for a port that is currently free on host
+func freePort(host string) (int, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
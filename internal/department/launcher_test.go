@@ -0,0 +1,155 @@
+package department
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLauncher struct {
+	mu         sync.Mutex
+	launched   []MemberSpec
+	terminated []string
+}
+
+func (l *fakeLauncher) Launch(ctx context.Context, spec MemberSpec) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.launched = append(l.launched, spec)
+	return fmt.Sprintf("http://127.0.0.1:%d", 9000+len(l.launched)), nil
+}
+
+func (l *fakeLauncher) Terminate(ctx context.Context, memberID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.terminated = append(l.terminated, memberID)
+	return nil
+}
+
+func TestAutoScalerLaunchesAndTerminatesMembers(t *testing.T) {
+	t.Parallel()
+
+	launcher := &fakeLauncher{}
+	m, err := NewManager(t.Context(), &DepartmentConfig{Enabled: true}, WithMemberLauncher(launcher))
+	require.NoError(t, err)
+	as := NewAutoScaler(AutoScalingConfig{RoleScaling: map[string]int{"developer": 5}}, m)
+	t.Cleanup(as.Stop)
+
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+
+	added := as.scaleUp(dept, "high_utilization")
+	require.NotNil(t, added)
+	require.Len(t, launcher.launched, 1)
+	require.Equal(t, MemberSpec{
+		ID:              added.ID,
+		Name:            added.Name,
+		DepartmentID:    dept.ID,
+		Role:            added.Role,
+		Specializations: added.Specializations,
+		MaxConcurrent:   added.MaxConcurrent,
+	}, launcher.launched[0])
+	require.Equal(t, "http://127.0.0.1:9001", added.Endpoint)
+
+	removed := as.scaleDown(dept)
+	require.NotNil(t, removed)
+	require.Equal(t, added.ID, removed.ID)
+	require.Equal(t, []string{added.ID}, launcher.terminated)
+}
+
+func TestLocalProcessLauncher(t *testing.T) {
+	t.Parallel()
+
+	out := filepath.Join(t.TempDir(), "member.env")
+	launcher := NewLocalProcessLauncher("sh", "-c",
+		fmt.Sprintf(`echo "$%s $%s {port}" > %s; exec sleep 30`, EnvMemberID, EnvMemberPort, out))
+
+	endpoint, err := launcher.Launch(t.Context(), MemberSpec{ID: "dev-1", DepartmentID: "dept-dev", Role: RoleDeveloper})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(endpoint, "http://127.0.0.1:"))
+	port := strings.TrimPrefix(endpoint, "http://127.0.0.1:")
+
+	// The port reaches the process both ways
+	require.Eventually(t, func() bool {
+		data, err := os.ReadFile(out)
+		return err == nil && strings.TrimSpace(string(data)) == "dev-1 "+port+" "+port
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = launcher.Launch(t.Context(), MemberSpec{ID: "dev-1"})
+	require.ErrorContains(t, err, "already running")
+
+	start := time.Now()
+	require.NoError(t, launcher.Terminate(t.Context(), "dev-1"))
+	require.Less(t, time.Since(start), 10*time.Second)
+	require.ErrorContains(t, launcher.Terminate(t.Context(), "dev-1"), "no process running")
+}
+
+func TestLocalProcessLauncherReapsAndStops(t *testing.T) {
+	t.Parallel()
+
+	launcher := NewLocalProcessLauncher("sh", "-c", `[ "$`+EnvMemberID+`" = "dev-1" ] || exec sleep 30`)
+
+	// A process that exits on its own stops being tracked, so the member can
+	// be launched again
+	_, err := launcher.Launch(t.Context(), MemberSpec{ID: "dev-1"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return launcher.Terminate(t.Context(), "dev-1") != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = launcher.Launch(t.Context(), MemberSpec{ID: "dev-1"})
+	require.NoError(t, err)
+
+	_, err = launcher.Launch(t.Context(), MemberSpec{ID: "dev-2"})
+	require.NoError(t, err)
+	_, err = launcher.Launch(t.Context(), MemberSpec{ID: "dev-3"})
+	require.NoError(t, err)
+
+	require.NoError(t, launcher.Stop(t.Context()))
+	for _, id := range []string{"dev-2", "dev-3"} {
+		require.ErrorContains(t, launcher.Terminate(t.Context(), id), "no process running")
+	}
+}
+
+func TestManagerPruneTerminatesLaunchedMembers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	launcher := &fakeLauncher{}
+	m, err := NewManager(ctx, &DepartmentConfig{Enabled: true, CapActiveMembersOnly: true}, WithMemberLauncher(launcher))
+	require.NoError(t, err)
+
+	dept, err := m.GetDepartment("dept-security")
+	require.NoError(t, err)
+	for i := range dept.MaxMembers {
+		id := fmt.Sprintf("sec-%d", i)
+		require.NoError(t, m.RegisterMember(ctx, &Member{
+			ID:           id,
+			Role:         RoleSecurity,
+			DepartmentID: dept.ID,
+			Metadata:     map[string]string{"auto_scaled": strconv.FormatBool(i == 0)},
+		}))
+		require.NoError(t, m.UpdateMemberStatus(ctx, id, MemberStatusOffline))
+	}
+
+	registerTestMember(t, m, "sec-new", dept.ID, RoleSecurity, 1)
+
+	// Only the auto-scaled member had an instance to terminate
+	require.Eventually(t, func() bool {
+		launcher.mu.Lock()
+		defer launcher.mu.Unlock()
+		return len(launcher.terminated) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	launcher.mu.Lock()
+	defer launcher.mu.Unlock()
+	require.Equal(t, []string{"sec-0"}, launcher.terminated)
+}
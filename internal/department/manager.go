@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,14 +27,19 @@ type Manager struct {
 	departmentEvents *pubsub.Broker[*Department]
 	memberEvents     *pubsub.Broker[*Member]
 	taskEvents       *pubsub.Broker[*Task]
+	summaryEvents    *pubsub.Broker[*TaskLifecycleSummary]
+	progressEvents   *pubsub.Broker[*TaskProgress]
 
-	// Statistics tracking
-	departmentStats map[string]*DepartmentStats
-	memberStats     map[string]*MemberStats
+	// Statistics tracking. Department statistics are computed on read.
+	memberStats map[string]*MemberStats
 
 	// Management state
 	isRunning bool
 	mu        sync.RWMutex
+	// Set once Shutdown begins; no new tasks are accepted after it
+	shuttingDown bool
+	// Cancels the background monitors started by Start
+	stopMonitors context.CancelFunc
 
 	// Health monitoring
 	healthChecker *HealthChecker
@@ -40,7 +48,70 @@ type Manager struct {
 	taskRouter *TaskRouter
 
 	// Auto-scaling
-	scaler *AutoScaler
+	scaler   *AutoScaler
+	launcher MemberLauncher
+
+	// Notifications
+	notifier *Notifier
+	mailer   Mailer
+
+	// Periodic reports
+	reporter *Reporter
+
+	// Persistence. States are written in the background in the order they
+	// were taken; persistSeq numbers them and is guarded by mu, writtenSeq
+	// is the latest one written and is guarded by persistMu.
+	store      StateStore
+	persistMu  sync.Mutex
+	persistSeq uint64
+	writtenSeq uint64
+	persisting sync.WaitGroup
+
+	// Attachment content spilled out of memory
+	blobs BlobStore
+
+	// Busy members waiting for their tasks to finish before moving to
+	// another department, keyed by member ID
+	pendingMigrations map[string]string
+
+	// Transient teams formed for a single task, keyed by task ID
+	taskTeams map[string]string
+
+	// Workflow runs keyed by run ID
+	workflowRuns map[string]*WorkflowRun
+
+	// Queued tasks already flagged for exceeding their max queue wait
+	queueWaitAlerts map[string]bool
+
+	// Recent queue waits of assigned tasks, per department
+	queueWaits map[string][]time.Duration
+
+	// Routing retries per queued task
+	routingRetries map[string]int
+
+	// Departments reserved for a single session, keyed by department ID
+	reservations map[string]*DepartmentReservation
+
+	// Blocked tasks waiting on another task, keyed by the task they wait on
+	dependents map[string][]string
+
+	// Tasks per department, keyed by department ID and then task ID
+	departmentTasks map[string]map[string]*Task
+
+	// Department each task is indexed under, keyed by task ID
+	taskIn map[string]string
+
+	// Queued tasks per department, keyed by department ID and then task ID
+	queued map[string]map[string]*Task
+
+	// Department each queued task is indexed under, keyed by task ID
+	queuedIn map[string]string
+
+	// Blocked tasks already flagged for exceeding the max blocked time
+	blockedAlerts map[string]bool
+
+	// Tasks created, completed and failed since start, per department
+	taskCounts map[string]*taskCounts
 }
 
 // ManagerOption represents a configuration option for the department manager
@@ -48,6 +119,10 @@ type ManagerOption func(*Manager)
 
 // NewManager creates a new department manager with the given configuration
 func NewManager(ctx context.Context, config *DepartmentConfig, opts ...ManagerOption) (*Manager, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid department config: %w", err)
+	}
+
 	m := &Manager{
 		config:          config,
 		departments:     make(map[string]*Department),
@@ -58,8 +133,23 @@ func NewManager(ctx context.Context, config *DepartmentConfig, opts ...ManagerOp
 		departmentEvents: pubsub.NewBroker[*Department](),
 		memberEvents:     pubsub.NewBroker[*Member](),
 		taskEvents:       pubsub.NewBroker[*Task](),
-		departmentStats:  make(map[string]*DepartmentStats),
+		summaryEvents:    pubsub.NewBroker[*TaskLifecycleSummary](),
+		progressEvents:   pubsub.NewBroker[*TaskProgress](),
 		memberStats:      make(map[string]*MemberStats),
+		pendingMigrations: make(map[string]string),
+		taskTeams:         make(map[string]string),
+		workflowRuns:      make(map[string]*WorkflowRun),
+		queueWaitAlerts:   make(map[string]bool),
+		queueWaits:        make(map[string][]time.Duration),
+		routingRetries:    make(map[string]int),
+		reservations:      make(map[string]*DepartmentReservation),
+		dependents:        make(map[string][]string),
+		departmentTasks:   make(map[string]map[string]*Task),
+		taskIn:            make(map[string]string),
+		queued:            make(map[string]map[string]*Task),
+		queuedIn:          make(map[string]string),
+		blockedAlerts:     make(map[string]bool),
+		taskCounts:        make(map[string]*taskCounts),
 	}
 
 	// Apply options
@@ -93,18 +183,40 @@ func (m *Manager) initializeComponents(ctx context.Context) error {
 	// Initialize task router
 	m.taskRouter = NewTaskRouter(m.config.TaskRouting, m)
 
+	// Spill large attachments to files unless another store was given
+	if m.config.AttachmentSpillThreshold > 0 && m.blobs == nil {
+		dir := m.config.AttachmentDir
+		if dir == "" {
+			dir = defaultAttachmentDir
+		}
+		m.blobs = NewFileBlobStore(dir)
+	}
+
 	// Initialize auto-scaler
 	if m.config.AutoScaling.Enabled {
 		m.scaler = NewAutoScaler(m.config.AutoScaling, m)
 		go m.scaler.Start(ctx)
 	}
 
+	// Initialize reporter
+	if m.config.Reporting.Enabled && m.config.Reporting.ReportInterval > 0 {
+		m.reporter = NewReporter(m.config.Reporting, m)
+		go m.reporter.Start(ctx)
+	}
+
+	// Initialize notifier last so it can subscribe to every component
+	if m.config.Notifications.Enabled {
+		m.notifier = NewNotifier(m.config.Notifications, m, m.mailer)
+		m.notifier.Start(ctx)
+	}
+
 	return nil
 }
 
-// setupDefaultDepartments creates the default department structure
-func (m *Manager) setupDefaultDepartments() error {
-	defaultDepartments := []Department{
+// defaultDepartments returns the departments a manager starts with when it
+// has none
+func defaultDepartments() []Department {
+	return []Department{
 		{
 			ID:          "dept-dev",
 			Name:        "Development Services",
@@ -146,19 +258,15 @@ func (m *Manager) setupDefaultDepartments() error {
 			AutoScale:   true,
 		},
 	}
+}
 
+// setupDefaultDepartments creates the default department structure
+func (m *Manager) setupDefaultDepartments() error {
 	now := time.Now()
-	for _, dept := range defaultDepartments {
+	for _, dept := range defaultDepartments() {
 		dept.CreatedAt = now
 		dept.UpdatedAt = now
 		m.departments[dept.ID] = &dept
-		m.departmentStats[dept.ID] = &DepartmentStats{
-			DepartmentID:    dept.ID,
-			TotalMembers:    0,
-			ActiveMembers:   0,
-			RoleDistribution: make(map[string]int),
-			LastUpdated:     now,
-		}
 	}
 
 	return nil
@@ -172,31 +280,78 @@ func (m *Manager) Start(ctx context.Context) error {
 	if m.isRunning {
 		return fmt.Errorf("department manager is already running")
 	}
+	if m.shuttingDown {
+		return fmt.Errorf("department manager has been shut down")
+	}
+
+	if err := m.loadState(); err != nil {
+		return err
+	}
 
 	m.isRunning = true
+	ctx, m.stopMonitors = context.WithCancel(ctx)
 	slog.Info("Department manager started")
 
 	// Start background processes
-	go m.statisticsUpdater(ctx)
+	if len(m.config.TaskRouting.MaxQueueWait) > 0 {
+		go m.queueWaitMonitor(ctx)
+	}
+	if m.config.TaskRouting.AckTimeout > 0 {
+		go m.ackTimeoutMonitor(ctx)
+	}
+	if m.config.TaskRouting.RetryInterval > 0 {
+		go m.routingRetryMonitor(ctx)
+	}
+	if m.config.TaskRouting.OverdueCheckInterval > 0 {
+		go m.overdueMonitor(ctx)
+	}
+	if m.config.TaskRouting.MaxBlockedTime > 0 {
+		go m.blockedMonitor(ctx)
+	}
+	if m.config.MemberHeartbeatTimeout > 0 {
+		go m.heartbeatMonitor(ctx)
+	}
 
 	return nil
 }
 
-// Stop stops the department manager
+// Stop stops the department manager once pending state writes are done. Its
+// event brokers are shut down, so a stopped manager is not started again.
 func (m *Manager) Stop() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	// State writes do not take the manager lock, so they finish while it is
+	// held
+	m.persisting.Wait()
+
 	if !m.isRunning {
 		return nil
 	}
 
 	m.isRunning = false
+	m.stopMonitors()
+
+	if m.healthChecker != nil {
+		m.healthChecker.Stop()
+	}
+	if m.notifier != nil {
+		m.notifier.Stop()
+	}
+	if m.reporter != nil {
+		m.reporter.Stop()
+	}
+
+	for _, reservation := range m.reservations {
+		reservation.timer.Stop()
+	}
 
 	// Shutdown event brokers
 	m.departmentEvents.Shutdown()
 	m.memberEvents.Shutdown()
 	m.taskEvents.Shutdown()
+	m.summaryEvents.Shutdown()
+	m.progressEvents.Shutdown()
 
 	slog.Info("Department manager stopped")
 	return nil
@@ -212,10 +367,27 @@ func (m *Manager) RegisterMember(ctx context.Context, member *Member) error {
 	if !exists {
 		return fmt.Errorf("department %s does not exist", member.DepartmentID)
 	}
+	if dept.Disabled {
+		return fmt.Errorf("department %s is disabled", member.DepartmentID)
+	}
+	if member.CostPerTask < 0 {
+		return fmt.Errorf("member %s cost per task must not be negative", member.ID)
+	}
+
+	// A known member registering again is reconnecting
+	if existing, exists := m.members[member.ID]; exists {
+		return m.reconnectMember(existing, member)
+	}
 
 	// Check if we can add more members
 	if dept.MaxMembers > 0 {
 		currentCount := m.countDepartmentMembers(member.DepartmentID)
+		if m.config.CapActiveMembersOnly {
+			if currentCount >= dept.MaxMembers {
+				m.pruneInactiveMembers(member.DepartmentID)
+			}
+			currentCount = m.countActiveDepartmentMembers(member.DepartmentID)
+		}
 		if currentCount >= dept.MaxMembers {
 			return fmt.Errorf("department %s has reached maximum member capacity", member.DepartmentID)
 		}
@@ -228,19 +400,20 @@ func (m *Manager) RegisterMember(ctx context.Context, member *Member) error {
 	member.Status = MemberStatusOnline
 
 	// Determine if this is a lead role
-	member.IsLead = isLeadRole(member.Role)
+	member.IsLead = m.config.isLeadRole(member.Role)
 
 	// Add member
 	m.members[member.ID] = member
 
 	// Update statistics
-	m.updateDepartmentStats(member.DepartmentID)
 	m.memberStats[member.ID] = &MemberStats{
 		MemberID:   member.ID,
 		MemberRole: member.Role,
 		LastUpdated: now,
 	}
 
+	m.persist()
+
 	// Publish events
 	m.memberEvents.Publish(pubsub.CreatedEvent, member)
 
@@ -272,8 +445,7 @@ func (m *Manager) UnregisterMember(ctx context.Context, memberID string) error {
 	delete(m.members, memberID)
 	delete(m.memberStats, memberID)
 
-	// Update statistics
-	m.updateDepartmentStats(member.DepartmentID)
+	m.persist()
 
 	// Publish events
 	m.memberEvents.Publish(pubsub.DeletedEvent, member)
@@ -283,6 +455,210 @@ func (m *Manager) UnregisterMember(ctx context.Context, memberID string) error {
 	return nil
 }
 
+// Heartbeat records that a member is alive. Offline or unhealthy members are
+// brought back online with their task list reconciled.
+func (m *Manager) Heartbeat(ctx context.Context, memberID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	member, exists := m.members[memberID]
+	if !exists {
+		return fmt.Errorf("member %s does not exist", memberID)
+	}
+
+	member.LastSeen = time.Now()
+	if member.Status != MemberStatusOffline && member.Status != MemberStatusUnhealthy {
+		return nil
+	}
+
+	oldStatus := member.Status
+	m.reconcileMemberTasks(member)
+	m.persist()
+
+	m.memberEvents.Publish(pubsub.UpdatedEvent, member)
+
+	slog.Info("Member reconnected",
+		"member_id", memberID,
+		"old_status", string(oldStatus),
+		"current_tasks", len(member.CurrentTasks))
+
+	return nil
+}
+
+// reconnectMember refreshes an existing member from a new registration and
+// reconciles its task list. The caller must hold the manager lock.
+func (m *Manager) reconnectMember(existing, registration *Member) error {
+	if registration.DepartmentID != existing.DepartmentID {
+		return fmt.Errorf("member %s is already registered in department %s", existing.ID, existing.DepartmentID)
+	}
+
+	existing.Name = registration.Name
+	existing.Endpoint = registration.Endpoint
+	existing.AuthMethod = registration.AuthMethod
+	existing.AuthToken = registration.AuthToken
+	existing.Specializations = registration.Specializations
+	existing.Capabilities = registration.Capabilities
+	if registration.MaxConcurrent > 0 {
+		existing.MaxConcurrent = registration.MaxConcurrent
+	}
+	if registration.Metadata != nil {
+		existing.Metadata = registration.Metadata
+	}
+	if registration.HealthCheck != nil {
+		existing.HealthCheck = registration.HealthCheck
+	}
+	existing.HealthCheckType = registration.HealthCheckType
+	existing.LastSeen = time.Now()
+
+	m.reconcileMemberTasks(existing)
+	m.persist()
+
+	m.memberEvents.Publish(pubsub.UpdatedEvent, existing)
+
+	slog.Info("Member re-registered",
+		"member_id", existing.ID,
+		"current_tasks", len(existing.CurrentTasks))
+
+	return nil
+}
+
+// UpdateMember applies a patch to a registered member's name, role, skills
+// and capacity. When the role changes, IsLead follows it. When the
+// specializations or capabilities change, or the patch carries a newer
+// CapabilityVersion, the member's capability version is bumped. Lowering the
+// capacity below the member's current load keeps its tasks but takes no new
+// ones until it drains. Queued tasks in the member's department are routed
+// again whenever the change may make them routable.
+func (m *Manager) UpdateMember(ctx context.Context, memberID string, patch MemberPatch) error {
+	if patch.MaxConcurrent < 0 || patch.CapacityUnits < 0 {
+		return fmt.Errorf("member %s capacity must not be negative", memberID)
+	}
+	if patch.CostPerTask < 0 {
+		return fmt.Errorf("member %s cost per task must not be negative", memberID)
+	}
+	if patch.Role != "" && !m.config.isKnownRole(string(patch.Role)) {
+		return fmt.Errorf("unknown member role %s", patch.Role)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	member, exists := m.members[memberID]
+	if !exists {
+		return fmt.Errorf("member %s does not exist", memberID)
+	}
+
+	capabilitiesChanged := (patch.Specializations != nil && !slices.Equal(patch.Specializations, member.Specializations)) ||
+		(patch.Capabilities != nil && !reflect.DeepEqual(patch.Capabilities, member.Capabilities))
+	roleChanged := patch.Role != "" && patch.Role != member.Role
+	previousCapacity := memberCapacity(member)
+
+	if patch.Name != "" {
+		member.Name = patch.Name
+	}
+	if roleChanged {
+		member.Role = patch.Role
+		member.IsLead = m.config.isLeadRole(patch.Role)
+		if stats, exists := m.memberStats[member.ID]; exists {
+			stats.MemberRole = patch.Role
+		}
+	}
+	if patch.Specializations != nil {
+		member.Specializations = patch.Specializations
+	}
+	if patch.Capabilities != nil {
+		member.Capabilities = patch.Capabilities
+	}
+	if patch.MaxConcurrent > 0 {
+		member.MaxConcurrent = patch.MaxConcurrent
+	}
+	if patch.CostPerTask > 0 {
+		member.CostPerTask = patch.CostPerTask
+	}
+	if patch.CapacityUnits > 0 {
+		member.CapacityUnits = patch.CapacityUnits
+	}
+	if patch.Metadata != nil {
+		member.Metadata = patch.Metadata
+	}
+
+	// A member over its new capacity keeps its tasks but is busy until they
+	// drain; one with room again takes new work
+	capacityRaised := memberCapacity(member) > previousCapacity
+	switch {
+	case member.Status == MemberStatusOnline && m.remainingUnits(member) < defaultTaskWeight:
+		member.Status = MemberStatusBusy
+	case member.Status == MemberStatusBusy && m.remainingUnits(member) >= defaultTaskWeight:
+		member.Status = MemberStatusOnline
+	}
+
+	// A newer version from the caller wins; otherwise a capability change
+	// bumps it
+	versionChanged := true
+	switch {
+	case patch.CapabilityVersion > member.CapabilityVersion:
+		member.CapabilityVersion = patch.CapabilityVersion
+	case capabilitiesChanged:
+		member.CapabilityVersion++
+	default:
+		versionChanged = false
+	}
+
+	m.memberEvents.Publish(pubsub.UpdatedEvent, member)
+
+	slog.Info("Member updated",
+		"member_id", member.ID,
+		"role", string(member.Role),
+		"max_concurrent", member.MaxConcurrent,
+		"capability_version", member.CapabilityVersion)
+
+	if versionChanged || roleChanged || capacityRaised {
+		if routed := m.rerouteQueuedTasks(ctx, member.DepartmentID); routed > 0 {
+			slog.Info("Routed queued tasks after member update",
+				"member_id", member.ID,
+				"tasks", routed)
+		}
+	}
+
+	m.persist()
+	return nil
+}
+
+// reconcileMemberTasks rebuilds a member's current tasks from the tasks still
+// assigned to it, dropping anything reassigned or finished while it was away,
+// and brings the member back online
+func (m *Manager) reconcileMemberTasks(member *Member) {
+	var current []string
+	for id, task := range m.tasks {
+		if task.AssignedMember == member.ID && (task.Status == TaskStatusAssigned || task.Status == TaskStatusInProgress) {
+			current = append(current, id)
+		}
+	}
+	slices.Sort(current)
+
+	dropped := len(member.CurrentTasks) - len(current)
+	member.CurrentTasks = current
+	if member.CurrentTasks == nil {
+		member.CurrentTasks = []string{}
+	}
+
+	member.Status = MemberStatusOnline
+	if memberCapacity(member) > 0 && m.remainingUnits(member) < defaultTaskWeight {
+		member.Status = MemberStatusBusy
+	}
+
+	if stats, exists := m.memberStats[member.ID]; exists {
+		stats.CurrentLoad = len(member.CurrentTasks)
+		stats.LastUpdated = time.Now()
+	}
+
+	if dropped > 0 {
+		slog.Info("Dropped stale task assignments",
+			"member_id", member.ID,
+			"dropped", dropped)
+	}
+}
+
 // UpdateMemberStatus updates a member's status
 func (m *Manager) UpdateMemberStatus(ctx context.Context, memberID string, status MemberStatus) error {
 	m.mu.Lock()
@@ -297,9 +673,6 @@ func (m *Manager) UpdateMemberStatus(ctx context.Context, memberID string, statu
 	member.Status = status
 	member.LastSeen = time.Now()
 
-	// Update statistics
-	m.updateDepartmentStats(member.DepartmentID)
-
 	// Publish events
 	m.memberEvents.Publish(pubsub.UpdatedEvent, member)
 
@@ -311,39 +684,100 @@ func (m *Manager) UpdateMemberStatus(ctx context.Context, memberID string, statu
 	return nil
 }
 
+// EstimateTokens roughly estimates the number of tokens in text, at about
+// four bytes per token
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// CheckPromptSize returns an error wrapping ErrPromptTooLarge if prompt is
+// estimated to exceed the configured MaxPromptTokens
+func (m *Manager) CheckPromptSize(prompt string) error {
+	limit := m.config.TaskRouting.MaxPromptTokens
+	if limit <= 0 {
+		return nil
+	}
+	if tokens := EstimateTokens(prompt); tokens > limit {
+		return fmt.Errorf("%w: about %d tokens, limit is %d", ErrPromptTooLarge, tokens, limit)
+	}
+	return nil
+}
+
 // CreateTask creates a new task and routes it to appropriate member
 func (m *Manager) CreateTask(ctx context.Context, task *Task) (*Task, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	if err := m.CheckPromptSize(task.Description); err != nil {
+		return nil, err
+	}
+	if task.RetryPolicy != nil {
+		if err := task.RetryPolicy.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid retry policy: %w", err)
+		}
+	}
 
 	// Generate ID if not provided
 	if task.ID == "" {
 		task.ID = generateTaskID()
 	}
 
+	// Check the task is accepted before spilling, so a rejected task leaves
+	// no blobs behind. Spilling does I/O, so it runs without the lock.
+	m.mu.Lock()
+	err := m.admitTask(task)
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := m.spillAttachments(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Check again since the state may have changed while spilling
+	if err := m.admitTask(task); err != nil {
+		go m.deleteAttachments(context.WithoutCancel(ctx), attachments)
+		return nil, err
+	}
+	task.Attachments = attachments
+
 	// Set timestamps
 	now := time.Now()
 	task.CreatedAt = now
 	task.UpdatedAt = now
+	task.QueuedAt = nil
 	task.Status = TaskStatusQueued
 
-	// Validate department exists
-	if _, exists := m.departments[task.DepartmentID]; !exists {
-		return nil, fmt.Errorf("department %s does not exist", task.DepartmentID)
+	// Tasks waiting on unfinished dependencies stay blocked until they finish
+	if m.unfinishedDependencies(task) > 0 {
+		task.Status = TaskStatusBlocked
+		m.waitForDependencies(task)
+		m.updateBlockedOn(task)
 	}
 
 	// Add task
 	m.tasks[task.ID] = task
+	m.indexTask(task)
+	if len(task.Dependencies) > 0 {
+		m.inheritPriorities()
+	}
 
 	// Route task to appropriate member
-	if m.taskRouter != nil {
-		if err := m.taskRouter.RouteTask(ctx, task); err != nil {
+	if m.taskRouter != nil && task.Status == TaskStatusQueued {
+		if err := m.taskRouter.routeTask(ctx, task); err != nil {
 			slog.Warn("Failed to route task", "task_id", task.ID, "error", err)
 		}
 	}
 
+	m.persist()
+
 	// Publish events
+	m.countTasks(task.DepartmentID).created++
 	m.taskEvents.Publish(pubsub.CreatedEvent, task)
+	if task.Status == TaskStatusBlocked {
+		m.taskEvents.Publish(pubsub.UpdatedEvent, task)
+	}
 
 	slog.Info("Task created",
 		"task_id", task.ID,
@@ -354,11 +788,72 @@ func (m *Manager) CreateTask(ctx context.Context, task *Task) (*Task, error) {
 	return task, nil
 }
 
+// admitTask checks that a new task can be accepted, letting the router pick
+// its department when none is given. The caller must hold the manager lock.
+func (m *Manager) admitTask(task *Task) error {
+	if m.shuttingDown {
+		return fmt.Errorf("department manager is shutting down")
+	}
+
+	// Let the router pick the department when none is given, remembering
+	// why for the routing decision
+	if task.DepartmentID == "" && m.taskRouter != nil {
+		deptID, reason, err := m.taskRouter.determineDepartment(task)
+		if err != nil {
+			return fmt.Errorf("failed to determine department: %w", err)
+		}
+		task.DepartmentID = deptID
+		task.RoutingDecision = &RoutingDecision{DepartmentID: deptID, DepartmentReason: reason}
+	}
+
+	// Validate department exists
+	dept, exists := m.departments[task.DepartmentID]
+	if !exists {
+		return fmt.Errorf("department %s does not exist", task.DepartmentID)
+	}
+	if dept.Disabled {
+		return fmt.Errorf("department %s is disabled", task.DepartmentID)
+	}
+	if err := m.checkQueueCapacity(dept); err != nil {
+		return err
+	}
+	return m.validateDependencies(task)
+}
+
+// TaskSummaryEvent is published on the task summary stream when a task
+// completes or fails
+const TaskSummaryEvent pubsub.EventType = "task_summary"
+
+// taskLifecycleSummary builds the summary record for a finished task
+func taskLifecycleSummary(task *Task) *TaskLifecycleSummary {
+	summary := &TaskLifecycleSummary{
+		TaskID:         task.ID,
+		DepartmentID:   task.DepartmentID,
+		AssignedMember: task.AssignedMember,
+		FinalStatus:    task.Status,
+		Retries:        task.Retries,
+		QueuedDuration: task.QueuedDuration,
+		CompletedAt:    task.UpdatedAt,
+	}
+	if task.CompletedAt != nil {
+		summary.CompletedAt = *task.CompletedAt
+	}
+	if task.StartedAt != nil {
+		summary.ExecutionDuration = summary.CompletedAt.Sub(*task.StartedAt)
+	}
+	summary.TotalDuration = summary.CompletedAt.Sub(task.CreatedAt)
+	return summary
+}
+
 // UpdateTaskStatus updates the status of a task
 func (m *Manager) UpdateTaskStatus(ctx context.Context, taskID string, status TaskStatus, result map[string]interface{}) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	return m.updateTaskStatus(ctx, taskID, status, result)
+}
+
+func (m *Manager) updateTaskStatus(ctx context.Context, taskID string, status TaskStatus, result map[string]interface{}) error {
 	task, exists := m.tasks[taskID]
 	if !exists {
 		return fmt.Errorf("task %s does not exist", taskID)
@@ -367,10 +862,20 @@ func (m *Manager) UpdateTaskStatus(ctx context.Context, taskID string, status Ta
 	oldStatus := task.Status
 	task.Status = status
 	task.UpdatedAt = time.Now()
+	m.indexTask(task)
+
+	// Progress reported on a task shows its member is alive
+	switch status {
+	case TaskStatusInProgress, TaskStatusCompleted, TaskStatusFailed:
+		m.touchMember(task.AssignedMember, task.UpdatedAt)
+	}
 
 	// Handle status-specific logic
 	switch status {
 	case TaskStatusInProgress:
+		if oldStatus != TaskStatusInProgress {
+			task.Attempts++
+		}
 		if task.StartedAt == nil {
 			start := time.Now()
 			task.StartedAt = &start
@@ -383,7 +888,12 @@ func (m *Manager) UpdateTaskStatus(ctx context.Context, taskID string, status Ta
 		// Update member stats and free up capacity
 		if task.AssignedMember != "" {
 			m.updateMemberTaskCompletion(task.AssignedMember, taskID, status == TaskStatusCompleted)
+			if status == TaskStatusCompleted {
+				m.recordTaskDuration(task)
+			}
 		}
+		m.disbandTaskTeam(taskID)
+		m.finishSubtasks(ctx, task)
 	}
 
 	// Store results if provided
@@ -396,61 +906,649 @@ func (m *Manager) UpdateTaskStatus(ctx context.Context, taskID string, status Ta
 		}
 	}
 
+	if isTaskDone(status) {
+		m.resolveDependencies(ctx, task)
+	}
+
+	m.persist()
+
 	// Publish events
 	m.taskEvents.Publish(pubsub.UpdatedEvent, task)
+	if status == TaskStatusCompleted || status == TaskStatusFailed {
+		m.summaryEvents.Publish(TaskSummaryEvent, taskLifecycleSummary(task))
+		if status == TaskStatusCompleted {
+			m.countTasks(task.DepartmentID).completed++
+		} else {
+			m.countTasks(task.DepartmentID).failed++
+		}
+	}
 
 	slog.Info("Task status updated",
 		"task_id", taskID,
 		"old_status", string(oldStatus),
 		"new_status", string(status))
 
+	if status == TaskStatusCompleted || status == TaskStatusFailed {
+		m.advanceWorkflow(ctx, task)
+	}
+
+	// Hand the freed capacity to the most urgent queued work, once the
+	// dependents and workflow steps this task unblocked are queued too
+	if (status == TaskStatusCompleted || status == TaskStatusFailed) && task.AssignedMember != "" {
+		if m.fillFreedCapacity(task.AssignedMember) > 0 {
+			m.persist()
+		}
+	}
+
 	return nil
 }
 
-// GetDepartment returns a department by ID
-func (m *Manager) GetDepartment(departmentID string) (*Department, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// CancelTask cancels a queued, assigned or in-progress task and frees the
+// capacity held by its assigned member
+func (m *Manager) CancelTask(ctx context.Context, taskID, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	dept, exists := m.departments[departmentID]
+	task, exists := m.tasks[taskID]
 	if !exists {
-		return nil, fmt.Errorf("department %s does not exist", departmentID)
+		return fmt.Errorf("task %s does not exist", taskID)
+	}
+
+	switch task.Status {
+	case TaskStatusCompleted, TaskStatusFailed, TaskStatusCancelled:
+		return fmt.Errorf("cannot cancel task %s: task is already %s", taskID, task.Status)
 	}
-	return dept, nil
+
+	m.cancelTask(ctx, task, reason)
+	return nil
 }
 
-// GetMember returns a member by ID
-func (m *Manager) GetMember(memberID string) (*Member, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// cancelTask cancels an unfinished task. The caller must hold the manager
+// lock.
+func (m *Manager) cancelTask(ctx context.Context, task *Task, reason string) {
+	taskID := task.ID
+	oldStatus := task.Status
+	task.Status = TaskStatusCancelled
+	task.UpdatedAt = time.Now()
+	m.indexTask(task)
+	if task.Results == nil {
+		task.Results = make(map[string]interface{})
+	}
+	task.Results["cancel_reason"] = reason
 
-	member, exists := m.members[memberID]
-	if !exists {
-		return nil, fmt.Errorf("member %s does not exist", memberID)
+	if task.AssignedMember != "" {
+		m.releaseTask(task.AssignedMember, taskID)
 	}
-	return member, nil
+	m.disbandTaskTeam(taskID)
+	m.finishSubtasks(ctx, task)
+	m.resolveDependencies(ctx, task)
+
+	m.persist()
+
+	// Publish events
+	m.taskEvents.Publish(pubsub.UpdatedEvent, task)
+
+	slog.Info("Task cancelled",
+		"task_id", taskID,
+		"old_status", string(oldStatus),
+		"reason", reason)
+
+	m.advanceWorkflow(ctx, task)
 }
 
-// GetTask returns a task by ID
-func (m *Manager) GetTask(taskID string) (*Task, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// UpdateTaskPriority changes the priority of an unfinished task. Queued
+// tasks in the task's department are routed again, so a task that became
+// more urgent can be placed ahead of older work, and the priorities
+// inherited by the task's dependencies are recomputed.
+func (m *Manager) UpdateTaskPriority(ctx context.Context, taskID string, priority Priority) error {
+	if priority.Rank() == 0 {
+		return fmt.Errorf("unknown priority %q", priority)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	task, exists := m.tasks[taskID]
 	if !exists {
-		return nil, fmt.Errorf("task %s does not exist", taskID)
+		return fmt.Errorf("task %s does not exist", taskID)
+	}
+	if isTaskDone(task.Status) {
+		return fmt.Errorf("cannot change priority of task %s: task is %s", taskID, task.Status)
+	}
+	if task.Priority == priority {
+		return nil
 	}
-	return task, nil
-}
 
-// ListDepartments returns all departments
-func (m *Manager) ListDepartments() []*Department {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	// UpdatedAt is left alone since it marks when a queued task started
+	// waiting
+	oldPriority := task.Priority
+	task.Priority = priority
+	m.inheritPriorities()
+	m.taskEvents.Publish(pubsub.UpdatedEvent, task)
 
-	departments := make([]*Department, 0, len(m.departments))
-	for _, dept := range m.departments {
-		departments = append(departments, dept)
+	slog.Info("Task priority updated",
+		"task_id", taskID,
+		"old_priority", string(oldPriority),
+		"priority", string(priority))
+
+	if task.Status == TaskStatusQueued || task.Status == TaskStatusBlocked {
+		m.rerouteQueuedTasks(ctx, task.DepartmentID)
+	}
+
+	m.persist()
+
+	return nil
+}
+
+// FormTeamForTask forms a temporary team with one available member for each
+// of the task's required roles. Members from the task's department are
+// preferred, falling back to other departments. The team is disbanded when
+// the task completes, fails or is cancelled.
+func (m *Manager) FormTeamForTask(task *Task) (*Team, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.tasks[task.ID]; !exists {
+		return nil, fmt.Errorf("task %s does not exist", task.ID)
+	}
+	if len(task.RequiredRoles) == 0 {
+		return nil, fmt.Errorf("task %s has no required roles", task.ID)
+	}
+	if teamID, exists := m.taskTeams[task.ID]; exists {
+		return nil, fmt.Errorf("task %s already has team %s", task.ID, teamID)
+	}
+
+	selected := make(map[string]bool)
+	var members []*Member
+	for _, role := range task.RequiredRoles {
+		member := m.findTeamCandidate(role, task.DepartmentID, selected)
+		if member == nil {
+			return nil, fmt.Errorf("no available member with role %s for task %s", role, task.ID)
+		}
+		selected[member.ID] = true
+		members = append(members, member)
+	}
+
+	// Lead the team with the first lead-role member, if any
+	lead := members[0]
+	for _, member := range members {
+		if member.IsLead {
+			lead = member
+			break
+		}
+	}
+
+	now := time.Now()
+	team := &Team{
+		ID:           fmt.Sprintf("team-%s", task.ID),
+		Name:         fmt.Sprintf("Team for %s", task.ID),
+		DepartmentID: task.DepartmentID,
+		LeadID:       lead.ID,
+		LeadRole:     lead.Role,
+		Roles:        task.RequiredRoles,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	for _, member := range members {
+		team.MemberIDs = append(team.MemberIDs, member.ID)
+	}
+
+	m.teams[team.ID] = team
+	m.taskTeams[task.ID] = team.ID
+	m.persist()
+
+	slog.Info("Team formed for task",
+		"task_id", task.ID,
+		"team_id", team.ID,
+		"lead_id", lead.ID,
+		"members", len(team.MemberIDs))
+
+	return team, nil
+}
+
+// findTeamCandidate returns the least loaded available member with the given
+// role, preferring members of the given department
+func (m *Manager) findTeamCandidate(role MemberRole, departmentID string, exclude map[string]bool) *Member {
+	var best *Member
+	for _, member := range m.members {
+		if member.Role != role || exclude[member.ID] {
+			continue
+		}
+		if member.Status != MemberStatusOnline && member.Status != MemberStatusBusy {
+			continue
+		}
+		if m.remainingUnits(member) < defaultTaskWeight {
+			continue
+		}
+
+		if best == nil {
+			best = member
+			continue
+		}
+		inDept, bestInDept := member.DepartmentID == departmentID, best.DepartmentID == departmentID
+		if inDept != bestInDept {
+			if inDept {
+				best = member
+			}
+			continue
+		}
+		if len(member.CurrentTasks) < len(best.CurrentTasks) {
+			best = member
+		}
+	}
+	return best
+}
+
+// disbandTaskTeam removes the transient team formed for a task, if any
+func (m *Manager) disbandTaskTeam(taskID string) {
+	teamID, exists := m.taskTeams[taskID]
+	if !exists {
+		return
+	}
+
+	delete(m.teams, teamID)
+	delete(m.taskTeams, taskID)
+
+	slog.Info("Team disbanded", "task_id", taskID, "team_id", teamID)
+}
+
+// AssignTask assigns a task to a specific member, bypassing the router's
+// member selection. The member must be online with spare capacity and
+// suitable for the task, and the task must not already be assigned to
+// someone else; use ForceAssignTask to move an assigned task.
+func (m *Manager) AssignTask(ctx context.Context, taskID, memberID string) error {
+	return m.assignTask(taskID, memberID, false, "")
+}
+
+// ForceAssignTask assigns a task to a specific member without checking
+// whether the member is suitable or has spare capacity
+func (m *Manager) ForceAssignTask(ctx context.Context, taskID, memberID string) error {
+	return m.assignTask(taskID, memberID, true, "")
+}
+
+// DelegateTask assigns a task to a member on behalf of a lead. Like
+// AssignTask the member must be suitable for the task, and the lead's role
+// definition must also allow handing tasks to the member's role. Unlike
+// AssignTask it moves a task already assigned to someone else, unless the
+// task is pinned to its member.
+func (m *Manager) DelegateTask(ctx context.Context, leadID, taskID, memberID string) error {
+	return m.assignTask(taskID, memberID, false, leadID)
+}
+
+// CanRoleHandle reports whether members in role are permitted to handle
+// tasks of taskType. Roles without configured permissions handle any type.
+func (m *Manager) CanRoleHandle(role MemberRole, taskType string) bool {
+	return m.config.canRoleHandle(role, taskType)
+}
+
+// assignTask assigns the task to the member, delegated by the lead with
+// leadID when that is set
+func (m *Manager) assignTask(taskID, memberID string, force bool, leadID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	task, exists := m.tasks[taskID]
+	if !exists {
+		return fmt.Errorf("task %s does not exist", taskID)
+	}
+
+	member, exists := m.members[memberID]
+	if !exists {
+		return fmt.Errorf("member %s does not exist", memberID)
+	}
+
+	if leadID != "" {
+		lead, exists := m.members[leadID]
+		if !exists {
+			return fmt.Errorf("lead %s does not exist", leadID)
+		}
+		if !m.config.isLeadRole(lead.Role) {
+			return fmt.Errorf("member %s has non-lead role %s and cannot delegate tasks", leadID, lead.Role)
+		}
+		if !m.config.canAssignTo(lead.Role, member.Role) {
+			return fmt.Errorf("lead role %s cannot assign tasks to role %s", lead.Role, member.Role)
+		}
+	}
+
+	switch task.Status {
+	case TaskStatusCompleted, TaskStatusFailed, TaskStatusCancelled:
+		return fmt.Errorf("cannot assign task %s: task is %s", taskID, task.Status)
+	}
+
+	if task.AssignedMember == memberID {
+		return nil
+	}
+
+	if !force {
+		if task.AssignedMember != "" && leadID == "" {
+			return fmt.Errorf("cannot assign task %s to %s: assigned to %s: %w", taskID, memberID, task.AssignedMember, ErrTaskAlreadyAssigned)
+		}
+		if task.pinned() {
+			return fmt.Errorf("cannot reassign task %s: task is pinned to member %s", taskID, task.AssignedMember)
+		}
+		if member.Status != MemberStatusOnline && member.Status != MemberStatusBusy {
+			return fmt.Errorf("cannot assign task %s: member %s is %s", taskID, memberID, member.Status)
+		}
+		if remaining, weight := m.remainingUnits(member), taskWeight(task); remaining < weight {
+			return fmt.Errorf("cannot assign task %s: member %s has %g of %g required capacity units", taskID, memberID, remaining, weight)
+		}
+		if !m.taskRouter.isMemberSuitable(member, task) {
+			return fmt.Errorf("member %s is not suitable for task %s", memberID, taskID)
+		}
+	}
+
+	// Release the previous assignment
+	previous := task.AssignedMember
+	if previous != "" {
+		m.releaseTask(previous, taskID)
+	}
+
+	if err := m.taskRouter.assignTaskToMember(task, member); err != nil {
+		return fmt.Errorf("failed to assign task: %w", err)
+	}
+
+	reason := "it was assigned manually"
+	switch {
+	case force:
+		reason = "it was force-assigned, bypassing suitability checks"
+	case leadID != "":
+		reason = fmt.Sprintf("it was delegated by lead %s", leadID)
+	}
+	m.taskRouter.recordDecision(task, &RoutingDecision{
+		DepartmentReason: "it was specified on the task",
+		MemberReason:     reason,
+		DecidedAt:        time.Now(),
+	})
+
+	m.persist()
+
+	// Publish events
+	m.taskEvents.Publish(pubsub.UpdatedEvent, task)
+	m.memberEvents.Publish(pubsub.UpdatedEvent, member)
+	if prev, exists := m.members[previous]; exists {
+		m.memberEvents.Publish(pubsub.UpdatedEvent, prev)
+	}
+
+	slog.Info("Task manually assigned",
+		"task_id", taskID,
+		"member_id", memberID,
+		"previous_member", previous,
+		"forced", force)
+
+	return nil
+}
+
+// validateDepartment checks a department's ID and member bounds
+func validateDepartment(dept *Department) error {
+	if dept == nil {
+		return fmt.Errorf("department is required")
+	}
+	if strings.TrimSpace(dept.ID) == "" {
+		return fmt.Errorf("department ID is required")
+	}
+	if dept.MinMembers < 0 || dept.MaxMembers < 0 {
+		return fmt.Errorf("department %s member bounds must not be negative", dept.ID)
+	}
+	if dept.MaxQueuedTasks < 0 {
+		return fmt.Errorf("department %s max queued tasks must not be negative", dept.ID)
+	}
+	if dept.MaxMembers > 0 && dept.MinMembers > dept.MaxMembers {
+		return fmt.Errorf("department %s min members %d exceeds max members %d", dept.ID, dept.MinMembers, dept.MaxMembers)
+	}
+	return nil
+}
+
+// CreateDepartment adds a department at runtime. The manager keeps its own
+// copy of dept.
+func (m *Manager) CreateDepartment(ctx context.Context, dept *Department) error {
+	if err := validateDepartment(dept); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.departments[dept.ID]; exists {
+		return fmt.Errorf("department %s already exists", dept.ID)
+	}
+
+	now := time.Now()
+	created := dept.clone()
+	created.CreatedAt = now
+	created.UpdatedAt = now
+	m.departments[created.ID] = created
+	m.departmentEvents.Publish(pubsub.CreatedEvent, created)
+	m.persist()
+
+	slog.Info("Department created", "department", created.ID, "name", created.Name)
+	return nil
+}
+
+// UpdateDepartment replaces the settings of an existing department, keeping
+// its creation time. Queued tasks of the department are routed again so
+// changes such as re-enabling it take effect at once.
+func (m *Manager) UpdateDepartment(ctx context.Context, dept *Department) error {
+	if err := validateDepartment(dept); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, exists := m.departments[dept.ID]
+	if !exists {
+		return fmt.Errorf("department %s does not exist", dept.ID)
+	}
+	if members := m.countDepartmentMembers(dept.ID); dept.MaxMembers > 0 && members > dept.MaxMembers {
+		return fmt.Errorf("department %s has %d members, more than max members %d", dept.ID, members, dept.MaxMembers)
+	}
+
+	// Swap in a new department rather than writing through the old pointer,
+	// which published events may still be reading
+	updated := dept.clone()
+	updated.CreatedAt = existing.CreatedAt
+	updated.UpdatedAt = time.Now()
+	m.departments[dept.ID] = updated
+
+	m.departmentEvents.Publish(pubsub.UpdatedEvent, updated)
+	m.rerouteQueuedTasks(ctx, dept.ID)
+	m.persist()
+
+	slog.Info("Department updated", "department", dept.ID)
+	return nil
+}
+
+// DeleteDepartment removes a department. It refuses while the department
+// still has members or unfinished tasks; migrate or remove them first.
+func (m *Manager) DeleteDepartment(ctx context.Context, departmentID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dept, exists := m.departments[departmentID]
+	if !exists {
+		return fmt.Errorf("department %s does not exist", departmentID)
+	}
+	if members := m.countDepartmentMembers(departmentID); members > 0 {
+		return fmt.Errorf("department %s still has %d members", departmentID, members)
+	}
+	unfinished := 0
+	for _, task := range m.tasks {
+		if task.DepartmentID == departmentID && !isTaskDone(task.Status) {
+			unfinished++
+		}
+	}
+	if unfinished > 0 {
+		return fmt.Errorf("department %s still has %d unfinished tasks", departmentID, unfinished)
+	}
+
+	if reservation, reserved := m.reservations[departmentID]; reserved {
+		reservation.timer.Stop()
+		delete(m.reservations, departmentID)
+	}
+	delete(m.departments, departmentID)
+	m.departmentEvents.Publish(pubsub.DeletedEvent, dept)
+	m.persist()
+
+	slog.Info("Department deleted", "department", departmentID)
+	return nil
+}
+
+// MigrateOption configures a department migration
+type MigrateOption func(*migrateOptions)
+
+type migrateOptions struct {
+	disableSource bool
+}
+
+// WithDisableSource disables the source department once it has been migrated
+func WithDisableSource() MigrateOption {
+	return func(o *migrateOptions) {
+		o.disableSource = true
+	}
+}
+
+// MigrateDepartment moves all members and queued tasks of one department into
+// another. Idle members move immediately; busy members are drained and move
+// once their current tasks finish. Queued tasks are re-routed in the target.
+func (m *Manager) MigrateDepartment(ctx context.Context, fromID, toID string, opts ...MigrateOption) error {
+	var options migrateOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if fromID == toID {
+		return fmt.Errorf("cannot migrate department %s into itself", fromID)
+	}
+
+	source, exists := m.departments[fromID]
+	if !exists {
+		return fmt.Errorf("department %s does not exist", fromID)
+	}
+	target, exists := m.departments[toID]
+	if !exists {
+		return fmt.Errorf("department %s does not exist", toID)
+	}
+	if target.Disabled {
+		return fmt.Errorf("department %s is disabled", toID)
+	}
+
+	members := m.listMembers(fromID)
+	if target.MaxMembers > 0 && m.countDepartmentMembers(toID)+len(members) > target.MaxMembers {
+		return fmt.Errorf("department %s cannot take %d more members", toID, len(members))
+	}
+
+	// Move idle members now and drain busy ones
+	moved, draining := 0, 0
+	for _, member := range members {
+		if len(member.CurrentTasks) == 0 {
+			m.moveMember(member, target)
+			moved++
+		} else {
+			m.pendingMigrations[member.ID] = toID
+			draining++
+		}
+	}
+
+	// Re-route queued tasks in the target department
+	rerouted := 0
+	for _, task := range m.queuedTasks(fromID) {
+		task.DepartmentID = toID
+		task.UpdatedAt = time.Now()
+		m.indexTask(task)
+		if err := m.taskRouter.routeTask(ctx, task); err != nil {
+			slog.Warn("Failed to route migrated task", "task_id", task.ID, "error", err)
+		}
+		m.taskEvents.Publish(pubsub.UpdatedEvent, task)
+		rerouted++
+	}
+
+	if options.disableSource {
+		disabled := source.clone()
+		disabled.Disabled = true
+		disabled.UpdatedAt = time.Now()
+		m.departments[fromID] = disabled
+		m.departmentEvents.Publish(pubsub.UpdatedEvent, disabled)
+	}
+
+	m.persist()
+
+	slog.Info("Department migrated",
+		"from", fromID,
+		"to", toID,
+		"members_moved", moved,
+		"members_draining", draining,
+		"tasks_rerouted", rerouted,
+		"source_disabled", options.disableSource)
+
+	return nil
+}
+
+// moveMember transfers a member into another department
+func (m *Manager) moveMember(member *Member, target *Department) {
+	from := member.DepartmentID
+	member.DepartmentID = target.ID
+	member.DepartmentType = target.Type
+	delete(m.pendingMigrations, member.ID)
+
+	m.memberEvents.Publish(pubsub.UpdatedEvent, member)
+
+	slog.Info("Member moved to department",
+		"member_id", member.ID,
+		"from", from,
+		"to", target.ID)
+}
+
+// GetDepartment returns a copy of a department by ID. Changes to the copy do not
+// affect the manager; use the manager's methods to update the department.
+func (m *Manager) GetDepartment(departmentID string) (*Department, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	dept, exists := m.departments[departmentID]
+	if !exists {
+		return nil, fmt.Errorf("department %s does not exist", departmentID)
+	}
+	return dept.clone(), nil
+}
+
+// GetMember returns a copy of a member by ID. Changes to the copy do not
+// affect the manager; use the manager's methods to update the member.
+func (m *Manager) GetMember(memberID string) (*Member, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	member, exists := m.members[memberID]
+	if !exists {
+		return nil, fmt.Errorf("member %s does not exist", memberID)
+	}
+	return member.clone(), nil
+}
+
+// GetTask returns a copy of a task by ID. Changes to the copy do not
+// affect the manager; use the manager's methods to update the task.
+func (m *Manager) GetTask(taskID string) (*Task, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	task, exists := m.tasks[taskID]
+	if !exists {
+		return nil, fmt.Errorf("task %s does not exist", taskID)
+	}
+	return task.clone(), nil
+}
+
+// ListDepartments returns copies of all departments. Changes to the copies
+// do not affect the manager.
+func (m *Manager) ListDepartments() []*Department {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	departments := make([]*Department, 0, len(m.departments))
+	for _, dept := range m.departments {
+		departments = append(departments, dept.clone())
 	}
 	return departments
 }
@@ -460,6 +1558,10 @@ func (m *Manager) ListMembers(departmentID string) []*Member {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	return m.listMembers(departmentID)
+}
+
+func (m *Manager) listMembers(departmentID string) []*Member {
 	members := make([]*Member, 0)
 	for _, member := range m.members {
 		if departmentID == "" || member.DepartmentID == departmentID {
@@ -469,34 +1571,29 @@ func (m *Manager) ListMembers(departmentID string) []*Member {
 	return members
 }
 
-// ListTasks returns all tasks, optionally filtered by department and status
+// ListTasks returns copies of all tasks, optionally filtered by department
+// and status, oldest first. Use ListTasksPaged for other filters and paging.
 func (m *Manager) ListTasks(departmentID string, status TaskStatus) []*Task {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	tasks := make([]*Task, 0)
-	for _, task := range m.tasks {
-		if (departmentID == "" || task.DepartmentID == departmentID) &&
-			(status == "" || task.Status == status) {
-			tasks = append(tasks, task)
-		}
-	}
-	return tasks
+	// The default sort key and paging cannot fail
+	page, _ := m.ListTasksPaged(TaskFilter{DepartmentID: departmentID, Status: status})
+	return page.Tasks
 }
 
-// GetDepartmentStats returns statistics for a department
+// GetDepartmentStats returns a copy of the statistics for a department
 func (m *Manager) GetDepartmentStats(departmentID string) (*DepartmentStats, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	stats, exists := m.departmentStats[departmentID]
-	if !exists {
+	if _, exists := m.departments[departmentID]; !exists {
 		return nil, fmt.Errorf("department %s does not exist", departmentID)
 	}
-	return stats, nil
+
+	// Computed fresh, so the counts reflect every task and member change
+	stats := m.computeDepartmentStats(departmentID, time.Now())
+	return &stats, nil
 }
 
-// GetMemberStats returns statistics for a member
+// GetMemberStats returns a copy of the statistics for a member
 func (m *Manager) GetMemberStats(memberID string) (*MemberStats, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -505,26 +1602,131 @@ func (m *Manager) GetMemberStats(memberID string) (*MemberStats, error) {
 	if !exists {
 		return nil, fmt.Errorf("member %s does not exist", memberID)
 	}
-	return stats, nil
+
+	// Return a copy; the manager keeps updating its own
+	statsCopy := *stats
+	return &statsCopy, nil
 }
 
-// SubscribeToDepartmentEvents returns a channel for department events
+// SubscribeToDepartmentEvents returns a channel for department events. After Stop the
+// channel is returned already closed, so callers never block on it.
 func (m *Manager) SubscribeToDepartmentEvents(ctx context.Context) <-chan pubsub.Event[*Department] {
 	return m.departmentEvents.Subscribe(ctx)
 }
 
-// SubscribeToMemberEvents returns a channel for member events
+// SubscribeToMemberEvents returns a channel for member events. After Stop the
+// channel is returned already closed, so callers never block on it.
 func (m *Manager) SubscribeToMemberEvents(ctx context.Context) <-chan pubsub.Event[*Member] {
 	return m.memberEvents.Subscribe(ctx)
 }
 
-// SubscribeToTaskEvents returns a channel for task events
+// SubscribeToTaskEvents returns a channel for task events. After Stop the
+// channel is returned already closed, so callers never block on it.
 func (m *Manager) SubscribeToTaskEvents(ctx context.Context) <-chan pubsub.Event[*Task] {
 	return m.taskEvents.Subscribe(ctx)
 }
 
+// SubscribeToTaskSummaries returns a channel carrying a lifecycle summary
+// for every task that completes or fails. After Stop the channel is returned
+// already closed.
+func (m *Manager) SubscribeToTaskSummaries(ctx context.Context) <-chan pubsub.Event[*TaskLifecycleSummary] {
+	return m.summaryEvents.Subscribe(ctx)
+}
+
+// SubscribeToHealthEvents returns a channel for member health transitions.
+// The channel is closed immediately when health checking is disabled.
+func (m *Manager) SubscribeToHealthEvents(ctx context.Context) <-chan pubsub.Event[*MemberHealth] {
+	if m.healthChecker == nil {
+		ch := make(chan pubsub.Event[*MemberHealth])
+		close(ch)
+		return ch
+	}
+	return m.healthChecker.SubscribeToHealthEvents(ctx)
+}
+
+// PauseHealthChecks suspends health-driven member status changes for d, for
+// example while a deploy restarts members. It fails when health checking is
+// disabled.
+func (m *Manager) PauseHealthChecks(d time.Duration) error {
+	if m.healthChecker == nil {
+		return fmt.Errorf("health checking is disabled")
+	}
+	if d <= 0 {
+		return fmt.Errorf("pause duration must be positive")
+	}
+	m.healthChecker.Pause(d)
+	return nil
+}
+
+// ResumeHealthChecks ends a pause started by PauseHealthChecks early
+func (m *Manager) ResumeHealthChecks() error {
+	if m.healthChecker == nil {
+		return fmt.Errorf("health checking is disabled")
+	}
+	m.healthChecker.Resume()
+	return nil
+}
+
 // Helper functions
 
+// SubscribeToScalingEvents returns a channel for auto-scaling events. The
+// channel is closed immediately when auto-scaling is disabled.
+func (m *Manager) SubscribeToScalingEvents(ctx context.Context) <-chan pubsub.Event[*ScalingEvent] {
+	if m.scaler == nil {
+		ch := make(chan pubsub.Event[*ScalingEvent])
+		close(ch)
+		return ch
+	}
+	return m.scaler.SubscribeToScalingEvents(ctx)
+}
+
+// countActiveDepartmentMembers counts the online and busy members of a
+// department
+func (m *Manager) countActiveDepartmentMembers(departmentID string) int {
+	count := 0
+	for _, member := range m.members {
+		if member.DepartmentID == departmentID && isAvailable(member) {
+			count++
+		}
+	}
+	return count
+}
+
+// pruneInactiveMembers removes offline and unhealthy members of a department
+// that hold no tasks
+func (m *Manager) pruneInactiveMembers(departmentID string) {
+	for id, member := range m.members {
+		if member.DepartmentID != departmentID || isAvailable(member) || len(member.CurrentTasks) > 0 {
+			continue
+		}
+
+		delete(m.members, id)
+		delete(m.memberStats, id)
+		m.memberEvents.Publish(pubsub.DeletedEvent, member)
+
+		// The launcher may wait for the instance to exit, so it is not
+		// called under the manager lock
+		if m.launcher != nil && member.Metadata["auto_scaled"] == "true" {
+			go m.terminateInstance(id)
+		}
+
+		slog.Info("Removed inactive member",
+			"member_id", id,
+			"status", string(member.Status),
+			"department", departmentID)
+	}
+}
+
+// terminateInstance stops the instance launched for a removed auto-scaled
+// member
+func (m *Manager) terminateInstance(memberID string) {
+	if err := m.launcher.Terminate(context.Background(), memberID); err != nil {
+		slog.Error("Failed to terminate removed member",
+			"member_id", memberID,
+			"error", err)
+	}
+}
+
 func (m *Manager) countDepartmentMembers(departmentID string) int {
 	count := 0
 	for _, member := range m.members {
@@ -535,27 +1737,57 @@ func (m *Manager) countDepartmentMembers(departmentID string) int {
 	return count
 }
 
-func (m *Manager) updateDepartmentStats(departmentID string) {
-	dept := m.departments[departmentID]
-	stats := m.departmentStats[departmentID]
+// computeDepartmentStats derives a department's statistics from its current
+// members and tasks without storing them. The caller must hold the manager
+// lock, for reading at least.
+func (m *Manager) computeDepartmentStats(departmentID string, now time.Time) DepartmentStats {
+	stats := DepartmentStats{
+		DepartmentID:     departmentID,
+		RoleDistribution: make(map[string]int),
+		LastUpdated:      now,
+	}
 
 	// Count members and roles
-	roleDistribution := make(map[string]int)
-	activeMembers := 0
-
 	for _, member := range m.members {
 		if member.DepartmentID == departmentID {
-			roleDistribution[string(member.Role)]++
+			stats.TotalMembers++
+			stats.RoleDistribution[string(member.Role)]++
 			if member.Status == MemberStatusOnline || member.Status == MemberStatusBusy {
-				activeMembers++
+				stats.ActiveMembers++
 			}
 		}
 	}
 
-	stats.TotalMembers = len(m.members)
-	stats.ActiveMembers = activeMembers
-	stats.RoleDistribution = roleDistribution
-	stats.LastUpdated = time.Now()
+	// Count the department's tasks by outcome
+	for _, task := range m.departmentTasks[departmentID] {
+		stats.TotalTasks++
+		stats.EstimatedCost += task.EstimatedCost
+		switch task.Status {
+		case TaskStatusCompleted:
+			stats.CompletedTasks++
+		case TaskStatusFailed:
+			stats.FailedTasks++
+		}
+	}
+
+	// Average the member response times, weighted by how many tasks each
+	// average covers
+	timedTasks := 0
+	for id, member := range m.members {
+		memberStats, exists := m.memberStats[id]
+		if member.DepartmentID != departmentID || !exists || memberStats.TimedTasks == 0 {
+			continue
+		}
+		timedTasks += memberStats.TimedTasks
+		stats.AverageResponse += (memberStats.AverageTime - stats.AverageResponse) * float64(memberStats.TimedTasks) / float64(timedTasks)
+	}
+
+	// Queue waits of the most recent assignments
+	waits := slices.Sorted(slices.Values(m.queueWaits[departmentID]))
+	stats.QueueWaitP50 = percentile(waits, 50)
+	stats.QueueWaitP95 = percentile(waits, 95)
+
+	return stats
 }
 
 func (m *Manager) updateMemberTaskCompletion(memberID, taskID string, success bool) {
@@ -565,12 +1797,7 @@ func (m *Manager) updateMemberTaskCompletion(memberID, taskID string, success bo
 	}
 
 	// Remove task from current tasks
-	for i, task := range member.CurrentTasks {
-		if task == taskID {
-			member.CurrentTasks = append(member.CurrentTasks[:i], member.CurrentTasks[i+1:]...)
-			break
-		}
-	}
+	m.releaseTask(memberID, taskID)
 
 	// Update member stats
 	stats := m.memberStats[memberID]
@@ -585,40 +1812,92 @@ func (m *Manager) updateMemberTaskCompletion(memberID, taskID string, success bo
 	stats.LastUpdated = time.Now()
 }
 
-func (m *Manager) statisticsUpdater(ctx context.Context) {
-	ticker := time.NewTicker(30 * time.Second) // Update every 30 seconds
-	defer ticker.Stop()
+// releaseTask removes a task from a member's current tasks and frees the
+// capacity it was holding
+func (m *Manager) releaseTask(memberID, taskID string) {
+	member, exists := m.members[memberID]
+	if !exists {
+		return
+	}
+
+	for i, task := range member.CurrentTasks {
+		if task == taskID {
+			member.CurrentTasks = append(member.CurrentTasks[:i], member.CurrentTasks[i+1:]...)
+			break
+		}
+	}
+
+	// Update member status if no longer busy
+	if member.Status == MemberStatusBusy && m.remainingUnits(member) >= defaultTaskWeight {
+		member.Status = MemberStatusOnline
+	}
+
+	if stats, exists := m.memberStats[memberID]; exists {
+		stats.CurrentLoad = len(member.CurrentTasks)
+		stats.LastUpdated = time.Now()
+	}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			m.updateAllStatistics()
+	// Finish draining members that are migrating to another department
+	if toID, pending := m.pendingMigrations[memberID]; pending && len(member.CurrentTasks) == 0 {
+		if target, exists := m.departments[toID]; exists {
+			m.moveMember(member, target)
 		}
 	}
 }
 
-func (m *Manager) updateAllStatistics() {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// recordTaskDuration folds the time a completed task took from start to
+// completion into its member's running average, in seconds
+func (m *Manager) recordTaskDuration(task *Task) {
+	stats, exists := m.memberStats[task.AssignedMember]
+	if !exists || task.StartedAt == nil || task.CompletedAt == nil {
+		return
+	}
+
+	duration := task.CompletedAt.Sub(*task.StartedAt).Seconds()
+	stats.TimedTasks++
+	// Incremental mean avoids summing durations across many tasks
+	stats.AverageTime += (duration - stats.AverageTime) / float64(stats.TimedTasks)
+}
 
-	// Update department statistics
-	for deptID := range m.departments {
-		m.updateDepartmentStats(deptID)
+// taskWeight returns the capacity units a task consumes
+func taskWeight(task *Task) float64 {
+	if task.Weight > 0 {
+		return task.Weight
 	}
+	return defaultTaskWeight
+}
 
-	// Update task statistics
-	for _, stats := range m.memberStats {
-		// Additional statistics calculations can be added here
-		stats.LastUpdated = time.Now()
+// memberCapacity returns the total capacity units of a member
+func memberCapacity(member *Member) float64 {
+	if member.CapacityUnits > 0 {
+		return member.CapacityUnits
 	}
+	return float64(member.MaxConcurrent)
 }
 
+// remainingUnits returns the capacity units a member has left after its
+// current tasks. The caller must hold the manager lock.
+func (m *Manager) remainingUnits(member *Member) float64 {
+	consumed := 0.0
+	for _, taskID := range member.CurrentTasks {
+		if task, exists := m.tasks[taskID]; exists {
+			consumed += taskWeight(task)
+		} else {
+			consumed += defaultTaskWeight
+		}
+	}
+	return memberCapacity(member) - consumed
+}
+
+// defaultTaskWeight is the weight of a task without an explicit Weight. A
+// member with less capacity than this left is considered busy.
+const defaultTaskWeight = 1.0
+
+// isLeadRole reports whether role is one of the built-in lead roles
 func isLeadRole(role MemberRole) bool {
 	return role == RoleLeadTechnical || role == RoleLeadBA || role == RoleLeadDev || role == RoleLeadTest
 }
 
 func generateTaskID() string {
 	return fmt.Sprintf("task-%d", time.Now().UnixNano())
-}
\ No newline at end of file
+}
\ No newline at end of file
@@ -0,0 +1,991 @@
+package department
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+
+	m, err := NewManager(context.Background(), &DepartmentConfig{Enabled: true})
+	require.NoError(t, err)
+	return m
+}
+
+func registerTestMember(t *testing.T, m *Manager, id, departmentID string, role MemberRole, maxConcurrent int) *Member {
+	t.Helper()
+
+	member := &Member{
+		ID:            id,
+		Name:          id,
+		Role:          role,
+		DepartmentID:  departmentID,
+		MaxConcurrent: maxConcurrent,
+	}
+	require.NoError(t, m.RegisterMember(context.Background(), member))
+	return member
+}
+
+func TestManagerForceAssignTask(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	dev1 := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+
+	first, err := m.CreateTask(ctx, &Task{ID: "task-1", Title: "first", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, dev1.ID, first.AssignedMember)
+	require.Equal(t, MemberStatusBusy, dev1.Status)
+
+	// dev-1 is at capacity, so the second task stays queued
+	second, err := m.CreateTask(ctx, &Task{ID: "task-2", Title: "second", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, second.Status)
+
+	require.ErrorContains(t, m.AssignTask(ctx, second.ID, dev1.ID), "member dev-1 has 0 of 1 required capacity units")
+
+	events := m.SubscribeToTaskEvents(ctx)
+	require.NoError(t, m.ForceAssignTask(ctx, second.ID, dev1.ID))
+
+	require.Equal(t, dev1.ID, second.AssignedMember)
+	require.Equal(t, TaskStatusAssigned, second.Status)
+	require.Equal(t, []string{"task-1", "task-2"}, dev1.CurrentTasks)
+
+	stats, err := m.GetMemberStats(dev1.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, stats.CurrentLoad)
+
+	event := <-events
+	require.Equal(t, pubsub.UpdatedEvent, event.Type)
+	require.Equal(t, second.ID, event.Payload.ID)
+
+	// Moving the task to a member with spare capacity releases dev-1
+	dev2 := registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 2)
+	require.ErrorIs(t, m.AssignTask(ctx, second.ID, dev2.ID), ErrTaskAlreadyAssigned)
+	require.NoError(t, m.ForceAssignTask(ctx, second.ID, dev2.ID))
+
+	require.Equal(t, dev2.ID, second.AssignedMember)
+	require.Equal(t, []string{"task-1"}, dev1.CurrentTasks)
+	require.Equal(t, []string{"task-2"}, dev2.CurrentTasks)
+	require.Equal(t, MemberStatusOnline, dev2.Status)
+
+	stats, err = m.GetMemberStats(dev1.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, stats.CurrentLoad)
+}
+
+func TestManagerAssignTaskRejectsFinishedTasks(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusCompleted, nil))
+
+	require.ErrorContains(t, m.ForceAssignTask(ctx, task.ID, "dev-1"), "task is completed")
+	require.ErrorContains(t, m.AssignTask(ctx, task.ID, "missing"), "member missing does not exist")
+}
+
+func TestManagerAssignTaskChecksMember(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	dev1 := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	require.NoError(t, m.UpdateMemberStatus(ctx, dev1.ID, MemberStatusOffline))
+
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, task.Status)
+
+	require.ErrorContains(t, m.AssignTask(ctx, task.ID, dev1.ID), "member dev-1 is offline")
+
+	require.NoError(t, m.UpdateMemberStatus(ctx, dev1.ID, MemberStatusOnline))
+	require.NoError(t, m.AssignTask(ctx, task.ID, dev1.ID))
+	require.Equal(t, TaskStatusAssigned, task.Status)
+	require.Equal(t, []string{"task-1"}, dev1.CurrentTasks)
+	require.Equal(t, MemberStatusBusy, dev1.Status)
+
+	// Assigning again to the same member is a no-op
+	require.NoError(t, m.AssignTask(ctx, task.ID, dev1.ID))
+	require.Equal(t, []string{"task-1"}, dev1.CurrentTasks)
+}
+
+func TestManagerMigrateDepartment(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+
+	busy := registerTestMember(t, m, "sec-busy", "dept-security", RoleSecurity, 1)
+	active, err := m.CreateTask(ctx, &Task{ID: "task-active", DepartmentID: "dept-security"})
+	require.NoError(t, err)
+	require.Equal(t, busy.ID, active.AssignedMember)
+
+	queued, err := m.CreateTask(ctx, &Task{ID: "task-queued", DepartmentID: "dept-security"})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, queued.Status)
+
+	idle := registerTestMember(t, m, "sec-idle", "dept-security", RoleSecurity, 1)
+
+	require.NoError(t, m.MigrateDepartment(ctx, "dept-security", "dept-dev", WithDisableSource()))
+
+	// The idle member moves right away and picks up the queued task
+	require.Equal(t, "dept-dev", idle.DepartmentID)
+	require.Equal(t, DepartmentDevelopment, idle.DepartmentType)
+	require.Equal(t, "dept-dev", queued.DepartmentID)
+	require.Equal(t, idle.ID, queued.AssignedMember)
+
+	// The busy member drains first
+	require.Equal(t, "dept-security", busy.DepartmentID)
+	require.NoError(t, m.UpdateTaskStatus(ctx, active.ID, TaskStatusCompleted, nil))
+	require.Equal(t, "dept-dev", busy.DepartmentID)
+	require.Equal(t, MemberStatusOnline, busy.Status)
+
+	require.Empty(t, m.ListMembers("dept-security"))
+	require.Len(t, m.ListMembers("dept-dev"), 2)
+
+	source, err := m.GetDepartment("dept-security")
+	require.NoError(t, err)
+	require.True(t, source.Disabled)
+
+	_, err = m.CreateTask(ctx, &Task{DepartmentID: "dept-security"})
+	require.ErrorContains(t, err, "is disabled")
+}
+
+func TestManagerCancelTask(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	dev := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, MemberStatusBusy, dev.Status)
+
+	events := m.SubscribeToTaskEvents(ctx)
+	require.NoError(t, m.CancelTask(ctx, task.ID, "no longer needed"))
+
+	require.Equal(t, TaskStatusCancelled, task.Status)
+	require.Equal(t, "no longer needed", task.Results["cancel_reason"])
+	require.Empty(t, dev.CurrentTasks)
+	require.Equal(t, MemberStatusOnline, dev.Status)
+
+	event := <-events
+	require.Equal(t, pubsub.UpdatedEvent, event.Type)
+	require.Equal(t, TaskStatusCancelled, event.Payload.Status)
+
+	require.ErrorContains(t, m.CancelTask(ctx, task.ID, "again"), "already cancelled")
+
+	done, err := m.CreateTask(ctx, &Task{ID: "task-2", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, done.ID, TaskStatusCompleted, nil))
+	require.ErrorContains(t, m.CancelTask(ctx, done.ID, "too late"), "already completed")
+}
+
+func TestManagerUpdateTaskPriority(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+
+	_, err := m.CreateTask(ctx, &Task{ID: "busy", DepartmentID: "dept-dev", Priority: PriorityLow})
+	require.NoError(t, err)
+	_, err = m.CreateTask(ctx, &Task{ID: "older", DepartmentID: "dept-dev", Priority: PriorityLow})
+	require.NoError(t, err)
+	_, err = m.CreateTask(ctx, &Task{ID: "newer", DepartmentID: "dept-dev", Priority: PriorityLow})
+	require.NoError(t, err)
+
+	events := m.SubscribeToTaskEvents(ctx)
+	require.NoError(t, m.UpdateTaskPriority(ctx, "newer", PriorityHigh))
+	event := <-events
+	require.Equal(t, pubsub.UpdatedEvent, event.Type)
+	require.Equal(t, "newer", event.Payload.ID)
+	require.Equal(t, PriorityHigh, event.Payload.Priority)
+
+	// The re-prioritized task jumps ahead of the older one once dev-1 frees up
+	require.NoError(t, m.UpdateTaskStatus(ctx, "busy", TaskStatusCompleted, nil))
+
+	newer, err := m.GetTask("newer")
+	require.NoError(t, err)
+	require.Equal(t, "dev-1", newer.AssignedMember)
+	older, err := m.GetTask("older")
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, older.Status)
+
+	require.ErrorContains(t, m.UpdateTaskPriority(ctx, "busy", PriorityHigh), "task is completed")
+	require.ErrorContains(t, m.UpdateTaskPriority(ctx, "older", "urgent"), `unknown priority "urgent"`)
+	require.ErrorContains(t, m.UpdateTaskPriority(ctx, "missing", PriorityHigh), "task missing does not exist")
+}
+
+func TestManagerFormTeamForTask(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	lead := registerTestMember(t, m, "lead-1", "dept-dev", RoleLeadDev, 2)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+	qa := registerTestMember(t, m, "qa-1", "dept-qa", RoleQA, 2)
+
+	task, err := m.CreateTask(ctx, &Task{
+		ID:            "task-1",
+		DepartmentID:  "dept-dev",
+		AssignedRole:  RoleLeadDev,
+		RequiredRoles: []MemberRole{RoleLeadDev, RoleQA},
+	})
+	require.NoError(t, err)
+
+	team, err := m.FormTeamForTask(task)
+	require.NoError(t, err)
+	require.Equal(t, lead.ID, team.LeadID)
+	require.Equal(t, []string{lead.ID, qa.ID}, team.MemberIDs)
+	require.Contains(t, m.teams, team.ID)
+
+	_, err = m.FormTeamForTask(task)
+	require.ErrorContains(t, err, "already has team")
+
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusCompleted, nil))
+	require.NotContains(t, m.teams, team.ID)
+}
+
+func TestManagerFormTeamForTaskMissingRole(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+
+	task, err := m.CreateTask(ctx, &Task{
+		ID:            "task-1",
+		DepartmentID:  "dept-dev",
+		RequiredRoles: []MemberRole{RoleDeveloper, RoleSecurity},
+	})
+	require.NoError(t, err)
+
+	_, err = m.FormTeamForTask(task)
+	require.ErrorContains(t, err, "no available member with role security")
+	require.Empty(t, m.teams)
+}
+
+func TestManagerMemberReconnectReconcilesTasks(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	dev1 := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+
+	reassigned, err := m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	kept, err := m.CreateTask(ctx, &Task{ID: "task-2", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"task-1", "task-2"}, dev1.CurrentTasks)
+
+	// dev-1 drops off and one of its tasks moves to dev-2
+	require.NoError(t, m.UpdateMemberStatus(ctx, dev1.ID, MemberStatusOffline))
+	dev2 := registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 2)
+	require.NoError(t, m.ForceAssignTask(ctx, reassigned.ID, dev2.ID))
+	require.Equal(t, []string{"task-2"}, dev1.CurrentTasks)
+
+	// dev-1 comes back still believing it owns both tasks
+	require.NoError(t, m.RegisterMember(ctx, &Member{
+		ID:            dev1.ID,
+		Name:          "dev-1 (restarted)",
+		Role:          RoleDeveloper,
+		DepartmentID:  "dept-dev",
+		MaxConcurrent: 2,
+		CurrentTasks:  []string{reassigned.ID, kept.ID},
+	}))
+
+	// The manager keeps the member it had rather than the new registration
+	m.mu.RLock()
+	require.Same(t, dev1, m.members[dev1.ID])
+	m.mu.RUnlock()
+	member, err := m.GetMember(dev1.ID)
+	require.NoError(t, err)
+	require.Equal(t, MemberStatusOnline, member.Status)
+	require.Equal(t, "dev-1 (restarted)", member.Name)
+	require.Equal(t, []string{kept.ID}, member.CurrentTasks)
+	require.Len(t, m.ListMembers("dept-dev"), 2)
+	require.Equal(t, dev2.ID, reassigned.AssignedMember)
+
+	stats, err := m.GetMemberStats(dev1.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, stats.CurrentLoad)
+}
+
+func TestManagerHeartbeatRestoresOfflineMember(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	dev := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateMemberStatus(ctx, dev.ID, MemberStatusOffline))
+
+	// The task finished elsewhere while the member was away
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusCompleted, nil))
+	dev.CurrentTasks = []string{task.ID}
+
+	require.NoError(t, m.Heartbeat(ctx, dev.ID))
+	require.Equal(t, MemberStatusOnline, dev.Status)
+	require.Empty(t, dev.CurrentTasks)
+
+	require.ErrorContains(t, m.Heartbeat(ctx, "missing"), "does not exist")
+}
+
+func TestManagerStatsReadsDuringCompletion(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 100)
+
+	const tasks = 50
+	for i := range tasks {
+		_, err := m.CreateTask(ctx, &Task{ID: fmt.Sprintf("task-%d", i), DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+	}
+
+	// Each goroutine reports at most one error, checked once they are done
+	errs := make(chan error, tasks+1)
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Go(func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			memberStats, err := m.GetMemberStats("dev-1")
+			if err == nil && memberStats.CompletedTasks > tasks {
+				err = fmt.Errorf("%d tasks completed, want at most %d", memberStats.CompletedTasks, tasks)
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			deptStats, err := m.GetDepartmentStats("dept-dev")
+			if err == nil && deptStats.RoleDistribution["developer"] != 1 {
+				err = fmt.Errorf("%d developers counted, want 1", deptStats.RoleDistribution["developer"])
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+		}
+	})
+
+	var completers sync.WaitGroup
+	for i := range tasks {
+		completers.Go(func() {
+			if err := m.UpdateTaskStatus(ctx, fmt.Sprintf("task-%d", i), TaskStatusCompleted, nil); err != nil {
+				errs <- err
+			}
+		})
+	}
+	completers.Wait()
+	close(stop)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	stats, err := m.GetMemberStats("dev-1")
+	require.NoError(t, err)
+	require.Equal(t, tasks, stats.CompletedTasks)
+	require.Zero(t, stats.CurrentLoad)
+
+	// Mutating a returned copy does not touch the manager
+	stats.CompletedTasks = 0
+	stats, err = m.GetMemberStats("dev-1")
+	require.NoError(t, err)
+	require.Equal(t, tasks, stats.CompletedTasks)
+}
+
+func TestManagerAverageResponseTime(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+
+	complete := func(id string, took time.Duration) {
+		t.Helper()
+		task, err := m.CreateTask(ctx, &Task{ID: id, DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+		require.Equal(t, "dev-1", task.AssignedMember)
+		require.NoError(t, m.UpdateTaskStatus(ctx, id, TaskStatusInProgress, nil))
+		started := time.Now().Add(-took)
+		task.StartedAt = &started
+		require.NoError(t, m.UpdateTaskStatus(ctx, id, TaskStatusCompleted, nil))
+	}
+
+	complete("task-1", 10*time.Second)
+	stats, err := m.GetMemberStats("dev-1")
+	require.NoError(t, err)
+	require.InDelta(t, 10, stats.AverageTime, 0.5)
+
+	complete("task-2", 30*time.Second)
+	stats, err = m.GetMemberStats("dev-1")
+	require.NoError(t, err)
+	require.InDelta(t, 20, stats.AverageTime, 0.5)
+	require.Equal(t, 2, stats.TimedTasks)
+
+	// A second member's average is weighted by its task count
+	registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 1)
+	require.NoError(t, m.UpdateMemberStatus(ctx, "dev-1", MemberStatusOffline))
+	task, err := m.CreateTask(ctx, &Task{ID: "task-3", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, "dev-2", task.AssignedMember)
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+	started := time.Now().Add(-50 * time.Second)
+	task.StartedAt = &started
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusCompleted, nil))
+
+	deptStats, err := m.GetDepartmentStats("dept-dev")
+	require.NoError(t, err)
+	require.InDelta(t, 30, deptStats.AverageResponse, 0.5)
+}
+
+func TestManagerSubscribeAfterStop(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+	require.NoError(t, m.Start(t.Context()))
+	require.NoError(t, m.Stop())
+
+	select {
+	case _, ok := <-m.SubscribeToTaskEvents(t.Context()):
+		require.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("task event channel was not closed after stop")
+	}
+	_, ok := <-m.SubscribeToMemberEvents(t.Context())
+	require.False(t, ok)
+	_, ok = <-m.SubscribeToDepartmentEvents(t.Context())
+	require.False(t, ok)
+}
+
+func TestManagerCapActiveMembersOnly(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	newFullDepartment := func(activeOnly bool) *Manager {
+		m, err := NewManager(ctx, &DepartmentConfig{Enabled: true, CapActiveMembersOnly: activeOnly})
+		require.NoError(t, err)
+
+		dept, err := m.GetDepartment("dept-security")
+		require.NoError(t, err)
+		for i := range dept.MaxMembers {
+			registerTestMember(t, m, fmt.Sprintf("sec-%d", i), dept.ID, RoleSecurity, 1)
+		}
+
+		// One member still holds a task when it drops off
+		task, err := m.CreateTask(ctx, &Task{ID: "task-1", Title: "audit", DepartmentID: dept.ID})
+		require.NoError(t, err)
+		require.NotEmpty(t, task.AssignedMember)
+
+		for i := range dept.MaxMembers {
+			require.NoError(t, m.UpdateMemberStatus(ctx, fmt.Sprintf("sec-%d", i), MemberStatusOffline))
+		}
+		return m
+	}
+
+	// By default dead members still count toward the cap
+	m := newFullDepartment(false)
+	err := m.RegisterMember(ctx, &Member{ID: "sec-new", Role: RoleSecurity, DepartmentID: "dept-security"})
+	require.ErrorContains(t, err, "reached maximum member capacity")
+
+	m = newFullDepartment(true)
+	registerTestMember(t, m, "sec-new", "dept-security", RoleSecurity, 1)
+
+	// Idle offline members were cleaned up; the one holding a task stays
+	task, err := m.GetTask("task-1")
+	require.NoError(t, err)
+	var ids []string
+	for _, member := range m.ListMembers("dept-security") {
+		ids = append(ids, member.ID)
+	}
+	require.ElementsMatch(t, []string{task.AssignedMember, "sec-new"}, ids)
+}
+
+func TestManagerUpdateMemberCapabilities(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	dev := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+
+	// Nobody knows rust yet, so the task cannot be routed
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", Title: "port", DepartmentID: "dept-dev", RequiredSkills: []string{"rust"}})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, task.Status)
+
+	// Updates that don't touch capabilities keep the version
+	require.NoError(t, m.UpdateMember(ctx, dev.ID, MemberPatch{Name: "Dev One"}))
+	require.Equal(t, 0, dev.CapabilityVersion)
+	require.Equal(t, TaskStatusQueued, task.Status)
+
+	// The upgraded member picks up the task
+	require.NoError(t, m.UpdateMember(ctx, dev.ID, MemberPatch{Specializations: []string{"go", "rust"}}))
+	require.Equal(t, 1, dev.CapabilityVersion)
+	require.Equal(t, TaskStatusAssigned, task.Status)
+	require.Equal(t, dev.ID, task.AssignedMember)
+
+	// A caller-supplied newer version is kept as is
+	require.NoError(t, m.UpdateMember(ctx, dev.ID, MemberPatch{CapabilityVersion: 5}))
+	require.Equal(t, 5, dev.CapabilityVersion)
+
+	require.ErrorContains(t, m.UpdateMember(ctx, "missing", MemberPatch{}), "member missing does not exist")
+}
+
+func TestManagerUpdateMemberRoleAndCapacity(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 3)
+	events := m.SubscribeToMemberEvents(t.Context())
+
+	for _, id := range []string{"task-1", "task-2", "task-3"} {
+		_, err := m.CreateTask(ctx, &Task{ID: id, DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+	}
+
+	// Promotion to a lead role makes the member a lead
+	require.NoError(t, m.UpdateMember(ctx, "dev-1", MemberPatch{Role: RoleLeadDev}))
+	select {
+	case event := <-events:
+		require.Equal(t, pubsub.UpdatedEvent, event.Type)
+	case <-time.After(time.Second):
+		t.Fatal("member update was not published")
+	}
+	member, err := m.GetMember("dev-1")
+	require.NoError(t, err)
+	require.Equal(t, RoleLeadDev, member.Role)
+	require.True(t, member.IsLead)
+	stats, err := m.GetDepartmentStats("dept-dev")
+	require.NoError(t, err)
+	require.Equal(t, 1, stats.RoleDistribution[string(RoleLeadDev)])
+	require.Zero(t, stats.RoleDistribution[string(RoleDeveloper)])
+
+	// Lowering capacity below the load keeps the tasks but takes no new ones
+	require.NoError(t, m.UpdateMember(ctx, "dev-1", MemberPatch{MaxConcurrent: 1}))
+	member, err = m.GetMember("dev-1")
+	require.NoError(t, err)
+	require.Len(t, member.CurrentTasks, 3)
+	require.Equal(t, MemberStatusBusy, member.Status)
+
+	queued, err := m.CreateTask(ctx, &Task{ID: "task-4", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, queued.Status)
+
+	// It stays busy until drained below the new capacity
+	require.NoError(t, m.UpdateTaskStatus(ctx, "task-1", TaskStatusCompleted, nil))
+	require.NoError(t, m.UpdateTaskStatus(ctx, "task-2", TaskStatusCompleted, nil))
+	member, err = m.GetMember("dev-1")
+	require.NoError(t, err)
+	require.Equal(t, MemberStatusBusy, member.Status)
+
+	// Once drained, the freed slot goes to the queued task
+	require.NoError(t, m.UpdateTaskStatus(ctx, "task-3", TaskStatusCompleted, nil))
+	stored, err := m.GetTask("task-4")
+	require.NoError(t, err)
+	require.Equal(t, "dev-1", stored.AssignedMember)
+	member, err = m.GetMember("dev-1")
+	require.NoError(t, err)
+	require.Equal(t, MemberStatusBusy, member.Status)
+
+	queued, err = m.CreateTask(ctx, &Task{ID: "task-5", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, queued.Status)
+
+	// Raising capacity again routes the queued task right away
+	require.NoError(t, m.UpdateMember(ctx, "dev-1", MemberPatch{MaxConcurrent: 2}))
+	stored, err = m.GetTask("task-5")
+	require.NoError(t, err)
+	require.Equal(t, "dev-1", stored.AssignedMember)
+
+	require.ErrorContains(t, m.UpdateMember(ctx, "dev-1", MemberPatch{Role: "wizard"}), "unknown member role")
+	require.Error(t, m.UpdateMember(ctx, "dev-1", MemberPatch{MaxConcurrent: -1}))
+}
+
+func TestManagerPublishesTaskLifecycleSummary(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m := newTestManager(t)
+	summaries := m.SubscribeToTaskSummaries(ctx)
+
+	dev1 := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", Title: "work", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, dev1.ID, task.AssignedMember)
+
+	// Move the task once, so it is retried on dev-2
+	dev2 := registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 1)
+	moved, err := m.taskRouter.ReassignMemberTasks(ctx, dev1.ID, "member_unhealthy")
+	require.NoError(t, err)
+	require.Equal(t, 1, moved)
+
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusCompleted, nil))
+
+	select {
+	case event := <-summaries:
+		require.Equal(t, TaskSummaryEvent, event.Type)
+		summary := event.Payload
+		require.Equal(t, task.ID, summary.TaskID)
+		require.Equal(t, "dept-dev", summary.DepartmentID)
+		require.Equal(t, dev2.ID, summary.AssignedMember)
+		require.Equal(t, TaskStatusCompleted, summary.FinalStatus)
+		require.Equal(t, 1, summary.Retries)
+		require.Positive(t, summary.QueuedDuration)
+		require.GreaterOrEqual(t, summary.ExecutionDuration, 10*time.Millisecond)
+		require.GreaterOrEqual(t, summary.TotalDuration, summary.ExecutionDuration+summary.QueuedDuration)
+	case <-time.After(time.Second):
+		t.Fatal("no lifecycle summary published")
+	}
+}
+
+func TestManagerRejectsOversizedPrompt(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewManager(t.Context(), &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{MaxPromptTokens: 10},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, 10, EstimateTokens(strings.Repeat("a", 40)))
+	require.NoError(t, m.CheckPromptSize(strings.Repeat("a", 40)))
+
+	_, err = m.CreateTask(t.Context(), &Task{ID: "big", DepartmentID: "dept-dev", Description: strings.Repeat("a", 41)})
+	require.ErrorIs(t, err, ErrPromptTooLarge)
+	_, err = m.GetTask("big")
+	require.Error(t, err)
+}
+
+func TestManagerGettersReturnCopies(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+	_, err := m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-dev", Metadata: map[string]string{"key": "value"}})
+	require.NoError(t, err)
+
+	// Readers and a writer run concurrently; -race flags any shared state.
+	// Each goroutine reports at most one error, checked once they are done.
+	errs := make(chan error, 2)
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for range 100 {
+			task, err := m.GetTask("task-1")
+			if err != nil {
+				errs <- err
+				return
+			}
+			_ = task.Status
+			_ = task.Results["output"]
+			member, err := m.GetMember("dev-1")
+			if err != nil {
+				errs <- err
+				return
+			}
+			_ = len(member.CurrentTasks)
+			for _, dept := range m.ListDepartments() {
+				_ = dept.Description
+			}
+		}
+	})
+	wg.Go(func() {
+		for i := range 100 {
+			status := TaskStatusInProgress
+			if i%2 == 1 {
+				status = TaskStatusAssigned
+			}
+			if err := m.UpdateTaskStatus(ctx, "task-1", status, map[string]interface{}{"output": i}); err != nil {
+				errs <- err
+				return
+			}
+			dept, err := m.GetDepartment("dept-qa")
+			if err == nil {
+				dept.Description = fmt.Sprintf("revision %d", i)
+				err = m.UpdateDepartment(ctx, dept)
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+		}
+	})
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// Changing a copy leaves the manager's state alone
+	task, err := m.GetTask("task-1")
+	require.NoError(t, err)
+	task.Status = TaskStatusCancelled
+	task.Metadata["key"] = "changed"
+	member, err := m.GetMember("dev-1")
+	require.NoError(t, err)
+	member.CurrentTasks[0] = "other"
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	dept.Capabilities[0] = "changed"
+
+	task, err = m.GetTask("task-1")
+	require.NoError(t, err)
+	require.NotEqual(t, TaskStatusCancelled, task.Status)
+	require.Equal(t, "value", task.Metadata["key"])
+	member, err = m.GetMember("dev-1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"task-1"}, member.CurrentTasks)
+	dept, err = m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	require.NotEqual(t, "changed", dept.Capabilities[0])
+}
+
+func TestManagerDepartmentLifecycle(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	events := m.SubscribeToDepartmentEvents(t.Context())
+	nextEvent := func() pubsub.Event[*Department] {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("no department event published")
+			return pubsub.Event[*Department]{}
+		}
+	}
+
+	dept := &Department{ID: "dept-docs", Name: "Docs", Type: DepartmentDevelopment, MinMembers: 1, MaxMembers: 2}
+	require.NoError(t, m.CreateDepartment(ctx, dept))
+	event := nextEvent()
+	require.Equal(t, pubsub.CreatedEvent, event.Type)
+	require.Equal(t, "dept-docs", event.Payload.ID)
+
+	stats, err := m.GetDepartmentStats("dept-docs")
+	require.NoError(t, err)
+	require.Zero(t, stats.TotalMembers)
+
+	require.ErrorContains(t, m.CreateDepartment(ctx, dept), "already exists")
+	require.ErrorContains(t, m.CreateDepartment(ctx, &Department{ID: "dept-bad", MinMembers: 3, MaxMembers: 2}), "exceeds max members")
+	require.Error(t, m.CreateDepartment(ctx, &Department{Name: "No ID"}))
+
+	// Changes to the caller's copy do not leak into the manager
+	dept.Name = "Changed"
+	stored, err := m.GetDepartment("dept-docs")
+	require.NoError(t, err)
+	require.Equal(t, "Docs", stored.Name)
+
+	registerTestMember(t, m, "writer-1", "dept-docs", RoleDeveloper, 1)
+	registerTestMember(t, m, "writer-2", "dept-docs", RoleDeveloper, 1)
+	require.ErrorContains(t, m.UpdateDepartment(ctx, &Department{ID: "dept-docs", MaxMembers: 1}), "more than max members")
+	require.ErrorContains(t, m.UpdateDepartment(ctx, &Department{ID: "dept-missing"}), "does not exist")
+
+	require.NoError(t, m.UpdateDepartment(ctx, &Department{ID: "dept-docs", Name: "Documentation", Type: DepartmentDevelopment, MaxMembers: 4}))
+	event = nextEvent()
+	require.Equal(t, pubsub.UpdatedEvent, event.Type)
+	stored, err = m.GetDepartment("dept-docs")
+	require.NoError(t, err)
+	require.Equal(t, "Documentation", stored.Name)
+	require.Equal(t, 4, stored.MaxMembers)
+	require.False(t, stored.CreatedAt.IsZero())
+
+	// Members and unfinished tasks keep the department alive
+	require.ErrorContains(t, m.DeleteDepartment(ctx, "dept-docs"), "still has 2 members")
+	require.NoError(t, m.UnregisterMember(ctx, "writer-1"))
+	require.NoError(t, m.UnregisterMember(ctx, "writer-2"))
+	_, err = m.CreateTask(ctx, &Task{ID: "guide", DepartmentID: "dept-docs"})
+	require.NoError(t, err)
+	require.ErrorContains(t, m.DeleteDepartment(ctx, "dept-docs"), "unfinished tasks")
+	require.NoError(t, m.CancelTask(ctx, "guide", "no longer needed"))
+
+	require.NoError(t, m.DeleteDepartment(ctx, "dept-docs"))
+	event = nextEvent()
+	require.Equal(t, pubsub.DeletedEvent, event.Type)
+	require.Equal(t, "dept-docs", event.Payload.ID)
+
+	_, err = m.GetDepartment("dept-docs")
+	require.Error(t, err)
+	_, err = m.GetDepartmentStats("dept-docs")
+	require.Error(t, err)
+	require.Error(t, m.DeleteDepartment(ctx, "dept-docs"))
+}
+
+// BenchmarkManagerConcurrentWorkload mixes task writes with member and
+// statistics reads, on a manager holding a large task history
+func BenchmarkManagerConcurrentWorkload(b *testing.B) {
+	// Logging would dominate the measurement
+	logger := slog.Default()
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	b.Cleanup(func() { slog.SetDefault(logger) })
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{Enabled: true})
+	require.NoError(b, err)
+
+	departments := []string{"dept-dev", "dept-devops", "dept-security", "dept-qa"}
+	for i := range 16 {
+		member := &Member{
+			ID:            fmt.Sprintf("member-%d", i),
+			Role:          RoleDeveloper,
+			DepartmentID:  departments[i%len(departments)],
+			MaxConcurrent: 1000,
+		}
+		require.NoError(b, m.RegisterMember(ctx, member))
+	}
+
+	now := time.Now()
+	m.mu.Lock()
+	for i := range 20000 {
+		id := fmt.Sprintf("history-%d", i)
+		m.tasks[id] = &Task{ID: id, DepartmentID: departments[i%len(departments)], Status: TaskStatusCompleted, CreatedAt: now, UpdatedAt: now}
+		m.indexTask(m.tasks[id])
+	}
+	m.mu.Unlock()
+
+	var next atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			n := next.Add(1)
+			switch n % 5 {
+			case 0:
+				id := fmt.Sprintf("bench-%d", n)
+				if _, err := m.CreateTask(ctx, &Task{ID: id, DepartmentID: departments[n%int64(len(departments))]}); err != nil {
+					b.Error(err)
+					return
+				}
+				if err := m.UpdateTaskStatus(ctx, id, TaskStatusCompleted, nil); err != nil {
+					b.Error(err)
+					return
+				}
+			case 1, 2:
+				if _, err := m.GetMember(fmt.Sprintf("member-%d", n%16)); err != nil {
+					b.Error(err)
+					return
+				}
+			case 3:
+				m.ListMembers(departments[n%int64(len(departments))])
+			case 4:
+				if _, err := m.GetDepartmentStats(departments[n%int64(len(departments))]); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}
+	})
+}
+
+func TestManagerDelegateTask(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled: true,
+		Roles: RoleConfig{RoleDefinitions: map[string]RoleDefinition{
+			string(RoleLeadDev): {Name: "Dev Lead", LeadRole: true, CanAssignTo: []string{string(RoleDeveloper)}},
+		}},
+	})
+	require.NoError(t, err)
+	registerTestMember(t, m, "lead-1", "dept-dev", RoleLeadDev, 1)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 3)
+	registerTestMember(t, m, "qa-1", "dept-dev", RoleQA, 3)
+	require.NoError(t, m.UpdateMemberStatus(ctx, "lead-1", MemberStatusOffline))
+	require.NoError(t, m.UpdateMemberStatus(ctx, "dev-1", MemberStatusOffline))
+	require.NoError(t, m.UpdateMemberStatus(ctx, "qa-1", MemberStatusOffline))
+
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, task.Status)
+	require.NoError(t, m.UpdateMemberStatus(ctx, "dev-1", MemberStatusOnline))
+	require.NoError(t, m.UpdateMemberStatus(ctx, "qa-1", MemberStatusOnline))
+
+	require.ErrorContains(t, m.DelegateTask(ctx, "lead-1", "task-1", "qa-1"), "cannot assign tasks to role qa")
+	require.ErrorContains(t, m.DelegateTask(ctx, "dev-1", "task-1", "qa-1"), "non-lead role")
+
+	require.NoError(t, m.DelegateTask(ctx, "lead-1", "task-1", "dev-1"))
+	task, err = m.GetTask("task-1")
+	require.NoError(t, err)
+	require.Equal(t, "dev-1", task.AssignedMember)
+	require.Contains(t, task.RoutingDecision.MemberReason, "delegated by lead lead-1")
+
+	// An assigned task can be delegated again, still within CanAssignTo
+	registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 3)
+	require.ErrorContains(t, m.DelegateTask(ctx, "lead-1", "task-1", "qa-1"), "cannot assign tasks to role qa")
+	require.ErrorIs(t, m.AssignTask(ctx, "task-1", "dev-2"), ErrTaskAlreadyAssigned)
+	require.NoError(t, m.DelegateTask(ctx, "lead-1", "task-1", "dev-2"))
+	task, err = m.GetTask("task-1")
+	require.NoError(t, err)
+	require.Equal(t, "dev-2", task.AssignedMember)
+	dev1, err := m.GetMember("dev-1")
+	require.NoError(t, err)
+	require.Empty(t, dev1.CurrentTasks)
+}
+
+// BenchmarkManagerDepartmentStats reads the statistics of one of many
+// departments, which should cost in proportion to that department's tasks
+// rather than all tasks
+func BenchmarkManagerDepartmentStats(b *testing.B) {
+	logger := slog.Default()
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	b.Cleanup(func() { slog.SetDefault(logger) })
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{Enabled: true})
+	require.NoError(b, err)
+
+	departments := make([]string, 16)
+	for i := range departments {
+		departments[i] = fmt.Sprintf("dept-bench-%d", i)
+		require.NoError(b, m.CreateDepartment(ctx, &Department{ID: departments[i], Name: departments[i], Type: DepartmentDevelopment}))
+	}
+
+	now := time.Now()
+	m.mu.Lock()
+	for i := range 32000 {
+		id := fmt.Sprintf("history-%d", i)
+		m.tasks[id] = &Task{ID: id, DepartmentID: departments[i%len(departments)], Status: TaskStatusCompleted, CreatedAt: now, UpdatedAt: now}
+		m.indexTask(m.tasks[id])
+	}
+	m.mu.Unlock()
+
+	b.ResetTimer()
+	for i := range b.N {
+		if _, err := m.GetDepartmentStats(departments[i%len(departments)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
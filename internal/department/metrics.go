@@ -0,0 +1,110 @@
+package department
+
+import (
+	"maps"
+	"time"
+)
+
+// DepartmentMetrics is a point-in-time view of one department for export to
+// a monitoring system
+type DepartmentMetrics struct {
+	// MembersByStatus and TasksByStatus hold every status, zero or not
+	MembersByStatus map[MemberStatus]int `json:"members_by_status"`
+	TasksByStatus   map[TaskStatus]int   `json:"tasks_by_status"`
+	QueueDepth      int                  `json:"queue_depth"`
+	// AverageResponse is the average task execution time in seconds
+	AverageResponse float64 `json:"average_response"`
+
+	// Counters since the manager started
+	TasksCreated   int `json:"tasks_created"`
+	TasksCompleted int `json:"tasks_completed"`
+	TasksFailed    int `json:"tasks_failed"`
+	ScaleUps       int `json:"scale_ups"`
+	ScaleDowns     int `json:"scale_downs"`
+}
+
+// taskCounts counts the tasks of a department by lifecycle event
+type taskCounts struct {
+	created   int
+	completed int
+	failed    int
+}
+
+// countTasks returns the task counters of a department, creating them on
+// first use. The caller must hold the manager lock.
+func (m *Manager) countTasks(departmentID string) *taskCounts {
+	counts, exists := m.taskCounts[departmentID]
+	if !exists {
+		counts = &taskCounts{}
+		m.taskCounts[departmentID] = counts
+	}
+	return counts
+}
+
+// Metrics returns gauges and counters for every department, keyed by
+// department ID. It depends on no metrics library; builds with the
+// prometheus tag export it through MetricsCollector.
+func (m *Manager) Metrics() map[string]DepartmentMetrics {
+	// The scaler's lock is taken before the manager's, so its counts are read
+	// first
+	var scaleCounts map[string]map[string]int
+	if m.scaler != nil {
+		scaleCounts = m.scaler.scalingCounts()
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	metrics := make(map[string]DepartmentMetrics, len(m.departments))
+	for id := range m.departments {
+		dm := DepartmentMetrics{
+			MembersByStatus: make(map[MemberStatus]int, len(memberStatuses)),
+			TasksByStatus:   make(map[TaskStatus]int, len(taskStatuses)),
+			AverageResponse: m.computeDepartmentStats(id, time.Now()).AverageResponse,
+			ScaleUps:        scaleCounts[id][scaleUp],
+			ScaleDowns:      scaleCounts[id][scaleDown],
+		}
+		for _, status := range memberStatuses {
+			dm.MembersByStatus[status] = 0
+		}
+		for _, status := range taskStatuses {
+			dm.TasksByStatus[status] = 0
+		}
+		if counts, exists := m.taskCounts[id]; exists {
+			dm.TasksCreated = counts.created
+			dm.TasksCompleted = counts.completed
+			dm.TasksFailed = counts.failed
+		}
+		metrics[id] = dm
+	}
+
+	for _, member := range m.members {
+		if dm, exists := metrics[member.DepartmentID]; exists {
+			dm.MembersByStatus[member.Status]++
+		}
+	}
+	for _, task := range m.tasks {
+		if dm, exists := metrics[task.DepartmentID]; exists {
+			dm.TasksByStatus[task.Status]++
+		}
+	}
+	for id, dm := range metrics {
+		dm.QueueDepth = dm.TasksByStatus[TaskStatusQueued]
+		metrics[id] = dm
+	}
+
+	return metrics
+}
+
+// scalingCounts returns a copy of the scaling event counts by department and
+// action
+func (as *AutoScaler) scalingCounts() map[string]map[string]int {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	counts := make(map[string]map[string]int, len(as.scaleCounts))
+	for deptID, actions := range as.scaleCounts {
+		counts[deptID] = maps.Clone(actions)
+	}
+	return counts
+}
@@ -0,0 +1,70 @@
+//go:build prometheus
+
+// Building with the prometheus tag requires github.com/prometheus/client_golang
+// in go.mod; it is left out by default so builds without metrics don't pull
+// it in.
+
+package department
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	membersDesc = prometheus.NewDesc("department_members",
+		"Members of a department by status.", []string{"department", "status"}, nil)
+	tasksDesc = prometheus.NewDesc("department_tasks",
+		"Tasks of a department by status.", []string{"department", "status"}, nil)
+	queueDepthDesc = prometheus.NewDesc("department_queue_depth",
+		"Tasks queued in a department waiting for a member.", []string{"department"}, nil)
+	averageResponseDesc = prometheus.NewDesc("department_average_response_seconds",
+		"Average task execution time of a department's members.", []string{"department"}, nil)
+	tasksCreatedDesc = prometheus.NewDesc("department_tasks_created_total",
+		"Tasks created in a department.", []string{"department"}, nil)
+	tasksCompletedDesc = prometheus.NewDesc("department_tasks_completed_total",
+		"Tasks completed in a department.", []string{"department"}, nil)
+	tasksFailedDesc = prometheus.NewDesc("department_tasks_failed_total",
+		"Tasks failed in a department.", []string{"department"}, nil)
+	scalingEventsDesc = prometheus.NewDesc("department_scaling_events_total",
+		"Members added or removed by the auto-scaler.", []string{"department", "action"}, nil)
+)
+
+// MetricsCollector returns a Prometheus collector for the manager's metrics,
+// to be registered with the caller's registry. Metrics are read from the
+// manager on every scrape.
+func (m *Manager) MetricsCollector() prometheus.Collector {
+	return &metricsCollector{manager: m}
+}
+
+type metricsCollector struct {
+	manager *Manager
+}
+
+// Describe implements prometheus.Collector
+func (c *metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- membersDesc
+	ch <- tasksDesc
+	ch <- queueDepthDesc
+	ch <- averageResponseDesc
+	ch <- tasksCreatedDesc
+	ch <- tasksCompletedDesc
+	ch <- tasksFailedDesc
+	ch <- scalingEventsDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for id, dm := range c.manager.Metrics() {
+		for status, count := range dm.MembersByStatus {
+			ch <- prometheus.MustNewConstMetric(membersDesc, prometheus.GaugeValue, float64(count), id, string(status))
+		}
+		for status, count := range dm.TasksByStatus {
+			ch <- prometheus.MustNewConstMetric(tasksDesc, prometheus.GaugeValue, float64(count), id, string(status))
+		}
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(dm.QueueDepth), id)
+		ch <- prometheus.MustNewConstMetric(averageResponseDesc, prometheus.GaugeValue, dm.AverageResponse, id)
+		ch <- prometheus.MustNewConstMetric(tasksCreatedDesc, prometheus.CounterValue, float64(dm.TasksCreated), id)
+		ch <- prometheus.MustNewConstMetric(tasksCompletedDesc, prometheus.CounterValue, float64(dm.TasksCompleted), id)
+		ch <- prometheus.MustNewConstMetric(tasksFailedDesc, prometheus.CounterValue, float64(dm.TasksFailed), id)
+		ch <- prometheus.MustNewConstMetric(scalingEventsDesc, prometheus.CounterValue, float64(dm.ScaleUps), id, scaleUp)
+		ch <- prometheus.MustNewConstMetric(scalingEventsDesc, prometheus.CounterValue, float64(dm.ScaleDowns), id, scaleDown)
+	}
+}
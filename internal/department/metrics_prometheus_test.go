@@ -0,0 +1,42 @@
+//go:build prometheus
+
+package department
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerMetricsCollector(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	_, err := m.CreateTask(ctx, &Task{ID: "scan", DepartmentID: "dept-security"})
+	require.NoError(t, err)
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(m.MetricsCollector()))
+
+	expected := `
+# HELP department_queue_depth Tasks queued in a department waiting for a member.
+# TYPE department_queue_depth gauge
+department_queue_depth{department="dept-dev"} 0
+department_queue_depth{department="dept-devops"} 0
+department_queue_depth{department="dept-qa"} 0
+department_queue_depth{department="dept-security"} 1
+# HELP department_tasks_created_total Tasks created in a department.
+# TYPE department_tasks_created_total counter
+department_tasks_created_total{department="dept-dev"} 0
+department_tasks_created_total{department="dept-devops"} 0
+department_tasks_created_total{department="dept-qa"} 0
+department_tasks_created_total{department="dept-security"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"department_queue_depth", "department_tasks_created_total"))
+}
@@ -0,0 +1,41 @@
+package department
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerMetrics(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "sec-1", "dept-security", RoleSecurity, 1)
+
+	for _, id := range []string{"scan-1", "scan-2", "scan-3"} {
+		_, err := m.CreateTask(ctx, &Task{ID: id, DepartmentID: "dept-security"})
+		require.NoError(t, err)
+	}
+	require.NoError(t, m.UpdateTaskStatus(ctx, "scan-1", TaskStatusFailed, nil))
+
+	metrics := m.Metrics()
+	require.Len(t, metrics, 4)
+
+	security := metrics["dept-security"]
+	// The failed task's slot went to the next queued scan
+	require.Equal(t, 1, security.MembersByStatus[MemberStatusBusy])
+	require.Contains(t, security.MembersByStatus, MemberStatusDraining)
+	require.Equal(t, 1, security.TasksByStatus[TaskStatusFailed])
+	require.Equal(t, 1, security.TasksByStatus[TaskStatusAssigned])
+	require.Equal(t, 1, security.TasksByStatus[TaskStatusQueued])
+	require.Equal(t, 1, security.QueueDepth)
+	require.Equal(t, 3, security.TasksCreated)
+	require.Equal(t, 1, security.TasksFailed)
+	require.Zero(t, security.TasksCompleted)
+
+	dev := metrics["dept-dev"]
+	require.Zero(t, dev.TasksCreated)
+	require.Len(t, dev.TasksByStatus, len(taskStatuses))
+}
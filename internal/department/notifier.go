@@ -0,0 +1,426 @@
+package department
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+)
+
+const (
+	// notificationQueueSize is how many notifications wait for delivery to
+	// a single webhook or mailer before new ones for it are dropped
+	notificationQueueSize = 256
+	// maxFinishedTasks is how many tasks whose final status was notified are
+	// remembered
+	maxFinishedTasks = 1024
+	// webhookTimeout bounds a single webhook request
+	webhookTimeout = 10 * time.Second
+)
+
+// notificationRetry is how often and how patiently a failed delivery to a
+// single webhook or mailer is attempted
+var notificationRetry = RetryPolicy{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: 30 * time.Second}
+
+// notificationEvents lists the event names NotificationConfig.Events may
+// contain. Department, member and task events are named after what
+// happened to them; updates that leave a task completed, failed or
+// cancelled are also sent as task_completed, task_failed and
+// task_cancelled.
+var notificationEvents = []string{
+	"department_created", "department_updated", "department_deleted",
+	"member_created", "member_updated", "member_deleted",
+	"task_created", "task_updated", "task_deleted",
+	"task_completed", "task_failed", "task_cancelled",
+	string(QueueWaitExceededEvent), string(TaskSummaryEvent),
+	string(HealthChangedEvent), string(ScaledEvent),
+}
+
+// Mailer sends notification emails
+type Mailer interface {
+	SendMail(ctx context.Context, to []string, subject, body string) error
+}
+
+// WithMailer sets the mailer notifications are emailed with. Without one
+// the configured email addresses are ignored.
+func WithMailer(mailer Mailer) ManagerOption {
+	return func(m *Manager) {
+		m.mailer = mailer
+	}
+}
+
+// Notification is the JSON payload posted to webhooks and emailed
+type Notification struct {
+	Event        string     `json:"event"`
+	DepartmentID string     `json:"department_id,omitempty"`
+	Role         MemberRole `json:"role,omitempty"`
+	// Channels are the configured channels plus those of the role the event
+	// concerns
+	Channels []string  `json:"channels,omitempty"`
+	Payload  any       `json:"payload"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// Notifier sends the manager's events listed in the notification config to
+// the configured webhooks and email addresses
+type Notifier struct {
+	config  NotificationConfig
+	manager *Manager
+	client  *http.Client
+	mailer  Mailer
+	retry   RetryPolicy
+	limiter *tokenBucket
+
+	// Every webhook and the mailer get their own queue and worker, so a slow
+	// or failing one does not hold up the others
+	destinations []*notificationDestination
+
+	// Tasks whose final status was notified, oldest first, so repeated
+	// updates of a finished task are sent once. Only the most recent
+	// maxFinishedTasks are remembered.
+	finishedTasks map[string]bool
+	finishedOrder []string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// notificationDestination is a webhook or the mailer along with the
+// encoded notifications waiting to be sent to it
+type notificationDestination struct {
+	name  string
+	send  func(ctx context.Context, event string, body []byte) error
+	queue chan queuedNotification
+}
+
+// queuedNotification is a notification encoded for delivery
+type queuedNotification struct {
+	event string
+	body  []byte
+}
+
+// NewNotifier creates a notifier for the manager's events
+func NewNotifier(config NotificationConfig, manager *Manager, mailer Mailer) *Notifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Notifier{
+		config:        config,
+		manager:       manager,
+		client:        &http.Client{Timeout: webhookTimeout},
+		mailer:        mailer,
+		retry:         notificationRetry,
+		finishedTasks: make(map[string]bool),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	if config.RateLimit > 0 {
+		n.limiter = newTokenBucket(config.RateLimit, time.Minute)
+	}
+
+	for _, url := range config.Webhooks {
+		n.addDestination(url, func(ctx context.Context, _ string, body []byte) error {
+			return n.postWebhook(ctx, url, body)
+		})
+	}
+	if mailer != nil && len(config.Emails) > 0 {
+		n.addDestination("email", func(ctx context.Context, event string, body []byte) error {
+			subject := fmt.Sprintf("Department notification: %s", event)
+			return mailer.SendMail(ctx, config.Emails, subject, string(body))
+		})
+	}
+	return n
+}
+
+// addDestination adds a webhook or mailer notifications are delivered to
+func (n *Notifier) addDestination(name string, send func(ctx context.Context, event string, body []byte) error) {
+	n.destinations = append(n.destinations, &notificationDestination{
+		name:  name,
+		send:  send,
+		queue: make(chan queuedNotification, notificationQueueSize),
+	})
+}
+
+// Start subscribes to the manager's events and delivers notifications in the
+// background until ctx ends or Stop is called. It returns once subscribed,
+// so no event published after it returns is missed.
+func (n *Notifier) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-n.ctx.Done():
+		}
+		cancel()
+	}()
+
+	m := n.manager
+	departments := m.SubscribeToDepartmentEvents(ctx)
+	members := m.SubscribeToMemberEvents(ctx)
+	tasks := m.SubscribeToTaskEvents(ctx)
+	summaries := m.SubscribeToTaskSummaries(ctx)
+	health := m.SubscribeToHealthEvents(ctx)
+	scaling := m.SubscribeToScalingEvents(ctx)
+
+	go func() {
+		defer func() {
+			for _, dest := range n.destinations {
+				close(dest.queue)
+			}
+		}()
+		for departments != nil || members != nil || tasks != nil || summaries != nil || health != nil || scaling != nil {
+			select {
+			case event, ok := <-departments:
+				if !ok {
+					departments = nil
+					continue
+				}
+				n.handleDepartmentEvent(event)
+			case event, ok := <-members:
+				if !ok {
+					members = nil
+					continue
+				}
+				n.handleMemberEvent(event)
+			case event, ok := <-tasks:
+				if !ok {
+					tasks = nil
+					continue
+				}
+				n.handleTaskEvent(event)
+			case event, ok := <-summaries:
+				if !ok {
+					summaries = nil
+					continue
+				}
+				n.notify(string(event.Type), event.Payload.DepartmentID, "", event.Payload)
+			case event, ok := <-health:
+				if !ok {
+					health = nil
+					continue
+				}
+				dept, role := n.memberDepartmentAndRole(event.Payload.MemberID)
+				n.notify(string(event.Type), dept, role, event.Payload)
+			case event, ok := <-scaling:
+				if !ok {
+					scaling = nil
+					continue
+				}
+				n.notify(string(event.Type), event.Payload.DepartmentID, event.Payload.Role, event.Payload)
+			}
+		}
+	}()
+	for _, dest := range n.destinations {
+		go func() {
+			for notification := range dest.queue {
+				n.withRetry(ctx, notification.event, dest.name, func() error {
+					return dest.send(ctx, notification.event, notification.body)
+				})
+			}
+		}()
+	}
+}
+
+// Stop stops the notifier. A delivery in progress is abandoned.
+func (n *Notifier) Stop() {
+	n.cancel()
+}
+
+func (n *Notifier) handleDepartmentEvent(event pubsub.Event[*Department]) {
+	payload := any(event.Payload)
+	if dept, err := n.manager.GetDepartment(event.Payload.ID); err == nil {
+		payload = dept
+	}
+	n.notify(crudEventName("department", event.Type), event.Payload.ID, "", payload)
+}
+
+func (n *Notifier) handleMemberEvent(event pubsub.Event[*Member]) {
+	member := event.Payload
+	if current, err := n.manager.GetMember(member.ID); err == nil {
+		member = current
+	}
+	n.notify(crudEventName("member", event.Type), member.DepartmentID, member.Role, member)
+}
+
+func (n *Notifier) handleTaskEvent(event pubsub.Event[*Task]) {
+	task := event.Payload
+	if current, err := n.manager.GetTask(task.ID); err == nil {
+		task = current
+	}
+	role := task.AssignedRole
+	if role == "" && task.AssignedMember != "" {
+		_, role = n.memberDepartmentAndRole(task.AssignedMember)
+	}
+
+	n.notify(crudEventName("task", event.Type), task.DepartmentID, role, task)
+
+	if event.Type == pubsub.DeletedEvent {
+		delete(n.finishedTasks, task.ID)
+		return
+	}
+	if !isTaskDone(task.Status) || n.finishedTasks[task.ID] {
+		return
+	}
+	n.rememberFinished(task.ID)
+	n.notify("task_"+string(task.Status), task.DepartmentID, role, task)
+}
+
+// rememberFinished records that a task's final status was notified,
+// forgetting the oldest such task past maxFinishedTasks
+func (n *Notifier) rememberFinished(taskID string) {
+	n.finishedTasks[taskID] = true
+	n.finishedOrder = append(n.finishedOrder, taskID)
+	if len(n.finishedOrder) > maxFinishedTasks {
+		delete(n.finishedTasks, n.finishedOrder[0])
+		n.finishedOrder = n.finishedOrder[1:]
+	}
+}
+
+// crudEventName names a created, updated or deleted event after its kind.
+// Other events keep their own name.
+func crudEventName(kind string, eventType pubsub.EventType) string {
+	switch eventType {
+	case pubsub.CreatedEvent, pubsub.UpdatedEvent, pubsub.DeletedEvent:
+		return kind + "_" + string(eventType)
+	}
+	return string(eventType)
+}
+
+// memberDepartmentAndRole returns the department and role of a member, or
+// empty strings when it is unknown
+func (n *Notifier) memberDepartmentAndRole(memberID string) (string, MemberRole) {
+	member, err := n.manager.GetMember(memberID)
+	if err != nil {
+		return "", ""
+	}
+	return member.DepartmentID, member.Role
+}
+
+// notify queues a notification for an event when the event is configured
+// and the rate limit allows it
+func (n *Notifier) notify(event, departmentID string, role MemberRole, payload any) {
+	if !slices.Contains(n.config.Events, event) {
+		return
+	}
+	if n.limiter != nil && !n.limiter.allow(time.Now()) {
+		slog.Warn("Notification dropped by rate limit", "event", event, "rate_limit", n.config.RateLimit)
+		return
+	}
+
+	channels := slices.Clone(n.config.Channels)
+	for _, channel := range n.config.RoleNotifications[string(role)] {
+		if !slices.Contains(channels, channel) {
+			channels = append(channels, channel)
+		}
+	}
+
+	body, err := json.Marshal(&Notification{
+		Event:        event,
+		DepartmentID: departmentID,
+		Role:         role,
+		Channels:     channels,
+		Payload:      payload,
+		SentAt:       time.Now(),
+	})
+	if err != nil {
+		slog.Error("Failed to encode notification", "event", event, "error", err)
+		return
+	}
+
+	for _, dest := range n.destinations {
+		select {
+		case dest.queue <- queuedNotification{event: event, body: body}:
+		default:
+			slog.Warn("Notification dropped, delivery queue is full", "event", event, "destination", dest.name)
+		}
+	}
+}
+
+// withRetry runs send until it succeeds, the retry policy gives up or ctx
+// ends. Failures are logged, never returned.
+func (n *Notifier) withRetry(ctx context.Context, event, destination string, send func() error) {
+	for attempt := 1; ; attempt++ {
+		err := send()
+		if err == nil {
+			return
+		}
+		if attempt >= n.retry.MaxAttempts {
+			slog.Error("Failed to deliver notification",
+				"event", event,
+				"destination", destination,
+				"attempts", attempt,
+				"error", err)
+			return
+		}
+
+		backoff := n.retry.backoff(attempt)
+		slog.Warn("Retrying notification delivery",
+			"event", event,
+			"destination", destination,
+			"attempt", attempt,
+			"backoff", backoff,
+			"error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// postWebhook posts the encoded notification to a webhook URL
+func (n *Notifier) postWebhook(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// tokenBucket allows bursts of up to its capacity and refills at a steady
+// rate. It is not safe for concurrent use.
+type tokenBucket struct {
+	capacity float64
+	tokens   float64
+	// Tokens added per second
+	rate float64
+	last time.Time
+}
+
+// newTokenBucket creates a full bucket allowing limit events per period
+func newTokenBucket(limit int, period time.Duration) *tokenBucket {
+	return &tokenBucket{
+		capacity: float64(limit),
+		tokens:   float64(limit),
+		rate:     float64(limit) / period.Seconds(),
+		last:     time.Now(),
+	}
+}
+
+// allow takes a token and reports whether one was left
+func (b *tokenBucket) allow(now time.Time) bool {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(b.capacity, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,177 @@
+package department
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (f *fakeMailer) SendMail(ctx context.Context, to []string, subject, body string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, subject)
+	return nil
+}
+
+func TestNotifierPostsConfiguredEvents(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		attempts int
+	)
+	received := make(chan Notification, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		first := attempts == 1
+		mu.Unlock()
+		// The first delivery fails and must be retried
+		if first {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var notification Notification
+		if err := json.NewDecoder(r.Body).Decode(&notification); err == nil {
+			received <- notification
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 3)
+
+	mailer := &fakeMailer{}
+	n := NewNotifier(NotificationConfig{
+		Enabled:           true,
+		Events:            []string{"task_created", "task_failed"},
+		Channels:          []string{"#ops"},
+		Webhooks:          []string{server.URL},
+		Emails:            []string{"oncall@example.com"},
+		RoleNotifications: map[string][]string{string(RoleDeveloper): {"#dev-leads"}},
+	}, m, mailer)
+	n.retry = RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	n.Start(ctx)
+	t.Cleanup(n.Stop)
+
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusFailed, nil))
+
+	var events []string
+	for range 2 {
+		select {
+		case notification := <-received:
+			events = append(events, notification.Event)
+			require.Equal(t, "dept-dev", notification.DepartmentID)
+			require.Equal(t, RoleDeveloper, notification.Role)
+			require.Equal(t, []string{"#ops", "#dev-leads"}, notification.Channels)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for notifications")
+		}
+	}
+	require.Equal(t, []string{"task_created", "task_failed"}, events)
+
+	require.Eventually(t, func() bool {
+		mailer.mu.Lock()
+		defer mailer.mu.Unlock()
+		return len(mailer.sent) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNotifierDeliversToEachWebhookOnItsOwn(t *testing.T) {
+	t.Parallel()
+
+	// One webhook hangs until the test ends; the other must still get
+	// every notification
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(release) })
+
+	received := make(chan string, 10)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification Notification
+		if err := json.NewDecoder(r.Body).Decode(&notification); err == nil {
+			received <- notification.Event
+		}
+	}))
+	t.Cleanup(fast.Close)
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	n := NewNotifier(NotificationConfig{
+		Enabled:  true,
+		Events:   []string{"task_created", "task_completed"},
+		Webhooks: []string{slow.URL, fast.URL},
+	}, m, nil)
+	n.Start(ctx)
+	t.Cleanup(n.Stop)
+
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusCompleted, nil))
+	// Updating the finished task again is not a second completion
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusCompleted, nil))
+
+	var events []string
+	for range 2 {
+		select {
+		case event := <-received:
+			events = append(events, event)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for notifications")
+		}
+	}
+	require.Equal(t, []string{"task_created", "task_completed"}, events)
+	select {
+	case event := <-received:
+		t.Fatalf("unexpected notification %s", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNotifierForgetsOldFinishedTasks(t *testing.T) {
+	t.Parallel()
+
+	n := NewNotifier(NotificationConfig{}, nil, nil)
+	for i := range maxFinishedTasks + 1 {
+		n.rememberFinished(fmt.Sprintf("task-%d", i))
+	}
+	require.Len(t, n.finishedTasks, maxFinishedTasks)
+	require.Len(t, n.finishedOrder, maxFinishedTasks)
+	require.False(t, n.finishedTasks["task-0"])
+	require.True(t, n.finishedTasks[fmt.Sprintf("task-%d", maxFinishedTasks)])
+}
+
+func TestTokenBucket(t *testing.T) {
+	t.Parallel()
+
+	bucket := newTokenBucket(2, time.Minute)
+	now := bucket.last
+
+	// Bursts up to the limit, then refills at the limit per period
+	require.True(t, bucket.allow(now))
+	require.True(t, bucket.allow(now))
+	require.False(t, bucket.allow(now))
+	require.False(t, bucket.allow(now.Add(20*time.Second)))
+	require.True(t, bucket.allow(now.Add(30*time.Second)))
+	require.True(t, bucket.allow(now.Add(10*time.Minute)))
+	require.True(t, bucket.allow(now.Add(10*time.Minute)))
+	require.False(t, bucket.allow(now.Add(10*time.Minute)))
+}
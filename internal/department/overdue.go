@@ -0,0 +1,82 @@
+package department
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+)
+
+// overdueMonitor periodically flags tasks that are past their due date
+func (m *Manager) overdueMonitor(ctx context.Context) {
+	ticker := time.NewTicker(max(m.config.TaskRouting.OverdueCheckInterval, minQueueWaitCheckInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.markOverdueTasks(time.Now())
+		}
+	}
+}
+
+// markOverdueTasks sets Overdue on every unfinished task whose due date has
+// passed and publishes an UpdatedEvent for each, once per task
+func (m *Manager) markOverdueTasks(now time.Time) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	marked := 0
+	for _, task := range m.tasks {
+		if task.Overdue || task.DueDate == nil || !now.After(*task.DueDate) || isTaskDone(task.Status) {
+			continue
+		}
+
+		task.Overdue = true
+		slog.Warn("Task is overdue",
+			"task_id", task.ID,
+			"department", task.DepartmentID,
+			"priority", task.Priority,
+			"due_date", *task.DueDate,
+			"status", task.Status)
+		m.taskEvents.Publish(pubsub.UpdatedEvent, task)
+		marked++
+	}
+
+	if marked > 0 {
+		m.persist()
+	}
+	return marked
+}
+
+// ListOverdueTasks returns copies of the unfinished tasks flagged as overdue,
+// earliest due date first
+func (m *Manager) ListOverdueTasks() []*Task {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var overdue []*Task
+	for _, task := range m.tasks {
+		if task.Overdue && !isTaskDone(task.Status) {
+			overdue = append(overdue, task.clone())
+		}
+	}
+	slices.SortFunc(overdue, func(a, b *Task) int {
+		if c := a.DueDate.Compare(*b.DueDate); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return overdue
+}
+
+// escalated reports whether a task is an overdue critical task bumped by
+// EscalateOverdueCritical
+func (m *Manager) escalated(task *Task) bool {
+	return m.config.TaskRouting.EscalateOverdueCritical && task.Overdue && task.Priority == PriorityCritical
+}
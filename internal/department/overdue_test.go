@@ -0,0 +1,98 @@
+package department
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerMarksOverdueTasks(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{OverdueCheckInterval: 100 * time.Millisecond},
+	})
+	require.NoError(t, err)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	for _, task := range []*Task{
+		{ID: "late", DepartmentID: "dept-dev", DueDate: &past},
+		{ID: "on-time", DepartmentID: "dept-dev", DueDate: &future},
+		{ID: "done", DepartmentID: "dept-dev", DueDate: &past},
+		{ID: "undated", DepartmentID: "dept-qa"},
+	} {
+		_, err := m.CreateTask(ctx, task)
+		require.NoError(t, err)
+	}
+	require.NoError(t, m.UpdateTaskStatus(ctx, "done", TaskStatusCompleted, nil))
+
+	events := m.SubscribeToTaskEvents(ctx)
+	require.NoError(t, m.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, m.Stop()) })
+
+	select {
+	case event := <-events:
+		require.Equal(t, pubsub.UpdatedEvent, event.Type)
+		require.Equal(t, "late", event.Payload.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the overdue task to be published")
+	}
+
+	overdue := m.ListOverdueTasks()
+	require.Len(t, overdue, 1)
+	require.Equal(t, "late", overdue[0].ID)
+	require.True(t, overdue[0].Overdue)
+
+	// Tasks are flagged once
+	require.Zero(t, m.markOverdueTasks(time.Now()))
+
+	// The on-time task is flagged once its due date passes
+	require.Equal(t, 1, m.markOverdueTasks(future.Add(time.Minute)))
+
+	// Finished tasks drop out of the listing
+	require.NoError(t, m.UpdateTaskStatus(ctx, "late", TaskStatusCompleted, nil))
+	overdue = m.ListOverdueTasks()
+	require.Len(t, overdue, 1)
+	require.Equal(t, "on-time", overdue[0].ID)
+}
+
+func TestManagerEscalatesOverdueCriticalTasks(t *testing.T) {
+	t.Parallel()
+
+	for _, escalate := range []bool{false, true} {
+		ctx := t.Context()
+		m, err := NewManager(ctx, &DepartmentConfig{
+			Enabled:     true,
+			TaskRouting: TaskRoutingConfig{EscalateOverdueCritical: escalate},
+		})
+		require.NoError(t, err)
+
+		// The critical task is queued before anyone can take it
+		past := time.Now().Add(-time.Minute)
+		_, err = m.CreateTask(ctx, &Task{ID: "critical", DepartmentID: "dept-dev", Priority: PriorityCritical, DueDate: &past})
+		require.NoError(t, err)
+		registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+		low, err := m.CreateTask(ctx, &Task{ID: "low", DepartmentID: "dept-dev", Priority: PriorityLow})
+		require.NoError(t, err)
+		require.Equal(t, "dev-1", low.AssignedMember)
+		require.NoError(t, m.UpdateTaskStatus(ctx, low.ID, TaskStatusInProgress, nil))
+
+		require.Equal(t, 1, m.markOverdueTasks(time.Now()))
+		m.retryQueuedTasks(ctx)
+
+		// Escalated, the overdue critical task preempts the low one
+		critical, err := m.GetTask("critical")
+		require.NoError(t, err)
+		if escalate {
+			require.Equal(t, "dev-1", critical.AssignedMember)
+		} else {
+			require.Equal(t, TaskStatusQueued, critical.Status)
+		}
+	}
+}
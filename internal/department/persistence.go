@@ -0,0 +1,250 @@
+package department
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"time"
+)
+
+// stateKey is the key the manager state is stored under in a StateStore
+const stateKey = "department-manager"
+
+// StateStore persists manager state between restarts
+type StateStore interface {
+	// Save stores data under key, replacing any previous value
+	Save(key string, data []byte) error
+	// Load returns the data stored under key, or nil if nothing was stored
+	Load(key string) ([]byte, error)
+}
+
+// managerState is the serialized form of the manager's in-memory maps
+type managerState struct {
+	Departments  map[string]*Department  `json:"departments"`
+	Members      map[string]*Member      `json:"members"`
+	Tasks        map[string]*Task        `json:"tasks"`
+	Teams        map[string]*Team        `json:"teams"`
+	Workflows    map[string]*Workflow    `json:"workflows"`
+	WorkflowRuns map[string]*WorkflowRun `json:"workflow_runs,omitempty"`
+	// TaskTeams maps tasks to the transient teams formed for them
+	TaskTeams map[string]string `json:"task_teams,omitempty"`
+	// PendingMigrations maps busy members to the departments they move to
+	// once their tasks finish
+	PendingMigrations map[string]string `json:"pending_migrations,omitempty"`
+}
+
+// WithPersistence makes the manager load its state from store on Start and
+// write changes back to it as they happen
+func WithPersistence(store StateStore) ManagerOption {
+	return func(m *Manager) {
+		m.store = store
+	}
+}
+
+// Snapshot serializes the departments, members, tasks, teams and workflows
+// held by the manager
+func (m *Manager) Snapshot() ([]byte, error) {
+	m.mu.RLock()
+	state := m.state()
+	m.mu.RUnlock()
+
+	return state.marshal()
+}
+
+// Restore replaces the manager state with a snapshot produced by Snapshot and
+// rebuilds the statistics from the restored tasks
+func (m *Manager) Restore(ctx context.Context, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.restore(data)
+}
+
+// state returns a copy of the manager state that can be serialized without
+// holding the manager lock. The caller must hold the manager lock.
+func (m *Manager) state() *managerState {
+	return &managerState{
+		Departments:  cloneValues(m.departments),
+		Members:      cloneValues(m.members),
+		Tasks:        cloneValues(m.tasks),
+		Teams:        cloneValues(m.teams),
+		Workflows:    cloneValues(m.workflows),
+		WorkflowRuns: cloneValues(m.workflowRuns),
+		TaskTeams:    maps.Clone(m.taskTeams),
+
+		PendingMigrations: maps.Clone(m.pendingMigrations),
+	}
+}
+
+func (s *managerState) marshal() ([]byte, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manager state: %w", err)
+	}
+	return data, nil
+}
+
+func (m *Manager) restore(data []byte) error {
+	var state managerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to unmarshal manager state: %w", err)
+	}
+
+	if len(state.Departments) > 0 {
+		m.departments = state.Departments
+	}
+	m.members = nonNilMap(state.Members)
+	m.tasks = nonNilMap(state.Tasks)
+	m.teams = nonNilMap(state.Teams)
+	m.workflows = nonNilMap(state.Workflows)
+	m.workflowRuns = nonNilMap(state.WorkflowRuns)
+	m.taskTeams = nonNilMap(state.TaskTeams)
+	m.pendingMigrations = nonNilMap(state.PendingMigrations)
+
+	m.rebuildStats()
+	m.rebuildDependents()
+	m.rebuildTaskIndexes()
+	m.resumeMigrations()
+
+	slog.Info("Department state restored",
+		"departments", len(m.departments),
+		"members", len(m.members),
+		"tasks", len(m.tasks))
+
+	return nil
+}
+
+// rebuildStats recomputes member assignments and all statistics from the
+// current tasks
+func (m *Manager) rebuildStats() {
+	now := time.Now()
+
+	m.memberStats = make(map[string]*MemberStats, len(m.members))
+	for id, member := range m.members {
+		member.CurrentTasks = []string{}
+		m.memberStats[id] = &MemberStats{
+			MemberID:    id,
+			MemberRole:  member.Role,
+			LastUpdated: now,
+		}
+	}
+
+	for id, task := range m.tasks {
+		member, exists := m.members[task.AssignedMember]
+		if !exists {
+			continue
+		}
+		stats := m.memberStats[member.ID]
+
+		switch task.Status {
+		case TaskStatusAssigned, TaskStatusInProgress:
+			member.CurrentTasks = append(member.CurrentTasks, id)
+		case TaskStatusCompleted:
+			stats.TotalTasks++
+			stats.CompletedTasks++
+			if task.StartedAt != nil && task.CompletedAt != nil {
+				stats.TimedTasks++
+				stats.AverageTime += (task.CompletedAt.Sub(*task.StartedAt).Seconds() - stats.AverageTime) / float64(stats.TimedTasks)
+			}
+		case TaskStatusFailed:
+			stats.TotalTasks++
+			stats.FailedTasks++
+		}
+	}
+
+	for id, member := range m.members {
+		stats := m.memberStats[id]
+		stats.CurrentLoad = len(member.CurrentTasks)
+		if stats.TotalTasks > 0 {
+			stats.SuccessRate = float64(stats.CompletedTasks) / float64(stats.TotalTasks)
+		}
+	}
+}
+
+// resumeMigrations drops pending migrations whose member or target department
+// is gone and moves the members whose tasks finished in the meantime
+func (m *Manager) resumeMigrations() {
+	for memberID, toID := range m.pendingMigrations {
+		member, memberExists := m.members[memberID]
+		target, targetExists := m.departments[toID]
+		if !memberExists || !targetExists {
+			delete(m.pendingMigrations, memberID)
+			continue
+		}
+		if len(member.CurrentTasks) == 0 {
+			m.moveMember(member, target)
+		}
+	}
+}
+
+// loadState restores the persisted state, if any. The caller must hold the
+// manager lock.
+func (m *Manager) loadState() error {
+	if m.store == nil {
+		return nil
+	}
+
+	data, err := m.store.Load(stateKey)
+	if err != nil {
+		return fmt.Errorf("failed to load department state: %w", err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	return m.restore(data)
+}
+
+// persist writes the current state through to the store. The state is
+// copied right away and serialized and saved in the background, so the
+// caller is not held up by the store. The caller must hold the manager lock.
+func (m *Manager) persist() {
+	if m.store == nil {
+		return
+	}
+
+	m.persistSeq++
+	seq, state := m.persistSeq, m.state()
+	m.persisting.Add(1)
+	go func() {
+		defer m.persisting.Done()
+		m.writeState(seq, state)
+	}()
+}
+
+// writeState saves state unless a later one was saved already
+func (m *Manager) writeState(seq uint64, state *managerState) {
+	m.persistMu.Lock()
+	defer m.persistMu.Unlock()
+
+	if seq <= m.writtenSeq {
+		return
+	}
+	m.writtenSeq = seq
+
+	data, err := state.marshal()
+	if err == nil {
+		err = m.store.Save(stateKey, data)
+	}
+	if err != nil {
+		slog.Warn("Failed to persist department state", "error", err)
+	}
+}
+
+// cloneValues copies a map along with its values
+func cloneValues[V interface{ clone() V }](in map[string]V) map[string]V {
+	out := make(map[string]V, len(in))
+	for key, value := range in {
+		out[key] = value.clone()
+	}
+	return out
+}
+
+func nonNilMap[V any](in map[string]V) map[string]V {
+	if in == nil {
+		return make(map[string]V)
+	}
+	return in
+}
@@ -0,0 +1,204 @@
+package department
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (s *memoryStore) Save(key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data == nil {
+		s.data = make(map[string][]byte)
+	}
+	s.data[key] = data
+	return nil
+}
+
+func (s *memoryStore) Load(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data[key], nil
+}
+
+func TestManagerPersistenceAcrossRestart(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	store := &memoryStore{}
+
+	m, err := NewManager(ctx, &DepartmentConfig{Enabled: true}, WithPersistence(store))
+	require.NoError(t, err)
+	require.NoError(t, m.Start(ctx))
+
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 3)
+
+	done, err := m.CreateTask(ctx, &Task{ID: "task-done", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, done.ID, TaskStatusCompleted, map[string]interface{}{"response": "ok"}))
+
+	active, err := m.CreateTask(ctx, &Task{ID: "task-active", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, active.ID, TaskStatusInProgress, nil))
+	require.NoError(t, m.Stop())
+
+	restarted, err := NewManager(ctx, &DepartmentConfig{Enabled: true}, WithPersistence(store))
+	require.NoError(t, err)
+	require.NoError(t, restarted.Start(ctx))
+	t.Cleanup(func() { _ = restarted.Stop() })
+
+	task, err := restarted.GetTask(active.ID)
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusInProgress, task.Status)
+	require.Equal(t, "dev-1", task.AssignedMember)
+
+	member, err := restarted.GetMember("dev-1")
+	require.NoError(t, err)
+	require.Equal(t, []string{active.ID}, member.CurrentTasks)
+
+	stats, err := restarted.GetMemberStats("dev-1")
+	require.NoError(t, err)
+	require.Equal(t, 1, stats.TotalTasks)
+	require.Equal(t, 1, stats.CompletedTasks)
+	require.Equal(t, 1, stats.CurrentLoad)
+	require.Equal(t, 1.0, stats.SuccessRate)
+
+	deptStats, err := restarted.GetDepartmentStats("dept-dev")
+	require.NoError(t, err)
+	require.Equal(t, 1, deptStats.RoleDistribution[string(RoleDeveloper)])
+}
+
+func TestManagerSnapshotRestore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "qa-1", "dept-qa", RoleQA, 2)
+	_, err := m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-qa"})
+	require.NoError(t, err)
+
+	data, err := m.Snapshot()
+	require.NoError(t, err)
+
+	restored := newTestManager(t)
+	require.NoError(t, restored.Restore(ctx, data))
+
+	task, err := restored.GetTask("task-1")
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusAssigned, task.Status)
+	require.Equal(t, "qa-1", task.AssignedMember)
+	require.Len(t, restored.ListDepartments(), 4)
+
+	require.Error(t, restored.Restore(ctx, []byte("not json")))
+}
+
+func TestManagerRestoreDisbandsTaskTeams(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "lead-1", "dept-dev", RoleLeadDev, 2)
+	registerTestMember(t, m, "qa-1", "dept-qa", RoleQA, 2)
+	task, err := m.CreateTask(ctx, &Task{
+		ID:            "task-1",
+		DepartmentID:  "dept-dev",
+		AssignedRole:  RoleLeadDev,
+		RequiredRoles: []MemberRole{RoleLeadDev, RoleQA},
+	})
+	require.NoError(t, err)
+	team, err := m.FormTeamForTask(task)
+	require.NoError(t, err)
+
+	data, err := m.Snapshot()
+	require.NoError(t, err)
+	restored := newTestManager(t)
+	require.NoError(t, restored.Restore(ctx, data))
+
+	// The restored team is still tied to its task
+	_, err = restored.FormTeamForTask(task)
+	require.ErrorContains(t, err, "already has team")
+	require.NoError(t, restored.UpdateTaskStatus(ctx, task.ID, TaskStatusCompleted, nil))
+	_, err = restored.GetTeam(team.ID)
+	require.Error(t, err)
+}
+
+func TestManagerRestoreResumesMigrations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "sec-1", "dept-security", RoleSecurity, 1)
+	active, err := m.CreateTask(ctx, &Task{ID: "task-active", DepartmentID: "dept-security"})
+	require.NoError(t, err)
+	require.NoError(t, m.MigrateDepartment(ctx, "dept-security", "dept-dev"))
+
+	data, err := m.Snapshot()
+	require.NoError(t, err)
+	restored := newTestManager(t)
+	require.NoError(t, restored.Restore(ctx, data))
+
+	// The member still drains into the target after the restore
+	member, err := restored.GetMember("sec-1")
+	require.NoError(t, err)
+	require.Equal(t, "dept-security", member.DepartmentID)
+	require.NoError(t, restored.UpdateTaskStatus(ctx, active.ID, TaskStatusCompleted, nil))
+	member, err = restored.GetMember("sec-1")
+	require.NoError(t, err)
+	require.Equal(t, "dept-dev", member.DepartmentID)
+}
+
+// blockingStore holds up every save until release is closed
+type blockingStore struct {
+	memoryStore
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Save(key string, data []byte) error {
+	select {
+	case s.saving <- struct{}{}:
+	default:
+	}
+	<-s.release
+	return s.memoryStore.Save(key, data)
+}
+
+func TestManagerPersistsWithoutHoldingLock(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := &blockingStore{saving: make(chan struct{}, 1), release: make(chan struct{})}
+	m, err := NewManager(ctx, &DepartmentConfig{Enabled: true}, WithPersistence(store))
+	require.NoError(t, err)
+	require.NoError(t, m.Start(ctx))
+
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+	<-store.saving
+
+	// The manager keeps working while the store is stuck
+	_, err = m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	_, err = m.GetTask("task-1")
+	require.NoError(t, err)
+
+	// Stop waits for the writes, and the latest state wins
+	close(store.release)
+	require.NoError(t, m.Stop())
+	data, err := store.Load(stateKey)
+	require.NoError(t, err)
+	restored := newTestManager(t)
+	require.NoError(t, restored.Restore(ctx, data))
+	task, err := restored.GetTask("task-1")
+	require.NoError(t, err)
+	require.Equal(t, "dev-1", task.AssignedMember)
+}
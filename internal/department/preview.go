@@ -0,0 +1,89 @@
+package department
+
+import (
+	"fmt"
+	"slices"
+)
+
+// RoutePreview is where the router would place a task if it were routed now
+type RoutePreview struct {
+	DepartmentID     string          `json:"department_id"`
+	DepartmentReason string          `json:"department_reason"`
+	MemberID         string          `json:"member_id"`
+	MemberReason     string          `json:"member_reason"`
+	Strategy         RoutingStrategy `json:"strategy"`
+	// Candidates are the suitable members with the score the strategy gives
+	// them, highest first. Round-robin does not score candidates.
+	Candidates []RoutingCandidate `json:"candidates,omitempty"`
+}
+
+// PreviewRoute works out the department and member a task would be routed
+// to without assigning it. Neither the task nor the router's rotation and
+// skill pick history change, so a preview does not affect later routing.
+// Teams, reservations, cold starts, preemption and fallback routing are not
+// previewed; a task no member can take now is an error.
+func (tr *TaskRouter) PreviewRoute(task *Task) (*RoutePreview, error) {
+	tr.manager.mu.RLock()
+	defer tr.manager.mu.RUnlock()
+
+	return tr.previewRoute(task)
+}
+
+// PreviewRoute works out where a task would be routed without assigning it
+func (m *Manager) PreviewRoute(task *Task) (*RoutePreview, error) {
+	return m.taskRouter.PreviewRoute(task)
+}
+
+// previewRoute previews the routing of a copy of task. The caller must hold
+// the manager lock; a read lock will do.
+func (tr *TaskRouter) previewRoute(task *Task) (*RoutePreview, error) {
+	// Baseline skills are merged into the copy only
+	preview := *task
+	preview.RequiredSkills = slices.Clone(task.RequiredSkills)
+
+	result := &RoutePreview{
+		DepartmentID:     preview.DepartmentID,
+		DepartmentReason: "it was specified on the task",
+		Strategy:         tr.strategy(),
+	}
+	if preview.DepartmentID == "" {
+		deptID, reason, err := tr.determineDepartment(&preview)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine department: %w", err)
+		}
+		preview.DepartmentID = deptID
+		result.DepartmentID, result.DepartmentReason = deptID, reason
+	}
+	tr.applyBaselineSkills(&preview)
+
+	candidates, err := tr.findSuitableMembers(&preview, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find suitable members: %w", err)
+	}
+	primary := preview.DepartmentID
+	if len(candidates) == 0 {
+		candidates = tr.crossDepartmentMembers(&preview, nil)
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("no suitable members found in department %s", primary)
+		}
+	}
+	result.Candidates = tr.scoreCandidates(&preview, candidates)
+
+	// Affinity only applies within the task's department
+	member := tr.affinityMember(&preview, candidates)
+	if member != nil && member.DepartmentID == primary {
+		result.MemberReason = fmt.Sprintf("it handled the last task with affinity key %q", preview.AffinityKey)
+	} else {
+		member, err = tr.chooseMember(&preview, candidates, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select member: %w", err)
+		}
+		result.MemberReason = tr.selectionReason(&preview, member)
+	}
+	result.MemberID = member.ID
+	if member.DepartmentID != primary {
+		result.DepartmentID = member.DepartmentID
+		result.DepartmentReason = crossDepartmentReason(primary, result.DepartmentReason, member.DepartmentID)
+	}
+	return result, nil
+}
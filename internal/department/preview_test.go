@@ -0,0 +1,77 @@
+package department
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewRouteDoesNotAssign(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled: true,
+		TaskRouting: TaskRoutingConfig{
+			Strategy: RoutingRoundRobin,
+			DepartmentRules: map[string][]string{
+				"dept-devops": {"deploy"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	registerTestMember(t, m, "ops-1", "dept-devops", RoleDevOps, 2)
+	registerTestMember(t, m, "ops-2", "dept-devops", RoleDevOps, 2)
+
+	task := &Task{Title: "Deploy the API", RequiredSkills: []string{}}
+	preview, err := m.PreviewRoute(task)
+	require.NoError(t, err)
+	require.Equal(t, "dept-devops", preview.DepartmentID)
+	require.Contains(t, preview.DepartmentReason, `"deploy"`)
+	require.Equal(t, RoutingRoundRobin, preview.Strategy)
+	require.Equal(t, "ops-1", preview.MemberID)
+	require.Equal(t, "it was next in the department's rotation", preview.MemberReason)
+
+	// Previewing again gives the same answer: the rotation did not move
+	again, err := m.PreviewRoute(task)
+	require.NoError(t, err)
+	require.Equal(t, preview, again)
+
+	require.Empty(t, task.DepartmentID)
+	require.Empty(t, task.AssignedMember)
+	require.Empty(t, task.RequiredSkills)
+	for _, id := range []string{"ops-1", "ops-2"} {
+		member, err := m.GetMember(id)
+		require.NoError(t, err)
+		require.Empty(t, member.CurrentTasks)
+	}
+
+	// Routing for real follows the preview
+	created, err := m.CreateTask(ctx, &Task{ID: "task-1", Title: "Deploy the API"})
+	require.NoError(t, err)
+	require.Equal(t, preview.MemberID, created.AssignedMember)
+}
+
+func TestPreviewRouteRanksCandidates(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewManager(t.Context(), &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{Strategy: RoutingSkillBased},
+	})
+	require.NoError(t, err)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+	registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 4)
+
+	preview, err := m.PreviewRoute(&Task{DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, "it was specified on the task", preview.DepartmentReason)
+	require.Equal(t, "dev-2", preview.MemberID)
+	require.Len(t, preview.Candidates, 2)
+	require.Equal(t, "dev-2", preview.Candidates[0].MemberID)
+	require.Greater(t, preview.Candidates[0].Score, preview.Candidates[1].Score)
+	require.Empty(t, m.taskRouter.skillPicks)
+
+	_, err = m.PreviewRoute(&Task{DepartmentID: "dept-qa"})
+	require.ErrorContains(t, err, "no members in department dept-qa")
+}
@@ -0,0 +1,162 @@
+package department
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"time"
+)
+
+// HealthCheckType selects how a member's health is probed
+type HealthCheckType string
+
+const (
+	// HealthCheckHTTP requests the member's /health endpoint (the default)
+	HealthCheckHTTP HealthCheckType = "http"
+	// HealthCheckTCP dials the member's endpoint
+	HealthCheckTCP HealthCheckType = "tcp"
+	// HealthCheckExec runs the health command configured for the member's role
+	// and checks its exit code
+	HealthCheckExec HealthCheckType = "exec"
+)
+
+// defaultProbeTimeout bounds TCP and exec probes when no check timeout is
+// configured
+const defaultProbeTimeout = 5 * time.Second
+
+// Prober performs a single health probe of one kind
+type Prober interface {
+	// Probe returns nil if the member is healthy
+	Probe(ctx context.Context, member *Member) error
+}
+
+// probeTimeout is the per-probe timeout for probers without an HTTP client
+func probeTimeout(config HealthCheckConfig) time.Duration {
+	if config.Timeout > 0 {
+		return config.Timeout
+	}
+	return defaultProbeTimeout
+}
+
+// httpProber requests a member's /health endpoint and applies the health
+// criteria to the metrics it reports. The timeout is the HTTP client's.
+type httpProber struct {
+	checker *HealthChecker
+}
+
+func (p *httpProber) Probe(ctx context.Context, member *Member) error {
+	// Create health check URL
+	healthURL := fmt.Sprintf("%s/health", member.Endpoint)
+
+	// Create request
+	req, err := http.NewRequestWithContext(ctx, "GET", healthURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Add authentication headers if needed. Members registered without a
+	// token are probed without credentials.
+	if member.AuthMethod != "" && member.AuthToken == "" {
+		slog.Debug("Member has no auth token, probing without credentials", "member_id", member.ID)
+	} else if member.AuthMethod != "" {
+		switch member.AuthMethod {
+		case "bearer":
+			req.Header.Set("Authorization", "Bearer "+member.AuthToken)
+		case "api-key":
+			req.Header.Set("X-API-Key", member.AuthToken)
+		}
+		slog.Debug("Sending authenticated health probe",
+			"member_id", member.ID,
+			"auth_method", member.AuthMethod,
+			"token", redactToken(member.AuthToken))
+	}
+
+	// Perform the request
+	resp, err := p.checker.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() {
+		// Drain the body so the connection can be reused
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	// Check response status
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	// Parse response body
+	var healthResp struct {
+		Status  string                 `json:"status"`
+		Metrics map[string]interface{} `json:"metrics,omitempty"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&healthResp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// Check if member reports as healthy
+	if healthResp.Status != "healthy" && healthResp.Status != "ok" {
+		return fmt.Errorf("member reports status: %s", healthResp.Status)
+	}
+
+	// Apply role-specific health checks
+	if !p.checker.checkRoleSpecificHealth(member, healthResp.Metrics) {
+		return fmt.Errorf("role-specific health check failed")
+	}
+
+	return nil
+}
+
+// tcpProber considers a member healthy if its endpoint accepts connections
+type tcpProber struct {
+	timeout time.Duration
+}
+
+func (p *tcpProber) Probe(ctx context.Context, member *Member) error {
+	address := member.Endpoint
+	if u, err := url.Parse(member.Endpoint); err == nil && u.Host != "" {
+		address = u.Host
+	}
+
+	dialer := net.Dialer{Timeout: p.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("dial failed: %w", err)
+	}
+	return conn.Close()
+}
+
+// execProber runs the health command configured for a member's role and
+// considers the member healthy if it exits with status zero
+type execProber struct {
+	timeout  time.Duration
+	commands map[string][]string
+}
+
+func (p *execProber) Probe(ctx context.Context, member *Member) error {
+	command := p.commands[string(member.Role)]
+	if len(command) == 0 {
+		return fmt.Errorf("no health command configured for role %s", member.Role)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("health command timed out after %s: %w", p.timeout, ctx.Err())
+		}
+		return fmt.Errorf("health command failed: %w", err)
+	}
+	return nil
+}
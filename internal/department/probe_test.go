@@ -0,0 +1,106 @@
+package department
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCheckerHTTPProbe(t *testing.T) {
+	t.Parallel()
+
+	paths := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+		_, _ = w.Write([]byte(`{"status":"healthy"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	h := NewHealthChecker(HealthCheckConfig{Timeout: time.Second}, nil)
+	for _, checkType := range []HealthCheckType{"", HealthCheckHTTP} {
+		healthy, _, err := h.probeMember(t.Context(), &Member{ID: "dev-1", Endpoint: server.URL, HealthCheckType: checkType})
+		require.NoError(t, err)
+		require.True(t, healthy)
+		require.Equal(t, "/health", <-paths)
+	}
+
+	_, _, err := h.probeMember(t.Context(), &Member{ID: "dev-1", HealthCheckType: "carrier-pigeon"})
+	require.ErrorContains(t, err, `unknown health check type "carrier-pigeon"`)
+}
+
+func TestHealthCheckerTCPProbe(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	h := NewHealthChecker(HealthCheckConfig{Timeout: time.Second}, nil)
+	member := &Member{ID: "cli-1", Endpoint: listener.Addr().String(), HealthCheckType: HealthCheckTCP}
+
+	healthy, _, err := h.probeMember(t.Context(), member)
+	require.NoError(t, err)
+	require.True(t, healthy)
+
+	// URL endpoints are dialed by host
+	member.Endpoint = "tcp://" + listener.Addr().String()
+	healthy, _, err = h.probeMember(t.Context(), member)
+	require.NoError(t, err)
+	require.True(t, healthy)
+
+	require.NoError(t, listener.Close())
+	healthy, _, err = h.probeMember(t.Context(), member)
+	require.ErrorContains(t, err, "dial failed")
+	require.False(t, healthy)
+}
+
+func TestHealthCheckerExecProbe(t *testing.T) {
+	t.Parallel()
+
+	h := NewHealthChecker(HealthCheckConfig{
+		Timeout: time.Second,
+		Commands: map[string][]string{
+			string(RoleDeveloper): {"sh", "-c", "exit 0"},
+			string(RoleQA):        {"sh", "-c", "exit 3"},
+		},
+	}, nil)
+
+	_, _, err := h.probeMember(t.Context(), &Member{ID: "ops-1", Role: RoleDevOps, HealthCheckType: HealthCheckExec})
+	require.ErrorContains(t, err, "no health command configured for role devops")
+
+	healthy, _, err := h.probeMember(t.Context(), &Member{ID: "dev-1", Role: RoleDeveloper, HealthCheckType: HealthCheckExec})
+	require.NoError(t, err)
+	require.True(t, healthy)
+
+	healthy, _, err = h.probeMember(t.Context(), &Member{ID: "qa-1", Role: RoleQA, HealthCheckType: HealthCheckExec})
+	require.ErrorContains(t, err, "exit status 3")
+	require.False(t, healthy)
+}
+
+func TestHealthCheckerExecProbeTimeout(t *testing.T) {
+	t.Parallel()
+
+	h := NewHealthChecker(HealthCheckConfig{
+		Timeout:  50 * time.Millisecond,
+		Commands: map[string][]string{string(RoleDeveloper): {"sleep", "5"}},
+	}, nil)
+	member := &Member{ID: "dev-1", Role: RoleDeveloper, HealthCheckType: HealthCheckExec}
+
+	start := time.Now()
+	healthy, _, err := h.probeMember(t.Context(), member)
+	require.ErrorContains(t, err, "timed out")
+	require.False(t, healthy)
+	require.Less(t, time.Since(start), 2*time.Second)
+}
@@ -0,0 +1,63 @@
+package department
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+)
+
+// TaskProgressEvent is published on the task progress stream for every
+// progress update a member reports
+const TaskProgressEvent pubsub.EventType = "task_progress"
+
+// TaskProgress is an incremental update on a running task
+type TaskProgress struct {
+	TaskID   string `json:"task_id"`
+	MemberID string `json:"member_id,omitempty"`
+	// Percent is how much of the task is done, from 0 to 100. Zero leaves
+	// the task's last reported percentage unchanged.
+	Percent float64 `json:"percent,omitempty"`
+	// Content and Reasoning are the chunks of output and thinking produced
+	// since the previous update
+	Content    string    `json:"content,omitempty"`
+	Reasoning  string    `json:"reasoning,omitempty"`
+	ReportedAt time.Time `json:"reported_at"`
+}
+
+// ReportTaskProgress publishes a progress update for an assigned or
+// in-progress task. A reported percentage is also kept on the task.
+func (m *Manager) ReportTaskProgress(taskID string, update TaskProgress) error {
+	if update.Percent < 0 || update.Percent > 100 {
+		return fmt.Errorf("progress must be between 0 and 100, got %v", update.Percent)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	task, exists := m.tasks[taskID]
+	if !exists {
+		return fmt.Errorf("task %s does not exist", taskID)
+	}
+	if task.Status != TaskStatusAssigned && task.Status != TaskStatusInProgress {
+		return fmt.Errorf("task %s is %s, not running", taskID, task.Status)
+	}
+
+	update.TaskID = taskID
+	update.MemberID = task.AssignedMember
+	update.ReportedAt = time.Now()
+	m.touchMember(task.AssignedMember, update.ReportedAt)
+	if update.Percent > 0 {
+		task.Progress = update.Percent
+	}
+
+	m.progressEvents.Publish(TaskProgressEvent, &update)
+	return nil
+}
+
+// SubscribeToTaskProgress returns a channel carrying every progress update
+// reported for any task. After Stop the channel is returned already closed.
+func (m *Manager) SubscribeToTaskProgress(ctx context.Context) <-chan pubsub.Event[*TaskProgress] {
+	return m.progressEvents.Subscribe(ctx)
+}
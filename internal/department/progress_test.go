@@ -0,0 +1,48 @@
+package department
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerReportTaskProgress(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+	updates := m.SubscribeToTaskProgress(t.Context())
+
+	task, err := m.CreateTask(ctx, &Task{ID: "long", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+
+	require.NoError(t, m.ReportTaskProgress(task.ID, TaskProgress{Percent: 40, Content: "half"}))
+	require.NoError(t, m.ReportTaskProgress(task.ID, TaskProgress{Content: "way"}))
+
+	for _, want := range []string{"half", "way"} {
+		select {
+		case event := <-updates:
+			require.Equal(t, TaskProgressEvent, event.Type)
+			require.Equal(t, want, event.Payload.Content)
+			require.Equal(t, "long", event.Payload.TaskID)
+			require.Equal(t, "dev-1", event.Payload.MemberID)
+		case <-time.After(time.Second):
+			t.Fatalf("no progress event for %q", want)
+		}
+	}
+
+	// The last percentage is kept when an update reports none
+	task, err = m.GetTask(task.ID)
+	require.NoError(t, err)
+	require.Equal(t, 40.0, task.Progress)
+
+	require.ErrorContains(t, m.ReportTaskProgress(task.ID, TaskProgress{Percent: 120}), "between 0 and 100")
+	require.ErrorContains(t, m.ReportTaskProgress("missing", TaskProgress{}), "does not exist")
+
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusCompleted, nil))
+	require.ErrorContains(t, m.ReportTaskProgress(task.ID, TaskProgress{Content: "late"}), "not running")
+}
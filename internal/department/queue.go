@@ -0,0 +1,455 @@
+package department
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+)
+
+// QueueWaitExceededEvent is published on the task event stream when a queued
+// task has waited longer than the max queue wait for its priority
+const QueueWaitExceededEvent pubsub.EventType = "queue_wait_exceeded"
+
+// minQueueWaitCheckInterval bounds how often queued tasks are checked
+const minQueueWaitCheckInterval = 100 * time.Millisecond
+
+// maxQueueWaitSamples bounds how many recent queue waits are kept per
+// department for percentile calculations
+const maxQueueWaitSamples = 100
+
+// queueWaitMonitor periodically flags tasks that have been queued too long
+func (m *Manager) queueWaitMonitor(ctx context.Context) {
+	ticker := time.NewTicker(m.queueWaitCheckInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.checkQueueWaits(time.Now())
+		}
+	}
+}
+
+// queueWaitCheckInterval checks twice per shortest threshold so a task is
+// flagged at most half a threshold late
+func (m *Manager) queueWaitCheckInterval() time.Duration {
+	var interval time.Duration
+	for _, wait := range m.config.TaskRouting.MaxQueueWait {
+		if interval == 0 || wait/2 < interval {
+			interval = wait / 2
+		}
+	}
+	return max(interval, minQueueWaitCheckInterval)
+}
+
+// checkQueueWaits publishes a QueueWaitExceededEvent for every queued task
+// that has exceeded its priority's threshold, once per task, and optionally
+// asks the auto-scaler for more capacity in the affected departments
+func (m *Manager) checkQueueWaits(now time.Time) {
+	m.mu.Lock()
+
+	var exceeded []*Task
+	for _, queued := range m.queued {
+		for id, task := range queued {
+			limit, ok := m.config.TaskRouting.MaxQueueWait[task.Priority]
+			if !ok || m.queueWaitAlerts[id] || now.Sub(*task.QueuedAt) <= limit {
+				continue
+			}
+			m.queueWaitAlerts[id] = true
+			exceeded = append(exceeded, task)
+
+			slog.Warn("Task exceeded max queue wait",
+				"task_id", id,
+				"priority", string(task.Priority),
+				"department", task.DepartmentID,
+				"waited", now.Sub(*task.QueuedAt),
+				"max_wait", limit)
+		}
+	}
+
+	departments := make(map[string]*Department)
+	for _, task := range exceeded {
+		m.taskEvents.Publish(QueueWaitExceededEvent, task)
+		if dept, exists := m.departments[task.DepartmentID]; exists {
+			departments[dept.ID] = dept
+		}
+	}
+
+	m.mu.Unlock()
+
+	// Scaling registers members, so it must run without the manager lock
+	if !m.config.TaskRouting.ScaleOnQueueWait || m.scaler == nil {
+		return
+	}
+	for _, dept := range departments {
+		m.scaler.RequestScaleUp(dept, "queue_wait_exceeded")
+	}
+}
+
+// ackTimeoutMonitor periodically reclaims tasks whose members never started
+// them
+func (m *Manager) ackTimeoutMonitor(ctx context.Context) {
+	ticker := time.NewTicker(max(m.config.TaskRouting.AckTimeout/2, minQueueWaitCheckInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.reclaimUnacknowledgedTasks(ctx, time.Now())
+		}
+	}
+}
+
+// ackTimeoutReason is the reassignment reason of tasks reclaimed from members
+// that did not acknowledge them in time
+const ackTimeoutReason = "ack_timeout"
+
+// reclaimUnacknowledgedTasks requeues tasks that have been assigned for longer
+// than the ack timeout without moving to in_progress, flags the member that
+// held them and routes them to someone else
+func (m *Manager) reclaimUnacknowledgedTasks(ctx context.Context, now time.Time) {
+	timeout := m.config.TaskRouting.AckTimeout
+	if timeout <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reclaimed := 0
+	for id, task := range m.tasks {
+		if task.Status != TaskStatusAssigned || task.AssignedAt == nil || now.Sub(*task.AssignedAt) <= timeout {
+			continue
+		}
+
+		memberID := task.AssignedMember
+		slog.Warn("Reclaiming unacknowledged task",
+			"task_id", id,
+			"member_id", memberID,
+			"assigned_for", now.Sub(*task.AssignedAt))
+
+		if err := m.taskRouter.reassignTask(ctx, task, ackTimeoutReason, map[string]bool{memberID: true}); err != nil {
+			slog.Warn("Failed to reroute reclaimed task", "task_id", id, "error", err)
+		}
+		if stats, exists := m.memberStats[memberID]; exists {
+			stats.MissedAcks++
+			stats.LastUpdated = now
+		}
+		if member, exists := m.members[memberID]; exists {
+			m.memberEvents.Publish(pubsub.UpdatedEvent, member)
+		}
+		m.taskEvents.Publish(pubsub.UpdatedEvent, task)
+		reclaimed++
+	}
+
+	if reclaimed > 0 {
+		m.persist()
+	}
+}
+
+// QueueBacklog returns how many tasks are queued in a department and how long
+// the oldest of them has waited
+func (m *Manager) QueueBacklog(departmentID string, now time.Time) (int, time.Duration) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var oldest time.Duration
+	for _, task := range m.queued[departmentID] {
+		oldest = max(oldest, now.Sub(*task.QueuedAt))
+	}
+	return len(m.queued[departmentID]), oldest
+}
+
+// checkQueueCapacity returns ErrQueueFull when the department already holds
+// as many queued tasks as its limit allows. The caller must hold the manager
+// lock.
+func (m *Manager) checkQueueCapacity(dept *Department) error {
+	limit := dept.MaxQueuedTasks
+	if limit == 0 {
+		limit = m.config.MaxQueuedTasks
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	queued := len(m.queued[dept.ID])
+	if queued >= limit {
+		return fmt.Errorf("department %s has %d queued tasks: %w", dept.ID, queued, ErrQueueFull)
+	}
+	return nil
+}
+
+// recordQueueWait remembers how long a queued task waited since it entered
+// the queue before being assigned. The caller must hold the manager lock.
+func (m *Manager) recordQueueWait(task *Task, now time.Time) {
+	samples := append(m.queueWaits[task.DepartmentID], now.Sub(*task.QueuedAt))
+	if len(samples) > maxQueueWaitSamples {
+		samples = samples[len(samples)-maxQueueWaitSamples:]
+	}
+	m.queueWaits[task.DepartmentID] = samples
+}
+
+// QueueWaitPercentile returns the given percentile (0-100) of queue wait in a
+// department. It covers recently assigned tasks as well as the time tasks
+// still in the queue have waited so far, so a stuck queue shows up before
+// anything gets assigned.
+func (m *Manager) QueueWaitPercentile(departmentID string, pct float64, now time.Time) time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	waits := slices.Clone(m.queueWaits[departmentID])
+	for _, task := range m.queued[departmentID] {
+		waits = append(waits, now.Sub(*task.QueuedAt))
+	}
+	slices.Sort(waits)
+	return percentile(waits, pct)
+}
+
+// percentile returns the nearest-rank percentile (0-100) of sorted waits, or
+// zero without any
+func percentile(waits []time.Duration, pct float64) time.Duration {
+	if len(waits) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(pct / 100 * float64(len(waits))))
+	return waits[min(max(rank, 1), len(waits))-1]
+}
+
+// routingRetryMonitor periodically routes queued tasks again in case capacity
+// freed up without a routing attempt
+func (m *Manager) routingRetryMonitor(ctx context.Context) {
+	ticker := time.NewTicker(m.config.TaskRouting.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.retryQueuedTasks(ctx)
+		}
+	}
+}
+
+// retryQueuedTasks routes every queued task again, most urgent and oldest
+// first. Blocked workflow steps are left alone; their workflow releases them
+// once their dependencies finish.
+func (m *Manager) retryQueuedTasks(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	maxRetries := m.config.TaskRouting.MaxRetries
+
+	var queued []*Task
+	for id, task := range m.tasks {
+		if task.Status != TaskStatusQueued {
+			delete(m.routingRetries, id)
+			continue
+		}
+		if maxRetries > 0 && m.routingRetries[id] >= maxRetries {
+			continue
+		}
+		queued = append(queued, task)
+	}
+	m.sortByUrgency(queued)
+
+	routed := m.routeQueuedTasks(ctx, queued, func(task *Task, err error) {
+		m.routingRetries[task.ID]++
+		if maxRetries > 0 && m.routingRetries[task.ID] == maxRetries {
+			slog.Warn("Giving up on routing queued task",
+				"task_id", task.ID,
+				"retries", maxRetries,
+				"error", err)
+		}
+	})
+	if routed > 0 {
+		slog.Info("Routed queued tasks on retry", "tasks", routed)
+		m.persist()
+	}
+}
+
+// rerouteQueuedTasks routes the queued tasks of a department again, for
+// example after a member gained capabilities, and returns how many were
+// assigned. The caller must hold the manager lock.
+func (m *Manager) rerouteQueuedTasks(ctx context.Context, departmentID string) int {
+	return m.routeQueuedTasks(ctx, m.queuedTasks(departmentID), nil)
+}
+
+// routeQueuedTasks routes queued tasks in the given order and returns how
+// many were assigned. Tasks that stay queued are passed to unrouted, if set,
+// along with the routing error. The caller must hold the manager lock.
+func (m *Manager) routeQueuedTasks(ctx context.Context, queued []*Task, unrouted func(*Task, error)) int {
+	routed := 0
+	for _, task := range queued {
+		if err := m.taskRouter.routeTask(ctx, task); err != nil || task.Status == TaskStatusQueued {
+			if unrouted != nil {
+				unrouted(task, err)
+			}
+			continue
+		}
+		delete(m.routingRetries, task.ID)
+		m.taskEvents.Publish(pubsub.UpdatedEvent, task)
+		routed++
+	}
+	return routed
+}
+
+// sortByUrgency orders tasks by priority, counting inherited priority, most
+// urgent first, then by age. Escalated overdue tasks go before all others.
+func (m *Manager) sortByUrgency(tasks []*Task) {
+	slices.SortFunc(tasks, func(a, b *Task) int {
+		if ea, eb := m.escalated(a), m.escalated(b); ea != eb {
+			if ea {
+				return -1
+			}
+			return 1
+		}
+		if ra, rb := effectivePriority(a).Rank(), effectivePriority(b).Rank(); ra != rb {
+			return rb - ra
+		}
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+}
+
+// fillFreedCapacity hands the most urgent queued tasks of a member's
+// department to the member after one of its tasks finished, until its freed
+// capacity is used up, and returns how many were assigned. Only the
+// department's queue index is drained. Blocked tasks are not considered: they
+// are queued by resolveDependencies or advanceWorkflow once their
+// dependencies finish, which runs first. The caller must hold the manager
+// lock.
+func (m *Manager) fillFreedCapacity(memberID string) int {
+	member, exists := m.members[memberID]
+	if !exists || m.taskRouter == nil || !isAvailable(member) || m.remainingUnits(member) < defaultTaskWeight {
+		return 0
+	}
+	if _, migrating := m.pendingMigrations[memberID]; migrating {
+		return 0
+	}
+
+	routed := 0
+	for _, task := range m.queuedTasks(member.DepartmentID) {
+		if m.remainingUnits(member) < defaultTaskWeight {
+			break
+		}
+		if m.reservedForOther(task.DepartmentID, task) || !m.taskRouter.isMemberSuitable(member, task) {
+			continue
+		}
+		if err := m.taskRouter.assignTaskToMember(task, member); err != nil {
+			slog.Warn("Failed to assign queued task to freed member", "task_id", task.ID, "member_id", memberID, "error", err)
+			continue
+		}
+		decision := &RoutingDecision{
+			Strategy:         m.taskRouter.strategy(),
+			DepartmentReason: "it was specified on the task",
+			MemberReason:     "it freed capacity and this was the most urgent queued task it could take",
+			DecidedAt:        time.Now(),
+		}
+		if task.RoutingDecision != nil && task.RoutingDecision.DepartmentID == task.DepartmentID {
+			decision.DepartmentReason = task.RoutingDecision.DepartmentReason
+		}
+		m.taskRouter.recordDecision(task, decision)
+		delete(m.routingRetries, task.ID)
+		m.taskEvents.Publish(pubsub.UpdatedEvent, task)
+		routed++
+	}
+	if routed > 0 {
+		m.memberEvents.Publish(pubsub.UpdatedEvent, member)
+	}
+	return routed
+}
+
+// indexTask keeps the per-department task and queue indexes and the task's
+// QueuedAt in step with a task. It must be called whenever a task is stored,
+// changes status or moves to another department, after its UpdatedAt is set.
+// The caller must hold the manager lock.
+func (m *Manager) indexTask(task *Task) {
+	if deptID, indexed := m.taskIn[task.ID]; indexed && deptID != task.DepartmentID {
+		removeIndexed(m.departmentTasks, deptID, task.ID)
+	}
+	addIndexed(m.departmentTasks, task)
+	m.taskIn[task.ID] = task.DepartmentID
+
+	if deptID, indexed := m.queuedIn[task.ID]; indexed {
+		if task.Status == TaskStatusQueued && deptID == task.DepartmentID {
+			m.queued[deptID][task.ID] = task
+			return
+		}
+		removeIndexed(m.queued, deptID, task.ID)
+		delete(m.queuedIn, task.ID)
+	}
+
+	if task.Status != TaskStatusQueued {
+		task.QueuedAt = nil
+		delete(m.queueWaitAlerts, task.ID)
+		return
+	}
+	// Moving to another department keeps the time it was queued
+	if task.QueuedAt == nil {
+		queuedAt := task.UpdatedAt
+		task.QueuedAt = &queuedAt
+	}
+	addIndexed(m.queued, task)
+	m.queuedIn[task.ID] = task.DepartmentID
+}
+
+// unindexTask drops a task that was removed from m.tasks from the indexes.
+// The caller must hold the manager lock.
+func (m *Manager) unindexTask(task *Task) {
+	if deptID, indexed := m.taskIn[task.ID]; indexed {
+		removeIndexed(m.departmentTasks, deptID, task.ID)
+		delete(m.taskIn, task.ID)
+	}
+	if deptID, indexed := m.queuedIn[task.ID]; indexed {
+		removeIndexed(m.queued, deptID, task.ID)
+		delete(m.queuedIn, task.ID)
+	}
+	delete(m.queueWaitAlerts, task.ID)
+}
+
+// addIndexed adds a task to a per-department index
+func addIndexed(index map[string]map[string]*Task, task *Task) {
+	if index[task.DepartmentID] == nil {
+		index[task.DepartmentID] = make(map[string]*Task)
+	}
+	index[task.DepartmentID][task.ID] = task
+}
+
+// removeIndexed removes a task from a per-department index
+func removeIndexed(index map[string]map[string]*Task, deptID, taskID string) {
+	delete(index[deptID], taskID)
+	if len(index[deptID]) == 0 {
+		delete(index, deptID)
+	}
+}
+
+// rebuildTaskIndexes indexes the tasks restored from persisted state
+func (m *Manager) rebuildTaskIndexes() {
+	m.departmentTasks = make(map[string]map[string]*Task)
+	m.taskIn = make(map[string]string)
+	m.queued = make(map[string]map[string]*Task)
+	m.queuedIn = make(map[string]string)
+	for _, task := range m.tasks {
+		m.indexTask(task)
+	}
+}
+
+// queuedTasks returns the queued tasks of a department, most urgent first.
+// The caller must hold the manager lock.
+func (m *Manager) queuedTasks(departmentID string) []*Task {
+	queued := make([]*Task, 0, len(m.queued[departmentID]))
+	for _, task := range m.queued[departmentID] {
+		queued = append(queued, task)
+	}
+	m.sortByUrgency(queued)
+	return queued
+}
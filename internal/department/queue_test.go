@@ -0,0 +1,390 @@
+package department
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerQueueWaitExceeded(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled: true,
+		AutoScaling: AutoScalingConfig{
+			Enabled:           true,
+			CheckInterval:     time.Hour,
+			MaxMembersPerDept: 10,
+			RoleScaling:       map[string]int{"developer": 5},
+		},
+		TaskRouting: TaskRoutingConfig{
+			MaxQueueWait: map[Priority]time.Duration{
+				PriorityCritical: 2 * time.Second,
+				PriorityLow:      time.Hour,
+			},
+			ScaleOnQueueWait: true,
+		},
+	})
+	require.NoError(t, err)
+
+	events := m.SubscribeToTaskEvents(ctx)
+
+	// Nobody is registered in the department, so both tasks stay queued
+	critical, err := m.CreateTask(ctx, &Task{ID: "critical", DepartmentID: "dept-dev", Priority: PriorityCritical})
+	require.NoError(t, err)
+	low, err := m.CreateTask(ctx, &Task{ID: "low", DepartmentID: "dept-dev", Priority: PriorityLow})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, critical.Status)
+	require.Equal(t, TaskStatusQueued, low.Status)
+	for range 2 {
+		<-events
+	}
+
+	now := time.Now()
+	m.checkQueueWaits(now.Add(time.Second))
+	require.Empty(t, m.ListMembers("dept-dev"))
+
+	m.checkQueueWaits(now.Add(5 * time.Second))
+
+	select {
+	case event := <-events:
+		require.Equal(t, QueueWaitExceededEvent, event.Type)
+		require.Equal(t, critical.ID, event.Payload.ID)
+	case <-time.After(time.Second):
+		t.Fatal("expected a queue wait exceeded event")
+	}
+
+	members := m.ListMembers("dept-dev")
+	require.Len(t, members, 1)
+	require.Equal(t, "queue_wait_exceeded", members[0].Metadata["scaling_reason"])
+
+	// Each task is only flagged once while it stays queued
+	m.checkQueueWaits(now.Add(10 * time.Second))
+	select {
+	case event := <-events:
+		require.NotEqual(t, QueueWaitExceededEvent, event.Type)
+	default:
+	}
+	require.Len(t, m.ListMembers("dept-dev"), 1)
+}
+
+func TestManagerQueueWaitCheckInterval(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+	m.config.TaskRouting.MaxQueueWait = map[Priority]time.Duration{
+		PriorityCritical: 4 * time.Second,
+		PriorityHigh:     time.Minute,
+	}
+	require.Equal(t, 2*time.Second, m.queueWaitCheckInterval())
+
+	m.config.TaskRouting.MaxQueueWait[PriorityCritical] = time.Millisecond
+	require.Equal(t, minQueueWaitCheckInterval, m.queueWaitCheckInterval())
+}
+
+func TestManagerReclaimsUnacknowledgedTasks(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{AckTimeout: time.Minute},
+	})
+	require.NoError(t, err)
+
+	stuck := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, stuck.ID, task.AssignedMember)
+	require.Equal(t, MemberStatusBusy, stuck.Status)
+
+	healthy := registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 1)
+	started, err := m.CreateTask(ctx, &Task{ID: "task-2", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, healthy.ID, started.AssignedMember)
+	require.NoError(t, m.UpdateTaskStatus(ctx, started.ID, TaskStatusInProgress, nil))
+	require.NoError(t, m.UpdateTaskStatus(ctx, started.ID, TaskStatusCompleted, nil))
+
+	assignedAt := *task.AssignedAt
+
+	// Within the timeout nothing changes
+	m.reclaimUnacknowledgedTasks(ctx, assignedAt.Add(30*time.Second))
+	require.Equal(t, stuck.ID, task.AssignedMember)
+
+	// Past it the task moves to the other member and dev-1 is flagged
+	m.reclaimUnacknowledgedTasks(ctx, assignedAt.Add(2*time.Minute))
+	require.Equal(t, healthy.ID, task.AssignedMember)
+	require.Equal(t, TaskStatusAssigned, task.Status)
+	require.Empty(t, stuck.CurrentTasks)
+	require.Equal(t, MemberStatusOnline, stuck.Status)
+
+	stats, err := m.GetMemberStats(stuck.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, stats.MissedAcks)
+
+	// In-progress tasks are never reclaimed
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+	m.reclaimUnacknowledgedTasks(ctx, assignedAt.Add(time.Hour))
+	require.Equal(t, healthy.ID, task.AssignedMember)
+}
+
+func TestManagerRetriesQueuedTasks(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{RetryInterval: 10 * time.Millisecond},
+	})
+	require.NoError(t, err)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+
+	first, err := m.CreateTask(ctx, &Task{ID: "task-1", Title: "first", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	second, err := m.CreateTask(ctx, &Task{ID: "task-2", Title: "second", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, second.Status)
+
+	require.NoError(t, m.Start(ctx))
+	t.Cleanup(func() { _ = m.Stop() })
+
+	// The member frees up without going through the manager, so nothing
+	// triggers a routing attempt but the retry loop
+	m.mu.Lock()
+	m.releaseTask("dev-1", first.ID)
+	first.Status = TaskStatusCompleted
+	m.mu.Unlock()
+
+	require.Eventually(t, func() bool {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return second.AssignedMember == "dev-1"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestManagerRetriesQueuedTasksUpToMax(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{RetryInterval: time.Hour, MaxRetries: 2},
+	})
+	require.NoError(t, err)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+
+	_, err = m.CreateTask(ctx, &Task{ID: "task-1", Title: "first", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	_, err = m.CreateTask(ctx, &Task{ID: "task-2", Title: "second", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+
+	for range 4 {
+		m.retryQueuedTasks(ctx)
+	}
+	require.Equal(t, 2, m.routingRetries["task-2"])
+}
+
+func TestManagerMaxQueuedTasks(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m, err := NewManager(ctx, &DepartmentConfig{Enabled: true, MaxQueuedTasks: 2})
+	require.NoError(t, err)
+
+	// No members, so every task stays queued
+	for range 2 {
+		_, err := m.CreateTask(ctx, &Task{DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+	}
+	_, err = m.CreateTask(ctx, &Task{DepartmentID: "dept-dev"})
+	require.ErrorIs(t, err, ErrQueueFull)
+	require.ErrorContains(t, err, "department dept-dev has 2 queued tasks")
+
+	// The limit is per department
+	_, err = m.CreateTask(ctx, &Task{DepartmentID: "dept-qa"})
+	require.NoError(t, err)
+
+	// A department's own limit overrides the global one
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	dept.MaxQueuedTasks = 3
+	require.NoError(t, m.UpdateDepartment(ctx, dept))
+	_, err = m.CreateTask(ctx, &Task{DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	_, err = m.CreateTask(ctx, &Task{DepartmentID: "dept-dev"})
+	require.ErrorIs(t, err, ErrQueueFull)
+
+	// Finishing queued work makes room again
+	tasks := m.ListTasks("dept-dev", TaskStatusQueued)
+	require.NoError(t, m.CancelTask(ctx, tasks[0].ID, "no longer needed"))
+	_, err = m.CreateTask(ctx, &Task{DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+}
+
+func TestManagerFillsFreedCapacity(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+
+	for _, task := range []*Task{
+		{ID: "busy-1", DepartmentID: "dept-dev"},
+		{ID: "busy-2", DepartmentID: "dept-dev"},
+		{ID: "low", DepartmentID: "dept-dev", Priority: PriorityLow},
+		{ID: "medium", DepartmentID: "dept-dev", Priority: PriorityMedium},
+		{ID: "critical", DepartmentID: "dept-dev", Priority: PriorityCritical},
+		{ID: "qa", DepartmentID: "dept-qa", Priority: PriorityCritical},
+	} {
+		_, err := m.CreateTask(ctx, task)
+		require.NoError(t, err)
+	}
+
+	// Each finished task hands its slot to the most urgent queued task of
+	// the member's department
+	require.NoError(t, m.UpdateTaskStatus(ctx, "busy-1", TaskStatusCompleted, nil))
+	critical, err := m.GetTask("critical")
+	require.NoError(t, err)
+	require.Equal(t, "dev-1", critical.AssignedMember)
+	require.Equal(t, "it freed capacity and this was the most urgent queued task it could take", critical.RoutingDecision.MemberReason)
+
+	require.NoError(t, m.UpdateTaskStatus(ctx, "busy-2", TaskStatusFailed, nil))
+	medium, err := m.GetTask("medium")
+	require.NoError(t, err)
+	require.Equal(t, "dev-1", medium.AssignedMember)
+
+	for _, id := range []string{"low", "qa"} {
+		task, err := m.GetTask(id)
+		require.NoError(t, err)
+		require.Equal(t, TaskStatusQueued, task.Status)
+	}
+}
+
+func TestManagerQueueIndex(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m := newTestManager(t)
+
+	queuedIDs := func(departmentID string) []string {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		var ids []string
+		for _, task := range m.queuedTasks(departmentID) {
+			ids = append(ids, task.ID)
+		}
+		return ids
+	}
+
+	for _, task := range []*Task{
+		{ID: "low", DepartmentID: "dept-dev", Priority: PriorityLow},
+		{ID: "critical", DepartmentID: "dept-dev", Priority: PriorityCritical},
+		{ID: "medium", DepartmentID: "dept-dev", Priority: PriorityMedium},
+		{ID: "qa", DepartmentID: "dept-qa"},
+		{ID: "blocked", DepartmentID: "dept-dev", Dependencies: []string{"qa"}},
+	} {
+		_, err := m.CreateTask(ctx, task)
+		require.NoError(t, err)
+	}
+	require.Equal(t, []string{"critical", "medium", "low"}, queuedIDs("dept-dev"))
+	require.Equal(t, []string{"qa"}, queuedIDs("dept-qa"))
+
+	// Tasks leave the index once cancelled or assigned, and blocked tasks
+	// join it once their dependencies finish
+	require.NoError(t, m.CancelTask(ctx, "medium", "no longer needed"))
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	m.retryQueuedTasks(ctx)
+	require.Equal(t, []string{"low"}, queuedIDs("dept-dev"))
+
+	require.NoError(t, m.UpdateTaskStatus(ctx, "qa", TaskStatusCompleted, nil))
+	require.Empty(t, queuedIDs("dept-qa"))
+	require.Equal(t, []string{"low", "blocked"}, queuedIDs("dept-dev"))
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	require.Len(t, m.queuedIn, 2)
+	require.Len(t, m.departmentTasks["dept-dev"], 4)
+	require.Len(t, m.departmentTasks["dept-qa"], 1)
+}
+
+func TestManagerTracksQueuedAt(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m := newTestManager(t)
+
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", Title: "work", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, task.CreatedAt, *task.QueuedAt)
+
+	dev := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	m.retryQueuedTasks(ctx)
+	task, err = m.GetTask(task.ID)
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusAssigned, task.Status)
+	require.Nil(t, task.QueuedAt)
+
+	// Requeueing starts a new wait, which the backlog counts from
+	_, err = m.taskRouter.ReassignMemberTasks(ctx, dev.ID, "member_unhealthy")
+	require.ErrorContains(t, err, "no suitable members")
+	task, err = m.GetTask(task.ID)
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, task.Status)
+	require.True(t, task.QueuedAt.After(task.CreatedAt))
+
+	queued, oldest := m.QueueBacklog("dept-dev", task.QueuedAt.Add(time.Minute))
+	require.Equal(t, 1, queued)
+	require.Equal(t, time.Minute, oldest)
+}
+
+func TestManagerDepartmentStatsQueueWait(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m := newTestManager(t)
+
+	// A queued task records its wait once it is assigned
+	_, err := m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-qa"})
+	require.NoError(t, err)
+	registerTestMember(t, m, "qa-1", "dept-qa", RoleQA, 1)
+	m.retryQueuedTasks(ctx)
+	task, err := m.GetTask("task-1")
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusAssigned, task.Status)
+	stats, err := m.GetDepartmentStats("dept-qa")
+	require.NoError(t, err)
+	require.Positive(t, stats.QueueWaitP95)
+
+	// Waits of 1s to 20s, after the one above, counted from when each task
+	// entered the queue
+	now := time.Now()
+	m.mu.Lock()
+	for i := 1; i <= 20; i++ {
+		queuedAt := now.Add(-time.Duration(i) * time.Second)
+		m.recordQueueWait(&Task{DepartmentID: "dept-qa", CreatedAt: now.Add(-time.Hour), QueuedAt: &queuedAt}, now)
+	}
+	m.mu.Unlock()
+	stats, err = m.GetDepartmentStats("dept-qa")
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Second, stats.QueueWaitP50)
+	require.Equal(t, 19*time.Second, stats.QueueWaitP95)
+
+	// Only the most recent waits are kept
+	m.mu.Lock()
+	queuedAt := now.Add(-time.Minute)
+	for range maxQueueWaitSamples {
+		m.recordQueueWait(&Task{DepartmentID: "dept-qa", QueuedAt: &queuedAt}, now)
+	}
+	require.Len(t, m.queueWaits["dept-qa"], maxQueueWaitSamples)
+	m.mu.Unlock()
+	stats, err = m.GetDepartmentStats("dept-qa")
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, stats.QueueWaitP50)
+	require.Equal(t, time.Minute, stats.QueueWaitP95)
+
+	stats, err = m.GetDepartmentStats("dept-dev")
+	require.NoError(t, err)
+	require.Zero(t, stats.QueueWaitP95)
+}
@@ -0,0 +1,314 @@
+package department
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Report export formats
+const (
+	ReportFormatJSON = "json"
+	ReportFormatCSV  = "csv"
+)
+
+// Report metrics
+const (
+	ReportThroughput      = "throughput"
+	ReportSuccessRate     = "success_rate"
+	ReportAverageResponse = "average_response"
+	// ReportScalingActivity reports scale_ups and scale_downs
+	ReportScalingActivity = "scaling_activity"
+)
+
+// reportMetrics lists every report metric, in report order
+var reportMetrics = []string{ReportThroughput, ReportSuccessRate, ReportAverageResponse, ReportScalingActivity}
+
+// reportFormats lists every report export format
+var reportFormats = []string{ReportFormatJSON, ReportFormatCSV}
+
+// defaultReportWindow is the throughput window of reports without a
+// ReportInterval
+const defaultReportWindow = time.Hour
+
+// Report holds the requested metrics of every department and of the roles
+// listed in ReportingConfig.RoleReports. Throughput is tasks completed per
+// hour within Window, success rate is the share of finished tasks that
+// completed, and average response is in seconds.
+type Report struct {
+	GeneratedAt time.Time                         `json:"generated_at"`
+	Window      time.Duration                     `json:"window"`
+	Departments map[string]map[string]float64     `json:"departments"`
+	Roles       map[MemberRole]map[string]float64 `json:"roles,omitempty"`
+}
+
+// GenerateReport builds a report of the metrics configured for reporting,
+// encoded as json or csv
+func (m *Manager) GenerateReport(format string) ([]byte, error) {
+	if !slices.Contains(reportFormats, format) {
+		return nil, fmt.Errorf("unknown report format %q", format)
+	}
+	return encodeReport(m.report(time.Now()), format)
+}
+
+// report snapshots the configured metrics
+func (m *Manager) report(now time.Time) Report {
+	config := m.config.Reporting
+	metrics := config.Metrics
+	if len(metrics) == 0 {
+		metrics = reportMetrics
+	}
+	window := config.ReportInterval
+	if window <= 0 {
+		window = defaultReportWindow
+	}
+
+	// The scaler's lock is taken before the manager's, so its state is read
+	// first
+	var (
+		scaleCounts map[string]map[string]int
+		history     []ScalingEvent
+	)
+	if m.scaler != nil {
+		scaleCounts = m.scaler.scalingCounts()
+		history = m.scaler.GetScalingHistory(0)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	report := Report{
+		GeneratedAt: now,
+		Window:      window,
+		Departments: make(map[string]map[string]float64, len(m.departments)),
+	}
+	roles := make(map[MemberRole]bool, len(config.RoleReports))
+	if len(config.RoleReports) > 0 {
+		report.Roles = make(map[MemberRole]map[string]float64, len(config.RoleReports))
+		for _, role := range config.RoleReports {
+			roles[MemberRole(role)] = true
+		}
+	}
+
+	// Tasks completed within the window, by department and by the role of
+	// the member that completed them
+	completedByDept := make(map[string]int)
+	completedByRole := make(map[MemberRole]int)
+	for _, task := range m.tasks {
+		if task.Status != TaskStatusCompleted || task.CompletedAt == nil || now.Sub(*task.CompletedAt) > window {
+			continue
+		}
+		completedByDept[task.DepartmentID]++
+		role := task.AssignedRole
+		if member, exists := m.members[task.AssignedMember]; exists {
+			role = member.Role
+		}
+		completedByRole[role]++
+	}
+
+	for id := range m.departments {
+		stats := m.computeDepartmentStats(id, now)
+		values := make(map[string]float64, len(metrics))
+		for _, metric := range metrics {
+			switch metric {
+			case ReportThroughput:
+				values[metric] = float64(completedByDept[id]) / window.Hours()
+			case ReportSuccessRate:
+				values[metric] = successRate(stats.CompletedTasks, stats.FailedTasks)
+			case ReportAverageResponse:
+				values[metric] = stats.AverageResponse
+			case ReportScalingActivity:
+				values["scale_ups"] = float64(scaleCounts[id][scaleUp])
+				values["scale_downs"] = float64(scaleCounts[id][scaleDown])
+			}
+		}
+		report.Departments[id] = values
+	}
+
+	for role := range roles {
+		var completed, failed, timed int
+		var average float64
+		for _, stats := range m.memberStats {
+			if stats.MemberRole != role {
+				continue
+			}
+			completed += stats.CompletedTasks
+			failed += stats.FailedTasks
+			if stats.TimedTasks > 0 {
+				timed += stats.TimedTasks
+				average += (stats.AverageTime - average) * float64(stats.TimedTasks) / float64(timed)
+			}
+		}
+
+		values := make(map[string]float64, len(metrics))
+		for _, metric := range metrics {
+			switch metric {
+			case ReportThroughput:
+				values[metric] = float64(completedByRole[role]) / window.Hours()
+			case ReportSuccessRate:
+				values[metric] = successRate(completed, failed)
+			case ReportAverageResponse:
+				values[metric] = average
+			case ReportScalingActivity:
+				values["scale_ups"], values["scale_downs"] = 0, 0
+				for _, event := range history {
+					if event.Role != role {
+						continue
+					}
+					switch event.Action {
+					case scaleUp:
+						values["scale_ups"]++
+					case scaleDown:
+						values["scale_downs"]++
+					}
+				}
+			}
+		}
+		report.Roles[role] = values
+	}
+
+	return report
+}
+
+// successRate returns the share of finished tasks that completed, or zero
+// when none finished
+func successRate(completed, failed int) float64 {
+	if completed+failed == 0 {
+		return 0
+	}
+	return float64(completed) / float64(completed+failed)
+}
+
+// encodeReport encodes a report as indented JSON, or as CSV with one
+// scope,id,metric,value row per value in a stable order
+func encodeReport(report Report, format string) ([]byte, error) {
+	switch format {
+	case ReportFormatJSON:
+		return json.MarshalIndent(report, "", "  ")
+	case ReportFormatCSV:
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		rows := [][]string{{"scope", "id", "metric", "value"}}
+		addRows := func(scope, id string, values map[string]float64) {
+			for _, metric := range slices.Sorted(maps.Keys(values)) {
+				rows = append(rows, []string{scope, id, metric, strconv.FormatFloat(values[metric], 'f', -1, 64)})
+			}
+		}
+		for _, id := range slices.Sorted(maps.Keys(report.Departments)) {
+			addRows("department", id, report.Departments[id])
+		}
+		for _, role := range slices.Sorted(maps.Keys(report.Roles)) {
+			addRows("role", string(role), report.Roles[role])
+		}
+		if err := w.WriteAll(rows); err != nil {
+			return nil, fmt.Errorf("failed to encode report: %w", err)
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+// Reporter periodically writes reports to the report directory, one file
+// per export format
+type Reporter struct {
+	config  ReportingConfig
+	manager *Manager
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewReporter creates a reporter for the manager's metrics
+func NewReporter(config ReportingConfig, manager *Manager) *Reporter {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Reporter{
+		config:  config,
+		manager: manager,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Start writes a report every ReportInterval until ctx ends or Stop is
+// called
+func (r *Reporter) Start(ctx context.Context) {
+	slog.Info("Starting reporter", "interval", r.config.ReportInterval, "dir", r.config.ReportDir)
+
+	ticker := time.NewTicker(r.config.ReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Reporter stopped")
+			return
+		case <-r.ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := r.writeReports(now); err != nil {
+				slog.Error("Failed to write report", "error", err)
+			}
+		}
+	}
+}
+
+// Stop stops the reporter
+func (r *Reporter) Stop() {
+	r.cancel()
+}
+
+// writeReports writes the report taken at now in every export format,
+// json when none is configured. Each file is replaced whole, so readers
+// never see a partial report.
+func (r *Reporter) writeReports(now time.Time) error {
+	formats := r.config.ExportFormats
+	if len(formats) == 0 {
+		formats = []string{ReportFormatJSON}
+	}
+
+	report := r.manager.report(now)
+	for _, format := range formats {
+		data, err := encodeReport(report, format)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(r.config.ReportDir, "department-report."+format)
+		if err := writeFileAtomic(path, data); err != nil {
+			return fmt.Errorf("failed to write %s report: %w", format, err)
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,89 @@
+package department
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerGenerateReport(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled: true,
+		Reporting: ReportingConfig{
+			Metrics:     []string{ReportThroughput, ReportSuccessRate},
+			RoleReports: []string{string(RoleDeveloper)},
+		},
+	})
+	require.NoError(t, err)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 3)
+
+	for _, status := range []TaskStatus{TaskStatusCompleted, TaskStatusCompleted, TaskStatusCompleted, TaskStatusFailed} {
+		task, err := m.CreateTask(ctx, &Task{DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+		require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+		require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, status, nil))
+	}
+
+	data, err := m.GenerateReport(ReportFormatJSON)
+	require.NoError(t, err)
+	var report Report
+	require.NoError(t, json.Unmarshal(data, &report))
+	require.Equal(t, defaultReportWindow, report.Window)
+
+	// Only the requested metrics are reported, per department and role
+	want := map[string]float64{ReportThroughput: 3, ReportSuccessRate: 0.75}
+	require.Equal(t, want, report.Departments["dept-dev"])
+	require.Equal(t, map[string]float64{ReportThroughput: 0, ReportSuccessRate: 0}, report.Departments["dept-qa"])
+	require.Equal(t, map[MemberRole]map[string]float64{RoleDeveloper: want}, report.Roles)
+
+	data, err = m.GenerateReport(ReportFormatCSV)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Equal(t, "scope,id,metric,value", lines[0])
+	require.Contains(t, lines, "department,dept-dev,success_rate,0.75")
+	require.Equal(t, "role,developer,throughput,3", lines[len(lines)-1])
+
+	_, err = m.GenerateReport("xml")
+	require.ErrorContains(t, err, `unknown report format "xml"`)
+}
+
+func TestReporterWritesReports(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	m, err := NewManager(context.Background(), &DepartmentConfig{
+		Enabled: true,
+		Reporting: ReportingConfig{
+			Enabled:        true,
+			ReportInterval: 10 * time.Millisecond,
+			ExportFormats:  []string{ReportFormatJSON, ReportFormatCSV},
+			ReportDir:      dir,
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(m.reporter.Stop)
+
+	for _, name := range []string{"department-report.json", "department-report.csv"} {
+		require.Eventually(t, func() bool {
+			_, err := os.Stat(filepath.Join(dir, name))
+			return err == nil
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	// Reports are renamed into place, so no temporary file is left behind
+	// between ticks
+	m.reporter.Stop()
+	time.Sleep(20 * time.Millisecond)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+}
@@ -0,0 +1,146 @@
+package department
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// DepartmentReservation dedicates a department to a single session so no
+// other work interleaves with it
+type DepartmentReservation struct {
+	DepartmentID string    `json:"department_id"`
+	SessionID    string    `json:"session_id"`
+	ReservedAt   time.Time `json:"reserved_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+
+	timer *time.Timer
+}
+
+// ReserveDepartment reserves a department for a session until ttl passes or
+// the reservation is released. While it holds, the router only assigns the
+// session's tasks to the department: other tasks naming it wait in the queue,
+// and tasks routed by department rules prefer another matching department.
+// Tasks the department already holds are not affected. Reserving again for
+// the same session extends the reservation.
+func (m *Manager) ReserveDepartment(deptID, sessionID string, ttl time.Duration) (*DepartmentReservation, error) {
+	if sessionID == "" {
+		return nil, fmt.Errorf("session is required to reserve department %s", deptID)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("reservation of department %s needs a positive ttl", deptID)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.departments[deptID]; !exists {
+		return nil, fmt.Errorf("department %s not found", deptID)
+	}
+
+	now := time.Now()
+	if existing := m.activeReservation(deptID, now); existing != nil {
+		if existing.SessionID != sessionID {
+			return nil, fmt.Errorf("department %s is reserved by session %s until %s", deptID, existing.SessionID, existing.ExpiresAt.Format(time.RFC3339))
+		}
+		existing.timer.Stop()
+	}
+
+	reservation := &DepartmentReservation{
+		DepartmentID: deptID,
+		SessionID:    sessionID,
+		ReservedAt:   now,
+		ExpiresAt:    now.Add(ttl),
+	}
+	reservation.timer = time.AfterFunc(ttl, func() {
+		m.expireReservation(reservation)
+	})
+	m.reservations[deptID] = reservation
+
+	slog.Info("Department reserved",
+		"department", deptID,
+		"session_id", sessionID,
+		"expires_at", reservation.ExpiresAt)
+
+	copied := *reservation
+	copied.timer = nil
+	return &copied, nil
+}
+
+// ReleaseDepartment ends a session's reservation of a department and routes
+// the tasks that waited for it
+func (m *Manager) ReleaseDepartment(deptID, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reservation := m.activeReservation(deptID, time.Now())
+	if reservation == nil || reservation.SessionID != sessionID {
+		return fmt.Errorf("department %s is not reserved by session %s", deptID, sessionID)
+	}
+
+	reservation.timer.Stop()
+	m.endReservation(reservation, "released")
+	return nil
+}
+
+// GetReservation returns the active reservation of a department, or nil if
+// it is not reserved
+func (m *Manager) GetReservation(deptID string) *DepartmentReservation {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	reservation := m.activeReservation(deptID, time.Now())
+	if reservation == nil {
+		return nil
+	}
+	copied := *reservation
+	copied.timer = nil
+	return &copied
+}
+
+// expireReservation ends a reservation once its ttl has passed, unless it
+// was released or replaced in the meantime
+func (m *Manager) expireReservation(reservation *DepartmentReservation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.reservations[reservation.DepartmentID] != reservation {
+		return
+	}
+	m.endReservation(reservation, "expired")
+}
+
+// endReservation removes a reservation and routes the department's queued
+// tasks. The caller must hold the manager lock.
+func (m *Manager) endReservation(reservation *DepartmentReservation, reason string) {
+	delete(m.reservations, reservation.DepartmentID)
+
+	routed := m.rerouteQueuedTasks(context.Background(), reservation.DepartmentID)
+	if routed > 0 {
+		m.persist()
+	}
+
+	slog.Info("Department reservation ended",
+		"department", reservation.DepartmentID,
+		"session_id", reservation.SessionID,
+		"reason", reason,
+		"routed_tasks", routed)
+}
+
+// activeReservation returns a department's reservation if it has not expired.
+// The caller must hold the manager lock.
+func (m *Manager) activeReservation(deptID string, now time.Time) *DepartmentReservation {
+	reservation, exists := m.reservations[deptID]
+	if !exists || !now.Before(reservation.ExpiresAt) {
+		return nil
+	}
+	return reservation
+}
+
+// reservedForOther reports whether a department is reserved by a session
+// other than the task's. The caller must hold the manager lock.
+func (m *Manager) reservedForOther(deptID string, task *Task) bool {
+	reservation := m.activeReservation(deptID, time.Now())
+	return reservation != nil && reservation.SessionID != task.SessionID
+}
@@ -0,0 +1,88 @@
+package department
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerReserveDepartment(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled: true,
+		TaskRouting: TaskRoutingConfig{
+			DepartmentRules: map[string][]string{
+				"dept-dev": {"flaky"},
+				"dept-qa":  {"flaky"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 4)
+	registerTestMember(t, m, "qa-1", "dept-qa", RoleQA, 4)
+
+	reservation, err := m.ReserveDepartment("dept-dev", "session-a", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, "session-a", reservation.SessionID)
+	require.Equal(t, "session-a", m.GetReservation("dept-dev").SessionID)
+
+	_, err = m.ReserveDepartment("dept-dev", "session-b", time.Hour)
+	require.ErrorContains(t, err, "reserved by session session-a")
+
+	// The reserving session keeps using the department
+	own, err := m.CreateTask(ctx, &Task{ID: "own", DepartmentID: "dept-dev", SessionID: "session-a"})
+	require.NoError(t, err)
+	require.Equal(t, "dev-1", own.AssignedMember)
+
+	// Other sessions wait for the department they name...
+	waiting, err := m.CreateTask(ctx, &Task{ID: "waiting", DepartmentID: "dept-dev", SessionID: "session-b"})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, waiting.Status)
+
+	// ...and are routed elsewhere when another department matches as well
+	elsewhere, err := m.CreateTask(ctx, &Task{ID: "elsewhere", Description: "fix the flaky test", SessionID: "session-b"})
+	require.NoError(t, err)
+	require.Equal(t, "dept-qa", elsewhere.DepartmentID)
+	require.Equal(t, "qa-1", elsewhere.AssignedMember)
+
+	require.Error(t, m.ReleaseDepartment("dept-dev", "session-b"))
+	require.NoError(t, m.ReleaseDepartment("dept-dev", "session-a"))
+	require.Nil(t, m.GetReservation("dept-dev"))
+
+	// Releasing routes the task that waited
+	stored, err := m.GetTask("waiting")
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusAssigned, stored.Status)
+	require.Equal(t, "dev-1", stored.AssignedMember)
+}
+
+func TestManagerReservationExpires(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "sec-1", "dept-security", RoleSecurity, 1)
+
+	_, err := m.ReserveDepartment("dept-security", "session-a", 200*time.Millisecond)
+	require.NoError(t, err)
+
+	task, err := m.CreateTask(ctx, &Task{ID: "scan", DepartmentID: "dept-security"})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, task.Status)
+
+	require.Eventually(t, func() bool {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return task.AssignedMember == "sec-1"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Nil(t, m.GetReservation("dept-security"))
+
+	_, err = m.ReserveDepartment("dept-missing", "session-a", time.Hour)
+	require.ErrorContains(t, err, "not found")
+	_, err = m.ReserveDepartment("dept-security", "", time.Hour)
+	require.Error(t, err)
+}
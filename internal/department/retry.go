@@ -0,0 +1,109 @@
+package department
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+)
+
+// retryReason is the reassignment reason of tasks retried after a failed
+// attempt
+const retryReason = "retry after failed attempt"
+
+// RetryPolicy decides how often a failed task is attempted again and how
+// long to wait between attempts
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first. One
+	// or less never retries.
+	MaxAttempts int `json:"max_attempts"`
+	// Backoff is the wait before the second attempt. It doubles with every
+	// further attempt, up to MaxBackoff when that is set.
+	Backoff    time.Duration `json:"backoff,omitempty"`
+	MaxBackoff time.Duration `json:"max_backoff,omitempty"`
+}
+
+// Validate checks the retry policy for negative values
+func (p RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("max attempts must not be negative")
+	}
+	if p.Backoff < 0 || p.MaxBackoff < 0 {
+		return fmt.Errorf("backoff must not be negative")
+	}
+	return nil
+}
+
+// backoff returns the wait after the given failed attempt, counting from 1
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	wait := p.Backoff
+	for range attempt - 1 {
+		if p.MaxBackoff > 0 && wait >= p.MaxBackoff {
+			break
+		}
+		wait *= 2
+	}
+	if p.MaxBackoff > 0 {
+		wait = min(wait, p.MaxBackoff)
+	}
+	return wait
+}
+
+// FailTaskAttempt records that the task's current attempt failed. The
+// attempt number is added to results. When retryable is set and the task's
+// RetryPolicy allows another attempt, the task is taken off its member and
+// routed again, to a different member when one can take it, and FailTaskAttempt
+// returns true with how long the caller should wait before running it.
+// Otherwise the task is failed with results. Tasks pinned to their member
+// are never retried.
+func (m *Manager) FailTaskAttempt(ctx context.Context, taskID string, results map[string]interface{}, retryable bool) (time.Duration, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	task, exists := m.tasks[taskID]
+	if !exists {
+		return 0, false, fmt.Errorf("task %s does not exist", taskID)
+	}
+
+	if results == nil {
+		results = make(map[string]interface{})
+	}
+	results["attempt"] = task.Attempts
+
+	policy := task.RetryPolicy
+	if !retryable || policy == nil || task.Attempts >= policy.MaxAttempts || task.pinned() || m.taskRouter == nil {
+		return 0, false, m.updateTaskStatus(ctx, taskID, TaskStatusFailed, results)
+	}
+
+	if task.Results == nil {
+		task.Results = make(map[string]interface{})
+	}
+	for k, v := range results {
+		task.Results[k] = v
+	}
+
+	// Prefer another member, but retry on the same one rather than wait
+	// when no other member can take the task
+	failedMember := task.AssignedMember
+	if err := m.taskRouter.reassignTask(ctx, task, retryReason, map[string]bool{failedMember: true}); err != nil || task.Status == TaskStatusQueued {
+		if err := m.taskRouter.routeTask(ctx, task); err != nil {
+			slog.Warn("Retried task left queued", "task_id", taskID, "error", err)
+		}
+	}
+
+	m.persist()
+	m.taskEvents.Publish(pubsub.UpdatedEvent, task)
+
+	backoff := policy.backoff(task.Attempts)
+	slog.Info("Retrying failed task",
+		"task_id", taskID,
+		"attempt", task.Attempts,
+		"max_attempts", policy.MaxAttempts,
+		"failed_member", failedMember,
+		"next_member", task.AssignedMember,
+		"backoff", backoff)
+
+	return backoff, true, nil
+}
@@ -0,0 +1,121 @@
+package department
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	t.Parallel()
+
+	policy := RetryPolicy{MaxAttempts: 5, Backoff: time.Second, MaxBackoff: 3 * time.Second}
+	require.Equal(t, time.Second, policy.backoff(1))
+	require.Equal(t, 2*time.Second, policy.backoff(2))
+	require.Equal(t, 3*time.Second, policy.backoff(3))
+	require.Equal(t, 3*time.Second, policy.backoff(4))
+
+	require.Zero(t, RetryPolicy{MaxAttempts: 3}.backoff(2))
+	require.ErrorContains(t, RetryPolicy{Backoff: -time.Second}.Validate(), "backoff must not be negative")
+}
+
+func TestManagerFailTaskAttemptRetriesOnAnotherMember(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+	registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 2)
+
+	task, err := m.CreateTask(ctx, &Task{
+		ID:           "flaky",
+		DepartmentID: "dept-dev",
+		RetryPolicy:  &RetryPolicy{MaxAttempts: 2, Backoff: 10 * time.Millisecond},
+	})
+	require.NoError(t, err)
+	first := task.AssignedMember
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+
+	backoff, retrying, err := m.FailTaskAttempt(ctx, task.ID, map[string]interface{}{"error": "member dropped"}, true)
+	require.NoError(t, err)
+	require.True(t, retrying)
+	require.Equal(t, 10*time.Millisecond, backoff)
+
+	task, err = m.GetTask(task.ID)
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusAssigned, task.Status)
+	require.NotEqual(t, first, task.AssignedMember)
+	require.Equal(t, 1, task.Results["attempt"])
+
+	// The second attempt succeeds
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusCompleted, map[string]interface{}{"response": "done"}))
+
+	task, err = m.GetTask(task.ID)
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusCompleted, task.Status)
+	require.Equal(t, 2, task.Attempts)
+}
+
+func TestManagerFailTaskAttemptExhaustsRetries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+
+	task, err := m.CreateTask(ctx, &Task{
+		ID:           "broken",
+		DepartmentID: "dept-dev",
+		RetryPolicy:  &RetryPolicy{MaxAttempts: 3},
+	})
+	require.NoError(t, err)
+
+	// The only member gets the task again rather than it waiting
+	for attempt := 1; attempt <= 3; attempt++ {
+		require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+		_, retrying, err := m.FailTaskAttempt(ctx, task.ID, map[string]interface{}{"error": "boom"}, true)
+		require.NoError(t, err)
+		require.Equal(t, attempt < 3, retrying, "attempt %d", attempt)
+
+		task, err = m.GetTask(task.ID)
+		require.NoError(t, err)
+		require.Equal(t, attempt, task.Results["attempt"])
+		if retrying {
+			require.Equal(t, "dev-1", task.AssignedMember)
+		}
+	}
+	require.Equal(t, TaskStatusFailed, task.Status)
+	require.Equal(t, 3, task.Attempts)
+
+	member, err := m.GetMember("dev-1")
+	require.NoError(t, err)
+	require.Empty(t, member.CurrentTasks)
+}
+
+func TestManagerFailTaskAttemptNotRetryable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+
+	task, err := m.CreateTask(ctx, &Task{
+		ID:           "denied",
+		DepartmentID: "dept-dev",
+		RetryPolicy:  &RetryPolicy{MaxAttempts: 3},
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+
+	_, retrying, err := m.FailTaskAttempt(ctx, task.ID, map[string]interface{}{"error": "permission denied"}, false)
+	require.NoError(t, err)
+	require.False(t, retrying)
+
+	task, err = m.GetTask(task.ID)
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusFailed, task.Status)
+	require.Equal(t, 1, task.Results["attempt"])
+}
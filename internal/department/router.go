@@ -2,72 +2,341 @@ package department
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"math/rand"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+)
+
+// Task metadata keys linking team subtasks to their parent task
+const (
+	metadataParentTask  = "parent_task"
+	metadataCoordinator = "coordinator"
 )
 
 // TaskRouter handles intelligent task routing to appropriate members
 type TaskRouter struct {
 	config  TaskRoutingConfig
 	manager *Manager
+
+	// Last member picked by round-robin routing, keyed by department ID
+	rotation   map[string]string
+	rotationMu sync.Mutex
+
+	// Sequence number of each member's last skill-based pick, keyed by
+	// department ID, so ties go to the least recently assigned member.
+	// Guarded by rotationMu.
+	skillPicks   map[string]map[string]uint64
+	skillPickSeq uint64
+
+	// Routine assignments made, for log sampling
+	assignments atomic.Uint64
+
+	// Compares required skills with member specializations
+	skills *skillMatcher
+
+	// Member that last handled a task with each affinity key, and when
+	// expired mappings were last swept. Guarded by the manager lock.
+	affinities      map[string]affinity
+	affinitiesSwept time.Time
+
+	// Last routing trace per task, oldest first in traceOrder, when
+	// TraceRouting is set. Guarded by the manager lock.
+	traces     map[string]*RoutingTrace
+	traceOrder []string
 }
 
 // NewTaskRouter creates a new task router
 func NewTaskRouter(config TaskRoutingConfig, manager *Manager) *TaskRouter {
 	return &TaskRouter{
-		config:  config,
-		manager: manager,
+		config:     config,
+		manager:    manager,
+		rotation:   make(map[string]string),
+		skillPicks: make(map[string]map[string]uint64),
+		skills:     newSkillMatcher(config.SkillAliases),
+		affinities: make(map[string]affinity),
+		traces:     make(map[string]*RoutingTrace),
 	}
 }
 
 // RouteTask assigns a task to the most appropriate member
 func (tr *TaskRouter) RouteTask(ctx context.Context, task *Task) error {
+	tr.manager.mu.Lock()
+	defer tr.manager.mu.Unlock()
+
+	return tr.routeTask(ctx, task)
+}
+
+// routeTask assigns a task to the most appropriate member. The caller must
+// hold the manager lock.
+func (tr *TaskRouter) routeTask(ctx context.Context, task *Task) error {
+	return tr.routeTaskExcluding(ctx, task, nil)
+}
+
+// routeTaskExcluding routes a task like routeTask but never picks one of the
+// excluded members. The caller must hold the manager lock.
+func (tr *TaskRouter) routeTaskExcluding(ctx context.Context, task *Task, exclude map[string]bool) (err error) {
+	decision := &RoutingDecision{
+		Strategy:         tr.strategy(),
+		DepartmentReason: "it was specified on the task",
+		DecidedAt:        time.Now(),
+	}
+	trace := tr.startTrace(task)
+	defer func() {
+		tr.finishTrace(trace, task, decision, err)
+	}()
+	if task.RoutingDecision != nil && task.RoutingDecision.DepartmentID == task.DepartmentID {
+		// Keep the original reason when routing the task again
+		decision.DepartmentReason = task.RoutingDecision.DepartmentReason
+	}
+
 	// Determine target department if not specified
 	if task.DepartmentID == "" {
-		deptID, err := tr.determineDepartment(task)
+		deptID, reason, err := tr.determineDepartment(task)
 		if err != nil {
 			return fmt.Errorf("failed to determine department: %w", err)
 		}
 		task.DepartmentID = deptID
+		decision.DepartmentReason = reason
+	}
+
+	// A department reserved by another session takes none of this task's
+	// work until the reservation ends
+	if tr.manager.reservedForOther(task.DepartmentID, task) {
+		slog.Info("Task waiting for reserved department", "task_id", task.ID, "department", task.DepartmentID)
+		return nil
+	}
+	tr.applyBaselineSkills(task)
+
+	// Team-based routing hands the whole task to a team; step subtasks it
+	// creates are routed to individual members
+	if tr.config.Strategy == RoutingTeamBased && task.Metadata[metadataParentTask] == "" {
+		if team := tr.selectTeam(task); team != nil {
+			if err := tr.assignTaskToTeam(task, team); err != nil {
+				return err
+			}
+			decision.TeamID = team.ID
+			decision.MemberReason = fmt.Sprintf("team %s covers the required skills with the lowest load (%.2f); its lead coordinates", team.ID, tr.teamLoad(team))
+			tr.recordDecision(task, decision)
+			return nil
+		}
+		if !tr.config.FallbackEnabled {
+			return fmt.Errorf("no team covers the required skills of task %s", task.ID)
+		}
+		slog.Info("No team covers task, falling back to member routing", "task_id", task.ID)
+	}
+
+	// A department scaled to zero keeps the task queued until a member has
+	// been cold-started for it
+	if tr.coldStartFor(task) {
+		slog.Info("Task waiting for cold start", "task_id", task.ID, "department", task.DepartmentID)
+		return nil
 	}
 
 	// Find suitable members
-	candidates, err := tr.findSuitableMembers(task)
+	candidates, err := tr.findSuitableMembers(task, exclude)
 	if err != nil {
 		return fmt.Errorf("failed to find suitable members: %w", err)
 	}
+	tr.traceRejections(trace, task, candidates, exclude)
 
 	if len(candidates) == 0 {
+		if (tr.config.PreemptionEnabled || tr.manager.escalated(task)) && effectivePriority(task) == PriorityCritical {
+			if _, victim := tr.preemptFor(ctx, task, exclude); victim != nil {
+				decision.PreemptedTaskID = victim.ID
+				decision.MemberReason = fmt.Sprintf("no member had capacity, so lower-priority task %s was preempted", victim.ID)
+				tr.recordDecision(task, decision)
+				return nil
+			}
+		}
+		// Members of other departments sharing the task's skills come before
+		// fallback routing
+		if others := tr.crossDepartmentMembers(task, exclude); len(others) > 0 {
+			decision.Candidates = tr.scoreCandidates(task, others)
+			selected, err := tr.selectMember(task, others)
+			if err != nil {
+				return fmt.Errorf("failed to select member: %w", err)
+			}
+			decision.DepartmentReason = crossDepartmentReason(task.DepartmentID, decision.DepartmentReason, selected.DepartmentID)
+			decision.MemberReason = tr.selectionReason(task, selected)
+
+			slog.Info("Task routed across departments",
+				"task_id", task.ID,
+				"from_department", task.DepartmentID,
+				"to_department", selected.DepartmentID,
+				"member", selected.ID)
+			task.DepartmentID = selected.DepartmentID
+			if err := tr.assignTaskToMember(task, selected); err != nil {
+				return err
+			}
+			tr.recordDecision(task, decision)
+			return nil
+		}
+		if !tr.config.FallbackEnabled && tr.typeNotPermitted(task, tr.manager.listMembers(task.DepartmentID)) {
+			return fmt.Errorf("cannot route %s task %s in department %s: %w", task.Type, task.ID, task.DepartmentID, ErrTaskTypeNotPermitted)
+		}
 		if tr.config.FallbackEnabled {
-			return tr.fallbackRouting(task)
+			reason, err := tr.fallbackRouting(task, exclude)
+			if err != nil {
+				return err
+			}
+			decision.Fallback = true
+			decision.MemberReason = "no suitable member was found, so fallback routing picked " + reason
+			tr.recordDecision(task, decision)
+			return nil
 		}
 		return fmt.Errorf("no suitable members found for task %s", task.ID)
 	}
 
-	// Select member based on routing strategy
-	selectedMember, err := tr.selectMember(task, candidates)
-	if err != nil {
-		return fmt.Errorf("failed to select member: %w", err)
+	// Score before assigning so the scores reflect what the strategy saw
+	decision.Candidates = tr.scoreCandidates(task, candidates)
+
+	// Related tasks stay with the member that handled the last one while it
+	// has room; otherwise the routing strategy decides
+	selectedMember := tr.affinityMember(task, candidates)
+	if selectedMember != nil {
+		decision.MemberReason = fmt.Sprintf("it handled the last task with affinity key %q", task.AffinityKey)
+	} else {
+		selectedMember, err = tr.selectMember(task, candidates)
+		if err != nil {
+			return fmt.Errorf("failed to select member: %w", err)
+		}
+		decision.MemberReason = tr.selectionReason(task, selectedMember)
 	}
 
 	// Assign task to member
-	return tr.assignTaskToMember(task, selectedMember)
+	if err := tr.assignTaskToMember(task, selectedMember); err != nil {
+		return err
+	}
+	tr.recordDecision(task, decision)
+	return nil
+}
+
+// strategy returns the routing strategy in effect
+func (tr *TaskRouter) strategy() RoutingStrategy {
+	if tr.config.Strategy == "" {
+		return RoutingLoadBased
+	}
+	return tr.config.Strategy
+}
+
+// recordDecision stores a completed routing decision on the task
+func (tr *TaskRouter) recordDecision(task *Task, decision *RoutingDecision) {
+	decision.DepartmentID = task.DepartmentID
+	decision.MemberID = task.AssignedMember
+	task.RoutingDecision = decision
+}
+
+// scoreCandidates returns the score the routing strategy gives each
+// candidate, highest first. Round-robin does not score candidates.
+func (tr *TaskRouter) scoreCandidates(task *Task, candidates []*Member) []RoutingCandidate {
+	if tr.strategy() == RoutingRoundRobin {
+		return nil
+	}
+
+	maxCost := maxCandidateCost(candidates)
+	scored := make([]RoutingCandidate, 0, len(candidates))
+	for _, member := range candidates {
+		score := tr.manager.remainingUnits(member)
+		switch tr.strategy() {
+		case RoutingSkillBased:
+			score = float64(tr.skillScore(task, member))
+		case RoutingCostBased:
+			score = tr.costScore(task, member, maxCost)
+		}
+		scored = append(scored, RoutingCandidate{MemberID: member.ID, Score: score})
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+	return scored
+}
+
+// selectionReason describes why the strategy picked member
+func (tr *TaskRouter) selectionReason(task *Task, member *Member) string {
+	switch tr.strategy() {
+	case RoutingRoundRobin:
+		return "it was next in the department's rotation"
+	case RoutingSkillBased:
+		return fmt.Sprintf("it had the highest skill score (%d)", tr.skillScore(task, member))
+	case RoutingRoleBased:
+		return fmt.Sprintf("it had the most remaining capacity (%.1f units) among members matching the role requirements", tr.manager.remainingUnits(member))
+	case RoutingCostBased:
+		if effectivePriority(task) == PriorityCritical {
+			return fmt.Sprintf("it had the best cost score for a critical task, which escalates to leads and pricier members (cost %.2f per task)", member.CostPerTask)
+		}
+		return fmt.Sprintf("it had the best blend of low cost (%.2f per task) and low load", member.CostPerTask)
+	default:
+		return fmt.Sprintf("it had the most remaining capacity (%.1f units)", tr.manager.remainingUnits(member))
+	}
 }
 
-// determineDepartment determines the best department for a task
-func (tr *TaskRouter) determineDepartment(task *Task) (string, error) {
-	// Check department-specific rules
+// applyBaselineSkills merges the department's baseline skills into the
+// task's required skills
+func (tr *TaskRouter) applyBaselineSkills(task *Task) {
+	dept, exists := tr.manager.departments[task.DepartmentID]
+	if !exists {
+		return
+	}
+
+	for _, skill := range dept.BaselineSkills {
+		if !slices.ContainsFunc(task.RequiredSkills, func(required string) bool {
+			return tr.skills.canonicalize(required) == tr.skills.canonicalize(skill)
+		}) {
+			task.RequiredSkills = append(task.RequiredSkills, skill)
+		}
+	}
+}
+
+// determineDepartment determines the best department for a task and
+// describes why it was chosen
+func (tr *TaskRouter) determineDepartment(task *Task) (string, string, error) {
+	// Check department-specific rules, preferring the department whose
+	// keywords the task mentions most
+	var (
+		matching  []string
+		bestScore int
+		matched   = make(map[string]string)
+	)
 	for deptID, keywords := range tr.config.DepartmentRules {
+		score := 0
 		for _, keyword := range keywords {
 			if strings.Contains(strings.ToLower(task.Description), strings.ToLower(keyword)) ||
 				strings.Contains(strings.ToLower(task.Title), strings.ToLower(keyword)) {
-				return deptID, nil
+				if score == 0 {
+					matched[deptID] = keyword
+				}
+				score++
 			}
 		}
+		switch {
+		case score == 0 || score < bestScore:
+		case score > bestScore:
+			matching, bestScore = []string{deptID}, score
+		default:
+			matching = append(matching, deptID)
+		}
+	}
+	if len(matching) > 0 {
+		// Prefer departments not reserved by another session
+		if open := slices.DeleteFunc(slices.Clone(matching), func(deptID string) bool {
+			return tr.manager.reservedForOther(deptID, task)
+		}); len(open) > 0 {
+			matching = open
+		}
+		deptID := tr.breakDepartmentTie(matching)
+		reason := fmt.Sprintf("the task mentions %q, a keyword in its department rules", matched[deptID])
+		if len(matching) > 1 {
+			reason += fmt.Sprintf(", and it won the %s tie break among %d equally matching departments", tr.departmentTieBreak(), len(matching))
+		}
+		return deptID, reason, nil
 	}
 
 	// Check task type mappings
@@ -91,21 +360,62 @@ func (tr *TaskRouter) determineDepartment(task *Task) (string, error) {
 	}
 
 	if deptID, exists := taskTypeDept[task.Type]; exists {
-		return deptID, nil
+		return deptID, fmt.Sprintf("it handles %q tasks", task.Type), nil
 	}
 
 	// Use default department
 	if tr.config.DefaultDepartment != "" {
-		return tr.config.DefaultDepartment, nil
+		return tr.config.DefaultDepartment, "it is the default department", nil
+	}
+
+	return "", "", fmt.Errorf("cannot determine department for task %s", task.ID)
+}
+
+// departmentTieBreak returns the configured department tie break
+func (tr *TaskRouter) departmentTieBreak() DepartmentTieBreak {
+	if tr.config.DepartmentTieBreak == "" {
+		return TieBreakLeastLoaded
+	}
+	return tr.config.DepartmentTieBreak
+}
+
+// breakDepartmentTie picks one of several equally matching departments,
+// falling back to ID order so the choice is deterministic
+func (tr *TaskRouter) breakDepartmentTie(deptIDs []string) string {
+	slices.Sort(deptIDs)
+	if tr.departmentTieBreak() == TieBreakByID {
+		return deptIDs[0]
+	}
+
+	selected, selectedLoad := deptIDs[0], tr.departmentLoad(deptIDs[0])
+	for _, deptID := range deptIDs[1:] {
+		if load := tr.departmentLoad(deptID); load < selectedLoad {
+			selected, selectedLoad = deptID, load
+		}
 	}
+	return selected
+}
 
-	return "", fmt.Errorf("cannot determine department for task %s", task.ID)
+// departmentLoad is the share of a department's available member capacity in
+// use; a department without available members counts as fully loaded
+func (tr *TaskRouter) departmentLoad(deptID string) float64 {
+	remaining, capacity := 0.0, 0.0
+	for _, member := range tr.manager.listMembers(deptID) {
+		if isAvailable(member) {
+			remaining += tr.manager.remainingUnits(member)
+			capacity += memberCapacity(member)
+		}
+	}
+	if capacity == 0 {
+		return 1
+	}
+	return 1 - remaining/capacity
 }
 
 // findSuitableMembers finds members capable of handling the task
-func (tr *TaskRouter) findSuitableMembers(task *Task) ([]*Member, error) {
+func (tr *TaskRouter) findSuitableMembers(task *Task, exclude map[string]bool) ([]*Member, error) {
 	// Get all members in the target department
-	members := tr.manager.ListMembers(task.DepartmentID)
+	members := tr.manager.listMembers(task.DepartmentID)
 	if len(members) == 0 {
 		return nil, fmt.Errorf("no members in department %s", task.DepartmentID)
 	}
@@ -113,7 +423,7 @@ func (tr *TaskRouter) findSuitableMembers(task *Task) ([]*Member, error) {
 	var suitable []*Member
 
 	for _, member := range members {
-		if tr.isMemberSuitable(member, task) {
+		if !exclude[member.ID] && tr.isMemberSuitable(member, task) {
 			suitable = append(suitable, member)
 		}
 	}
@@ -121,16 +431,46 @@ func (tr *TaskRouter) findSuitableMembers(task *Task) ([]*Member, error) {
 	return suitable, nil
 }
 
+// typeNotPermitted reports whether some of members are available but none
+// of those has a role permitted to handle the task's type
+func (tr *TaskRouter) typeNotPermitted(task *Task, members []*Member) bool {
+	var available bool
+	for _, member := range members {
+		if !isAvailable(member) {
+			continue
+		}
+		if tr.manager.CanRoleHandle(member.Role, task.Type) {
+			return false
+		}
+		available = true
+	}
+	return available
+}
+
 // isMemberSuitable checks if a member is suitable for a task
 func (tr *TaskRouter) isMemberSuitable(member *Member, task *Task) bool {
+	return tr.unsuitableReason(member, task) == ""
+}
+
+// unsuitableReason returns why a member cannot take a task, or an empty
+// string if it can
+func (tr *TaskRouter) unsuitableReason(member *Member, task *Task) string {
 	// Check member status
 	if member.Status != MemberStatusOnline && member.Status != MemberStatusBusy {
-		return false
+		return fmt.Sprintf("member is %s", member.Status)
+	}
+	if tr.config.ExcludeBusy && member.Status == MemberStatusBusy {
+		return "member is busy and busy members are excluded"
+	}
+
+	// Check if member has capacity for the task's weight
+	if remaining, weight := tr.manager.remainingUnits(member), taskWeight(task); remaining < weight {
+		return fmt.Sprintf("member has %g capacity units left but the task needs %g", remaining, weight)
 	}
 
-	// Check if member has capacity
-	if len(member.CurrentTasks) >= member.MaxConcurrent {
-		return false
+	// Check the role is permitted to handle the task type
+	if !tr.manager.CanRoleHandle(member.Role, task.Type) {
+		return fmt.Sprintf("role %s is not permitted to handle %s tasks", member.Role, task.Type)
 	}
 
 	// Check role-specific rules
@@ -139,7 +479,7 @@ func (tr *TaskRouter) isMemberSuitable(member *Member, task *Task) bool {
 			for _, keyword := range rules {
 				if !strings.Contains(strings.ToLower(task.Description), strings.ToLower(keyword)) &&
 					!strings.Contains(strings.ToLower(task.Title), strings.ToLower(keyword)) {
-					return false
+					return fmt.Sprintf("task does not mention %q, which role %s requires", keyword, member.Role)
 				}
 			}
 		}
@@ -147,76 +487,90 @@ func (tr *TaskRouter) isMemberSuitable(member *Member, task *Task) bool {
 
 	// Check required skills
 	if len(task.RequiredSkills) > 0 {
-		hasRequiredSkills := true
 		for _, skill := range task.RequiredSkills {
-			found := false
-			for _, memberSkill := range member.Specializations {
-				if strings.EqualFold(memberSkill, skill) {
-					found = true
-					break
-				}
+			if !tr.hasSpecialization(member, skill) {
+				return fmt.Sprintf("member lacks required skill %q", skill)
 			}
-			if !found {
-				hasRequiredSkills = false
-				break
-			}
-		}
-		if !hasRequiredSkills {
-			return false
 		}
 	}
 
 	// Check if role is assigned or if we need to assign one
 	if task.AssignedRole != "" && member.Role != task.AssignedRole {
-		return false
+		return fmt.Sprintf("task is for role %s", task.AssignedRole)
 	}
 
-	return true
+	return ""
 }
 
 // selectMember selects the best member based on the routing strategy
 func (tr *TaskRouter) selectMember(task *Task, candidates []*Member) (*Member, error) {
+	return tr.chooseMember(task, candidates, true)
+}
+
+// chooseMember selects the best member based on the routing strategy. With
+// record unset the rotation and skill pick history are left as they were,
+// so the choice can be previewed without affecting later routing.
+func (tr *TaskRouter) chooseMember(task *Task, candidates []*Member, record bool) (*Member, error) {
 	switch tr.config.Strategy {
-	case "round-robin":
-		return tr.selectRoundRobin(candidates)
-	case "load-based":
+	case RoutingRoundRobin:
+		return tr.selectRoundRobin(task.DepartmentID, candidates, record)
+	case RoutingLoadBased:
 		return tr.selectByLoad(candidates)
-	case "skill-based":
-		return tr.selectBySkill(task, candidates)
-	case "role-based":
+	case RoutingSkillBased:
+		return tr.selectBySkill(task, candidates, record)
+	case RoutingRoleBased:
 		return tr.selectByRole(task, candidates)
+	case RoutingCostBased:
+		return tr.selectByCost(task, candidates)
 	default:
 		return tr.selectByLoad(candidates)
 	}
 }
 
-// selectRoundRobin selects members in a round-robin fashion
-func (tr *TaskRouter) selectRoundRobin(candidates []*Member) (*Member, error) {
+// selectRoundRobin rotates through the candidates in member ID order. The
+// cursor remembers the last member picked rather than an index, so members
+// joining or leaving between calls do not reset or skip the rotation. The
+// cursor only moves with record set.
+func (tr *TaskRouter) selectRoundRobin(departmentID string, candidates []*Member, record bool) (*Member, error) {
 	if len(candidates) == 0 {
 		return nil, fmt.Errorf("no candidates available")
 	}
 
-	// Simple round-robin based on current load
 	sort.Slice(candidates, func(i, j int) bool {
-		return len(candidates[i].CurrentTasks) < len(candidates[j].CurrentTasks)
+		return candidates[i].ID < candidates[j].ID
 	})
 
-	return candidates[0], nil
+	tr.rotationMu.Lock()
+	defer tr.rotationMu.Unlock()
+
+	selected := candidates[0]
+	last := tr.rotation[departmentID]
+	for _, member := range candidates {
+		if member.ID > last {
+			selected = member
+			break
+		}
+	}
+	if record {
+		tr.rotation[departmentID] = selected.ID
+	}
+
+	return selected, nil
 }
 
-// selectByLoad selects the member with the lowest current load
+// selectByLoad selects the member with the most remaining capacity units
 func (tr *TaskRouter) selectByLoad(candidates []*Member) (*Member, error) {
 	if len(candidates) == 0 {
 		return nil, fmt.Errorf("no candidates available")
 	}
 
 	var selected *Member
-	minLoad := 999
+	maxRemaining := 0.0
 
 	for _, member := range candidates {
-		currentLoad := len(member.CurrentTasks)
-		if currentLoad < minLoad {
-			minLoad = currentLoad
+		remaining := tr.manager.remainingUnits(member)
+		if selected == nil || remaining > maxRemaining {
+			maxRemaining = remaining
 			selected = member
 		}
 	}
@@ -224,8 +578,9 @@ func (tr *TaskRouter) selectByLoad(candidates []*Member) (*Member, error) {
 	return selected, nil
 }
 
-// selectBySkill selects the member with the best matching skills
-func (tr *TaskRouter) selectBySkill(task *Task, candidates []*Member) (*Member, error) {
+// selectBySkill selects the member with the best matching skills. The pick
+// is only remembered for later tie breaks with record set.
+func (tr *TaskRouter) selectBySkill(task *Task, candidates []*Member, record bool) (*Member, error) {
 	if len(candidates) == 0 {
 		return nil, fmt.Errorf("no candidates available")
 	}
@@ -239,35 +594,77 @@ func (tr *TaskRouter) selectBySkill(task *Task, candidates []*Member) (*Member,
 	var scores []memberScore
 
 	for _, member := range candidates {
-		score := 0
+		scores = append(scores, memberScore{member: member, score: tr.skillScore(task, member)})
+	}
 
-		// Score based on required skills
-		for _, skill := range task.RequiredSkills {
-			for _, memberSkill := range member.Specializations {
-				if strings.EqualFold(memberSkill, skill) {
-					score += 10
-					break
-				}
-			}
+	tr.rotationMu.Lock()
+	defer tr.rotationMu.Unlock()
+
+	picks := tr.skillPicks[task.DepartmentID]
+	if picks == nil && record {
+		picks = make(map[string]uint64)
+		tr.skillPicks[task.DepartmentID] = picks
+	}
+
+	// Sort by score (highest first), breaking ties in favor of the least
+	// recently assigned member
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].score != scores[j].score {
+			return scores[i].score > scores[j].score
+		}
+		if picks[scores[i].member.ID] != picks[scores[j].member.ID] {
+			return picks[scores[i].member.ID] < picks[scores[j].member.ID]
 		}
+		return scores[i].member.ID < scores[j].member.ID
+	})
+
+	selected := scores[0].member
+	if record {
+		tr.skillPickSeq++
+		picks[selected.ID] = tr.skillPickSeq
+	}
 
-		// Score based on current load (lower load = higher score)
-		score += (member.MaxConcurrent - len(member.CurrentTasks)) * 2
+	return selected, nil
+}
 
-		// Score based on performance
-		if stats, err := tr.manager.GetMemberStats(member.ID); err == nil {
-			score += int(stats.SuccessRate * 5)
+// skillScore rates how well a member fits a task by matching skills,
+// remaining capacity and past success
+func (tr *TaskRouter) skillScore(task *Task, member *Member) int {
+	capability := 0
+
+	// Score based on required skills
+	for _, skill := range task.RequiredSkills {
+		if tr.hasSpecialization(member, skill) {
+			capability += 10
 		}
+	}
 
-		scores = append(scores, memberScore{member: member, score: score})
+	// Score based on performance
+	if stats, exists := tr.manager.memberStats[member.ID]; exists {
+		capability += int(stats.SuccessRate * 5)
 	}
 
-	// Sort by score (highest first)
-	sort.Slice(scores, func(i, j int) bool {
-		return scores[i].score > scores[j].score
-	})
+	// Score based on current load (lower load = higher score)
+	load := int(tr.manager.remainingUnits(member) * 2)
 
-	return scores[0].member, nil
+	capabilityWeight, loadWeight := skillPriorityWeights(task.Priority)
+	return capability*capabilityWeight + load*loadWeight
+}
+
+// skillPriorityWeights returns how much capability and spare capacity count
+// towards a skill score. Urgent tasks favor the most capable member even if
+// it is busier, while low-priority tasks favor spreading the load.
+func skillPriorityWeights(priority Priority) (capability, load int) {
+	switch priority {
+	case PriorityCritical:
+		return 3, 1
+	case PriorityHigh:
+		return 2, 1
+	case PriorityLow:
+		return 1, 2
+	default:
+		return 1, 1
+	}
 }
 
 // selectByRole selects a member based on role requirements
@@ -316,53 +713,376 @@ func (tr *TaskRouter) selectByRole(task *Task, candidates []*Member) (*Member, e
 
 // assignTaskToMember assigns a task to a member
 func (tr *TaskRouter) assignTaskToMember(task *Task, member *Member) error {
+	now := time.Now()
+	if task.Status == TaskStatusQueued {
+		tr.manager.recordQueueWait(task, now)
+		task.QueuedDuration += now.Sub(*task.QueuedAt)
+	}
+
 	// Update task
 	task.AssignedMember = member.ID
 	task.AssignedRole = member.Role
 	task.Status = TaskStatusAssigned
-	task.UpdatedAt = time.Now()
+	task.UpdatedAt = now
+	tr.manager.indexTask(task)
+	assignedAt := task.UpdatedAt
+	task.AssignedAt = &assignedAt
 
 	// Update member
 	member.CurrentTasks = append(member.CurrentTasks, task.ID)
-	if len(member.CurrentTasks) >= member.MaxConcurrent {
+	if tr.manager.remainingUnits(member) < defaultTaskWeight {
 		member.Status = MemberStatusBusy
 	}
 
 	// Update member statistics
-	if stats, err := tr.manager.GetMemberStats(member.ID); err == nil {
+	if stats, exists := tr.manager.memberStats[member.ID]; exists {
 		stats.CurrentLoad = len(member.CurrentTasks)
 		stats.LastUpdated = time.Now()
 	}
 
-	slog.Info("Task assigned to member",
+	task.EstimatedCost += taskCost(task, member)
+	tr.recordAffinity(task, member)
+	tr.logAssignment(task, member)
+
+	return nil
+}
+
+// logAssignment logs a routine assignment. With AssignmentLogSampling set
+// only the first of every N assignments is logged at info level.
+func (tr *TaskRouter) logAssignment(task *Task, member *Member) {
+	level := slog.LevelInfo
+	if n := uint64(tr.config.AssignmentLogSampling); n > 1 && tr.assignments.Add(1)%n != 1 {
+		level = slog.LevelDebug
+	}
+
+	slog.Log(context.Background(), level, "Task assigned to member",
 		"task_id", task.ID,
 		"task_title", task.Title,
 		"member_id", member.ID,
 		"member_name", member.Name,
 		"member_role", string(member.Role),
 		"department", member.DepartmentID)
+}
+
+// selectTeam picks the least loaded team in the task's department whose
+// members can cover all of the task's required skills and still have capacity
+func (tr *TaskRouter) selectTeam(task *Task) *Team {
+	var (
+		selected     *Team
+		selectedLoad float64
+	)
+
+	for _, team := range tr.manager.teams {
+		if team.DepartmentID != task.DepartmentID {
+			continue
+		}
+		if _, ok := tr.planTeamAssignment(team, task); !ok {
+			continue
+		}
+
+		load := tr.teamLoad(team)
+		if selected == nil || load < selectedLoad || (load == selectedLoad && team.ID < selected.ID) {
+			selected = team
+			selectedLoad = load
+		}
+	}
+
+	return selected
+}
+
+// planTeamAssignment picks, for every required skill, the team member with
+// the most remaining capacity that has the skill either as its role or as a
+// specialization. It reports false if the lead is unavailable or a skill
+// cannot be covered.
+func (tr *TaskRouter) planTeamAssignment(team *Team, task *Task) (map[string]*Member, bool) {
+	lead, exists := tr.manager.members[team.LeadID]
+	if !exists || !isAvailable(lead) {
+		return nil, false
+	}
+
+	// Track remaining units as subtasks are planned so one member is not
+	// overbooked; the lead also takes the parent task
+	remaining := map[string]float64{lead.ID: tr.manager.remainingUnits(lead) - taskWeight(task)}
+	if remaining[lead.ID] < 0 {
+		return nil, false
+	}
+	skills := uniqueSkills(task.RequiredSkills)
+	plan := make(map[string]*Member, len(skills))
+
+	for _, skill := range skills {
+		var best *Member
+		for _, memberID := range append([]string{team.LeadID}, team.MemberIDs...) {
+			member, exists := tr.manager.members[memberID]
+			if !exists || !isAvailable(member) || !tr.hasSkill(member, skill) {
+				continue
+			}
+			if _, seen := remaining[member.ID]; !seen {
+				remaining[member.ID] = tr.manager.remainingUnits(member)
+			}
+			if remaining[member.ID] < defaultTaskWeight {
+				continue
+			}
+			if best == nil || remaining[member.ID] > remaining[best.ID] {
+				best = member
+			}
+		}
+		if best == nil {
+			return nil, false
+		}
+		plan[skill] = best
+		remaining[best.ID] -= defaultTaskWeight
+	}
+
+	return plan, true
+}
+
+// uniqueSkills returns skills without repeats, compared case-insensitively,
+// in their original order
+func uniqueSkills(skills []string) []string {
+	seen := make(map[string]bool, len(skills))
+	unique := make([]string, 0, len(skills))
+	for _, skill := range skills {
+		key := strings.ToLower(skill)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, skill)
+	}
+	return unique
+}
+
+// assignTaskToTeam records the team on the task, makes the team lead its
+// coordinator and splits the task into one subtask per required skill. If
+// any assignment fails, the ones already made are undone.
+func (tr *TaskRouter) assignTaskToTeam(task *Task, team *Team) error {
+	plan, ok := tr.planTeamAssignment(team, task)
+	if !ok {
+		return fmt.Errorf("team %s cannot cover task %s", team.ID, task.ID)
+	}
+	lead := tr.manager.members[team.LeadID]
+
+	task.AssignedTeam = team.ID
+	if task.Metadata == nil {
+		task.Metadata = make(map[string]string)
+	}
+	task.Metadata[metadataCoordinator] = lead.ID
+	if err := tr.assignTaskToMember(task, lead); err != nil {
+		tr.manager.dropSubtasks(task)
+		return err
+	}
+
+	now := time.Now()
+	subtasks := make([]*Task, 0, len(plan))
+	for _, skill := range uniqueSkills(task.RequiredSkills) {
+		member := plan[skill]
+		subtask := &Task{
+			ID:             fmt.Sprintf("%s-%s", task.ID, skill),
+			Title:          fmt.Sprintf("%s (%s)", task.Title, skill),
+			Description:    task.Description,
+			Type:           task.Type,
+			Priority:       task.Priority,
+			Status:         TaskStatusQueued,
+			DepartmentID:   task.DepartmentID,
+			RequestedBy:    task.RequestedBy,
+			SessionID:      task.SessionID,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			RequiredSkills: []string{skill},
+			AssignedTeam:   team.ID,
+			Metadata: map[string]string{
+				metadataParentTask:  task.ID,
+				metadataCoordinator: lead.ID,
+			},
+		}
+		tr.manager.tasks[subtask.ID] = subtask
+		tr.manager.indexTask(subtask)
+		task.Subtasks = append(task.Subtasks, subtask.ID)
+		if err := tr.assignTaskToMember(subtask, member); err != nil {
+			tr.manager.dropSubtasks(task)
+			tr.unassign(task)
+			return err
+		}
+		subtasks = append(subtasks, subtask)
+	}
+	for _, subtask := range subtasks {
+		tr.manager.countTasks(subtask.DepartmentID).created++
+		tr.manager.taskEvents.Publish(pubsub.CreatedEvent, subtask)
+	}
+
+	slog.Info("Task assigned to team",
+		"task_id", task.ID,
+		"team_id", team.ID,
+		"coordinator", lead.ID,
+		"subtasks", len(subtasks))
 
 	return nil
 }
 
-// fallbackRouting provides fallback routing when no suitable members are found
-func (tr *TaskRouter) fallbackRouting(task *Task) error {
-	// Try to find any available member in any department
-	allMembers := tr.manager.ListMembers("")
+// teamLoad returns the fraction of a team's combined capacity in use
+func (tr *TaskRouter) teamLoad(team *Team) float64 {
+	remaining, capacity := 0.0, 0.0
+	for _, memberID := range append([]string{team.LeadID}, team.MemberIDs...) {
+		if member, exists := tr.manager.members[memberID]; exists {
+			remaining += tr.manager.remainingUnits(member)
+			capacity += memberCapacity(member)
+		}
+	}
+	if capacity == 0 {
+		return 1
+	}
+	return 1 - remaining/capacity
+}
+
+// isAvailable reports whether a member is online or busy and so can be
+// given work when it has capacity left
+func isAvailable(member *Member) bool {
+	return member.Status == MemberStatusOnline || member.Status == MemberStatusBusy
+}
+
+// hasSkill reports whether a member's role or specializations match skill
+func (tr *TaskRouter) hasSkill(member *Member, skill string) bool {
+	return strings.EqualFold(string(member.Role), skill) || tr.hasSpecialization(member, skill)
+}
+
+// hasSpecialization reports whether one of a member's specializations
+// covers skill, allowing for aliases
+func (tr *TaskRouter) hasSpecialization(member *Member, skill string) bool {
+	return slices.ContainsFunc(member.Specializations, func(specialization string) bool {
+		return tr.skills.matches(skill, specialization)
+	})
+}
+
+// crossDepartmentMembers returns the suitable members of departments other
+// than the task's whose specializations cover the skills it requires, when
+// CrossDepartmentEnabled is set. Tasks that require no skills stay in their
+// department. The caller must hold the manager lock.
+func (tr *TaskRouter) crossDepartmentMembers(task *Task, exclude map[string]bool) []*Member {
+	if !tr.config.CrossDepartmentEnabled || len(task.RequiredSkills) == 0 {
+		return nil
+	}
+
+	var members []*Member
+	for _, member := range tr.manager.listMembers("") {
+		if member.DepartmentID == task.DepartmentID || exclude[member.ID] ||
+			tr.manager.reservedForOther(member.DepartmentID, task) {
+			continue
+		}
+		if dept, exists := tr.manager.departments[member.DepartmentID]; !exists || dept.Disabled {
+			continue
+		}
+		if tr.isMemberSuitable(member, task) {
+			members = append(members, member)
+		}
+	}
+	return members
+}
+
+// crossDepartmentReason describes why a task went to another department
+// than the one first picked for it
+func crossDepartmentReason(primary, primaryReason, deptID string) string {
+	return fmt.Sprintf("no member of %s, picked because %s, could take the task, and %s has members whose specializations cover its required skills",
+		primary, primaryReason, deptID)
+}
+
+// fallbackDepartmentTypes lists, for each task type, the department types
+// closest to it, best first. Fallback routing prefers members of closer
+// departments.
+var fallbackDepartmentTypes = map[string][]DepartmentType{
+	TaskTypeBugFix:             {DepartmentDevelopment, DepartmentQA},
+	TaskTypeFeatureDevelopment: {DepartmentDevelopment, DepartmentProductManager},
+	TaskTypeTesting:            {DepartmentQA, DepartmentDevelopment},
+	TaskTypeDeployment:         {DepartmentDevOps, DepartmentDevelopment},
+	TaskTypeSecurity:           {DepartmentSecurity, DepartmentDevOps},
+}
+
+// departmentDistance ranks how far a department is from a task, lowest
+// first: the task's own department, then departments of the types closest
+// to its task type, then the rest
+func (tr *TaskRouter) departmentDistance(task *Task, deptID string) int {
+	if deptID == task.DepartmentID {
+		return 0
+	}
+	types := fallbackDepartmentTypes[task.Type]
+	if dept, exists := tr.manager.departments[deptID]; exists {
+		if i := slices.Index(types, dept.Type); i >= 0 {
+			return i + 1
+		}
+	}
+	return len(types) + 1
+}
+
+// fallbackStrategy returns the strategy fallback routing picks members with
+func (tr *TaskRouter) fallbackStrategy() RoutingStrategy {
+	if tr.config.FallbackStrategy == "" {
+		return RoutingLoadBased
+	}
+	return tr.config.FallbackStrategy
+}
 
+// fallbackRouting assigns a task no suitable member was found for to an
+// available member of any department. Members of the departments closest to
+// the task are considered first, and the fallback strategy picks among them;
+// ties go to the lowest member ID, so the choice only depends on the
+// manager's state. It returns why the member was chosen.
+func (tr *TaskRouter) fallbackRouting(task *Task, exclude map[string]bool) (string, error) {
 	var available []*Member
-	for _, member := range allMembers {
-		if member.Status == MemberStatusOnline && len(member.CurrentTasks) < member.MaxConcurrent {
+	for _, member := range tr.manager.listMembers("") {
+		if !exclude[member.ID] && member.Status == MemberStatusOnline && tr.manager.remainingUnits(member) >= taskWeight(task) &&
+			!tr.manager.reservedForOther(member.DepartmentID, task) {
 			available = append(available, member)
 		}
 	}
 
 	if len(available) == 0 {
-		return fmt.Errorf("no available members for fallback routing")
+		return "", fmt.Errorf("no available members for fallback routing")
+	}
+	if tr.typeNotPermitted(task, available) {
+		return "", fmt.Errorf("cannot route %s task %s using fallback: %w", task.Type, task.ID, ErrTaskTypeNotPermitted)
+	}
+	available = slices.DeleteFunc(available, func(member *Member) bool {
+		return !tr.manager.CanRoleHandle(member.Role, task.Type)
+	})
+
+	// Keep only the members of the closest departments
+	slices.SortFunc(available, func(a, b *Member) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	distance := tr.departmentDistance(task, available[0].DepartmentID)
+	for _, member := range available[1:] {
+		distance = min(distance, tr.departmentDistance(task, member.DepartmentID))
 	}
+	available = slices.DeleteFunc(available, func(member *Member) bool {
+		return tr.departmentDistance(task, member.DepartmentID) != distance
+	})
 
-	// Select a member randomly from available ones
-	selected := available[rand.Intn(len(available))]
+	var (
+		selected *Member
+		err      error
+		reason   string
+	)
+	switch tr.fallbackStrategy() {
+	case RoutingSkillBased:
+		selected, err = tr.selectBySkill(task, available, true)
+		if err != nil {
+			return "", err
+		}
+		reason = fmt.Sprintf("the closest available member by skill score (%d)", tr.skillScore(task, selected))
+	default:
+		selected, err = tr.selectByLoad(available)
+		if err != nil {
+			return "", err
+		}
+		reason = fmt.Sprintf("the closest available member with the most capacity left (%.2f units)", tr.manager.remainingUnits(selected))
+	}
+	switch {
+	case distance == 0:
+		reason += " in the task's own department"
+	case distance <= len(fallbackDepartmentTypes[task.Type]):
+		reason += fmt.Sprintf(" in %s, the closest department to a %s task", selected.DepartmentID, task.Type)
+	default:
+		reason += fmt.Sprintf(" in %s, as no department close to the task had one", selected.DepartmentID)
+	}
 
 	// Update task department
 	task.DepartmentID = selected.DepartmentID
@@ -371,45 +1091,195 @@ func (tr *TaskRouter) fallbackRouting(task *Task) error {
 		"task_id", task.ID,
 		"task_title", task.Title,
 		"fallback_member", selected.ID,
-		"fallback_department", selected.DepartmentID)
+		"fallback_department", selected.DepartmentID,
+		"reason", reason)
 
-	return tr.assignTaskToMember(task, selected)
+	return reason, tr.assignTaskToMember(task, selected)
+}
+
+// preemptFor frees a slot for a critical task by requeueing the
+// lowest-priority in-progress task held by a member that could otherwise take
+// it. It returns the member the critical task was assigned to and the task
+// that was preempted, or nils if nothing could be preempted.
+func (tr *TaskRouter) preemptFor(ctx context.Context, task *Task, exclude map[string]bool) (*Member, *Task) {
+	var (
+		victim *Task
+		holder *Member
+	)
+
+	for _, member := range tr.manager.listMembers(task.DepartmentID) {
+		if exclude[member.ID] || len(member.CurrentTasks) == 0 {
+			continue
+		}
+
+		for _, taskID := range member.CurrentTasks {
+			candidate, exists := tr.manager.tasks[taskID]
+			if !exists || candidate.Status != TaskStatusInProgress || effectivePriority(candidate) == PriorityCritical || candidate.pinned() {
+				continue
+			}
+			if victim != nil && !isBetterPreemptionVictim(candidate, victim) {
+				continue
+			}
+
+			// Check the member would be suitable without the candidate,
+			// which frees the candidate's weight
+			freed := *member
+			freed.CurrentTasks = slices.DeleteFunc(slices.Clone(member.CurrentTasks), func(id string) bool {
+				return id == candidate.ID
+			})
+			if tr.isMemberSuitable(&freed, task) {
+				victim = candidate
+				holder = member
+			}
+		}
+	}
+
+	if victim == nil {
+		return nil, nil
+	}
+
+	tr.manager.releaseTask(holder.ID, victim.ID)
+	victim.AssignedMember = ""
+	if err := tr.assignTaskToMember(task, holder); err != nil {
+		return nil, nil
+	}
+
+	// Requeue the preempted task anywhere but the member it was taken from
+	if err := tr.reassignTask(ctx, victim, fmt.Sprintf("preempted by %s", task.ID), map[string]bool{holder.ID: true}); err != nil {
+		slog.Warn("Preempted task stays queued", "task_id", victim.ID, "error", err)
+	}
+	tr.manager.taskEvents.Publish(pubsub.UpdatedEvent, victim)
+
+	slog.Info("Task preempted",
+		"task_id", victim.ID,
+		"priority", string(victim.Priority),
+		"preempted_by", task.ID,
+		"member_id", holder.ID)
+
+	return holder, victim
+}
+
+// isBetterPreemptionVictim prefers lower priority tasks and, among equal
+// priorities, the most recently started one so the least work is lost
+func isBetterPreemptionVictim(candidate, current *Task) bool {
+	if cr, vr := effectivePriority(candidate).Rank(), effectivePriority(current).Rank(); cr != vr {
+		return cr < vr
+	}
+	if candidate.StartedAt == nil || current.StartedAt == nil {
+		return current.StartedAt != nil
+	}
+	return candidate.StartedAt.After(*current.StartedAt)
 }
 
 // ReassignTask reassigns a task to a different member
 func (tr *TaskRouter) ReassignTask(ctx context.Context, taskID string, reason string) error {
-	task, err := tr.manager.GetTask(taskID)
-	if err != nil {
-		return fmt.Errorf("failed to get task: %w", err)
+	tr.manager.mu.Lock()
+	defer tr.manager.mu.Unlock()
+
+	task, exists := tr.manager.tasks[taskID]
+	if !exists {
+		return fmt.Errorf("failed to get task: task %s does not exist", taskID)
+	}
+	if task.pinned() {
+		return fmt.Errorf("cannot reassign task %s: task is pinned to member %s", taskID, task.AssignedMember)
 	}
 
-	// Remove from current member
-	if task.AssignedMember != "" {
-		member, err := tr.manager.GetMember(task.AssignedMember)
-		if err == nil {
-			// Remove task from member's current tasks
-			for i, currentTask := range member.CurrentTasks {
-				if currentTask == taskID {
-					member.CurrentTasks = append(member.CurrentTasks[:i], member.CurrentTasks[i+1:]...)
-					break
-				}
-			}
+	return tr.reassignTask(ctx, task, reason, nil)
+}
+
+// ReassignMemberTasks moves every task held by a member to other members and
+// returns how many were moved. The member itself is excluded from routing so
+// its tasks never land back on it. Pinned tasks cannot move and are failed
+// with reason member_lost instead.
+func (tr *TaskRouter) ReassignMemberTasks(ctx context.Context, memberID string, reason string) (int, error) {
+	tr.manager.mu.Lock()
+	defer tr.manager.mu.Unlock()
+
+	member, exists := tr.manager.members[memberID]
+	if !exists {
+		return 0, fmt.Errorf("member %s does not exist", memberID)
+	}
+
+	return tr.reassignMemberTasks(ctx, member, reason)
+}
 
-			// Update member status if no longer busy
-			if len(member.CurrentTasks) < member.MaxConcurrent {
-				member.Status = MemberStatusOnline
+// reassignMemberTasks is ReassignMemberTasks for a known member. The caller
+// must hold the manager lock.
+func (tr *TaskRouter) reassignMemberTasks(ctx context.Context, member *Member, reason string) (int, error) {
+	memberID := member.ID
+	exclude := map[string]bool{memberID: true}
+	var (
+		moved int
+		errs  []error
+	)
+	for _, taskID := range slices.Clone(member.CurrentTasks) {
+		task, exists := tr.manager.tasks[taskID]
+		if !exists {
+			continue
+		}
+		if task.pinned() {
+			if err := tr.failLostTask(ctx, task, memberID); err != nil {
+				errs = append(errs, fmt.Errorf("task %s: %w", taskID, err))
 			}
+			continue
+		}
+		if err := tr.reassignTask(ctx, task, reason, exclude); err != nil {
+			errs = append(errs, fmt.Errorf("task %s: %w", taskID, err))
+		} else {
+			moved++
 		}
+		tr.manager.taskEvents.Publish(pubsub.UpdatedEvent, task)
 	}
 
-	// Reset task assignment
+	if moved > 0 || len(errs) > 0 {
+		tr.manager.persist()
+	}
+	return moved, errors.Join(errs...)
+}
+
+// memberLostReason is the failure reason of pinned tasks whose member was
+// lost
+const memberLostReason = "member_lost"
+
+// failLostTask fails a pinned task whose member was lost. The caller must
+// hold the manager lock.
+func (tr *TaskRouter) failLostTask(ctx context.Context, task *Task, memberID string) error {
+	slog.Warn("Pinned task failed with its member",
+		"task_id", task.ID,
+		"member_id", memberID)
+
+	return tr.manager.updateTaskStatus(ctx, task.ID, TaskStatusFailed, map[string]interface{}{
+		"error":          fmt.Sprintf("member %s was lost while running a pinned task", memberID),
+		"failure_reason": memberLostReason,
+	})
+}
+
+// unassign takes a task off its member, drops any team subtasks it was split
+// into and puts it back in the queue. The caller must hold the manager lock.
+func (tr *TaskRouter) unassign(task *Task) {
+	if task.AssignedMember != "" {
+		tr.manager.releaseTask(task.AssignedMember, task.ID)
+	}
+	tr.manager.dropSubtasks(task)
+
 	task.AssignedMember = ""
 	task.AssignedRole = ""
+	task.AssignedAt = nil
 	task.Status = TaskStatusQueued
 	task.UpdatedAt = time.Now()
+	tr.manager.indexTask(task)
+}
+
+// reassignTask moves a task off its current member and routes it again,
+// avoiding the excluded members. The caller must hold the manager lock.
+func (tr *TaskRouter) reassignTask(ctx context.Context, task *Task, reason string, exclude map[string]bool) error {
+	taskID := task.ID
+
+	tr.unassign(task)
+	task.Retries++
 
 	// Route to new member
-	if err := tr.RouteTask(ctx, task); err != nil {
+	if err := tr.routeTaskExcluding(ctx, task, exclude); err != nil {
 		return fmt.Errorf("failed to reassign task: %w", err)
 	}
 
@@ -419,4 +1289,4 @@ func (tr *TaskRouter) ReassignTask(ctx context.Context, taskID string, reason st
 		"reason", reason)
 
 	return nil
-}
\ No newline at end of file
+}
\ No newline at end of file
@@ -0,0 +1,730 @@
+package department
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskRoutingConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	for _, strategy := range []RoutingStrategy{"", RoutingRoundRobin, RoutingLoadBased, RoutingSkillBased, RoutingRoleBased} {
+		require.NoError(t, TaskRoutingConfig{Strategy: strategy}.Validate(), strategy)
+	}
+
+	err := TaskRoutingConfig{Strategy: "load_based"}.Validate()
+	require.ErrorContains(t, err, `unknown routing strategy "load_based"`)
+
+	err = TaskRoutingConfig{MaxQueueWait: map[Priority]time.Duration{PriorityHigh: 0}}.Validate()
+	require.ErrorContains(t, err, `max queue wait for priority "high" must be positive`)
+
+	err = TaskRoutingConfig{DepartmentTieBreak: "random"}.Validate()
+	require.ErrorContains(t, err, `unknown department tie break "random"`)
+
+	err = TaskRoutingConfig{FallbackStrategy: RoutingRoundRobin}.Validate()
+	require.ErrorContains(t, err, `unsupported fallback strategy "round-robin"`)
+
+	err = TaskRoutingConfig{RetryInterval: -time.Second}.Validate()
+	require.ErrorContains(t, err, "retry interval must not be negative")
+
+	err = TaskRoutingConfig{AssignmentLogSampling: -1}.Validate()
+	require.ErrorContains(t, err, "assignment log sampling must not be negative")
+
+	err = TaskRoutingConfig{SkillAliases: map[string][]string{"go": {""}}}.Validate()
+	require.ErrorContains(t, err, "skill aliases must not be empty")
+}
+
+func newTeamRoutingManager(t *testing.T, fallback bool) *Manager {
+	t.Helper()
+
+	m, err := NewManager(context.Background(), &DepartmentConfig{
+		Enabled: true,
+		TaskRouting: TaskRoutingConfig{
+			Strategy:        RoutingTeamBased,
+			FallbackEnabled: fallback,
+		},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	lead := registerTestMember(t, m, "lead", "dept-dev", RoleLeadDev, 3)
+	dev1 := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+	dev2 := registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 2)
+	qa := registerTestMember(t, m, "qa-1", "dept-dev", RoleQA, 2)
+	require.NoError(t, m.CreateTeam(ctx, &Team{
+		ID:           "team-feature",
+		DepartmentID: "dept-dev",
+		LeadID:       lead.ID,
+		MemberIDs:    []string{dev1.ID, dev2.ID, qa.ID},
+	}))
+	return m
+}
+
+func TestTaskRouterTeamBased(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTeamRoutingManager(t, false)
+
+	// Keep dev-1 busier than dev-2 so the developer subtask goes to dev-2
+	m.mu.Lock()
+	m.members["dev-1"].CurrentTasks = []string{"other"}
+	m.mu.Unlock()
+
+	task, err := m.CreateTask(ctx, &Task{
+		ID:             "feature",
+		Title:          "Checkout flow",
+		DepartmentID:   "dept-dev",
+		RequiredSkills: []string{"developer", "qa"},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "team-feature", task.AssignedTeam)
+	require.Equal(t, "lead", task.AssignedMember)
+	require.Equal(t, "lead", task.Metadata["coordinator"])
+	require.Equal(t, TaskStatusAssigned, task.Status)
+
+	devTask, err := m.GetTask("feature-developer")
+	require.NoError(t, err)
+	require.Equal(t, "dev-2", devTask.AssignedMember)
+	require.Equal(t, "team-feature", devTask.AssignedTeam)
+	require.Equal(t, "feature", devTask.Metadata["parent_task"])
+
+	qaTask, err := m.GetTask("feature-qa")
+	require.NoError(t, err)
+	require.Equal(t, "qa-1", qaTask.AssignedMember)
+}
+
+func TestTaskRouterTeamSubtasksFinishWithParent(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTeamRoutingManager(t, false)
+
+	// A repeated skill yields a single subtask
+	task, err := m.CreateTask(ctx, &Task{
+		ID:             "feature",
+		DepartmentID:   "dept-dev",
+		RequiredSkills: []string{"developer", "qa", "Developer"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"feature-developer", "feature-qa"}, task.Subtasks)
+
+	require.NoError(t, m.UpdateTaskStatus(ctx, "feature", TaskStatusCompleted, nil))
+	for _, id := range task.Subtasks {
+		subtask, err := m.GetTask(id)
+		require.NoError(t, err)
+		require.Equal(t, TaskStatusCompleted, subtask.Status)
+	}
+
+	// Cancelling the parent cancels its subtasks
+	_, err = m.CreateTask(ctx, &Task{ID: "bugfix", DepartmentID: "dept-dev", RequiredSkills: []string{"developer"}})
+	require.NoError(t, err)
+	require.NoError(t, m.CancelTask(ctx, "bugfix", "no longer needed"))
+	subtask, err := m.GetTask("bugfix-developer")
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusCancelled, subtask.Status)
+
+	// Every member got its capacity back
+	for _, member := range m.ListMembers("dept-dev") {
+		require.Empty(t, member.CurrentTasks, member.ID)
+	}
+}
+
+func TestTaskRouterTeamReassignment(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTeamRoutingManager(t, false)
+
+	_, err := m.CreateTask(ctx, &Task{ID: "feature", DepartmentID: "dept-dev", RequiredSkills: []string{"developer", "qa"}})
+	require.NoError(t, err)
+	require.NoError(t, m.taskRouter.ReassignTask(ctx, "feature", "lead overloaded"))
+
+	task, err := m.GetTask("feature")
+	require.NoError(t, err)
+	require.Equal(t, "team-feature", task.AssignedTeam)
+	require.Equal(t, []string{"feature-developer", "feature-qa"}, task.Subtasks)
+
+	// The subtasks were replaced rather than assigned twice
+	held := make(map[string]int)
+	for _, member := range m.ListMembers("dept-dev") {
+		for _, id := range member.CurrentTasks {
+			held[id]++
+		}
+	}
+	require.Equal(t, map[string]int{"feature": 1, "feature-developer": 1, "feature-qa": 1}, held)
+}
+
+func TestTaskRouterTeamBasedFallback(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// Without fallback a task no team can cover stays queued
+	strict := newTeamRoutingManager(t, false)
+	task, err := strict.CreateTask(ctx, &Task{ID: "audit", DepartmentID: "dept-dev", RequiredSkills: []string{"security"}})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, task.Status)
+	require.Empty(t, task.AssignedTeam)
+
+	// With fallback it is routed to an individual member instead
+	lenient := newTeamRoutingManager(t, true)
+	task, err = lenient.CreateTask(ctx, &Task{ID: "audit", DepartmentID: "dept-dev", RequiredSkills: []string{"security"}})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusAssigned, task.Status)
+	require.NotEmpty(t, task.AssignedMember)
+	require.Empty(t, task.AssignedTeam)
+}
+
+func TestTaskRouterRoundRobin(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{Strategy: RoutingRoundRobin},
+	})
+	require.NoError(t, err)
+
+	for _, id := range []string{"dev-a", "dev-b", "dev-c"} {
+		registerTestMember(t, m, id, "dept-dev", RoleDeveloper, 100)
+	}
+
+	const tasks = 30
+	counts := make(map[string]int)
+	for i := range tasks {
+		task, err := m.CreateTask(ctx, &Task{ID: fmt.Sprintf("task-%d", i), DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+		counts[task.AssignedMember]++
+
+		// Complete every task so all members stay at equal load
+		require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusCompleted, nil))
+	}
+	require.Equal(t, map[string]int{"dev-a": 10, "dev-b": 10, "dev-c": 10}, counts)
+
+	// The last pick was dev-c; a new member slots into the rotation in ID
+	// order and a departed member is skipped without restarting the cycle
+	registerTestMember(t, m, "dev-d", "dept-dev", RoleDeveloper, 100)
+	require.NoError(t, m.UnregisterMember(ctx, "dev-a"))
+
+	var order []string
+	for i := range 6 {
+		task, err := m.CreateTask(ctx, &Task{ID: fmt.Sprintf("more-%d", i), DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+		order = append(order, task.AssignedMember)
+	}
+	require.Equal(t, []string{"dev-d", "dev-b", "dev-c", "dev-d", "dev-b", "dev-c"}, order)
+}
+
+func TestTaskRouterSkillBasedTieBreaksFairly(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{Strategy: RoutingSkillBased},
+	})
+	require.NoError(t, err)
+
+	var candidates []*Member
+	for _, id := range []string{"dev-b", "dev-a"} {
+		member := &Member{
+			ID:              id,
+			Name:            id,
+			Role:            RoleDeveloper,
+			DepartmentID:    "dept-dev",
+			MaxConcurrent:   2,
+			Specializations: []string{"go"},
+		}
+		require.NoError(t, m.RegisterMember(ctx, member))
+		candidates = append(candidates, member)
+	}
+
+	// Both members score the same for every task, so the least recently
+	// assigned one wins each time
+	task := &Task{ID: "task-1", DepartmentID: "dept-dev", RequiredSkills: []string{"go"}}
+	require.Equal(t, m.taskRouter.skillScore(task, candidates[0]), m.taskRouter.skillScore(task, candidates[1]))
+
+	var order []string
+	for range 6 {
+		selected, err := m.taskRouter.selectBySkill(task, slices.Clone(candidates), true)
+		require.NoError(t, err)
+		order = append(order, selected.ID)
+	}
+	require.Equal(t, []string{"dev-a", "dev-b", "dev-a", "dev-b", "dev-a", "dev-b"}, order)
+
+	// Picks are tracked per department
+	other := &Task{ID: "task-2", DepartmentID: "dept-qa", RequiredSkills: []string{"go"}}
+	selected, err := m.taskRouter.selectBySkill(other, slices.Clone(candidates), true)
+	require.NoError(t, err)
+	require.Equal(t, "dev-a", selected.ID)
+}
+
+func TestTaskRouterSkillBasedWeighsPriority(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{Strategy: RoutingSkillBased},
+	})
+	require.NoError(t, err)
+
+	register := func(id string, maxConcurrent int, successRate float64) {
+		require.NoError(t, m.RegisterMember(ctx, &Member{
+			ID:              id,
+			Name:            id,
+			Role:            RoleDeveloper,
+			DepartmentID:    "dept-dev",
+			MaxConcurrent:   maxConcurrent,
+			Specializations: []string{"go"},
+		}))
+		m.mu.Lock()
+		m.memberStats[id].SuccessRate = successRate
+		m.mu.Unlock()
+	}
+	// The expert has the best track record but the least spare capacity
+	register("expert", 2, 1.0)
+	register("dev-1", 4, 0.4)
+	register("dev-2", 4, 0.4)
+
+	critical, err := m.CreateTask(ctx, &Task{ID: "critical", DepartmentID: "dept-dev", Priority: PriorityCritical, RequiredSkills: []string{"go"}})
+	require.NoError(t, err)
+	require.Equal(t, "expert", critical.AssignedMember)
+
+	low, err := m.CreateTask(ctx, &Task{ID: "low", DepartmentID: "dept-dev", Priority: PriorityLow, RequiredSkills: []string{"go"}})
+	require.NoError(t, err)
+	require.Contains(t, []string{"dev-1", "dev-2"}, low.AssignedMember)
+
+	// Without the priority bias the expert would not have been picked
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expert, dev := m.members["expert"], m.members["dev-2"]
+	medium := &Task{Priority: PriorityMedium, RequiredSkills: []string{"go"}}
+	require.Less(t, m.taskRouter.skillScore(medium, expert), m.taskRouter.skillScore(medium, dev))
+}
+
+func TestTaskRouterPreemption(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	newManager := func(preemption bool) *Manager {
+		m, err := NewManager(ctx, &DepartmentConfig{
+			Enabled:     true,
+			TaskRouting: TaskRoutingConfig{PreemptionEnabled: preemption},
+		})
+		require.NoError(t, err)
+
+		registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+		registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 1)
+		for _, task := range []*Task{
+			{ID: "low", DepartmentID: "dept-dev", Priority: PriorityLow},
+			{ID: "high", DepartmentID: "dept-dev", Priority: PriorityHigh},
+		} {
+			_, err := m.CreateTask(ctx, task)
+			require.NoError(t, err)
+			require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+		}
+		return m
+	}
+
+	// Without the flag a critical task waits like any other
+	m := newManager(false)
+	critical, err := m.CreateTask(ctx, &Task{ID: "critical", DepartmentID: "dept-dev", Priority: PriorityCritical})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, critical.Status)
+
+	m = newManager(true)
+	low, err := m.GetTask("low")
+	require.NoError(t, err)
+	holder := low.AssignedMember
+	events := m.SubscribeToTaskEvents(ctx)
+
+	critical, err = m.CreateTask(ctx, &Task{ID: "critical", DepartmentID: "dept-dev", Priority: PriorityCritical})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusAssigned, critical.Status)
+	require.Equal(t, holder, critical.AssignedMember)
+
+	// The low priority task is requeued; nobody else has room for it
+	low, err = m.GetTask("low")
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, low.Status)
+	require.Empty(t, low.AssignedMember)
+	member, err := m.GetMember(holder)
+	require.NoError(t, err)
+	require.Equal(t, []string{critical.ID}, member.CurrentTasks)
+
+	high, err := m.GetTask("high")
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusInProgress, high.Status)
+
+	event := <-events
+	require.Equal(t, low.ID, event.Payload.ID)
+}
+
+func TestTaskRouterPreemptionFreesVictimWeight(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{PreemptionEnabled: true},
+	})
+	require.NoError(t, err)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 3)
+
+	// The heavy low priority task is the only victim, and it is not the
+	// member's most recent task
+	for _, task := range []*Task{
+		{ID: "heavy", DepartmentID: "dept-dev", Priority: PriorityLow, Weight: 2},
+		{ID: "light", DepartmentID: "dept-dev", Priority: PriorityCritical},
+	} {
+		_, err := m.CreateTask(ctx, task)
+		require.NoError(t, err)
+		require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+	}
+
+	// Preempting the heavy task frees the two units the new task needs
+	critical, err := m.CreateTask(ctx, &Task{ID: "critical", DepartmentID: "dept-dev", Priority: PriorityCritical, Weight: 2})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusAssigned, critical.Status)
+	require.Equal(t, "dev-1", critical.AssignedMember)
+
+	heavy, err := m.GetTask("heavy")
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, heavy.Status)
+	member, err := m.GetMember("dev-1")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"light", "critical"}, member.CurrentTasks)
+}
+
+func TestTaskRouterBaselineSkills(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m := newTestManager(t)
+	m.mu.Lock()
+	m.departments["dept-security"].BaselineSkills = []string{"security"}
+	m.mu.Unlock()
+
+	// Without the baseline skill this member is never picked
+	generalist := registerTestMember(t, m, "sec-generalist", "dept-security", RoleSecurity, 5)
+
+	task, err := m.CreateTask(ctx, &Task{ID: "scan", DepartmentID: "dept-security", RequiredSkills: []string{"SAST"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"SAST", "security"}, task.RequiredSkills)
+	require.Equal(t, TaskStatusQueued, task.Status)
+	require.Empty(t, generalist.CurrentTasks)
+
+	specialist := &Member{
+		ID:              "sec-specialist",
+		Role:            RoleSecurity,
+		DepartmentID:    "dept-security",
+		MaxConcurrent:   5,
+		Specializations: []string{"security", "sast"},
+	}
+	require.NoError(t, m.RegisterMember(ctx, specialist))
+
+	task, err = m.CreateTask(ctx, &Task{ID: "audit", DepartmentID: "dept-security", RequiredSkills: []string{"Security"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Security"}, task.RequiredSkills)
+	require.Equal(t, specialist.ID, task.AssignedMember)
+}
+
+func TestTaskRouterWeightedCapacity(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m := newTestManager(t)
+
+	big := &Member{ID: "dev-big", Role: RoleDeveloper, DepartmentID: "dept-dev", MaxConcurrent: 1, CapacityUnits: 4}
+	small := &Member{ID: "dev-small", Role: RoleDeveloper, DepartmentID: "dept-dev", MaxConcurrent: 2}
+	require.NoError(t, m.RegisterMember(ctx, big))
+	require.NoError(t, m.RegisterMember(ctx, small))
+
+	// The feature goes to the member with the most remaining units
+	feature, err := m.CreateTask(ctx, &Task{ID: "feature", DepartmentID: "dept-dev", Weight: 3})
+	require.NoError(t, err)
+	require.Equal(t, big.ID, feature.AssignedMember)
+	require.Equal(t, MemberStatusOnline, big.Status)
+
+	// A fix without a weight counts as one unit; both have room, small has more
+	fix, err := m.CreateTask(ctx, &Task{ID: "fix", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, small.ID, fix.AssignedMember)
+
+	// Another heavy task fits nowhere
+	heavy, err := m.CreateTask(ctx, &Task{ID: "heavy", DepartmentID: "dept-dev", Weight: 2})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, heavy.Status)
+
+	// Two more unit tasks use up the last unit on each member
+	fix2, err := m.CreateTask(ctx, &Task{ID: "fix-2", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	fix3, err := m.CreateTask(ctx, &Task{ID: "fix-3", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{big.ID, small.ID}, []string{fix2.AssignedMember, fix3.AssignedMember})
+	require.Equal(t, MemberStatusBusy, big.Status)
+	require.Equal(t, MemberStatusBusy, small.Status)
+
+	// Finishing the feature frees three units
+	require.NoError(t, m.UpdateTaskStatus(ctx, feature.ID, TaskStatusCompleted, nil))
+	require.Equal(t, MemberStatusOnline, big.Status)
+}
+
+func TestTaskRouterDepartmentTieBreak(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	newManager := func(tieBreak DepartmentTieBreak) *Manager {
+		m, err := NewManager(ctx, &DepartmentConfig{
+			Enabled: true,
+			TaskRouting: TaskRoutingConfig{
+				DepartmentTieBreak: tieBreak,
+				DepartmentRules: map[string][]string{
+					"dept-dev":    {"pipeline", "build"},
+					"dept-devops": {"pipeline"},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		// dev is half loaded while devops is idle
+		registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+		registerTestMember(t, m, "ops-1", "dept-devops", RoleDevOps, 2)
+		_, err = m.CreateTask(ctx, &Task{ID: "busy", Title: "feature", DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+		return m
+	}
+
+	m := newManager("")
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", Title: "Fix the pipeline"})
+	require.NoError(t, err)
+	require.Equal(t, "dept-devops", task.DepartmentID)
+	require.Contains(t, task.RoutingDecision.DepartmentReason, "least-loaded tie break among 2 equally matching departments")
+
+	// A department matching more keywords wins regardless of load
+	task, err = m.CreateTask(ctx, &Task{ID: "task-2", Title: "Speed up the pipeline build"})
+	require.NoError(t, err)
+	require.Equal(t, "dept-dev", task.DepartmentID)
+
+	m = newManager(TieBreakByID)
+	task, err = m.CreateTask(ctx, &Task{ID: "task-1", Title: "Fix the pipeline"})
+	require.NoError(t, err)
+	require.Equal(t, "dept-dev", task.DepartmentID)
+}
+
+func TestTaskRouterFallbackPrefersClosestDepartment(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{FallbackEnabled: true},
+	})
+	require.NoError(t, err)
+	registerTestMember(t, m, "sec-1", "dept-security", RoleSecurity, 2)
+	require.NoError(t, m.UpdateMemberStatus(ctx, "sec-1", MemberStatusOffline))
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 10)
+	registerTestMember(t, m, "ops-1", "dept-devops", RoleDevOps, 2)
+	registerTestMember(t, m, "qa-1", "dept-qa", RoleQA, 2)
+	registerTestMember(t, m, "qa-2", "dept-qa", RoleQA, 2)
+
+	// A testing task goes to QA even though development has more room, and
+	// equally loaded QA members are picked in ID order
+	task, err := m.CreateTask(ctx, &Task{ID: "testing", Type: TaskTypeTesting, DepartmentID: "dept-security"})
+	require.NoError(t, err)
+	require.True(t, task.RoutingDecision.Fallback)
+	require.Equal(t, "qa-1", task.AssignedMember)
+	require.Equal(t, "dept-qa", task.DepartmentID)
+	require.Contains(t, task.RoutingDecision.MemberReason, "the closest department to a testing task")
+
+	task, err = m.CreateTask(ctx, &Task{ID: "deployment", Type: TaskTypeDeployment, DepartmentID: "dept-security"})
+	require.NoError(t, err)
+	require.Equal(t, "ops-1", task.AssignedMember)
+
+	// Without a close department the least loaded member anywhere is picked
+	task, err = m.CreateTask(ctx, &Task{ID: "general", Type: TaskTypeGeneral, DepartmentID: "dept-security"})
+	require.NoError(t, err)
+	require.Equal(t, "dev-1", task.AssignedMember)
+	require.Contains(t, task.RoutingDecision.MemberReason, "no department close to the task")
+}
+
+func TestTaskRouterFallbackSkillBased(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled: true,
+		TaskRouting: TaskRoutingConfig{
+			FallbackEnabled:  true,
+			FallbackStrategy: RoutingSkillBased,
+		},
+	})
+	require.NoError(t, err)
+	registerTestMember(t, m, "sec-1", "dept-security", RoleSecurity, 2)
+	require.NoError(t, m.UpdateMemberStatus(ctx, "sec-1", MemberStatusOffline))
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 4)
+	require.NoError(t, m.RegisterMember(ctx, &Member{
+		ID:              "dev-2",
+		Name:            "dev-2",
+		Role:            RoleDeveloper,
+		DepartmentID:    "dept-dev",
+		MaxConcurrent:   2,
+		Specializations: []string{"go"},
+	}))
+
+	task, err := m.CreateTask(ctx, &Task{ID: "fix", Type: TaskTypeBugFix, DepartmentID: "dept-security", RequiredSkills: []string{"golang"}})
+	require.NoError(t, err)
+	require.True(t, task.RoutingDecision.Fallback)
+	require.Equal(t, "dev-2", task.AssignedMember)
+	require.Contains(t, task.RoutingDecision.MemberReason, "by skill score")
+}
+
+func TestTaskRouterExcludeBusy(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	for _, excludeBusy := range []bool{false, true} {
+		m, err := NewManager(ctx, &DepartmentConfig{
+			Enabled:     true,
+			TaskRouting: TaskRoutingConfig{ExcludeBusy: excludeBusy},
+		})
+		require.NoError(t, err)
+
+		// Every member is busy but still has capacity left
+		for _, id := range []string{"dev-1", "dev-2"} {
+			registerTestMember(t, m, id, "dept-dev", RoleDeveloper, 3)
+			require.NoError(t, m.UpdateMemberStatus(ctx, id, MemberStatusBusy))
+		}
+
+		task, err := m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+		if excludeBusy {
+			require.Equal(t, TaskStatusQueued, task.Status)
+			require.Empty(t, task.AssignedMember)
+		} else {
+			require.Equal(t, TaskStatusAssigned, task.Status)
+		}
+	}
+}
+
+// TestTaskRouterAssignmentLogSampling swaps the default logger, so it must
+// not run in parallel
+func TestTaskRouterAssignmentLogSampling(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled: true,
+		TaskRouting: TaskRoutingConfig{
+			FallbackEnabled:       true,
+			AssignmentLogSampling: 3,
+		},
+	})
+	require.NoError(t, err)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 10)
+
+	// Routine assignments: only the first of every three is logged
+	for i := range 6 {
+		_, err := m.CreateTask(ctx, &Task{ID: fmt.Sprintf("routine-%d", i), Title: "work", DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, strings.Count(logs.String(), `msg="Task assigned to member" task_id=routine-`))
+
+	// Fallback routing is always logged
+	registerTestMember(t, m, "sec-1", "dept-security", RoleSecurity, 10)
+	require.NoError(t, m.UpdateMemberStatus(ctx, "sec-1", MemberStatusOffline))
+	for i := range 3 {
+		task, err := m.CreateTask(ctx, &Task{ID: fmt.Sprintf("fallback-%d", i), Title: "work", DepartmentID: "dept-security"})
+		require.NoError(t, err)
+		require.True(t, task.RoutingDecision.Fallback)
+	}
+	require.Equal(t, 3, strings.Count(logs.String(), `msg="Task routed using fallback" task_id=fallback-`))
+}
+
+func TestTaskRouterRolePermissions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	for _, fallback := range []bool{false, true} {
+		m, err := NewManager(ctx, &DepartmentConfig{
+			Enabled:     true,
+			TaskRouting: TaskRoutingConfig{FallbackEnabled: fallback},
+			Roles: RoleConfig{Permissions: map[string][]string{
+				string(RoleDeveloper): {"feature", "bugfix"},
+				string(RoleQA):        {"test"},
+			}},
+		})
+		require.NoError(t, err)
+		registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 3)
+		registerTestMember(t, m, "qa-1", "dept-qa", RoleQA, 3)
+
+		// Permitted types route as before, and so do untyped tasks
+		for _, taskType := range []string{"Feature", ""} {
+			task, err := m.CreateTask(ctx, &Task{DepartmentID: "dept-dev", Type: taskType})
+			require.NoError(t, err)
+			require.Equal(t, "dev-1", task.AssignedMember)
+		}
+
+		err = m.taskRouter.RouteTask(ctx, &Task{ID: "deploy", DepartmentID: "dept-dev", Type: "deployment"})
+		require.ErrorIs(t, err, ErrTaskTypeNotPermitted)
+	}
+
+	// Roles without permissions handle every type
+	m := newTestManager(t)
+	require.True(t, m.CanRoleHandle(RoleDevOps, "deployment"))
+}
+
+func TestTaskRouterCrossDepartment(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	for _, enabled := range []bool{false, true} {
+		m, err := NewManager(ctx, &DepartmentConfig{
+			Enabled:     true,
+			TaskRouting: TaskRoutingConfig{CrossDepartmentEnabled: enabled},
+		})
+		require.NoError(t, err)
+		for _, member := range []*Member{
+			{ID: "dev-1", Role: RoleDeveloper, DepartmentID: "dept-dev", MaxConcurrent: 1, Specializations: []string{"go"}},
+			{ID: "ops-1", Role: RoleDevOps, DepartmentID: "dept-devops", MaxConcurrent: 3, Specializations: []string{"golang", "terraform"}},
+			{ID: "ops-2", Role: RoleDevOps, DepartmentID: "dept-devops", MaxConcurrent: 3},
+		} {
+			require.NoError(t, m.RegisterMember(ctx, member))
+		}
+
+		// The task's own department is preferred while it has room
+		first, err := m.CreateTask(ctx, &Task{ID: "first", DepartmentID: "dept-dev", RequiredSkills: []string{"go"}})
+		require.NoError(t, err)
+		require.Equal(t, "dev-1", first.AssignedMember)
+
+		second, err := m.CreateTask(ctx, &Task{ID: "second", DepartmentID: "dept-dev", RequiredSkills: []string{"go"}})
+		require.NoError(t, err)
+		unskilled, err := m.CreateTask(ctx, &Task{ID: "unskilled", DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+		require.Equal(t, TaskStatusQueued, unskilled.Status, "enabled=%v", enabled)
+
+		if !enabled {
+			require.Equal(t, TaskStatusQueued, second.Status)
+			continue
+		}
+		require.Equal(t, "ops-1", second.AssignedMember)
+		require.Equal(t, "dept-devops", second.DepartmentID)
+		require.False(t, second.RoutingDecision.Fallback)
+		require.Contains(t, second.RoutingDecision.DepartmentReason, "no member of dept-dev")
+		require.Contains(t, second.RoutingDecision.DepartmentReason, "dept-devops has members whose specializations cover")
+	}
+}
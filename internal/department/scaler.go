@@ -4,10 +4,59 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+)
+
+// defaultUtilizationSmoothing is the EWMA weight used when the config leaves
+// UtilizationSmoothing unset.
+const defaultUtilizationSmoothing = 0.3
+
+// defaultMemberCapacity is the task capacity assumed for members that set no
+// MaxConcurrent
+const defaultMemberCapacity = 5
+
+// defaultMemberNameTemplate is used for departments without a MemberNameTemplate.
+const defaultMemberNameTemplate = "Auto-Scaled {role}"
+
+// Scaling actions returned by decideScaling
+const (
+	scaleNone = "none"
+	scaleUp   = "scale_up"
+	scaleDown = "scale_down"
 )
 
+// defaultScalingHistorySize is how many scaling events are kept when the
+// config leaves ScalingHistorySize unset
+const defaultScalingHistorySize = 100
+
+// defaultDrainTimeout bounds how long a member drains when no DrainTimeout
+// is configured
+const defaultDrainTimeout = 10 * time.Minute
+
+// ScaledEvent is published on the scaling event stream for every member the
+// auto-scaler adds or removes
+const ScaledEvent pubsub.EventType = "scaled"
+
+// ScalingEvent records one member added or removed by the auto-scaler
+type ScalingEvent struct {
+	DepartmentID  string     `json:"department_id"`
+	Action        string     `json:"action"`
+	MemberID      string     `json:"member_id"`
+	Role          MemberRole `json:"role"`
+	Reason        string     `json:"reason"`
+	MembersBefore int        `json:"members_before"`
+	MembersAfter  int        `json:"members_after"`
+	Timestamp     time.Time  `json:"timestamp"`
+}
+
 // AutoScaler handles dynamic scaling of department members
 type AutoScaler struct {
 	config    AutoScalingConfig
@@ -18,6 +67,45 @@ type AutoScaler struct {
 	// Scaling state
 	lastScaleTime map[string]time.Time
 	scaleCooldown map[string]time.Time
+	// Direction of the last scaling action per department
+	lastScaleAction map[string]string
+
+	// Members draining before removal, with their drain deadline
+	draining map[string]time.Time
+
+	// Departments with a cold start under way. Guarded by its own lock since
+	// the router requests cold starts while holding the manager lock.
+	coldStartMu  sync.Mutex
+	coldStarting map[string]bool
+
+	// Members being started right now, across all departments
+	launching int
+
+	// Cold starts waiting for a launch slot, keyed by department ID
+	deferredColdStarts map[string]*Department
+
+	// Cold starts running in the background, waited for by Stop
+	coldStarts sync.WaitGroup
+
+	// Departments whose scale-up was deferred by the per-tick cap; they go
+	// first on the next tick
+	throttled map[string]bool
+
+	// Time zone the scheduled rules are evaluated in
+	location *time.Location
+
+	// Smoothed (EWMA) utilization per department
+	utilization map[string]float64
+
+	// Members being launched but not registered yet, keyed by member ID, so
+	// their names are not handed out twice
+	launchingMembers map[string]*Member
+
+	// Recent scaling events, oldest first
+	history []ScalingEvent
+	events  *pubsub.Broker[*ScalingEvent]
+	// Scaling events ever recorded, by department and action
+	scaleCounts map[string]map[string]int
 
 	// Control
 	ctx    context.Context
@@ -28,31 +116,59 @@ type AutoScaler struct {
 func NewAutoScaler(config AutoScalingConfig, manager *Manager) *AutoScaler {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	location, err := time.LoadLocation(config.Timezone)
+	if err != nil {
+		slog.Warn("Invalid auto-scaling timezone, using UTC", "timezone", config.Timezone, "error", err)
+		location = time.UTC
+	}
+
 	return &AutoScaler{
-		config:        config,
-		manager:       manager,
-		lastScaleTime: make(map[string]time.Time),
-		scaleCooldown: make(map[string]time.Time),
-		ctx:           ctx,
-		cancel:        cancel,
+		config:             config,
+		manager:            manager,
+		lastScaleTime:      make(map[string]time.Time),
+		scaleCooldown:      make(map[string]time.Time),
+		lastScaleAction:    make(map[string]string),
+		scaleCounts:        make(map[string]map[string]int),
+		draining:           make(map[string]time.Time),
+		coldStarting:       make(map[string]bool),
+		deferredColdStarts: make(map[string]*Department),
+		throttled:          make(map[string]bool),
+		location:           location,
+		utilization:        make(map[string]float64),
+		launchingMembers:   make(map[string]*Member),
+		events:             pubsub.NewBroker[*ScalingEvent](),
+		ctx:                ctx,
+		cancel:             cancel,
 	}
 }
 
-// Start begins the auto-scaling process
+// Start begins the auto-scaling process. It runs until ctx ends or Stop is
+// called; either one stops the scaler for good.
 func (as *AutoScaler) Start(ctx context.Context) {
+	as.mu.Lock()
+	if as.ctx.Err() != nil {
+		// Stopped before it got to start
+		as.mu.Unlock()
+		return
+	}
+	as.isRunning = true
+	as.restoreDrains()
+	as.mu.Unlock()
+
 	slog.Info("Starting auto-scaler", "interval", as.config.CheckInterval)
 
+	// Ending the passed context stops the scaler like Stop, so requests
+	// made outside the check cycle are refused as well
+	stopAfter := context.AfterFunc(ctx, as.Stop)
+	defer stopAfter()
+
 	ticker := time.NewTicker(as.config.CheckInterval)
 	defer ticker.Stop()
 
-	as.isRunning = true
-
 	for {
 		select {
-		case <-ctx.Done():
-			slog.Info("Auto-scaler stopped")
-			return
 		case <-as.ctx.Done():
+			slog.Info("Auto-scaler stopped")
 			return
 		case <-ticker.C:
 			as.checkAndScale()
@@ -60,13 +176,37 @@ func (as *AutoScaler) Start(ctx context.Context) {
 	}
 }
 
-// Stop stops the auto-scaler
+// Stop stops the auto-scaler. A scaling check or cold start under way is cut
+// short and waited for, so no scaling action starts once Stop returns.
+// Stopping an already stopped scaler does nothing.
 func (as *AutoScaler) Stop() {
-	as.mu.Lock()
-	defer as.mu.Unlock()
+	as.halt()
 
-	as.isRunning = false
+	// Instances launched for members go down with the scaler that started
+	// them
+	if launcher, ok := as.manager.launcher.(stoppableLauncher); ok {
+		if err := launcher.Stop(context.Background()); err != nil {
+			slog.Error("Failed to stop member launcher", "error", err)
+		}
+	}
+}
+
+// halt stops the scaler like Stop but leaves launched instances running, so
+// members can finish their tasks before Stop takes them down
+func (as *AutoScaler) halt() {
+	// Cancel before taking the lock so a check holding it gives up early
 	as.cancel()
+
+	as.mu.Lock()
+	as.isRunning = false
+	as.events.Shutdown()
+	as.mu.Unlock()
+
+	// Cold starts are only begun under one of the two locks after checking
+	// the context, so none can begin once both have been taken
+	as.coldStartMu.Lock()
+	as.coldStartMu.Unlock()
+	as.coldStarts.Wait()
 }
 
 // checkAndScale evaluates all departments and scales them if needed
@@ -74,100 +214,425 @@ func (as *AutoScaler) checkAndScale() {
 	as.mu.Lock()
 	defer as.mu.Unlock()
 
-	if !as.isRunning {
+	if !as.isRunning || as.ctx.Err() != nil {
 		return
 	}
 
 	departments := as.manager.ListDepartments()
 	now := time.Now()
 
+	as.progressDrains(now)
+
+	// Departments deferred last tick scale up before the others
+	slices.SortStableFunc(departments, func(a, b *Department) int {
+		switch {
+		case as.throttled[a.ID] == as.throttled[b.ID]:
+			return 0
+		case as.throttled[a.ID]:
+			return -1
+		}
+		return 1
+	})
+	clear(as.throttled)
+
+	var (
+		scaledUp  int
+		throttled []string
+	)
 	for _, dept := range departments {
-		if !dept.AutoScale {
+		// Stop may have been called since the check began
+		if as.ctx.Err() != nil {
+			return
+		}
+		if !dept.AutoScale || dept.Disabled {
 			continue
 		}
 
-		// Check cooldown period
-		if cooldown, exists := as.scaleCooldown[dept.ID]; exists {
-			if now.Sub(cooldown) < as.config.CooldownPeriod {
+		// Evaluate scaling needs
+		action, reason := as.evaluateScalingNeeds(dept, now)
+		if action == scaleNone {
+			continue
+		}
+
+		// Scale-ups over the launch limits wait for the next tick, without
+		// starting a cooldown
+		if action == scaleUp {
+			if as.config.MaxScaleUpPerTick > 0 && scaledUp >= as.config.MaxScaleUpPerTick || !as.beginLaunch() {
+				throttled = append(throttled, dept.ID)
+				as.throttled[dept.ID] = true
 				continue
 			}
+			scaledUp++
+			as.executeScalingAction(dept, action, reason)
+			as.endLaunch()
+		} else {
+			as.executeScalingAction(dept, action, reason)
 		}
+		as.scaleCooldown[dept.ID] = now
+	}
 
-		// Evaluate scaling needs
-		action := as.evaluateScalingNeeds(dept)
-		if action != "none" {
-			as.executeScalingAction(dept, action)
-			as.scaleCooldown[dept.ID] = now
+	if len(throttled) > 0 {
+		slog.Info("Scale-ups throttled, deferring to next check",
+			"departments", throttled,
+			"scaled_up", scaledUp,
+			"launching", as.launching)
+	}
+}
+
+// beginLaunch reserves a launch slot, reporting false when
+// MaxConcurrentLaunches members are already starting. The caller must hold
+// as.mu and call endLaunch once the member has started or failed to.
+func (as *AutoScaler) beginLaunch() bool {
+	if as.config.MaxConcurrentLaunches > 0 && as.launching >= as.config.MaxConcurrentLaunches {
+		return false
+	}
+	as.launching++
+	return true
+}
+
+// endLaunch releases a launch slot and resumes a cold start that was waiting
+// for one. Once the scaler has stopped the waiting cold starts are dropped.
+// The caller must hold as.mu.
+func (as *AutoScaler) endLaunch() {
+	as.launching--
+
+	for id, dept := range as.deferredColdStarts {
+		delete(as.deferredColdStarts, id)
+		if as.ctx.Err() != nil {
+			as.coldStartMu.Lock()
+			delete(as.coldStarting, id)
+			as.coldStartMu.Unlock()
+			continue
 		}
+		as.goColdStart(dept)
+		break
 	}
 }
 
-// evaluateScalingNeeds determines if a department needs to scale up or down
-func (as *AutoScaler) evaluateScalingNeeds(dept *Department) string {
+// evaluateScalingNeeds gathers a department's current load and decides if it
+// needs to scale up or down
+func (as *AutoScaler) evaluateScalingNeeds(dept *Department, now time.Time) (string, string) {
 	stats, err := as.manager.GetDepartmentStats(dept.ID)
 	if err != nil {
 		slog.Warn("Failed to get department stats for scaling evaluation",
 			"department", dept.ID,
 			"error", err)
-		return "none"
+		return scaleNone, "stats_unavailable"
 	}
 
-	// Calculate utilization metrics
-	totalCapacity := stats.ActiveMembers * 5 // Assume 5 tasks per member average
 	activeTasks := as.countActiveTasks(dept.ID)
-	utilization := float64(activeTasks) / float64(totalCapacity)
+	capacity := as.departmentCapacity(dept.ID)
+	rawUtilization := departmentUtilization(capacity, activeTasks)
+	utilization := as.smoothUtilization(dept.ID, rawUtilization)
+
+	signals := scalingSignals{utilization: utilization}
+	if as.config.QueueWaitP95Target > 0 {
+		signals.queueWaitP95 = as.manager.QueueWaitPercentile(dept.ID, 95, now)
+	}
+	signals.queuedTasks, signals.oldestQueueWait = as.manager.QueueBacklog(dept.ID, now)
+
+	// An active schedule replaces the member bounds, and the department is
+	// brought within them before load is considered
+	config := as.config
+	if rule := as.activeSchedule(dept.ID, now); rule != nil {
+		if action, reason := decideScheduledBounds(dept, stats, rule); action != scaleNone {
+			slog.Debug("Department outside scheduled bounds",
+				"department", dept.ID,
+				"schedule", rule.Name,
+				"active_members", stats.ActiveMembers,
+				"action", action)
+			return action, reason
+		}
+		dept, config = applySchedule(dept, config, rule)
+	}
+
+	action, reason := decideScaling(dept, stats, signals, as.scaleCooldown[dept.ID], as.lastScaleAction[dept.ID], now, config)
 
 	slog.Debug("Department utilization",
 		"department", dept.ID,
 		"active_members", stats.ActiveMembers,
-		"total_capacity", totalCapacity,
 		"active_tasks", activeTasks,
-		"utilization", utilization)
+		"capacity", capacity,
+		"raw_utilization", rawUtilization,
+		"utilization", utilization,
+		"queue_wait_p95", signals.queueWaitP95,
+		"queued_tasks", signals.queuedTasks,
+		"oldest_queue_wait", signals.oldestQueueWait,
+		"action", action,
+		"reason", reason)
+
+	return action, reason
+}
+
+// departmentCapacity is how many tasks a department's active members can work
+// on at once. A role's CapacityPerMember overrides the members' own
+// MaxConcurrent.
+func (as *AutoScaler) departmentCapacity(departmentID string) int {
+	capacity := 0
+	for _, member := range as.manager.ListMembers(departmentID) {
+		if !isAvailable(member) {
+			continue
+		}
+		switch {
+		case as.config.CapacityPerMember[string(member.Role)] > 0:
+			capacity += as.config.CapacityPerMember[string(member.Role)]
+		case member.MaxConcurrent > 0:
+			capacity += member.MaxConcurrent
+		default:
+			capacity += defaultMemberCapacity
+		}
+	}
+	return capacity
+}
+
+// departmentUtilization is the share of a department's task capacity in use
+func departmentUtilization(capacity, activeTasks int) float64 {
+	if capacity > 0 {
+		return float64(activeTasks) / float64(capacity)
+	}
+	if activeTasks > 0 {
+		return 1.0
+	}
+	return 0
+}
+
+// smoothUtilization folds a raw utilization sample into the department's
+// exponentially weighted moving average and returns the updated value
+func (as *AutoScaler) smoothUtilization(departmentID string, sample float64) float64 {
+	alpha := as.config.UtilizationSmoothing
+	if alpha <= 0 || alpha > 1 {
+		alpha = defaultUtilizationSmoothing
+	}
+
+	previous, exists := as.utilization[departmentID]
+	if !exists {
+		as.utilization[departmentID] = sample
+		return sample
+	}
+
+	smoothed := alpha*sample + (1-alpha)*previous
+	as.utilization[departmentID] = smoothed
+	return smoothed
+}
+
+// scalingSignals are the load measurements a scaling decision is based on
+type scalingSignals struct {
+	// Smoothed share of the department's task capacity in use
+	utilization float64
+	// 95th percentile queue wait, zero unless a p95 target is configured
+	queueWaitP95 time.Duration
+	// Tasks waiting for a member and how long the oldest has waited
+	queuedTasks     int
+	oldestQueueWait time.Duration
+}
+
+// decideScaling maps a department's load signals onto a scaling action and
+// the reason for it. It has no side effects so the decision can be tested in
+// isolation; lastScaled is the zero time and lastAction empty if the
+// department was never scaled. The action is held back while the cooldown
+// for its direction runs, or the longer one when it reverses lastAction.
+func decideScaling(dept *Department, stats *DepartmentStats, signals scalingSignals, lastScaled time.Time, lastAction string, now time.Time, config AutoScalingConfig) (string, string) {
+	action, reason := decideScalingAction(dept, stats, signals, config)
+	if action != scaleNone && !lastScaled.IsZero() && now.Sub(lastScaled) < config.cooldownAfter(lastAction, action) {
+		return scaleNone, "cooldown"
+	}
+	return action, reason
+}
+
+// decideScalingAction is decideScaling without the cooldown
+func decideScalingAction(dept *Department, stats *DepartmentStats, signals scalingSignals, config AutoScalingConfig) (string, string) {
+	// Zero limits mean no limit
+	atMaxMembers := (config.MaxMembersPerDept > 0 && stats.ActiveMembers >= config.MaxMembersPerDept) ||
+		(dept.MaxMembers > 0 && stats.TotalMembers >= dept.MaxMembers)
+
+	// Scale up if tasks wait too long, regardless of utilization
+	if config.QueueWaitP95Target > 0 && signals.queueWaitP95 > config.QueueWaitP95Target {
+		if atMaxMembers {
+			return scaleNone, "at_max_members"
+		}
+		return scaleUp, "queue_wait_p95"
+	}
+	if config.MaxQueueWait > 0 && signals.oldestQueueWait > config.MaxQueueWait {
+		if atMaxMembers {
+			return scaleNone, "at_max_members"
+		}
+		return scaleUp, "queue_wait_exceeded"
+	}
 
 	// Scale up if utilization is high
-	if utilization > as.config.ScaleUpThreshold {
-		if stats.ActiveMembers < as.config.MaxMembersPerDept {
-			if len(as.membersByRole(dept.ID)) < dept.MaxMembers {
-				return "scale_up"
-			}
+	if signals.utilization > config.ScaleUpThreshold {
+		if atMaxMembers {
+			return scaleNone, "at_max_members"
 		}
+		return scaleUp, "high_utilization"
 	}
 
-	// Scale down if utilization is low
-	if utilization < as.config.ScaleDownThreshold {
-		if stats.ActiveMembers > dept.MinMembers {
-			return "scale_down"
+	// Scale down if utilization is low, unless work is still waiting
+	if signals.utilization < config.ScaleDownThreshold {
+		if stats.ActiveMembers <= dept.MinMembers {
+			return scaleNone, "at_min_members"
+		}
+		if signals.queuedTasks > 0 {
+			return scaleNone, "tasks_queued"
 		}
+		return scaleDown, "low_utilization"
+	}
+
+	return scaleNone, "within_thresholds"
+}
+
+// RequestScaleUp adds a member to a department outside the regular check
+// cycle, for example when tasks wait too long for assignment. The request is
+// ignored while the department is cooling down or already at its maximum size.
+func (as *AutoScaler) RequestScaleUp(dept *Department, reason string) bool {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if as.ctx.Err() != nil || !dept.AutoScale || dept.Disabled {
+		return false
+	}
+
+	now := time.Now()
+	if last, exists := as.lastScaleTime[dept.ID]; exists && now.Sub(last) < as.config.cooldownAfter(as.lastScaleAction[dept.ID], scaleUp) {
+		slog.Debug("Scale up request ignored during cooldown",
+			"department", dept.ID,
+			"reason", reason,
+			"last_action", as.lastScaleAction[dept.ID])
+		return false
+	}
+
+	members := len(as.manager.ListMembers(dept.ID))
+	if (dept.MaxMembers > 0 && members >= dept.MaxMembers) || (as.config.MaxMembersPerDept > 0 && members >= as.config.MaxMembersPerDept) {
+		slog.Debug("Scale up request ignored at max members", "department", dept.ID, "reason", reason)
+		return false
+	}
+
+	if !as.beginLaunch() {
+		slog.Debug("Scale up request ignored at max concurrent launches", "department", dept.ID, "reason", reason)
+		return false
 	}
+	defer as.endLaunch()
 
-	return "none"
+	as.executeScalingAction(dept, scaleUp, reason)
+	as.scaleCooldown[dept.ID] = now
+	return true
 }
 
 // executeScalingAction performs the actual scaling
-func (as *AutoScaler) executeScalingAction(dept *Department, action string) {
+func (as *AutoScaler) executeScalingAction(dept *Department, action, reason string) {
+	before := len(as.manager.ListMembers(dept.ID))
+
+	var member *Member
 	switch action {
-	case "scale_up":
-		as.scaleUp(dept)
-	case "scale_down":
-		as.scaleDown(dept)
+	case scaleUp:
+		member = as.scaleUp(dept, reason)
+	case scaleDown:
+		member = as.scaleDown(dept)
+	}
+
+	now := time.Now()
+	as.lastScaleTime[dept.ID] = now
+	as.lastScaleAction[dept.ID] = action
+
+	if member != nil {
+		as.recordScalingEvent(ScalingEvent{
+			DepartmentID:  dept.ID,
+			Action:        action,
+			MemberID:      member.ID,
+			Role:          member.Role,
+			Reason:        reason,
+			MembersBefore: before,
+			MembersAfter:  len(as.manager.ListMembers(dept.ID)),
+			Timestamp:     now,
+		})
+	}
+}
+
+// recordScalingEvent appends an event to the bounded history and publishes it
+func (as *AutoScaler) recordScalingEvent(event ScalingEvent) {
+	size := as.config.ScalingHistorySize
+	if size <= 0 {
+		size = defaultScalingHistorySize
+	}
+
+	as.history = append(as.history, event)
+	if len(as.history) > size {
+		as.history = slices.Clone(as.history[len(as.history)-size:])
+	}
+
+	if as.scaleCounts[event.DepartmentID] == nil {
+		as.scaleCounts[event.DepartmentID] = make(map[string]int)
+	}
+	as.scaleCounts[event.DepartmentID][event.Action]++
+
+	as.events.Publish(ScaledEvent, &event)
+}
+
+// GetScalingHistory returns up to limit of the most recent scaling events,
+// oldest first. A limit of zero or less returns the whole history.
+func (as *AutoScaler) GetScalingHistory(limit int) []ScalingEvent {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	history := as.history
+	if limit > 0 && len(history) > limit {
+		history = history[len(history)-limit:]
 	}
+	return slices.Clone(history)
+}
 
-	as.lastScaleTime[dept.ID] = time.Now()
+// SubscribeToScalingEvents returns a channel of scaling events
+func (as *AutoScaler) SubscribeToScalingEvents(ctx context.Context) <-chan pubsub.Event[*ScalingEvent] {
+	return as.events.Subscribe(ctx)
 }
 
-// scaleUp adds a new member to the department
-func (as *AutoScaler) scaleUp(dept *Department) {
+// scaleUp adds a new member to the department and returns it, or nil if no
+// member was added
+func (as *AutoScaler) scaleUp(dept *Department, reason string) *Member {
 	// Determine which role to add based on current needs
 	role := as.determineRoleToAdd(dept)
 	if role == "" {
 		slog.Info("Cannot determine role to add", "department", dept.ID)
-		return
+		return nil
+	}
+
+	member := as.newScaledMember(dept, role, reason)
+	defer delete(as.launchingMembers, member.ID)
+	if err := as.launchMember(member); err != nil {
+		slog.Error("Failed to launch auto-scaled member",
+			"department", dept.ID,
+			"role", role,
+			"error", err)
+		return nil
+	}
+
+	// Register the new member
+	if err := as.manager.RegisterMember(context.Background(), member); err != nil {
+		slog.Error("Failed to register auto-scaled member",
+			"department", dept.ID,
+			"role", role,
+			"error", err)
+		if as.manager.launcher != nil {
+			as.terminateMember(member.ID)
+		}
+		return nil
 	}
 
-	// Create a new member configuration
+	slog.Info("Auto-scaled up department",
+		"department", dept.ID,
+		"member_id", member.ID,
+		"role", role)
+
+	return member
+}
+
+// newScaledMember creates the configuration of a member to add to a
+// department and tracks it as launching until the caller removes it from
+// launchingMembers. The caller must hold the scaler lock.
+func (as *AutoScaler) newScaledMember(dept *Department, role, reason string) *Member {
 	member := &Member{
-		ID:              fmt.Sprintf("member-%s-%d", dept.ID, time.Now().Unix()),
-		Name:            fmt.Sprintf("Auto-Scaled %s", role),
+		ID:              fmt.Sprintf("member-%s-%d", dept.ID, time.Now().UnixNano()),
+		Name:            as.nextMemberName(dept, role),
 		Role:            MemberRole(role),
 		DepartmentID:    dept.ID,
 		DepartmentType:  dept.Type,
@@ -175,50 +640,63 @@ func (as *AutoScaler) scaleUp(dept *Department) {
 		Specializations: as.getRoleSpecializations(role),
 		CurrentTasks:    []string{},
 		MaxConcurrent:   as.getRoleMaxConcurrent(role),
-		Endpoint:        fmt.Sprintf("http://localhost:8080/members/%s", dept.ID),
 		AuthMethod:      "api-key",
 		HealthScore:     1.0,
 		Performance:     make(map[string]float64),
 		Capabilities:    as.getRoleCapabilities(role),
-		IsLead:          isLeadRole(MemberRole(role)),
+		IsLead:          as.manager.config.isLeadRole(MemberRole(role)),
 		Metadata: map[string]string{
 			"auto_scaled":    "true",
 			"created_at":     time.Now().Format(time.RFC3339),
-			"scaling_reason": "high_utilization",
+			"scaling_reason": reason,
 		},
 	}
+	as.launchingMembers[member.ID] = member
+	return member
+}
 
-	// Register the new member
-	if err := as.manager.RegisterMember(context.Background(), member); err != nil {
-		slog.Error("Failed to register auto-scaled member",
-			"department", dept.ID,
-			"role", role,
-			"error", err)
-		return
+// launchMember starts an instance for the member when a launcher is
+// configured and records its endpoint. Without one the member is only a
+// placeholder for an instance started elsewhere.
+func (as *AutoScaler) launchMember(member *Member) error {
+	launcher := as.manager.launcher
+	if launcher == nil {
+		member.Endpoint = fmt.Sprintf("http://localhost:8080/members/%s", member.DepartmentID)
+		return nil
 	}
 
-	slog.Info("Auto-scaled up department",
-		"department", dept.ID,
-		"member_id", member.ID,
-		"role", role)
+	endpoint, err := launcher.Launch(as.ctx, MemberSpec{
+		ID:              member.ID,
+		Name:            member.Name,
+		DepartmentID:    member.DepartmentID,
+		Role:            member.Role,
+		Specializations: member.Specializations,
+		MaxConcurrent:   member.MaxConcurrent,
+	})
+	if err != nil {
+		return err
+	}
+	member.Endpoint = endpoint
+	return nil
 }
 
-// scaleDown removes a member from the department
-func (as *AutoScaler) scaleDown(dept *Department) {
+// scaleDown removes an idle member from the department and returns it, or
+// nil if no member was removed
+func (as *AutoScaler) scaleDown(dept *Department) *Member {
 	// Find a member that can be safely removed
 	candidate := as.findScaleDownCandidate(dept)
 	if candidate == nil {
 		slog.Info("No suitable candidate for scale down", "department", dept.ID)
-		return
+		return nil
 	}
 
-	// Ensure member has no active tasks
-	if len(candidate.CurrentTasks) > 0 {
-		slog.Info("Cannot scale down: candidate has active tasks",
-			"department", dept.ID,
-			"member_id", candidate.ID,
-			"active_tasks", len(candidate.CurrentTasks))
-		return
+	// Busy members drain first and are removed once their tasks are done
+	as.manager.mu.RLock()
+	activeTasks := len(candidate.CurrentTasks)
+	as.manager.mu.RUnlock()
+	if activeTasks > 0 {
+		as.startDrain(candidate, activeTasks)
+		return nil
 	}
 
 	// Unregister the member
@@ -227,17 +705,154 @@ func (as *AutoScaler) scaleDown(dept *Department) {
 			"department", dept.ID,
 			"member_id", candidate.ID,
 			"error", err)
-		return
+		return nil
+	}
+
+	if as.manager.launcher != nil && candidate.Metadata["auto_scaled"] == "true" {
+		as.terminateMember(candidate.ID)
 	}
 
 	slog.Info("Auto-scaled down department",
 		"department", dept.ID,
 		"member_id", candidate.ID,
 		"role", string(candidate.Role))
+
+	return candidate
+}
+
+// startDrain stops routing new tasks to a member so it can be removed once
+// its current tasks are done
+func (as *AutoScaler) startDrain(member *Member, activeTasks int) {
+	if err := as.manager.UpdateMemberStatus(context.Background(), member.ID, MemberStatusDraining); err != nil {
+		slog.Error("Failed to drain member during scale down",
+			"member_id", member.ID,
+			"error", err)
+		return
+	}
+
+	timeout := as.drainTimeout()
+	as.draining[member.ID] = time.Now().Add(timeout)
+
+	slog.Info("Draining member for scale down",
+		"department", member.DepartmentID,
+		"member_id", member.ID,
+		"active_tasks", activeTasks,
+		"drain_timeout", timeout)
+}
+
+// restoreDrains tracks members left draining by an earlier run, for example
+// one loaded from persisted state, so they are removed rather than left
+// draining forever. Their drain deadline was not kept, so they get a fresh
+// one. The caller must hold the scaler lock.
+func (as *AutoScaler) restoreDrains() {
+	deadline := time.Now().Add(as.drainTimeout())
+
+	as.manager.mu.RLock()
+	defer as.manager.mu.RUnlock()
+
+	for id, member := range as.manager.members {
+		if _, tracked := as.draining[id]; member.Status == MemberStatusDraining && !tracked {
+			as.draining[id] = deadline
+			slog.Info("Resuming drain of member", "member_id", id)
+		}
+	}
+}
+
+// drainTimeout returns how long a draining member may keep its tasks
+func (as *AutoScaler) drainTimeout() time.Duration {
+	if as.config.DrainTimeout <= 0 {
+		return defaultDrainTimeout
+	}
+	return as.config.DrainTimeout
+}
+
+// progressDrains removes draining members whose tasks are done. Members
+// still busy at their drain deadline have their tasks reassigned first.
+func (as *AutoScaler) progressDrains(now time.Time) {
+	for memberID, deadline := range as.draining {
+		member, err := as.manager.GetMember(memberID)
+		if err != nil {
+			// Already removed elsewhere
+			delete(as.draining, memberID)
+			continue
+		}
+
+		as.manager.mu.RLock()
+		activeTasks := len(member.CurrentTasks)
+		as.manager.mu.RUnlock()
+
+		reason := "drained"
+		if activeTasks > 0 {
+			if now.Before(deadline) {
+				continue
+			}
+			moved, err := as.manager.taskRouter.ReassignMemberTasks(as.ctx, memberID, "drain_timeout")
+			if err != nil {
+				slog.Warn("Some tasks of a drained member could not be reassigned",
+					"member_id", memberID,
+					"error", err)
+			}
+			slog.Info("Drain timed out, reassigned remaining tasks",
+				"member_id", memberID,
+				"reassigned", moved)
+			reason = "drain_timeout"
+		}
+
+		as.removeDrainedMember(member, reason, now)
+	}
+}
+
+// removeDrainedMember unregisters a member that has finished draining and
+// records the scale-down
+func (as *AutoScaler) removeDrainedMember(member *Member, reason string, now time.Time) {
+	before := len(as.manager.ListMembers(member.DepartmentID))
+	if err := as.manager.UnregisterMember(context.Background(), member.ID); err != nil {
+		// Try again on the next check
+		slog.Warn("Failed to remove drained member",
+			"member_id", member.ID,
+			"error", err)
+		return
+	}
+	delete(as.draining, member.ID)
+
+	if as.manager.launcher != nil && member.Metadata["auto_scaled"] == "true" {
+		as.terminateMember(member.ID)
+	}
+
+	slog.Info("Auto-scaled down department",
+		"department", member.DepartmentID,
+		"member_id", member.ID,
+		"role", string(member.Role),
+		"reason", reason)
+
+	as.recordScalingEvent(ScalingEvent{
+		DepartmentID:  member.DepartmentID,
+		Action:        scaleDown,
+		MemberID:      member.ID,
+		Role:          member.Role,
+		Reason:        reason,
+		MembersBefore: before,
+		MembersAfter:  len(as.manager.ListMembers(member.DepartmentID)),
+		Timestamp:     now,
+	})
+}
+
+// terminateMember stops the instance launched for an auto-scaled member
+func (as *AutoScaler) terminateMember(memberID string) {
+	if err := as.manager.launcher.Terminate(context.Background(), memberID); err != nil {
+		slog.Error("Failed to terminate auto-scaled member",
+			"member_id", memberID,
+			"error", err)
+	}
 }
 
 // determineRoleToAdd decides which role should be added to a department
 func (as *AutoScaler) determineRoleToAdd(dept *Department) string {
+	// The role blocking the most queued work is the bottleneck
+	if role := as.bottleneckRole(dept.ID); role != "" {
+		return role
+	}
+
 	// Check role-specific scaling rules
 	if as.config.RoleScaling != nil {
 		currentRoles := as.membersByRole(dept.ID)
@@ -265,7 +880,16 @@ func (as *AutoScaler) determineRoleToAdd(dept *Department) string {
 		DepartmentQA:          {"qa", "lead_test", "qa"},
 	}
 
-	if roles, exists := roleMap[dept.Type]; exists {
+	// Defined roles join the built-in ones of the departments they serve
+	roles := roleMap[dept.Type]
+	for _, role := range as.manager.config.roles() {
+		def, ok := as.manager.config.roleDefinition(string(role))
+		if ok && slices.Contains(def.DepartmentTypes, string(dept.Type)) && !slices.Contains(roles, string(role)) {
+			roles = append(roles, string(role))
+		}
+	}
+
+	if len(roles) > 0 {
 		// Return the role with the fewest members
 		roleCounts := as.membersByRole(dept.ID)
 		minCount := 999
@@ -290,16 +914,73 @@ func (as *AutoScaler) determineRoleToAdd(dept *Department) string {
 	return ""
 }
 
+// nextMemberName renders the department's member name template with the
+// next index. The index follows the highest one in the names of the
+// department's members and of those still launching, so it survives restarts
+// and is only used up by a member that gets registered. The caller must hold
+// the scaler lock.
+func (as *AutoScaler) nextMemberName(dept *Department, role string) string {
+	template := dept.MemberNameTemplate
+	if template == "" {
+		template = defaultMemberNameTemplate
+	}
+	fill := strings.NewReplacer("{department}", dept.ID, "{role}", role)
+	if !strings.Contains(template, "{index}") {
+		return fill.Replace(template)
+	}
+
+	// Match names rendered from the same template with any index
+	parts := strings.Split(template, "{index}")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(fill.Replace(part))
+	}
+	pattern := regexp.MustCompile("^" + strings.Join(parts, `(\d+)`) + "$")
+
+	highest := 0
+	useIndex := func(name string) {
+		match := pattern.FindStringSubmatch(name)
+		if match == nil {
+			return
+		}
+		for _, index := range match[1:] {
+			if n, err := strconv.Atoi(index); err == nil {
+				highest = max(highest, n)
+			}
+		}
+	}
+	as.manager.mu.RLock()
+	for _, member := range as.manager.listMembers(dept.ID) {
+		useIndex(member.Name)
+	}
+	as.manager.mu.RUnlock()
+	for _, member := range as.launchingMembers {
+		if member.DepartmentID == dept.ID {
+			useIndex(member.Name)
+		}
+	}
+
+	return strings.ReplaceAll(fill.Replace(template), "{index}", fmt.Sprintf("%02d", highest+1))
+}
+
 // findScaleDownCandidate finds a member that can be safely removed
 func (as *AutoScaler) findScaleDownCandidate(dept *Department) *Member {
-	members := as.manager.ListMembers(dept.ID)
+	as.manager.mu.RLock()
+	defer as.manager.mu.RUnlock()
 
-	// Prefer non-lead, auto-scaled members with no active tasks
+	members := as.manager.listMembers(dept.ID)
+
+	// Prefer non-lead, auto-scaled members
 	var candidates []*Member
 
 	for _, member := range members {
-		// Skip lead members if there are other members
-		if member.IsLead && len(members) > dept.MinMembers {
+		// Draining members are already on their way out
+		if member.Status == MemberStatusDraining {
+			continue
+		}
+
+		// Skip lead members if there are other members. Departments that
+		// scale to zero may remove their last lead.
+		if member.IsLead && len(members) > dept.MinMembers && !(dept.ScaleToZero && len(members) == 1) {
 			continue
 		}
 
@@ -308,33 +989,31 @@ func (as *AutoScaler) findScaleDownCandidate(dept *Department) *Member {
 			continue
 		}
 
-		// Must have no active tasks
-		if len(member.CurrentTasks) == 0 {
-			candidates = append(candidates, member)
-		}
+		candidates = append(candidates, member)
 	}
 
-	// If no auto-scaled candidates, consider any non-lead with no tasks
+	// If no auto-scaled candidates, consider any non-lead
 	if len(candidates) == 0 {
 		for _, member := range members {
-			if !member.IsLead && len(member.CurrentTasks) == 0 {
+			if (!member.IsLead || dept.ScaleToZero && len(members) == 1) && member.Status != MemberStatusDraining {
 				candidates = append(candidates, member)
 			}
 		}
 	}
 
-	// Select the newest member (most likely to be auto-scaled)
-	if len(candidates) > 0 {
-		var newest *Member
-		for _, candidate := range candidates {
-			if newest == nil || candidate.JoinedAt.After(newest.JoinedAt) {
-				newest = candidate
-			}
+	// Select the member with the least work to drain, then the newest (most
+	// likely to be auto-scaled)
+	var best *Member
+	for _, candidate := range candidates {
+		switch {
+		case best == nil,
+			len(candidate.CurrentTasks) < len(best.CurrentTasks),
+			len(candidate.CurrentTasks) == len(best.CurrentTasks) && candidate.JoinedAt.After(best.JoinedAt):
+			best = candidate
 		}
-		return newest
 	}
 
-	return nil
+	return best
 }
 
 // Helper functions
@@ -344,6 +1023,76 @@ func (as *AutoScaler) countActiveTasks(departmentID string) int {
 	return len(tasks)
 }
 
+// bottleneckRole returns the role with the largest unmet demand in a
+// department, or "" if no queued task is waiting on a role. A queued task
+// demands every role it requires, by RequiredRoles, AssignedRole or a
+// required skill naming a role, weighted by the task's weight. Room left on
+// available members of a role offsets its demand.
+func (as *AutoScaler) bottleneckRole(departmentID string) string {
+	as.manager.mu.RLock()
+	defer as.manager.mu.RUnlock()
+
+	roles := as.manager.config.roles()
+	demand := make(map[MemberRole]float64)
+	for _, task := range as.manager.queued[departmentID] {
+		for _, role := range demandedRoles(task, roles) {
+			demand[role] += taskWeight(task)
+		}
+	}
+	if len(demand) == 0 {
+		return ""
+	}
+
+	for _, member := range as.manager.listMembers(departmentID) {
+		if _, demanded := demand[member.Role]; demanded && isAvailable(member) {
+			demand[member.Role] -= as.manager.remainingUnits(member)
+		}
+	}
+
+	var (
+		bottleneck MemberRole
+		largest    float64
+	)
+	for _, role := range roles {
+		if demand[role] > largest {
+			bottleneck, largest = role, demand[role]
+		}
+	}
+	if bottleneck != "" {
+		slog.Debug("Scaling the bottleneck role",
+			"department", departmentID,
+			"role", string(bottleneck),
+			"unmet_demand", largest)
+	}
+	return string(bottleneck)
+}
+
+// demandedRoles returns the roles a task requires, each once. Required skills
+// naming one of roles demand that role.
+func demandedRoles(task *Task, roles []MemberRole) []MemberRole {
+	var demanded []MemberRole
+	add := func(role MemberRole) {
+		if !slices.Contains(demanded, role) {
+			demanded = append(demanded, role)
+		}
+	}
+
+	for _, role := range task.RequiredRoles {
+		add(role)
+	}
+	if task.AssignedRole != "" {
+		add(task.AssignedRole)
+	}
+	for _, skill := range task.RequiredSkills {
+		for _, role := range roles {
+			if strings.EqualFold(skill, string(role)) {
+				add(role)
+			}
+		}
+	}
+	return demanded
+}
+
 func (as *AutoScaler) membersByRole(departmentID string) []string {
 	members := as.manager.ListMembers(departmentID)
 	var roles []string
@@ -355,7 +1104,13 @@ func (as *AutoScaler) membersByRole(departmentID string) []string {
 	return roles
 }
 
+// getRoleSpecializations returns the skills of a new member in role: the
+// required skills of its definition, or the built-in ones
 func (as *AutoScaler) getRoleSpecializations(role string) []string {
+	if def, ok := as.manager.config.roleDefinition(role); ok && len(def.RequiredSkills) > 0 {
+		return slices.Clone(def.RequiredSkills)
+	}
+
 	specializations := map[string][]string{
 		"ba":           {"requirements", "analysis", "user-stories", "business-process"},
 		"pm":           {"planning", "coordination", "risk-management", "stakeholder-management"},
@@ -377,7 +1132,13 @@ func (as *AutoScaler) getRoleSpecializations(role string) []string {
 	return []string{"general"}
 }
 
+// getRoleMaxConcurrent returns the concurrency of a new member in role,
+// from its definition when that sets one
 func (as *AutoScaler) getRoleMaxConcurrent(role string) int {
+	if def, ok := as.manager.config.roleDefinition(role); ok && def.MaxConcurrent > 0 {
+		return def.MaxConcurrent
+	}
+
 	concurrency := map[string]int{
 		"ba":            3,
 		"pm":            5,
@@ -399,7 +1160,20 @@ func (as *AutoScaler) getRoleMaxConcurrent(role string) int {
 	return 3
 }
 
+// getRoleCapabilities returns the capabilities of a new member in role. A
+// definition grants its responsibilities and default tools.
 func (as *AutoScaler) getRoleCapabilities(role string) map[string]interface{} {
+	if def, ok := as.manager.config.roleDefinition(role); ok && (len(def.Responsibilities) > 0 || len(def.DefaultTools) > 0) {
+		caps := make(map[string]interface{}, len(def.Responsibilities)+1)
+		for _, responsibility := range def.Responsibilities {
+			caps[responsibility] = true
+		}
+		if len(def.DefaultTools) > 0 {
+			caps["tools"] = slices.Clone(def.DefaultTools)
+		}
+		return caps
+	}
+
 	capabilities := map[string]map[string]interface{}{
 		"ba": {
 			"requirements_analysis": true,
@@ -460,16 +1234,57 @@ func (as *AutoScaler) getRoleCapabilities(role string) map[string]interface{} {
 	return map[string]interface{}{"general": true}
 }
 
+// departmentState returns the scaling state of a department. The scaler
+// tracks draining members of every department together, so only those that
+// still belong to the department are reported.
+func (as *AutoScaler) departmentState(departmentID string) DepartmentScalingState {
+	as.mu.RLock()
+	state := DepartmentScalingState{
+		LastScaleTime:   as.lastScaleTime[departmentID],
+		LastScaleAction: as.lastScaleAction[departmentID],
+		Utilization:     as.utilization[departmentID],
+	}
+	draining := slices.Sorted(maps.Keys(as.draining))
+	if lastScaled := as.scaleCooldown[departmentID]; !lastScaled.IsZero() {
+		now := time.Now()
+		if until := lastScaled.Add(as.config.cooldownAfter(state.LastScaleAction, scaleUp)); until.After(now) {
+			state.ScaleUpCooldownUntil = until
+		}
+		if until := lastScaled.Add(as.config.cooldownAfter(state.LastScaleAction, scaleDown)); until.After(now) {
+			state.ScaleDownCooldownUntil = until
+		}
+	}
+	as.mu.RUnlock()
+
+	as.manager.mu.RLock()
+	for _, memberID := range draining {
+		if member, exists := as.manager.members[memberID]; exists && member.DepartmentID == departmentID {
+			state.DrainingMembers = append(state.DrainingMembers, memberID)
+		}
+	}
+	as.manager.mu.RUnlock()
+
+	as.coldStartMu.Lock()
+	state.ColdStarting = as.coldStarting[departmentID]
+	as.coldStartMu.Unlock()
+
+	return state
+}
+
 // GetScalingStatus returns the current scaling status
 func (as *AutoScaler) GetScalingStatus() map[string]interface{} {
 	as.mu.RLock()
 	defer as.mu.RUnlock()
 
+	// Copies, since the scaler keeps updating its maps under as.mu
 	status := make(map[string]interface{})
 	status["is_running"] = as.isRunning
-	status["last_scale_times"] = as.lastScaleTime
-	status["scale_cooldowns"] = as.scaleCooldown
+	status["last_scale_times"] = maps.Clone(as.lastScaleTime)
+	status["scale_cooldowns"] = maps.Clone(as.scaleCooldown)
+	status["last_scale_actions"] = maps.Clone(as.lastScaleAction)
+	status["launching"] = as.launching
+	status["utilization"] = maps.Clone(as.utilization)
 	status["config"] = as.config
 
 	return status
-}
\ No newline at end of file
+}
\ No newline at end of file
@@ -0,0 +1,849 @@
+package department
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoScalerSmoothingReducesFlapping(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+	config := AutoScalingConfig{
+		ScaleUpThreshold:     0.8,
+		ScaleDownThreshold:   0.2,
+		MaxMembersPerDept:    10,
+		UtilizationSmoothing: 0.3,
+	}
+	as := NewAutoScaler(config, m)
+
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	stats := &DepartmentStats{DepartmentID: dept.ID, ActiveMembers: 4, TotalMembers: 4}
+
+	// Moderate load with short spikes and dips on either side of the thresholds
+	series := []float64{0.5, 0.95, 0.45, 0.1, 0.55, 0.9, 0.5, 0.15, 0.6, 0.92, 0.4, 0.05}
+
+	now := time.Now()
+	rawActions, smoothedActions := 0, 0
+	for _, sample := range series {
+		if action, _ := decideScaling(dept, stats, scalingSignals{utilization: sample}, time.Time{}, "", now, config); action != scaleNone {
+			rawActions++
+		}
+		smoothed := as.smoothUtilization(dept.ID, sample)
+		if action, _ := decideScaling(dept, stats, scalingSignals{utilization: smoothed}, time.Time{}, "", now, config); action != scaleNone {
+			smoothedActions++
+		}
+	}
+
+	require.Equal(t, 6, rawActions)
+	require.Less(t, smoothedActions, rawActions)
+}
+
+func TestAutoScalerSmoothingFollowsSustainedLoad(t *testing.T) {
+	t.Parallel()
+
+	as := NewAutoScaler(AutoScalingConfig{UtilizationSmoothing: 0.5}, nil)
+
+	require.Equal(t, 0.0, as.smoothUtilization("dept", 0))
+	require.Equal(t, 0.5, as.smoothUtilization("dept", 1))
+	require.Equal(t, 0.75, as.smoothUtilization("dept", 1))
+	require.Equal(t, 0.875, as.smoothUtilization("dept", 1))
+
+	// Departments are tracked independently
+	require.Equal(t, 1.0, as.smoothUtilization("other", 1))
+}
+
+func TestAutoScalerMemberNameTemplate(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+	as := NewAutoScaler(AutoScalingConfig{RoleScaling: map[string]int{"developer": 5}}, m)
+
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	dept.MemberNameTemplate = "dev-svc-{role}-{index}"
+
+	for range 3 {
+		as.scaleUp(dept, "high_utilization")
+	}
+
+	var names []string
+	for _, member := range m.ListMembers(dept.ID) {
+		names = append(names, member.Name)
+	}
+	require.ElementsMatch(t, []string{
+		"dev-svc-developer-01",
+		"dev-svc-developer-02",
+		"dev-svc-developer-03",
+	}, names)
+
+	// Departments without a template keep the default naming
+	qa, err := m.GetDepartment("dept-qa")
+	require.NoError(t, err)
+	require.Equal(t, "Auto-Scaled qa", as.nextMemberName(qa, "qa"))
+
+	// A restarted scaler continues after the existing members, and a launch
+	// that fails does not use up an index
+	as = NewAutoScaler(AutoScalingConfig{RoleScaling: map[string]int{"developer": 5}}, m)
+	m.launcher = failingLauncher{}
+	require.Nil(t, as.scaleUp(dept, "high_utilization"))
+	m.launcher = nil
+	member := as.scaleUp(dept, "high_utilization")
+	require.NotNil(t, member)
+	require.Equal(t, "dev-svc-developer-04", member.Name)
+}
+
+// failingLauncher fails every launch
+type failingLauncher struct{}
+
+func (failingLauncher) Launch(ctx context.Context, spec MemberSpec) (string, error) {
+	return "", fmt.Errorf("no capacity for %s", spec.ID)
+}
+
+func (failingLauncher) Terminate(ctx context.Context, memberID string) error {
+	return nil
+}
+
+func TestDecideScaling(t *testing.T) {
+	t.Parallel()
+
+	config := AutoScalingConfig{
+		ScaleUpThreshold:   0.8,
+		ScaleDownThreshold: 0.2,
+		MaxMembersPerDept:  6,
+		CooldownPeriod:     5 * time.Minute,
+		MaxQueueWait:       10 * time.Minute,
+	}
+	dept := &Department{ID: "dept", MinMembers: 2, MaxMembers: 5}
+	now := time.Now()
+
+	tests := []struct {
+		name            string
+		activeMembers   int
+		totalMembers    int
+		activeTasks     int
+		queuedTasks     int
+		oldestQueueWait time.Duration
+		lastScaled      time.Time
+		noDeptMax       bool
+		noConfigMax     bool
+		expectedAction  string
+		expectedReason  string
+	}{
+		{
+			name:           "scale up under high load",
+			activeMembers:  3,
+			totalMembers:   3,
+			activeTasks:    14,
+			expectedAction: scaleUp,
+			expectedReason: "high_utilization",
+		},
+		{
+			name:           "scale down under low load",
+			activeMembers:  4,
+			totalMembers:   4,
+			activeTasks:    1,
+			expectedAction: scaleDown,
+			expectedReason: "low_utilization",
+		},
+		{
+			name:           "blocked at department max",
+			activeMembers:  5,
+			totalMembers:   5,
+			activeTasks:    25,
+			expectedAction: scaleNone,
+			expectedReason: "at_max_members",
+		},
+		{
+			name:           "blocked at config max",
+			activeMembers:  6,
+			totalMembers:   4,
+			activeTasks:    30,
+			expectedAction: scaleNone,
+			expectedReason: "at_max_members",
+		},
+		{
+			name:           "blocked at min members",
+			activeMembers:  2,
+			totalMembers:   2,
+			activeTasks:    0,
+			expectedAction: scaleNone,
+			expectedReason: "at_min_members",
+		},
+		{
+			name:           "within thresholds",
+			activeMembers:  3,
+			totalMembers:   3,
+			activeTasks:    7,
+			expectedAction: scaleNone,
+			expectedReason: "within_thresholds",
+		},
+		{
+			name:           "cooldown after recent scaling",
+			activeMembers:  3,
+			totalMembers:   3,
+			activeTasks:    14,
+			lastScaled:     now.Add(-time.Minute),
+			expectedAction: scaleNone,
+			expectedReason: "cooldown",
+		},
+		{
+			name:           "cooldown elapsed",
+			activeMembers:  3,
+			totalMembers:   3,
+			activeTasks:    14,
+			lastScaled:     now.Add(-10 * time.Minute),
+			expectedAction: scaleUp,
+			expectedReason: "high_utilization",
+		},
+		{
+			name:            "old queued tasks under low load",
+			activeMembers:   4,
+			totalMembers:    4,
+			activeTasks:     1,
+			queuedTasks:     3,
+			oldestQueueWait: 15 * time.Minute,
+			expectedAction:  scaleUp,
+			expectedReason:  "queue_wait_exceeded",
+		},
+		{
+			name:            "queued tasks block scale down",
+			activeMembers:   4,
+			totalMembers:    4,
+			activeTasks:     1,
+			queuedTasks:     2,
+			oldestQueueWait: time.Minute,
+			expectedAction:  scaleNone,
+			expectedReason:  "tasks_queued",
+		},
+		{
+			name:            "old queued tasks during cooldown",
+			activeMembers:   4,
+			totalMembers:    4,
+			queuedTasks:     3,
+			oldestQueueWait: 15 * time.Minute,
+			lastScaled:      now.Add(-time.Minute),
+			expectedAction:  scaleNone,
+			expectedReason:  "cooldown",
+		},
+		{
+			name:            "old queued tasks at department max",
+			activeMembers:   5,
+			totalMembers:    5,
+			queuedTasks:     3,
+			oldestQueueWait: 15 * time.Minute,
+			expectedAction:  scaleNone,
+			expectedReason:  "at_max_members",
+		},
+		{
+			name:           "no department max",
+			activeMembers:  5,
+			totalMembers:   5,
+			activeTasks:    25,
+			noDeptMax:      true,
+			expectedAction: scaleUp,
+			expectedReason: "high_utilization",
+		},
+		{
+			name:           "no config max",
+			activeMembers:  6,
+			totalMembers:   4,
+			activeTasks:    30,
+			noConfigMax:    true,
+			expectedAction: scaleUp,
+			expectedReason: "high_utilization",
+		},
+		{
+			name:           "no active members with queued work",
+			activeMembers:  0,
+			totalMembers:   0,
+			activeTasks:    1,
+			expectedAction: scaleUp,
+			expectedReason: "high_utilization",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dept, config := *dept, config
+			if tt.noDeptMax {
+				dept.MaxMembers = 0
+			}
+			if tt.noConfigMax {
+				config.MaxMembersPerDept = 0
+			}
+
+			stats := &DepartmentStats{ActiveMembers: tt.activeMembers, TotalMembers: tt.totalMembers}
+			// Five tasks per member
+			utilization := departmentUtilization(tt.activeMembers*5, tt.activeTasks)
+
+			action, reason := decideScaling(&dept, stats, scalingSignals{utilization: utilization, queuedTasks: tt.queuedTasks, oldestQueueWait: tt.oldestQueueWait}, tt.lastScaled, "", now, config)
+			require.Equal(t, tt.expectedAction, action)
+			require.Equal(t, tt.expectedReason, reason)
+		})
+	}
+}
+
+func TestDecideScalingDirectionalCooldowns(t *testing.T) {
+	t.Parallel()
+
+	config := AutoScalingConfig{
+		ScaleUpThreshold:   0.8,
+		ScaleDownThreshold: 0.2,
+		MaxMembersPerDept:  10,
+		CooldownPeriod:     5 * time.Minute,
+		ScaleUpCooldown:    30 * time.Second,
+		ScaleDownCooldown:  15 * time.Minute,
+	}
+	dept := &Department{ID: "dept", MinMembers: 1, MaxMembers: 10}
+	stats := &DepartmentStats{ActiveMembers: 4, TotalMembers: 4}
+	highLoad := scalingSignals{utilization: 0.9}
+	lowLoad := scalingSignals{utilization: 0.05}
+
+	// The department just scaled up, and load spikes again shortly after
+	scaledUp := time.Now()
+	action, _ := decideScaling(dept, stats, highLoad, scaledUp, scaleUp, scaledUp.Add(time.Minute), config)
+	require.Equal(t, scaleUp, action)
+
+	// Load then drops, but scaling back down waits for the longer cooldown
+	action, reason := decideScaling(dept, stats, lowLoad, scaledUp, scaleUp, scaledUp.Add(2*time.Minute), config)
+	require.Equal(t, scaleNone, action)
+	require.Equal(t, "cooldown", reason)
+
+	action, reason = decideScaling(dept, stats, lowLoad, scaledUp, scaleUp, scaledUp.Add(20*time.Minute), config)
+	require.Equal(t, scaleDown, action)
+	require.Equal(t, "low_utilization", reason)
+
+	// Without directional cooldowns both directions use CooldownPeriod
+	config.ScaleUpCooldown = 0
+	config.ScaleDownCooldown = 0
+	action, _ = decideScaling(dept, stats, highLoad, scaledUp, scaleUp, scaledUp.Add(time.Minute), config)
+	require.Equal(t, scaleNone, action)
+	action, _ = decideScaling(dept, stats, lowLoad, scaledUp, scaleUp, scaledUp.Add(6*time.Minute), config)
+	require.Equal(t, scaleDown, action)
+}
+
+func TestDecideScalingReversalWaitsForLongerCooldown(t *testing.T) {
+	t.Parallel()
+
+	config := AutoScalingConfig{
+		ScaleUpThreshold:   0.8,
+		ScaleDownThreshold: 0.2,
+		MaxMembersPerDept:  10,
+		ScaleUpCooldown:    30 * time.Second,
+		ScaleDownCooldown:  15 * time.Minute,
+	}
+	dept := &Department{ID: "dept", MinMembers: 1, MaxMembers: 10}
+	stats := &DepartmentStats{ActiveMembers: 4, TotalMembers: 4}
+	highLoad := scalingSignals{utilization: 0.9}
+
+	// Scaling up again soon after a scale-up only waits the short cooldown
+	scaled := time.Now()
+	action, _ := decideScaling(dept, stats, highLoad, scaled, scaleUp, scaled.Add(time.Minute), config)
+	require.Equal(t, scaleUp, action)
+
+	// Scaling up right after a scale-down waits the scale-down cooldown
+	action, reason := decideScaling(dept, stats, highLoad, scaled, scaleDown, scaled.Add(time.Minute), config)
+	require.Equal(t, scaleNone, action)
+	require.Equal(t, "cooldown", reason)
+	action, _ = decideScaling(dept, stats, highLoad, scaled, scaleDown, scaled.Add(20*time.Minute), config)
+	require.Equal(t, scaleUp, action)
+}
+
+func TestAutoScalerTracksLastScaleAction(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+	as := NewAutoScaler(AutoScalingConfig{
+		RoleScaling:       map[string]int{"developer": 5},
+		ScaleDownCooldown: time.Hour,
+	}, m)
+	t.Cleanup(as.Stop)
+
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	as.executeScalingAction(dept, scaleUp, "high_utilization")
+	require.Equal(t, scaleUp, as.lastScaleAction[dept.ID])
+
+	// Scale-up requests are not held back by the scale-down cooldown
+	m.mu.Lock()
+	dept.AutoScale = true
+	m.mu.Unlock()
+	require.True(t, as.RequestScaleUp(dept, "queue_wait_exceeded"))
+	require.Len(t, m.ListMembers(dept.ID), 2)
+
+	// After a scale-down they are, so the department does not flap
+	as.executeScalingAction(dept, scaleDown, "low_utilization")
+	require.Equal(t, scaleDown, as.lastScaleAction[dept.ID])
+	require.False(t, as.RequestScaleUp(dept, "queue_wait_exceeded"))
+}
+
+func TestAutoScalerScalesUpOnQueueWaitP95(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 1)
+
+	for i := range 5 {
+		task, err := m.CreateTask(ctx, &Task{ID: fmt.Sprintf("task-%d", i), Title: "work", DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+		if i == 0 {
+			require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+		}
+	}
+
+	// Both members hold a task, only one of which has started, and the
+	// remaining tasks have been queued for a while
+	now := time.Now()
+	m.mu.Lock()
+	for _, task := range m.tasks {
+		if task.Status == TaskStatusQueued {
+			queuedAt := now.Add(-10 * time.Minute)
+			task.QueuedAt = &queuedAt
+		}
+	}
+	m.mu.Unlock()
+	require.Equal(t, 10*time.Minute, m.QueueWaitPercentile("dept-dev", 95, now))
+
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	config := AutoScalingConfig{
+		ScaleUpThreshold:   0.8,
+		ScaleDownThreshold: 0.1,
+		MaxMembersPerDept:  10,
+	}
+
+	// Utilization alone is moderate, so nothing happens without a target
+	action, reason := NewAutoScaler(config, m).evaluateScalingNeeds(dept, now)
+	require.Equal(t, scaleNone, action)
+	require.Equal(t, "within_thresholds", reason)
+
+	config.QueueWaitP95Target = 5 * time.Minute
+	action, reason = NewAutoScaler(config, m).evaluateScalingNeeds(dept, now)
+	require.Equal(t, scaleUp, action)
+	require.Equal(t, "queue_wait_p95", reason)
+}
+
+func TestAutoScalerCapacityFromMembers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "lead-1", "dept-dev", RoleLeadDev, 3)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+	registerTestMember(t, m, "qa-1", "dept-dev", RoleQA, 1)
+	registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 4)
+	require.NoError(t, m.UpdateMemberStatus(ctx, "dev-2", MemberStatusOffline))
+
+	config := AutoScalingConfig{
+		ScaleUpThreshold:   0.8,
+		ScaleDownThreshold: 0.2,
+		MaxMembersPerDept:  10,
+	}
+
+	// Offline members add no capacity
+	as := NewAutoScaler(config, m)
+	require.Equal(t, 6, as.departmentCapacity("dept-dev"))
+
+	config.CapacityPerMember = map[string]int{string(RoleQA): 4}
+	require.Equal(t, 9, NewAutoScaler(config, m).departmentCapacity("dept-dev"))
+
+	// Five in-progress tasks nearly fill the three active members, which
+	// the old five-tasks-per-member estimate would have read as 33% load
+	for i := range 5 {
+		task, err := m.CreateTask(ctx, &Task{ID: fmt.Sprintf("task-%d", i), Title: "work", DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+		require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+	}
+
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	action, reason := as.evaluateScalingNeeds(dept, time.Now())
+	require.Equal(t, scaleUp, action)
+	require.Equal(t, "high_utilization", reason)
+}
+
+func TestAutoScalerScalingHistory(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+	as := NewAutoScaler(AutoScalingConfig{
+		RoleScaling:        map[string]int{"developer": 5},
+		ScalingHistorySize: 3,
+	}, m)
+	t.Cleanup(as.Stop)
+	events := as.SubscribeToScalingEvents(t.Context())
+
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	for range 3 {
+		as.executeScalingAction(dept, scaleUp, "high_utilization")
+	}
+	as.executeScalingAction(dept, scaleDown, "low_utilization")
+
+	for i := range 3 {
+		event := <-events
+		require.Equal(t, ScaledEvent, event.Type)
+		require.Equal(t, scaleUp, event.Payload.Action)
+		require.Equal(t, i, event.Payload.MembersBefore)
+		require.Equal(t, i+1, event.Payload.MembersAfter)
+	}
+	removed := <-events
+
+	// Only the most recent events are kept
+	history := as.GetScalingHistory(0)
+	require.Len(t, history, 3)
+	require.Equal(t, *removed.Payload, history[2])
+	require.Equal(t, ScalingEvent{
+		DepartmentID:  dept.ID,
+		Action:        scaleDown,
+		MemberID:      removed.Payload.MemberID,
+		Role:          RoleDeveloper,
+		Reason:        "low_utilization",
+		MembersBefore: 3,
+		MembersAfter:  2,
+		Timestamp:     removed.Payload.Timestamp,
+	}, history[2])
+	require.Equal(t, scaleUp, history[0].Action)
+	require.Equal(t, 1, history[0].MembersBefore)
+
+	require.Equal(t, history[1:], as.GetScalingHistory(2))
+}
+
+func TestAutoScalerDrainsBusyMemberOnScaleDown(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	as := NewAutoScaler(AutoScalingConfig{DrainTimeout: time.Hour}, m)
+	t.Cleanup(as.Stop)
+	events := as.SubscribeToScalingEvents(t.Context())
+
+	dev1 := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	first, err := m.CreateTask(ctx, &Task{ID: "task-1", Title: "work", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, dev1.ID, first.AssignedMember)
+	dev2 := registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 4)
+	for _, id := range []string{"other-1", "other-2"} {
+		task, err := m.CreateTask(ctx, &Task{ID: id, Title: "work", DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+		require.Equal(t, dev2.ID, task.AssignedMember)
+	}
+
+	// The least busy member drains instead of blocking the scale-down
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	as.executeScalingAction(dept, scaleDown, "low_utilization")
+	member, err := m.GetMember(dev1.ID)
+	require.NoError(t, err)
+	require.Equal(t, MemberStatusDraining, member.Status)
+
+	// No new work goes to it, and it is not picked again mid-drain
+	second, err := m.CreateTask(ctx, &Task{ID: "task-2", Title: "work", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, dev2.ID, second.AssignedMember)
+	require.Equal(t, dev2.ID, as.findScaleDownCandidate(dept).ID)
+
+	as.progressDrains(time.Now())
+	_, err = m.GetMember(dev1.ID)
+	require.NoError(t, err)
+
+	// Once its task is done it is removed
+	require.NoError(t, m.UpdateTaskStatus(ctx, first.ID, TaskStatusCompleted, nil))
+	as.progressDrains(time.Now())
+	_, err = m.GetMember(dev1.ID)
+	require.Error(t, err)
+
+	event := <-events
+	require.Equal(t, dev1.ID, event.Payload.MemberID)
+	require.Equal(t, scaleDown, event.Payload.Action)
+	require.Equal(t, "drained", event.Payload.Reason)
+	require.Equal(t, 2, event.Payload.MembersBefore)
+	require.Equal(t, 1, event.Payload.MembersAfter)
+}
+
+func TestAutoScalerReassignsTasksAfterDrainTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	as := NewAutoScaler(AutoScalingConfig{DrainTimeout: time.Minute}, m)
+	t.Cleanup(as.Stop)
+
+	dev1 := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", Title: "work", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusInProgress, nil))
+
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	as.executeScalingAction(dept, scaleDown, "low_utilization")
+	dev2 := registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 2)
+
+	as.progressDrains(time.Now().Add(2 * time.Minute))
+	_, err = m.GetMember(dev1.ID)
+	require.Error(t, err)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	require.Equal(t, dev2.ID, task.AssignedMember)
+	require.Equal(t, 1, task.Retries)
+	require.Equal(t, "drain_timeout", as.history[len(as.history)-1].Reason)
+}
+
+func TestAutoScalerResumesDrainsOnStart(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	dev1 := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", Title: "work", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.Equal(t, dev1.ID, task.AssignedMember)
+
+	// Left draining by a scaler that is gone, as after a restart
+	require.NoError(t, m.UpdateMemberStatus(ctx, dev1.ID, MemberStatusDraining))
+
+	as := NewAutoScaler(AutoScalingConfig{CheckInterval: time.Hour, DrainTimeout: time.Hour}, m)
+	t.Cleanup(as.Stop)
+	go as.Start(t.Context())
+	require.Eventually(t, func() bool {
+		as.mu.Lock()
+		defer as.mu.Unlock()
+		_, tracked := as.draining[dev1.ID]
+		return tracked
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, m.UpdateTaskStatus(ctx, task.ID, TaskStatusCompleted, nil))
+	as.mu.Lock()
+	as.progressDrains(time.Now())
+	as.mu.Unlock()
+	_, err = m.GetMember(dev1.ID)
+	require.Error(t, err)
+}
+
+func TestAutoScalerLimitsScaleUpsPerTick(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+	var rules []ScheduleRule
+	for _, deptID := range []string{"dept-dev", "dept-devops", "dept-security", "dept-qa"} {
+		rules = append(rules, ScheduleRule{DepartmentID: deptID, Start: "00:00", End: "00:00", MinMembers: 3})
+	}
+	as := NewAutoScaler(AutoScalingConfig{
+		RoleScaling:       map[string]int{"developer": 5},
+		MaxMembersPerDept: 10,
+		MaxScaleUpPerTick: 3,
+		ScheduledRules:    rules,
+	}, m)
+	t.Cleanup(as.Stop)
+	as.isRunning = true
+
+	// Every department is below its scheduled minimum, but only three grow
+	// per tick; the one left out goes first on the next one
+	as.checkAndScale()
+	require.Len(t, m.ListMembers(""), 3)
+	as.checkAndScale()
+	require.Len(t, m.ListMembers(""), 6)
+	for _, dept := range m.ListDepartments() {
+		require.NotEmpty(t, m.ListMembers(dept.ID), dept.ID)
+	}
+}
+
+func TestAutoScalerLimitsConcurrentLaunches(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+	as := NewAutoScaler(AutoScalingConfig{
+		RoleScaling:           map[string]int{"developer": 5},
+		MaxMembersPerDept:     10,
+		MaxConcurrentLaunches: 1,
+		ScheduledRules:        []ScheduleRule{{DepartmentID: "dept-dev", Start: "00:00", End: "00:00", MinMembers: 3}},
+	}, m)
+	t.Cleanup(as.Stop)
+	as.isRunning = true
+
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+
+	// A launch already in flight, such as a cold start, holds the only slot
+	as.launching = 1
+	as.checkAndScale()
+	require.Empty(t, m.ListMembers(dept.ID))
+	require.False(t, as.RequestScaleUp(dept, "queue_wait_exceeded"))
+
+	as.launching = 0
+	as.checkAndScale()
+	require.Len(t, m.ListMembers(dept.ID), 1)
+	require.Zero(t, as.launching)
+}
+
+func TestAutoScalingConfigValidateLaunchLimits(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, AutoScalingConfig{MaxConcurrentLaunches: 2, MaxScaleUpPerTick: 4}.Validate())
+	require.Error(t, AutoScalingConfig{MaxConcurrentLaunches: -1}.Validate())
+	require.Error(t, AutoScalingConfig{MaxScaleUpPerTick: -1}.Validate())
+}
+
+func TestAutoScalerAddsBottleneckRole(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+
+	_, err := m.CreateTask(ctx, &Task{ID: "feature", DepartmentID: "dept-dev", RequiredSkills: []string{"developer"}})
+	require.NoError(t, err)
+	for i := range 2 {
+		_, err := m.CreateTask(ctx, &Task{ID: fmt.Sprintf("bugfix-%d", i), DepartmentID: "dept-dev", AssignedRole: RoleDeveloper})
+		require.NoError(t, err)
+	}
+	for i := range 4 {
+		_, err := m.CreateTask(ctx, &Task{ID: fmt.Sprintf("audit-%d", i), DepartmentID: "dept-dev", RequiredSkills: []string{"Security"}})
+		require.NoError(t, err)
+	}
+
+	// Developers are short of their configured count, but most of the
+	// queue waits on a security member
+	as := NewAutoScaler(AutoScalingConfig{RoleScaling: map[string]int{"developer": 5}}, m)
+	t.Cleanup(as.Stop)
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	require.Equal(t, string(RoleSecurity), as.determineRoleToAdd(dept))
+
+	added := as.scaleUp(dept, "high_utilization")
+	require.NotNil(t, added)
+	require.Equal(t, RoleSecurity, added.Role)
+
+	// The new member's room offsets the security demand, so developers are
+	// the bottleneck again
+	require.Equal(t, string(RoleDeveloper), as.determineRoleToAdd(dept))
+}
+
+func TestAutoScalerScalesCustomRole(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled: true,
+		Roles: RoleConfig{RoleDefinitions: map[string]RoleDefinition{
+			"sre": {
+				Name:             "Site Reliability Engineer",
+				DepartmentTypes:  []string{string(DepartmentDevOps)},
+				Responsibilities: []string{"incident_response"},
+				RequiredSkills:   []string{"observability", "kubernetes"},
+				MaxConcurrent:    6,
+				DefaultTools:     []string{"bash"},
+			},
+			"lead_sre": {Name: "SRE Lead", LeadRole: true},
+		}},
+	})
+	require.NoError(t, err)
+
+	as := NewAutoScaler(AutoScalingConfig{RoleScaling: map[string]int{"sre": 1}}, m)
+	t.Cleanup(as.Stop)
+	dept, err := m.GetDepartment("dept-devops")
+	require.NoError(t, err)
+
+	added := as.scaleUp(dept, "high_utilization")
+	require.NotNil(t, added)
+	require.Equal(t, MemberRole("sre"), added.Role)
+	require.Equal(t, []string{"observability", "kubernetes"}, added.Specializations)
+	require.Equal(t, 6, added.MaxConcurrent)
+	require.Equal(t, map[string]interface{}{"incident_response": true, "tools": []string{"bash"}}, added.Capabilities)
+	require.False(t, added.IsLead)
+
+	// Defined roles serve their departments once the scaling rule is met
+	require.Contains(t, []string{string(RoleDevOps), "sre"}, as.determineRoleToAdd(dept))
+
+	// Tasks requiring the custom role make it the bottleneck
+	_, err = m.CreateTask(ctx, &Task{ID: "incident", DepartmentID: "dept-devops", RequiredRoles: []MemberRole{"sre"}, Weight: 10})
+	require.NoError(t, err)
+	require.Equal(t, "sre", as.determineRoleToAdd(dept))
+
+	// Lead status follows the definition, including for role changes
+	require.NoError(t, m.UpdateMember(ctx, added.ID, MemberPatch{Role: "lead_sre"}))
+	member, err := m.GetMember(added.ID)
+	require.NoError(t, err)
+	require.True(t, member.IsLead)
+	require.Error(t, m.UpdateMember(ctx, added.ID, MemberPatch{Role: "unknown"}))
+}
+
+func TestAutoScalerStopDuringScaling(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+	as := NewAutoScaler(AutoScalingConfig{
+		CheckInterval:     time.Millisecond,
+		RoleScaling:       map[string]int{"developer": 100},
+		MaxMembersPerDept: 100,
+		ScheduledRules:    []ScheduleRule{{DepartmentID: "dept-dev", Start: "00:00", End: "00:00", MinMembers: 100}},
+	}, m)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		as.Start(ctx)
+	}()
+
+	// Stop while the scaler is adding a member every tick
+	require.Eventually(t, func() bool {
+		return len(m.ListMembers("dept-dev")) > 0
+	}, 5*time.Second, time.Millisecond)
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			as.Stop()
+		}()
+	}
+	wg.Wait()
+	members := len(m.ListMembers("dept-dev"))
+
+	<-done
+	time.Sleep(20 * time.Millisecond)
+	require.Len(t, m.ListMembers("dept-dev"), members)
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	require.False(t, as.RequestScaleUp(dept, "queue_wait_exceeded"))
+
+	// Starting a stopped scaler does nothing
+	as.Start(ctx)
+}
+
+func TestAutoScalerStopsWithContext(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+	as := NewAutoScaler(AutoScalingConfig{CheckInterval: time.Hour}, m)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		as.Start(ctx)
+	}()
+	cancel()
+	<-done
+
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	require.False(t, as.RequestScaleUp(dept, "queue_wait_exceeded"))
+	require.Eventually(t, func() bool {
+		return as.GetScalingStatus()["is_running"] == false
+	}, time.Second, time.Millisecond)
+}
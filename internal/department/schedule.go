@@ -0,0 +1,174 @@
+package department
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ScheduleRule overrides a department's member bounds during a recurring
+// time window, for load that follows a predictable daily pattern
+type ScheduleRule struct {
+	Name         string `json:"name,omitempty"`
+	DepartmentID string `json:"department_id"`
+	// Days limits the rule to windows starting on these weekdays ("mon"
+	// through "sun"). Empty means every day.
+	Days []string `json:"days,omitempty"`
+	// Start and End are times of day as "HH:MM". A window whose end is not
+	// after its start runs past midnight into the next day.
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// MinMembers and MaxMembers replace the auto-scaling bounds while the
+	// rule is active. Zero keeps the usual bound. Registration still caps a
+	// department at its own MaxMembers.
+	MinMembers int `json:"min_members,omitempty"`
+	MaxMembers int `json:"max_members,omitempty"`
+	// Priority decides between overlapping rules: the highest wins, and
+	// among equal priorities the one listed last
+	Priority int `json:"priority,omitempty"`
+}
+
+// weekdays maps the accepted day names onto weekdays
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// Validate checks the rule's department, window and bounds
+func (r ScheduleRule) Validate() error {
+	if r.DepartmentID == "" {
+		return fmt.Errorf("department is required")
+	}
+	if _, _, err := r.window(); err != nil {
+		return err
+	}
+	for _, day := range r.Days {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("unknown day %q", day)
+		}
+	}
+	if r.MinMembers < 0 || r.MaxMembers < 0 {
+		return fmt.Errorf("member bounds must not be negative")
+	}
+	if r.MaxMembers > 0 && r.MinMembers > r.MaxMembers {
+		return fmt.Errorf("min members %d exceeds max members %d", r.MinMembers, r.MaxMembers)
+	}
+	return nil
+}
+
+// window returns the rule's start and end as minutes past midnight
+func (r ScheduleRule) window() (int, int, error) {
+	start, err := time.Parse("15:04", r.Start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid start %q: want HH:MM", r.Start)
+	}
+	end, err := time.Parse("15:04", r.End)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid end %q: want HH:MM", r.End)
+	}
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), nil
+}
+
+// onDay reports whether a window starting on day is covered by the rule
+func (r ScheduleRule) onDay(day time.Weekday) bool {
+	if len(r.Days) == 0 {
+		return true
+	}
+	for _, name := range r.Days {
+		if weekdays[strings.ToLower(name)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// activeAt reports whether t, already in the schedule's time zone, falls
+// inside one of the rule's windows. Invalid rules are never active.
+func (r ScheduleRule) activeAt(t time.Time) bool {
+	start, end, err := r.window()
+	if err != nil {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	if start < end {
+		return minute >= start && minute < end && r.onDay(t.Weekday())
+	}
+
+	// The window runs past midnight, so early hours belong to the window
+	// that started the day before
+	if minute >= start {
+		return r.onDay(t.Weekday())
+	}
+	if minute < end {
+		return r.onDay((t.Weekday() + 6) % 7)
+	}
+	return false
+}
+
+// Validate checks the auto-scaling time zone, scheduled rules and launch
+// limits
+func (c AutoScalingConfig) Validate() error {
+	if c.MaxConcurrentLaunches < 0 || c.MaxScaleUpPerTick < 0 {
+		return fmt.Errorf("launch limits must not be negative")
+	}
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", c.Timezone, err)
+	}
+	for i, rule := range c.ScheduledRules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("scheduled rule %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// activeSchedule returns the scheduled rule in force for a department at
+// now, or nil if none is
+func (as *AutoScaler) activeSchedule(departmentID string, now time.Time) *ScheduleRule {
+	local := now.In(as.location)
+
+	var active *ScheduleRule
+	for i := range as.config.ScheduledRules {
+		rule := &as.config.ScheduledRules[i]
+		if rule.DepartmentID != departmentID || !rule.activeAt(local) {
+			continue
+		}
+		if active == nil || rule.Priority >= active.Priority {
+			active = rule
+		}
+	}
+	return active
+}
+
+// applySchedule returns the department and config with a scheduled rule's
+// bounds in place of the usual ones
+func applySchedule(dept *Department, config AutoScalingConfig, rule *ScheduleRule) (*Department, AutoScalingConfig) {
+	bounded := *dept
+	if rule.MinMembers > 0 {
+		bounded.MinMembers = rule.MinMembers
+	}
+	if rule.MaxMembers > 0 {
+		bounded.MaxMembers = min(rule.MaxMembers, dept.MaxMembers)
+		config.MaxMembersPerDept = rule.MaxMembers
+	}
+	return &bounded, config
+}
+
+// decideScheduledBounds scales a department back within its scheduled
+// bounds. It runs before the load-based decision and ignores the cooldown,
+// since each step only moves the department toward the bound.
+func decideScheduledBounds(dept *Department, stats *DepartmentStats, rule *ScheduleRule) (string, string) {
+	switch {
+	case rule.MinMembers > 0 && stats.ActiveMembers < rule.MinMembers && stats.TotalMembers < dept.MaxMembers:
+		return scaleUp, "below_scheduled_min"
+	case rule.MaxMembers > 0 && stats.ActiveMembers > rule.MaxMembers:
+		return scaleDown, "above_scheduled_max"
+	}
+	return scaleNone, ""
+}
@@ -0,0 +1,132 @@
+package department
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleRuleActiveAt(t *testing.T) {
+	t.Parallel()
+
+	businessHours := ScheduleRule{DepartmentID: "dept-dev", Days: []string{"mon", "tue", "wed", "thu", "Friday"}, Start: "09:00", End: "18:00"}
+	fridayNight := ScheduleRule{DepartmentID: "dept-dev", Days: []string{"fri"}, Start: "22:00", End: "06:00"}
+
+	// 2026-10-16 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		rule   ScheduleRule
+		at     time.Time
+		active bool
+	}{
+		{"weekday inside window", businessHours, at(16, 9, 0), true},
+		{"weekday at window end", businessHours, at(16, 18, 0), false},
+		{"weekend", businessHours, at(17, 12, 0), false},
+		{"overnight before midnight", fridayNight, at(16, 23, 30), true},
+		{"overnight after midnight", fridayNight, at(17, 5, 59), true},
+		{"overnight started the wrong day", fridayNight, at(16, 3, 0), false},
+		{"between overnight windows", fridayNight, at(17, 12, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.active, tt.rule.activeAt(tt.at))
+		})
+	}
+}
+
+func TestAutoScalingConfigValidateSchedule(t *testing.T) {
+	t.Parallel()
+
+	valid := ScheduleRule{DepartmentID: "dept-dev", Start: "09:00", End: "17:30", MinMembers: 2, MaxMembers: 6}
+	require.NoError(t, AutoScalingConfig{Timezone: "Europe/Berlin", ScheduledRules: []ScheduleRule{valid}}.Validate())
+
+	require.ErrorContains(t, AutoScalingConfig{Timezone: "Mars/Olympus"}.Validate(), `invalid timezone "Mars/Olympus"`)
+
+	invalid := valid
+	invalid.End = "25:00"
+	require.ErrorContains(t, AutoScalingConfig{ScheduledRules: []ScheduleRule{valid, invalid}}.Validate(), `scheduled rule 1: invalid end "25:00"`)
+
+	invalid = valid
+	invalid.Days = []string{"someday"}
+	require.ErrorContains(t, invalid.Validate(), `unknown day "someday"`)
+
+	invalid = valid
+	invalid.MinMembers = 8
+	require.ErrorContains(t, invalid.Validate(), "min members 8 exceeds max members 6")
+}
+
+func TestAutoScalerActiveSchedule(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+	as := NewAutoScaler(AutoScalingConfig{
+		Timezone: "America/New_York",
+		ScheduledRules: []ScheduleRule{
+			{Name: "day", DepartmentID: "dept-dev", Start: "08:00", End: "20:00", MinMembers: 2, Priority: 1},
+			{Name: "peak", DepartmentID: "dept-dev", Start: "09:00", End: "12:00", MinMembers: 6, Priority: 1},
+			{Name: "audit", DepartmentID: "dept-dev", Start: "10:00", End: "11:00", MinMembers: 1},
+			{Name: "other", DepartmentID: "dept-qa", Start: "00:00", End: "00:00", MinMembers: 1},
+		},
+	}, m)
+	t.Cleanup(as.Stop)
+
+	// Windows are in New York time, four hours behind UTC in October
+	utc := func(hour, minute int) time.Time {
+		return time.Date(2026, 10, 14, hour, minute, 0, 0, time.UTC)
+	}
+
+	require.Equal(t, "day", as.activeSchedule("dept-dev", utc(12, 30)).Name)
+	// Overlapping rules of equal priority: the later one wins, while lower
+	// priorities lose even when listed last
+	require.Equal(t, "peak", as.activeSchedule("dept-dev", utc(14, 30)).Name)
+	require.Nil(t, as.activeSchedule("dept-dev", utc(11, 0)))
+	require.Equal(t, "other", as.activeSchedule("dept-qa", utc(11, 0)).Name)
+}
+
+func TestAutoScalerAppliesScheduledBounds(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 5)
+	registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 5)
+	registerTestMember(t, m, "dev-3", "dept-dev", RoleDeveloper, 5)
+
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	config := AutoScalingConfig{
+		ScaleUpThreshold:   0.8,
+		ScaleDownThreshold: 0.2,
+		MaxMembersPerDept:  10,
+		ScheduledRules: []ScheduleRule{
+			{DepartmentID: "dept-dev", Start: "09:00", End: "17:00", MinMembers: 4},
+			{DepartmentID: "dept-dev", Start: "22:00", End: "06:00", MaxMembers: 2},
+		},
+	}
+	as := NewAutoScaler(config, m)
+	t.Cleanup(as.Stop)
+
+	day := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	night := time.Date(2026, 10, 14, 23, 0, 0, 0, time.UTC)
+	evening := time.Date(2026, 10, 14, 19, 0, 0, 0, time.UTC)
+
+	// Idle, but business hours keep at least four members
+	action, reason := as.evaluateScalingNeeds(dept, day)
+	require.Equal(t, scaleUp, action)
+	require.Equal(t, "below_scheduled_min", reason)
+
+	action, reason = as.evaluateScalingNeeds(dept, night)
+	require.Equal(t, scaleDown, action)
+	require.Equal(t, "above_scheduled_max", reason)
+
+	// Outside any window the usual load-based decision applies
+	action, reason = as.evaluateScalingNeeds(dept, evening)
+	require.Equal(t, scaleDown, action)
+	require.Equal(t, "low_utilization", reason)
+}
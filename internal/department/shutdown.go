@@ -0,0 +1,119 @@
+package department
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// shutdownReason is the failure reason of tasks still running when a
+// graceful shutdown reaches its deadline
+const shutdownReason = "shutdown"
+
+// shutdownPollInterval bounds how long Shutdown waits between checks for
+// finished tasks, in case a task event was dropped
+const shutdownPollInterval = 100 * time.Millisecond
+
+// Shutdown stops the manager gracefully. It stops accepting new tasks and
+// stops the auto-scaler and background monitors, then waits for assigned and
+// in-progress tasks to finish or for ctx to end. Tasks still running when ctx
+// ends are failed with reason "shutdown" and ctx's error is returned. The
+// instances the auto-scaler launched and the health checker are stopped
+// next, and the event brokers last, so subscribers see every task's final
+// state. Queued tasks are kept in the persisted state for the next manager;
+// a manager that has shut down cannot be started again.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.isRunning || m.shuttingDown {
+		m.mu.Unlock()
+		return nil
+	}
+	m.shuttingDown = true
+	m.stopMonitors()
+	m.mu.Unlock()
+
+	slog.Info("Department manager shutting down")
+
+	// No member is scaled up or down while tasks drain. The scaler's lock is
+	// taken before the manager's, so it is stopped without holding the
+	// manager lock.
+	if m.scaler != nil {
+		m.scaler.halt()
+	}
+
+	// Subscribe before the first check so no finished task is missed
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := m.taskEvents.Subscribe(subCtx)
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	var drainErr error
+	for drainErr == nil && m.inFlightTasks() > 0 {
+		select {
+		case <-ctx.Done():
+			drainErr = ctx.Err()
+		case _, ok := <-events:
+			if !ok {
+				events = nil
+			}
+		case <-ticker.C:
+		}
+	}
+
+	if drainErr != nil {
+		if failed := m.failInFlightTasks(); failed > 0 {
+			drainErr = fmt.Errorf("%d tasks were still running at shutdown: %w", failed, drainErr)
+		}
+	}
+
+	// Launched instances are only taken down once their tasks are done
+	if m.scaler != nil {
+		m.scaler.Stop()
+	}
+
+	if err := m.Stop(); err != nil {
+		return err
+	}
+	return drainErr
+}
+
+// inFlightTasks counts the tasks assigned to or being worked on by members
+func (m *Manager) inFlightTasks() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for _, task := range m.tasks {
+		if task.Status == TaskStatusAssigned || task.Status == TaskStatusInProgress {
+			count++
+		}
+	}
+	return count
+}
+
+// failInFlightTasks fails the tasks still assigned or in progress so their
+// callers are not left waiting, and returns how many it failed
+func (m *Manager) failInFlightTasks() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var running []string
+	for id, task := range m.tasks {
+		if task.Status == TaskStatusAssigned || task.Status == TaskStatusInProgress {
+			running = append(running, id)
+		}
+	}
+
+	for _, id := range running {
+		err := m.updateTaskStatus(context.Background(), id, TaskStatusFailed, map[string]interface{}{
+			"error":          "the department manager shut down before the task finished",
+			"failure_reason": shutdownReason,
+		})
+		if err != nil {
+			slog.Warn("Failed to fail task at shutdown", "task_id", id, "error", err)
+		}
+	}
+	return len(running)
+}
@@ -0,0 +1,129 @@
+package department
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerShutdownDrainsTasks(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	require.NoError(t, m.Start(t.Context()))
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+
+	_, err := m.CreateTask(ctx, &Task{ID: "running", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, "running", TaskStatusInProgress, nil))
+
+	done := make(chan error, 1)
+	go func() { done <- m.Shutdown(ctx) }()
+
+	// New work is refused while the running task drains. Probes sent before
+	// shutdown begins stay queued in a department without members.
+	require.Eventually(t, func() bool {
+		_, err := m.CreateTask(ctx, &Task{DepartmentID: "dept-qa"})
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown returned before the task finished: %v", err)
+	default:
+	}
+
+	require.NoError(t, m.UpdateTaskStatus(ctx, "running", TaskStatusCompleted, nil))
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not return after the task finished")
+	}
+
+	task, err := m.GetTask("running")
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusCompleted, task.Status)
+	_, ok := <-m.SubscribeToTaskEvents(t.Context())
+	require.False(t, ok)
+}
+
+func TestManagerShutdownFailsTasksAtDeadline(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	require.NoError(t, m.Start(t.Context()))
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+
+	_, err := m.CreateTask(ctx, &Task{ID: "stuck", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, "stuck", TaskStatusInProgress, nil))
+	_, err = m.CreateTask(ctx, &Task{ID: "waiting", DepartmentID: "dept-security"})
+	require.NoError(t, err)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	err = m.Shutdown(shutdownCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorContains(t, err, "1 tasks were still running")
+
+	task, err := m.GetTask("stuck")
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusFailed, task.Status)
+	require.Equal(t, shutdownReason, task.Results["failure_reason"])
+
+	// Queued tasks are kept for the next start
+	task, err = m.GetTask("waiting")
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, task.Status)
+
+	member, err := m.GetMember("dev-1")
+	require.NoError(t, err)
+	require.Empty(t, member.CurrentTasks)
+
+	// Shutting down again is a no-op
+	require.NoError(t, m.Shutdown(ctx))
+}
+
+func TestManagerShutdownStopsScalerFirst(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:     true,
+		AutoScaling: AutoScalingConfig{Enabled: true, CheckInterval: time.Hour},
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.Start(t.Context()))
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+
+	_, err = m.CreateTask(ctx, &Task{ID: "running", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, "running", TaskStatusInProgress, nil))
+
+	done := make(chan error, 1)
+	go func() { done <- m.Shutdown(ctx) }()
+
+	// The scaler stops before the running task is drained
+	require.Eventually(t, func() bool {
+		return m.scaler.ctx.Err() != nil
+	}, time.Second, 10*time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown returned before the task finished: %v", err)
+	default:
+	}
+
+	require.NoError(t, m.UpdateTaskStatus(ctx, "running", TaskStatusCompleted, nil))
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not return after the task finished")
+	}
+
+	require.ErrorContains(t, m.Start(t.Context()), "has been shut down")
+}
@@ -0,0 +1,95 @@
+package department
+
+import (
+	"strings"
+	"unicode"
+)
+
+// defaultSkillAliases maps each canonical skill to other names it goes by.
+// TaskRoutingConfig.SkillAliases extends or overrides it.
+var defaultSkillAliases = map[string][]string{
+	"go":         {"golang"},
+	"javascript": {"js", "node", "nodejs"},
+	"python":     {"py"},
+	"docker":     {"container"},
+	"kubernetes": {"k8s"},
+	"security":   {"vulnerability", "penetration"},
+	"testing":    {"test", "qa"},
+}
+
+// skillMatcher compares skills by their canonical names, so a task needing
+// "golang" matches a member specializing in "go"
+type skillMatcher struct {
+	// Canonical skill of each lowercased alias
+	canonical map[string]string
+}
+
+// newSkillMatcher builds a matcher from the default aliases merged with
+// extra, whose entries win when an alias is listed under two skills
+func newSkillMatcher(extra map[string][]string) *skillMatcher {
+	sm := &skillMatcher{canonical: make(map[string]string)}
+	for _, aliases := range []map[string][]string{defaultSkillAliases, extra} {
+		for skill, names := range aliases {
+			skill = normalizeSkill(skill)
+			sm.canonical[skill] = skill
+			for _, name := range names {
+				sm.canonical[normalizeSkill(name)] = skill
+			}
+		}
+	}
+	return sm
+}
+
+// canonicalize returns the canonical name of skill, or skill itself
+// lowercased when it has no alias
+func (sm *skillMatcher) canonicalize(skill string) string {
+	skill = normalizeSkill(skill)
+	if canonical, ok := sm.canonical[skill]; ok {
+		return canonical
+	}
+	return skill
+}
+
+// lookup returns the canonical name of skill and whether it is a known skill
+// or alias
+func (sm *skillMatcher) lookup(skill string) (string, bool) {
+	canonical, ok := sm.canonical[normalizeSkill(skill)]
+	return canonical, ok
+}
+
+// matches reports whether an offered specialization covers a required
+// skill: both share a canonical name, or one word of a compound
+// specialization such as "go-backend" does. The match is one way, so a
+// member offering "go" does not cover a task needing "go-backend".
+func (sm *skillMatcher) matches(required, offered string) bool {
+	required = sm.canonicalize(required)
+	if required == "" {
+		return false
+	}
+	if sm.canonicalize(offered) == required {
+		return true
+	}
+
+	words := skillWords(offered)
+	if len(words) < 2 {
+		return false
+	}
+	for _, word := range words {
+		if sm.canonicalize(word) == required {
+			return true
+		}
+	}
+	return false
+}
+
+// skillWords splits text into lowercased words on anything that is not a
+// letter or digit
+func skillWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func normalizeSkill(skill string) string {
+	return strings.ToLower(strings.TrimSpace(skill))
+}
@@ -0,0 +1,80 @@
+package department
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkillMatcher(t *testing.T) {
+	t.Parallel()
+
+	sm := newSkillMatcher(map[string][]string{
+		"terraform": {"tf"},
+		"python":    {"py", "python3"},
+	})
+
+	for _, tc := range []struct {
+		required, offered string
+		want              bool
+	}{
+		{"golang", "go", true},
+		{"go", "Golang", true},
+		{"k8s", "kubernetes", true},
+		{"node", "javascript", true},
+		{"JS", "nodejs", true},
+		{"tf", "terraform", true},
+		{"python3", "py", true},
+		{"go", "go-backend", true},
+		{"kubernetes", "k8s admin", true},
+		{"go-backend", "go", false},
+		{"go", "django", false},
+		{"rust", "go", false},
+		{"", "go", false},
+	} {
+		require.Equal(t, tc.want, sm.matches(tc.required, tc.offered), "%q offered by %q", tc.required, tc.offered)
+	}
+}
+
+func TestTaskRouterMatchesSkillAliases(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled: true,
+		TaskRouting: TaskRoutingConfig{
+			Strategy:     RoutingSkillBased,
+			SkillAliases: map[string][]string{"terraform": {"tf"}},
+		},
+	})
+	require.NoError(t, err)
+
+	for id, skills := range map[string][]string{
+		"gopher":   {"go"},
+		"platform": {"kubernetes", "terraform"},
+	} {
+		require.NoError(t, m.RegisterMember(ctx, &Member{
+			ID:              id,
+			Name:            id,
+			Role:            RoleDeveloper,
+			DepartmentID:    "dept-dev",
+			MaxConcurrent:   2,
+			Specializations: skills,
+		}))
+	}
+
+	task, err := m.CreateTask(ctx, &Task{ID: "golang", DepartmentID: "dept-dev", RequiredSkills: []string{"golang"}})
+	require.NoError(t, err)
+	require.Equal(t, "gopher", task.AssignedMember)
+
+	task, err = m.CreateTask(ctx, &Task{ID: "infra", DepartmentID: "dept-dev", RequiredSkills: []string{"k8s", "tf"}})
+	require.NoError(t, err)
+	require.Equal(t, "platform", task.AssignedMember)
+
+	// A task whose skills no member covers stays queued
+	task, err = m.CreateTask(ctx, &Task{ID: "rust", DepartmentID: "dept-dev", RequiredSkills: []string{"rust"}})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, task.Status)
+	require.Empty(t, task.AssignedMember)
+}
@@ -0,0 +1,288 @@
+package department
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// StateSchemaVersion is the schema version of the documents produced by
+// ExportState. ImportState rejects documents of any other version.
+const StateSchemaVersion = 1
+
+// StateDocument is a self-contained dump of the manager state for debugging
+// and migration. It encodes the same way as JSON and as YAML: times use
+// RFC 3339, unset times such as Task.StartedAt are omitted, and attachment
+// content is base64.
+type StateDocument struct {
+	SchemaVersion int           `json:"schema_version"`
+	ExportedAt    time.Time     `json:"exported_at"`
+	Departments   []*Department `json:"departments"`
+	Members       []*Member     `json:"members"`
+	Tasks         []*Task       `json:"tasks"`
+	Teams         []*Team       `json:"teams"`
+	Workflows     []*Workflow   `json:"workflows"`
+	// WorkflowRuns are the runs of the workflows, so steps of a run in
+	// flight keep advancing it after an import
+	WorkflowRuns []*WorkflowRun `json:"workflow_runs,omitempty"`
+}
+
+// ExportState returns a copy of the departments, members, tasks, teams,
+// workflows and workflow runs held by the manager, each sorted by ID
+func (m *Manager) ExportState() (StateDocument, error) {
+	m.mu.RLock()
+	doc := StateDocument{
+		SchemaVersion: StateSchemaVersion,
+		ExportedAt:    time.Now(),
+		Departments:   sortedByID(m.departments),
+		Members:       sortedByID(m.members),
+		Tasks:         sortedByID(m.tasks),
+		Teams:         sortedByID(m.teams),
+		Workflows:     sortedByID(m.workflows),
+		WorkflowRuns:  sortedByID(m.workflowRuns),
+	}
+
+	// Detach the document from the live state before releasing the lock
+	data, err := json.Marshal(doc)
+	m.mu.RUnlock()
+	if err != nil {
+		return StateDocument{}, fmt.Errorf("failed to marshal state document: %w", err)
+	}
+
+	var exported StateDocument
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return StateDocument{}, fmt.Errorf("failed to copy state document: %w", err)
+	}
+	return exported, nil
+}
+
+// ImportState replaces the manager state with the contents of doc and
+// rebuilds the statistics from the imported tasks. The document is checked
+// as a whole first, so nothing is replaced when any department, member,
+// task, team, workflow or workflow run in it is invalid or refers to
+// something missing, or when its tasks' dependencies form a cycle.
+func (m *Manager) ImportState(doc StateDocument) error {
+	if doc.SchemaVersion != StateSchemaVersion {
+		return fmt.Errorf("unsupported state schema version %d, expected %d", doc.SchemaVersion, StateSchemaVersion)
+	}
+
+	// Copy the document so the caller cannot change the imported state
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state document: %w", err)
+	}
+	var imported StateDocument
+	if err := json.Unmarshal(data, &imported); err != nil {
+		return fmt.Errorf("failed to copy state document: %w", err)
+	}
+
+	state, err := imported.state()
+	if err != nil {
+		return fmt.Errorf("invalid state document: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Blobs spilled for tasks the import drops are not needed anymore
+	var dropped []TaskAttachment
+	for id, task := range m.tasks {
+		if _, kept := state.Tasks[id]; !kept {
+			dropped = append(dropped, task.Attachments...)
+		}
+	}
+	go m.deleteAttachments(context.Background(), dropped)
+
+	m.departments = state.Departments
+	m.members = state.Members
+	m.tasks = state.Tasks
+	m.teams = state.Teams
+	m.workflows = state.Workflows
+	m.workflowRuns = state.WorkflowRuns
+
+	// Bookkeeping tied to the replaced tasks and members no longer applies
+	m.taskTeams = make(map[string]string)
+	m.pendingMigrations = make(map[string]string)
+	m.routingRetries = make(map[string]int)
+	m.queueWaitAlerts = make(map[string]bool)
+	m.blockedAlerts = make(map[string]bool)
+
+	m.rebuildStats()
+	m.rebuildDependents()
+	m.rebuildTaskIndexes()
+	m.persist()
+
+	slog.Info("Department state imported",
+		"schema_version", doc.SchemaVersion,
+		"departments", len(m.departments),
+		"members", len(m.members),
+		"tasks", len(m.tasks))
+
+	return nil
+}
+
+// state indexes the document by ID, checking that IDs are unique, that
+// every entry is valid and that every reference between entries resolves
+func (doc StateDocument) state() (managerState, error) {
+	var errs []error
+	state := managerState{
+		Departments:  indexByID(doc.Departments, "department", func(d *Department) string { return d.ID }, &errs),
+		Members:      indexByID(doc.Members, "member", func(m *Member) string { return m.ID }, &errs),
+		Tasks:        indexByID(doc.Tasks, "task", func(t *Task) string { return t.ID }, &errs),
+		Teams:        indexByID(doc.Teams, "team", func(t *Team) string { return t.ID }, &errs),
+		Workflows:    indexByID(doc.Workflows, "workflow", func(w *Workflow) string { return w.ID }, &errs),
+		WorkflowRuns: indexByID(doc.WorkflowRuns, "workflow run", func(r *WorkflowRun) string { return r.ID }, &errs),
+	}
+	if len(state.Departments) == 0 {
+		errs = append(errs, fmt.Errorf("no departments"))
+	}
+
+	for _, dept := range sortedByID(state.Departments) {
+		if err := validateDepartment(dept); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, member := range sortedByID(state.Members) {
+		if _, exists := state.Departments[member.DepartmentID]; !exists {
+			errs = append(errs, fmt.Errorf("member %s belongs to unknown department %q", member.ID, member.DepartmentID))
+		}
+	}
+	for _, task := range sortedByID(state.Tasks) {
+		if _, exists := state.Departments[task.DepartmentID]; !exists {
+			errs = append(errs, fmt.Errorf("task %s references unknown department %q", task.ID, task.DepartmentID))
+		}
+		if _, exists := state.Members[task.AssignedMember]; task.AssignedMember != "" && !exists {
+			errs = append(errs, fmt.Errorf("task %s is assigned to unknown member %s", task.ID, task.AssignedMember))
+		}
+		for _, dep := range task.Dependencies {
+			if _, exists := state.Tasks[dep]; !exists {
+				errs = append(errs, fmt.Errorf("task %s depends on unknown task %s", task.ID, dep))
+			}
+		}
+	}
+	if cycle := findTaskCycle(state.Tasks); cycle != "" {
+		errs = append(errs, fmt.Errorf("task %s is part of a dependency cycle", cycle))
+	}
+	for _, team := range sortedByID(state.Teams) {
+		if _, exists := state.Departments[team.DepartmentID]; team.DepartmentID != "" && !exists {
+			errs = append(errs, fmt.Errorf("team %s belongs to unknown department %q", team.ID, team.DepartmentID))
+		}
+		for _, memberID := range append([]string{team.LeadID}, team.MemberIDs...) {
+			if _, exists := state.Members[memberID]; memberID != "" && !exists {
+				errs = append(errs, fmt.Errorf("team %s includes unknown member %s", team.ID, memberID))
+			}
+		}
+	}
+	for _, workflow := range sortedByID(state.Workflows) {
+		if err := validateWorkflow(workflow); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, run := range sortedByID(state.WorkflowRuns) {
+		if _, exists := state.Workflows[run.WorkflowID]; !exists {
+			errs = append(errs, fmt.Errorf("workflow run %s runs unknown workflow %s", run.ID, run.WorkflowID))
+		}
+		if _, exists := state.Tasks[run.TaskID]; !exists {
+			errs = append(errs, fmt.Errorf("workflow run %s belongs to unknown task %s", run.ID, run.TaskID))
+		}
+		for _, stepID := range slices.Sorted(maps.Keys(run.StepTasks)) {
+			if _, exists := state.Tasks[run.StepTasks[stepID]]; !exists {
+				errs = append(errs, fmt.Errorf("workflow run %s step %s has unknown task %s", run.ID, stepID, run.StepTasks[stepID]))
+			}
+		}
+	}
+
+	return state, errors.Join(errs...)
+}
+
+// MarshalYAML encodes the document with the same field names and value
+// formats as its JSON encoding
+func (doc StateDocument) MarshalYAML() (any, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return yamlNumbers(value), nil
+}
+
+// UnmarshalYAML decodes a document encoded by MarshalYAML
+func (doc *StateDocument) UnmarshalYAML(node *yaml.Node) error {
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, doc)
+}
+
+// yamlNumbers replaces the JSON numbers in a decoded value with integers
+// where they are whole, so durations and sizes survive as exact integers
+func yamlNumbers(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = yamlNumbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = yamlNumbers(item)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return value
+}
+
+// sortedByID returns the values of a state map in ID order
+func sortedByID[V any](entries map[string]V) []V {
+	sorted := make([]V, 0, len(entries))
+	for _, id := range slices.Sorted(maps.Keys(entries)) {
+		sorted = append(sorted, entries[id])
+	}
+	return sorted
+}
+
+// indexByID maps entries by ID, appending an error to errs for each nil
+// entry, missing ID or duplicate ID
+func indexByID[T any](entries []*T, kind string, id func(*T) string, errs *[]error) map[string]*T {
+	index := make(map[string]*T, len(entries))
+	for i, entry := range entries {
+		if entry == nil {
+			*errs = append(*errs, fmt.Errorf("%s %d is empty", kind, i))
+			continue
+		}
+		entryID := id(entry)
+		if entryID == "" {
+			*errs = append(*errs, fmt.Errorf("%s %d has no ID", kind, i))
+			continue
+		}
+		if _, exists := index[entryID]; exists {
+			*errs = append(*errs, fmt.Errorf("duplicate %s %s", kind, entryID))
+			continue
+		}
+		index[entryID] = entry
+	}
+	return index
+}
@@ -0,0 +1,115 @@
+package department
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v4"
+)
+
+func TestManagerExportImportState(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 3)
+
+	_, err := m.CreateTask(ctx, &Task{
+		ID:           "task-done",
+		DepartmentID: "dept-dev",
+		Attachments:  []TaskAttachment{{ID: "log", Name: "build.log", Content: []byte{0x00, 0xff, 'o', 'k'}}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, "task-done", TaskStatusInProgress, nil))
+	require.NoError(t, m.UpdateTaskStatus(ctx, "task-done", TaskStatusCompleted, nil))
+	_, err = m.CreateTask(ctx, &Task{ID: "task-active", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.RegisterWorkflow(&Workflow{ID: "release", TaskType: "release", Steps: []WorkflowStep{{ID: "build"}}}))
+	run, err := m.StartWorkflow(ctx, "release", &Task{ID: "wf-1", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+
+	doc, err := m.ExportState()
+	require.NoError(t, err)
+	require.Equal(t, StateSchemaVersion, doc.SchemaVersion)
+	require.Len(t, doc.Departments, 4)
+	require.Equal(t, "task-active", doc.Tasks[0].ID)
+
+	for name, codec := range map[string]struct {
+		marshal   func(any) ([]byte, error)
+		unmarshal func([]byte, any) error
+	}{
+		"json": {json.Marshal, json.Unmarshal},
+		"yaml": {yaml.Marshal, yaml.Unmarshal},
+	} {
+		data, err := codec.marshal(doc)
+		require.NoError(t, err, name)
+		var decoded StateDocument
+		require.NoError(t, codec.unmarshal(data, &decoded), name)
+
+		done := decoded.Tasks[1]
+		require.NotNil(t, done.StartedAt, name)
+		require.True(t, done.CompletedAt.Equal(*doc.Tasks[1].CompletedAt), name)
+		require.Nil(t, decoded.Tasks[0].StartedAt, name)
+		require.Equal(t, []byte{0x00, 0xff, 'o', 'k'}, done.Attachments[0].Content, name)
+
+		imported := newTestManager(t)
+		require.NoError(t, imported.ImportState(decoded), name)
+
+		member, err := imported.GetMember("dev-1")
+		require.NoError(t, err, name)
+		require.ElementsMatch(t, []string{"task-active", "wf-1-build"}, member.CurrentTasks, name)
+		stats, err := imported.GetMemberStats("dev-1")
+		require.NoError(t, err, name)
+		require.Equal(t, 1, stats.CompletedTasks, name)
+		workflow, found := imported.WorkflowForTaskType("release")
+		require.True(t, found, name)
+		require.Equal(t, "build", workflow.Steps[0].ID, name)
+
+		// The run in flight still finishes its parent
+		require.NoError(t, imported.UpdateTaskStatus(ctx, run.StepTasks["build"], TaskStatusCompleted, nil), name)
+		importedRun, err := imported.GetWorkflowRun(run.ID)
+		require.NoError(t, err, name)
+		require.Equal(t, TaskStatusCompleted, importedRun.Status, name)
+		parent, err := imported.GetTask("wf-1")
+		require.NoError(t, err, name)
+		require.Equal(t, TaskStatusCompleted, parent.Status, name)
+	}
+}
+
+func TestManagerImportStateChecksReferences(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	doc, err := m.ExportState()
+	require.NoError(t, err)
+
+	doc.Members = append(doc.Members, &Member{ID: "ghost", DepartmentID: "dept-missing"})
+	doc.Tasks = append(doc.Tasks,
+		&Task{ID: "task-1", DepartmentID: "dept-nowhere"},
+		&Task{ID: "task-2", DepartmentID: "dept-dev", AssignedMember: "dev-9", Dependencies: []string{"task-0"}},
+		&Task{ID: "task-2", DepartmentID: "dept-dev"},
+		&Task{ID: "loop-a", DepartmentID: "dept-dev", Dependencies: []string{"loop-b"}},
+		&Task{ID: "loop-b", DepartmentID: "dept-dev", Dependencies: []string{"loop-a"}},
+	)
+	doc.WorkflowRuns = append(doc.WorkflowRuns, &WorkflowRun{ID: "run-1", WorkflowID: "missing", TaskID: "task-9"})
+
+	target := newTestManager(t)
+	err = target.ImportState(doc)
+	require.ErrorContains(t, err, `member ghost belongs to unknown department "dept-missing"`)
+	require.ErrorContains(t, err, `task task-1 references unknown department "dept-nowhere"`)
+	require.ErrorContains(t, err, "task task-2 is assigned to unknown member dev-9")
+	require.ErrorContains(t, err, "task task-2 depends on unknown task task-0")
+	require.ErrorContains(t, err, "duplicate task task-2")
+	require.ErrorContains(t, err, "task loop-a is part of a dependency cycle")
+	require.ErrorContains(t, err, "workflow run run-1 runs unknown workflow missing")
+	require.ErrorContains(t, err, "workflow run run-1 belongs to unknown task task-9")
+
+	// Nothing was imported
+	_, err = target.GetMember("dev-1")
+	require.Error(t, err)
+
+	doc.SchemaVersion = 2
+	require.ErrorContains(t, target.ImportState(doc), "unsupported state schema version 2")
+}
@@ -0,0 +1,169 @@
+package department
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// StatusReport summarizes all departments, members and tasks. The report is
+// built under a single read lock, and department stats are computed in the
+// same pass, so its counts are consistent with each other: the department
+// totals add up to the member and task summaries.
+func (m *Manager) StatusReport() *DepartmentStatusReport {
+	// The health checker takes the manager lock while holding its own, so
+	// its state is read first
+	var health map[string]bool
+	if m.healthChecker != nil {
+		health = m.healthChecker.healthSnapshot()
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	report := &DepartmentStatusReport{
+		Departments: make(map[string]DepartmentStatus, len(m.departments)),
+		GeneratedAt: now,
+	}
+
+	for id, dept := range m.departments {
+		status := DepartmentStatus{
+			ID:        id,
+			Name:      dept.Name,
+			Type:      dept.Type,
+			AutoScale: dept.AutoScale,
+			Disabled:  dept.Disabled,
+		}
+		// Stats are derived from the same members and tasks as the totals
+		// below rather than read from the periodically refreshed copy
+		status.Stats = m.computeDepartmentStats(id, now)
+		if m.healthChecker != nil {
+			status.Health = m.departmentHealth(id, health)
+		}
+		report.Departments[id] = status
+	}
+
+	for _, member := range m.members {
+		report.Members.Total++
+		switch member.Status {
+		case MemberStatusOnline:
+			report.Members.Online++
+		case MemberStatusBusy:
+			report.Members.Busy++
+		case MemberStatusOffline:
+			report.Members.Offline++
+		case MemberStatusUnhealthy:
+			report.Members.Unhealthy++
+		case MemberStatusDraining:
+			report.Members.Draining++
+		}
+	}
+
+	for _, task := range m.tasks {
+		report.Tasks.add(task.Status)
+	}
+
+	return report
+}
+
+// add counts a task with the given status
+func (s *TaskStatusSummary) add(status TaskStatus) {
+	s.Total++
+	switch status {
+	case TaskStatusQueued:
+		s.Queued++
+	case TaskStatusBlocked:
+		s.Blocked++
+	case TaskStatusAssigned:
+		s.Assigned++
+	case TaskStatusInProgress:
+		s.Active++
+	case TaskStatusCompleted:
+		s.Completed++
+	case TaskStatusFailed:
+		s.Failed++
+	case TaskStatusCancelled:
+		s.Cancelled++
+	}
+}
+
+// DepartmentSnapshot returns a department with its members and their
+// health, task counts and scaling state. Like StatusReport it is built under
+// a single manager read lock, so the counts agree with the member list.
+func (m *Manager) DepartmentSnapshot(departmentID string) (DepartmentSnapshot, error) {
+	// The health checker and scaler take the manager lock while holding
+	// their own, so their state is read first
+	var health map[string]MemberHealth
+	if m.healthChecker != nil {
+		health = m.healthChecker.healthStatuses()
+	}
+	var scaling *DepartmentScalingState
+	if m.scaler != nil {
+		state := m.scaler.departmentState(departmentID)
+		scaling = &state
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	dept, exists := m.departments[departmentID]
+	if !exists {
+		return DepartmentSnapshot{}, fmt.Errorf("department %s does not exist", departmentID)
+	}
+
+	snapshot := DepartmentSnapshot{
+		Department:  *dept.clone(),
+		Members:     []MemberSnapshot{},
+		Scaling:     scaling,
+		GeneratedAt: time.Now(),
+	}
+
+	for _, member := range m.listMembers(departmentID) {
+		entry := MemberSnapshot{Member: *member.clone()}
+		if h, checked := health[member.ID]; checked {
+			entry.Health = &h
+		}
+		snapshot.Members = append(snapshot.Members, entry)
+	}
+	slices.SortFunc(snapshot.Members, func(a, b MemberSnapshot) int {
+		return strings.Compare(a.Member.ID, b.Member.ID)
+	})
+
+	for _, task := range m.tasks {
+		if task.DepartmentID == departmentID {
+			snapshot.Tasks.add(task.Status)
+		}
+	}
+	snapshot.QueueDepth = snapshot.Tasks.Queued
+
+	if m.healthChecker != nil {
+		healthy := make(map[string]bool, len(health))
+		for id, h := range health {
+			healthy[id] = h.IsHealthy
+		}
+		snapshot.Health = m.departmentHealth(departmentID, healthy)
+	}
+
+	return snapshot, nil
+}
+
+// departmentHealth rolls up the health of a department's members. Offline
+// members are not checked and not counted; members without a failed check
+// count as healthy.
+func (m *Manager) departmentHealth(departmentID string, health map[string]bool) *DepartmentHealth {
+	rollup := &DepartmentHealth{}
+	for _, member := range m.members {
+		if member.DepartmentID != departmentID || member.Status == MemberStatusOffline {
+			continue
+		}
+		if healthy, checked := health[member.ID]; checked && !healthy {
+			rollup.UnhealthyMembers++
+		} else {
+			rollup.HealthyMembers++
+		}
+	}
+	rollup.State = m.healthChecker.departmentHealthState(rollup.HealthyMembers, rollup.UnhealthyMembers)
+	return rollup
+}
@@ -0,0 +1,294 @@
+package department
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerStatusReport(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 1)
+	qa := registerTestMember(t, m, "qa-1", "dept-qa", RoleQA, 1)
+	require.NoError(t, m.UpdateMemberStatus(ctx, qa.ID, MemberStatusOffline))
+
+	done, err := m.CreateTask(ctx, &Task{ID: "done", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, done.ID, TaskStatusCompleted, nil))
+	_, err = m.CreateTask(ctx, &Task{ID: "running", DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, "running", TaskStatusInProgress, nil))
+	_, err = m.CreateTask(ctx, &Task{ID: "waiting", DepartmentID: "dept-qa"})
+	require.NoError(t, err)
+
+	report := m.StatusReport()
+
+	require.Len(t, report.Departments, len(m.ListDepartments()))
+	dev := report.Departments["dept-dev"]
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	require.Equal(t, dept.Name, dev.Name)
+	require.Equal(t, dept.Type, dev.Type)
+	require.Equal(t, dept.AutoScale, dev.AutoScale)
+
+	stats, err := m.GetDepartmentStats("dept-dev")
+	require.NoError(t, err)
+	stats.LastUpdated = dev.Stats.LastUpdated
+	require.Equal(t, *stats, dev.Stats)
+	require.Equal(t, 2, dev.Stats.TotalMembers)
+	require.Equal(t, 2, dev.Stats.TotalTasks)
+	require.Equal(t, 1, dev.Stats.CompletedTasks)
+
+	require.Equal(t, MemberStatusSummary{Total: 3, Online: 1, Busy: 1, Offline: 1}, report.Members)
+	require.Equal(t, TaskStatusSummary{Total: 3, Queued: 1, Active: 1, Completed: 1}, report.Tasks)
+
+	// The report does not share state with the manager
+	dev.Stats.RoleDistribution["developer"] = 99
+	require.Equal(t, 2, stats.RoleDistribution["developer"])
+}
+
+func TestManagerStatusReportHealth(t *testing.T) {
+	t.Parallel()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(healthy.Close)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(failing.Close)
+
+	m, err := NewManager(t.Context(), &DepartmentConfig{
+		Enabled: true,
+		HealthCheck: HealthCheckConfig{
+			Enabled:            true,
+			CheckInterval:      time.Hour,
+			Timeout:            time.Second,
+			UnhealthyThreshold: 1,
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, m.Stop()) })
+
+	// Half of the developers fail their health checks
+	for i, endpoint := range []string{healthy.URL, healthy.URL, failing.URL, failing.URL} {
+		member := registerTestMember(t, m, fmt.Sprintf("dev-%d", i), "dept-dev", RoleDeveloper, 1)
+		m.mu.Lock()
+		member.Endpoint = endpoint
+		m.mu.Unlock()
+		m.healthChecker.checkMemberHealth(member)
+	}
+	qa := registerTestMember(t, m, "qa-1", "dept-qa", RoleQA, 1)
+	m.mu.Lock()
+	qa.Endpoint = failing.URL
+	m.mu.Unlock()
+	m.healthChecker.checkMemberHealth(qa)
+
+	report := m.StatusReport()
+	require.Equal(t, &DepartmentHealth{HealthyMembers: 2, UnhealthyMembers: 2, State: DepartmentDegraded}, report.Departments["dept-dev"].Health)
+	require.Equal(t, &DepartmentHealth{UnhealthyMembers: 1, State: DepartmentCritical}, report.Departments["dept-qa"].Health)
+	require.Equal(t, &DepartmentHealth{State: DepartmentHealthy}, report.Departments["dept-security"].Health)
+
+	// Without health checking there is nothing to roll up
+	require.Nil(t, newTestManager(t).StatusReport().Departments["dept-dev"].Health)
+}
+
+func TestManagerStatusReportConsistentUnderChurn(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-0", "dept-dev", RoleDeveloper, 2)
+
+	// The churn stops at its first error, which is checked at the end
+	stop := make(chan struct{})
+	churnErr := make(chan error, 1)
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			// Members still holding a task cannot be unregistered
+			id := fmt.Sprintf("dev-%d", i%4+1)
+			var err error
+			if member, getErr := m.GetMember(id); getErr != nil {
+				err = m.RegisterMember(ctx, &Member{ID: id, Name: id, Role: RoleDeveloper, DepartmentID: "dept-dev", MaxConcurrent: 1})
+			} else if len(member.CurrentTasks) == 0 {
+				err = m.UnregisterMember(ctx, id)
+			}
+			if err != nil {
+				churnErr <- err
+				return
+			}
+
+			task, err := m.CreateTask(ctx, &Task{ID: fmt.Sprintf("task-%d", i), DepartmentID: "dept-dev"})
+			if err == nil && task.AssignedMember != "" && rand.Intn(2) == 0 {
+				err = m.UpdateTaskStatus(ctx, task.ID, TaskStatusCompleted, nil)
+			}
+			if err != nil {
+				churnErr <- err
+				return
+			}
+		}
+	})
+
+	for range 200 {
+		report := m.StatusReport()
+
+		var members, active, roles, tasks, completed, failed int
+		for _, dept := range report.Departments {
+			members += dept.Stats.TotalMembers
+			active += dept.Stats.ActiveMembers
+			for _, count := range dept.Stats.RoleDistribution {
+				roles += count
+			}
+			tasks += dept.Stats.TotalTasks
+			completed += dept.Stats.CompletedTasks
+			failed += dept.Stats.FailedTasks
+		}
+		require.Equal(t, report.Members.Total, members)
+		require.Equal(t, report.Members.Online+report.Members.Busy, active)
+		require.Equal(t, members, roles)
+		require.Equal(t, report.Tasks.Total, tasks)
+		require.Equal(t, report.Tasks.Completed, completed)
+		require.Equal(t, report.Tasks.Failed, failed)
+		require.Equal(t, report.Tasks.Total, report.Tasks.Queued+report.Tasks.Blocked+report.Tasks.Assigned+
+			report.Tasks.Active+report.Tasks.Completed+report.Tasks.Failed+report.Tasks.Cancelled)
+	}
+	close(stop)
+	wg.Wait()
+	close(churnErr)
+	require.NoError(t, <-churnErr)
+}
+
+func TestManagerDepartmentSnapshot(t *testing.T) {
+	t.Parallel()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(failing.Close)
+
+	ctx := t.Context()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled: true,
+		HealthCheck: HealthCheckConfig{
+			Enabled:            true,
+			CheckInterval:      time.Hour,
+			Timeout:            time.Second,
+			UnhealthyThreshold: 1,
+		},
+		AutoScaling: AutoScalingConfig{
+			Enabled:        true,
+			CheckInterval:  time.Hour,
+			CooldownPeriod: time.Hour,
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, m.Stop()) })
+
+	registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 1)
+	sick := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	m.mu.Lock()
+	sick.Endpoint = failing.URL
+	m.mu.Unlock()
+	m.healthChecker.checkMemberHealth(sick)
+	registerTestMember(t, m, "qa-1", "dept-qa", RoleQA, 1)
+
+	for _, id := range []string{"done", "running", "waiting"} {
+		_, err := m.CreateTask(ctx, &Task{ID: id, DepartmentID: "dept-dev"})
+		require.NoError(t, err)
+	}
+	require.NoError(t, m.UpdateTaskStatus(ctx, "done", TaskStatusCompleted, nil))
+	require.NoError(t, m.UpdateTaskStatus(ctx, "running", TaskStatusInProgress, nil))
+	_, err = m.CreateTask(ctx, &Task{ID: "qa-task", DepartmentID: "dept-qa"})
+	require.NoError(t, err)
+
+	dept, err := m.GetDepartment("dept-dev")
+	require.NoError(t, err)
+	m.scaler.mu.Lock()
+	m.scaler.executeScalingAction(dept, scaleUp, "test")
+	m.scaler.scaleCooldown[dept.ID] = time.Now()
+	m.scaler.mu.Unlock()
+
+	snapshot, err := m.DepartmentSnapshot("dept-dev")
+	require.NoError(t, err)
+	require.Equal(t, "dept-dev", snapshot.Department.ID)
+
+	// Members come sorted, with the health of those checked
+	require.Len(t, snapshot.Members, 3)
+	require.Equal(t, "dev-1", snapshot.Members[0].Member.ID)
+	require.NotNil(t, snapshot.Members[0].Health)
+	require.False(t, snapshot.Members[0].Health.IsHealthy)
+	require.Equal(t, "dev-2", snapshot.Members[1].Member.ID)
+	require.Nil(t, snapshot.Members[1].Health)
+	require.Equal(t, "true", snapshot.Members[2].Member.Metadata["auto_scaled"])
+	require.Equal(t, &DepartmentHealth{HealthyMembers: 2, UnhealthyMembers: 1, State: DepartmentDegraded}, snapshot.Health)
+
+	require.Equal(t, TaskStatusSummary{Total: 3, Queued: 1, Active: 1, Completed: 1}, snapshot.Tasks)
+	require.Equal(t, 1, snapshot.QueueDepth)
+
+	require.NotNil(t, snapshot.Scaling)
+	require.Equal(t, scaleUp, snapshot.Scaling.LastScaleAction)
+	require.False(t, snapshot.Scaling.ScaleUpCooldownUntil.IsZero())
+
+	require.Empty(t, snapshot.Scaling.DrainingMembers)
+
+	_, err = m.DepartmentSnapshot("dept-missing")
+	require.ErrorContains(t, err, "department dept-missing does not exist")
+
+	// Without health checking or auto-scaling those parts are left out
+	snapshot, err = newTestManager(t).DepartmentSnapshot("dept-qa")
+	require.NoError(t, err)
+	require.Nil(t, snapshot.Health)
+	require.Nil(t, snapshot.Scaling)
+	require.Empty(t, snapshot.Members)
+}
+
+func TestManagerDepartmentSnapshotDrainingMembers(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewManager(t.Context(), &DepartmentConfig{
+		Enabled: true,
+		AutoScaling: AutoScalingConfig{
+			Enabled:       true,
+			CheckInterval: time.Hour,
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, m.Stop()) })
+
+	dev := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 1)
+	qa := registerTestMember(t, m, "qa-1", "dept-qa", RoleQA, 1)
+
+	m.scaler.mu.Lock()
+	m.scaler.startDrain(dev, 0)
+	m.scaler.startDrain(qa, 0)
+	m.scaler.mu.Unlock()
+
+	// Each department only reports its own draining members
+	snapshot, err := m.DepartmentSnapshot("dept-dev")
+	require.NoError(t, err)
+	require.Equal(t, []string{"dev-1"}, snapshot.Scaling.DrainingMembers)
+
+	snapshot, err = m.DepartmentSnapshot("dept-qa")
+	require.NoError(t, err)
+	require.Equal(t, []string{"qa-1"}, snapshot.Scaling.DrainingMembers)
+}
@@ -0,0 +1,169 @@
+package department
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TaskSortKey selects the order of a task listing
+type TaskSortKey string
+
+const (
+	// TaskSortCreated orders tasks by creation time. It is the default.
+	TaskSortCreated TaskSortKey = "created"
+	// TaskSortUpdated orders tasks by when they last changed
+	TaskSortUpdated TaskSortKey = "updated"
+	// TaskSortPriority orders tasks from low to critical priority, then by
+	// creation time
+	TaskSortPriority TaskSortKey = "priority"
+)
+
+// TaskFilter selects and orders a page of tasks. Zero fields do not filter.
+type TaskFilter struct {
+	DepartmentID   string     `json:"department_id,omitempty"`
+	Status         TaskStatus `json:"status,omitempty"`
+	AssignedMember string     `json:"assigned_member,omitempty"`
+	// Tag matches tasks carrying the tag, ignoring case
+	Tag string `json:"tag,omitempty"`
+	// RequiredSkill matches tasks requiring the skill, ignoring case
+	RequiredSkill string   `json:"required_skill,omitempty"`
+	Priority      Priority `json:"priority,omitempty"`
+	// CreatedAfter and CreatedBefore bound the creation time, exclusive
+	CreatedAfter  time.Time `json:"created_after,omitempty"`
+	CreatedBefore time.Time `json:"created_before,omitempty"`
+
+	SortBy TaskSortKey `json:"sort_by,omitempty"`
+	// Descending reverses the order, putting the newest or most urgent
+	// tasks first
+	Descending bool `json:"descending,omitempty"`
+
+	Offset int `json:"offset,omitempty"`
+	// Limit caps the page size. Zero returns every task from Offset on.
+	Limit int `json:"limit,omitempty"`
+}
+
+// TaskPage is one page of a task listing
+type TaskPage struct {
+	Tasks []*Task `json:"tasks"`
+	// Total counts every task matching the filter, across all pages
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	Limit  int `json:"limit,omitempty"`
+}
+
+// ListTasksPaged returns copies of the tasks matching the filter, sorted and
+// paged as it asks. Ties in the sort order are broken by task ID so pages are
+// stable between calls.
+func (m *Manager) ListTasksPaged(filter TaskFilter) (TaskPage, error) {
+	if filter.Offset < 0 || filter.Limit < 0 {
+		return TaskPage{}, fmt.Errorf("offset and limit must not be negative")
+	}
+	compare, err := taskComparator(filter.SortBy)
+	if err != nil {
+		return TaskPage{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var matching []*Task
+	for _, task := range m.tasks {
+		if filter.matches(task) {
+			matching = append(matching, task)
+		}
+	}
+
+	slices.SortFunc(matching, func(a, b *Task) int {
+		c := compare(a, b)
+		if c == 0 {
+			c = strings.Compare(a.ID, b.ID)
+		}
+		if filter.Descending {
+			return -c
+		}
+		return c
+	})
+
+	page := TaskPage{Total: len(matching), Offset: filter.Offset, Limit: filter.Limit}
+	start := min(filter.Offset, len(matching))
+	end := len(matching)
+	if filter.Limit > 0 {
+		end = min(start+filter.Limit, end)
+	}
+	page.Tasks = make([]*Task, 0, end-start)
+	for _, task := range matching[start:end] {
+		page.Tasks = append(page.Tasks, task.clone())
+	}
+	return page, nil
+}
+
+// matches reports whether a task passes every filter field that is set
+func (f TaskFilter) matches(task *Task) bool {
+	switch {
+	case f.DepartmentID != "" && task.DepartmentID != f.DepartmentID,
+		f.Status != "" && task.Status != f.Status,
+		f.AssignedMember != "" && task.AssignedMember != f.AssignedMember,
+		f.Priority != "" && task.Priority != f.Priority,
+		!f.CreatedAfter.IsZero() && !task.CreatedAt.After(f.CreatedAfter),
+		!f.CreatedBefore.IsZero() && !task.CreatedAt.Before(f.CreatedBefore):
+		return false
+	}
+	if f.Tag != "" && !containsFold(task.Tags, f.Tag) {
+		return false
+	}
+	if f.RequiredSkill != "" && !containsFold(task.RequiredSkills, f.RequiredSkill) {
+		return false
+	}
+	return true
+}
+
+// containsFold reports whether values contains value, ignoring case
+func containsFold(values []string, value string) bool {
+	return slices.ContainsFunc(values, func(v string) bool {
+		return strings.EqualFold(v, value)
+	})
+}
+
+// ListTasksByTag returns copies of the tasks carrying a tag, ignoring case,
+// oldest first. An empty tag is an error rather than a match for every task.
+func (m *Manager) ListTasksByTag(tag string) ([]*Task, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return nil, fmt.Errorf("tag must not be empty")
+	}
+	page, err := m.ListTasksPaged(TaskFilter{Tag: tag})
+	return page.Tasks, err
+}
+
+// ListTasksBySkill returns copies of the tasks requiring a skill, ignoring
+// case, oldest first. An empty skill is an error rather than a match for
+// every task.
+func (m *Manager) ListTasksBySkill(skill string) ([]*Task, error) {
+	skill = strings.TrimSpace(skill)
+	if skill == "" {
+		return nil, fmt.Errorf("skill must not be empty")
+	}
+	page, err := m.ListTasksPaged(TaskFilter{RequiredSkill: skill})
+	return page.Tasks, err
+}
+
+// taskComparator returns the ascending comparison for a sort key
+func taskComparator(key TaskSortKey) (func(a, b *Task) int, error) {
+	switch key {
+	case "", TaskSortCreated:
+		return func(a, b *Task) int { return a.CreatedAt.Compare(b.CreatedAt) }, nil
+	case TaskSortUpdated:
+		return func(a, b *Task) int { return a.UpdatedAt.Compare(b.UpdatedAt) }, nil
+	case TaskSortPriority:
+		return func(a, b *Task) int {
+			if c := cmp.Compare(a.Priority.Rank(), b.Priority.Rank()); c != 0 {
+				return c
+			}
+			return a.CreatedAt.Compare(b.CreatedAt)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown task sort key %q", key)
+}
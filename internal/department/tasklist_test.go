@@ -0,0 +1,129 @@
+package department
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerListTasksPaged(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+
+	base := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	priorities := []Priority{PriorityLow, PriorityCritical, PriorityMedium, PriorityHigh, PriorityLow}
+	for i, priority := range priorities {
+		task := &Task{ID: fmt.Sprintf("task-%d", i), DepartmentID: "dept-dev", Priority: priority}
+		if i%2 == 0 {
+			task.Tags = []string{"Backend"}
+		}
+		_, err := m.CreateTask(ctx, task)
+		require.NoError(t, err)
+	}
+	_, err := m.CreateTask(ctx, &Task{ID: "qa-task", DepartmentID: "dept-qa", Priority: PriorityHigh})
+	require.NoError(t, err)
+
+	// Creation times an hour apart, with qa-task the newest
+	m.mu.Lock()
+	for i := range priorities {
+		m.tasks[fmt.Sprintf("task-%d", i)].CreatedAt = base.Add(time.Duration(i) * time.Hour)
+	}
+	m.tasks["qa-task"].CreatedAt = base.Add(10 * time.Hour)
+	m.mu.Unlock()
+
+	ids := func(page TaskPage) []string {
+		var ids []string
+		for _, task := range page.Tasks {
+			ids = append(ids, task.ID)
+		}
+		return ids
+	}
+
+	page, err := m.ListTasksPaged(TaskFilter{DepartmentID: "dept-dev", Offset: 1, Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, 5, page.Total)
+	require.Equal(t, []string{"task-1", "task-2"}, ids(page))
+
+	page, err = m.ListTasksPaged(TaskFilter{SortBy: TaskSortPriority, Descending: true, Limit: 3})
+	require.NoError(t, err)
+	require.Equal(t, 6, page.Total)
+	require.Equal(t, []string{"task-1", "qa-task", "task-3"}, ids(page))
+
+	page, err = m.ListTasksPaged(TaskFilter{Tag: "backend", Priority: PriorityLow})
+	require.NoError(t, err)
+	require.Equal(t, []string{"task-0", "task-4"}, ids(page))
+
+	page, err = m.ListTasksPaged(TaskFilter{AssignedMember: "dev-1"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"task-0"}, ids(page))
+
+	page, err = m.ListTasksPaged(TaskFilter{
+		CreatedAfter:  base,
+		CreatedBefore: base.Add(3 * time.Hour),
+		Status:        TaskStatusQueued,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"task-1", "task-2"}, ids(page))
+
+	// Past the end is an empty page, not an error
+	page, err = m.ListTasksPaged(TaskFilter{Offset: 100, Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, page.Tasks)
+	require.Equal(t, 6, page.Total)
+
+	_, err = m.ListTasksPaged(TaskFilter{SortBy: "size"})
+	require.ErrorContains(t, err, `unknown task sort key "size"`)
+	_, err = m.ListTasksPaged(TaskFilter{Limit: -1})
+	require.Error(t, err)
+
+	// The old listing keeps working on top of the new one
+	require.Len(t, m.ListTasks("dept-dev", TaskStatusQueued), 4)
+	require.Len(t, m.ListTasks("", ""), 6)
+}
+
+func TestManagerListTasksByTagAndSkill(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m := newTestManager(t)
+	for _, task := range []*Task{
+		{ID: "audit", DepartmentID: "dept-security", Tags: []string{"Security", "quarterly"}, RequiredSkills: []string{"SAST"}},
+		{ID: "pentest", DepartmentID: "dept-security", Tags: []string{"security"}},
+		{ID: "feature", DepartmentID: "dept-dev", Tags: []string{"frontend"}, RequiredSkills: []string{"sast", "react"}},
+	} {
+		_, err := m.CreateTask(ctx, task)
+		require.NoError(t, err)
+	}
+
+	ids := func(tasks []*Task) []string {
+		var ids []string
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	tasks, err := m.ListTasksByTag("SECURITY")
+	require.NoError(t, err)
+	require.Equal(t, []string{"audit", "pentest"}, ids(tasks))
+
+	tasks, err = m.ListTasksBySkill(" Sast ")
+	require.NoError(t, err)
+	require.Equal(t, []string{"audit", "feature"}, ids(tasks))
+
+	tasks, err = m.ListTasksByTag("backend")
+	require.NoError(t, err)
+	require.Empty(t, tasks)
+
+	_, err = m.ListTasksByTag("  ")
+	require.ErrorContains(t, err, "tag must not be empty")
+	_, err = m.ListTasksBySkill("")
+	require.ErrorContains(t, err, "skill must not be empty")
+}
@@ -0,0 +1,265 @@
+package department
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+)
+
+// CreateTeam registers a team led by a lead-role member. The lead and all
+// members must belong to the team's department; members report to the lead.
+func (m *Manager) CreateTeam(ctx context.Context, team *Team) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if team.ID == "" {
+		team.ID = fmt.Sprintf("team-%d", time.Now().UnixNano())
+	}
+	if _, exists := m.teams[team.ID]; exists {
+		return fmt.Errorf("team %s already exists", team.ID)
+	}
+	if _, exists := m.departments[team.DepartmentID]; !exists {
+		return fmt.Errorf("department %s does not exist", team.DepartmentID)
+	}
+
+	lead, err := m.validateTeamLead(team)
+	if err != nil {
+		return err
+	}
+	for _, memberID := range team.MemberIDs {
+		if _, err := m.validateTeamMember(team, memberID); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	team.LeadRole = lead.Role
+	team.CreatedAt = now
+	team.UpdatedAt = now
+
+	m.teams[team.ID] = team
+	for _, memberID := range team.MemberIDs {
+		m.linkTeamMember(lead, m.members[memberID])
+	}
+	m.refreshTeamRoles(team)
+	m.persist()
+
+	slog.Info("Team created",
+		"team_id", team.ID,
+		"department", team.DepartmentID,
+		"lead_id", lead.ID,
+		"members", len(team.MemberIDs))
+
+	return nil
+}
+
+// GetTeam returns a team by ID
+func (m *Manager) GetTeam(teamID string) (*Team, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	team, exists := m.teams[teamID]
+	if !exists {
+		return nil, fmt.Errorf("team %s does not exist", teamID)
+	}
+	return team, nil
+}
+
+// ListTeams returns all teams, optionally filtered by department
+func (m *Manager) ListTeams(departmentID string) []*Team {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	teams := make([]*Team, 0)
+	for _, team := range m.teams {
+		if departmentID == "" || team.DepartmentID == departmentID {
+			teams = append(teams, team)
+		}
+	}
+	return teams
+}
+
+// AddTeamMember adds a member to a team and makes it report to the team lead
+func (m *Manager) AddTeamMember(teamID, memberID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	team, exists := m.teams[teamID]
+	if !exists {
+		return fmt.Errorf("team %s does not exist", teamID)
+	}
+	if slices.Contains(team.MemberIDs, memberID) {
+		return fmt.Errorf("member %s is already in team %s", memberID, teamID)
+	}
+
+	member, err := m.validateTeamMember(team, memberID)
+	if err != nil {
+		return err
+	}
+
+	team.MemberIDs = append(team.MemberIDs, memberID)
+	team.UpdatedAt = time.Now()
+	if lead, exists := m.members[team.LeadID]; exists {
+		m.linkTeamMember(lead, member)
+	}
+	m.refreshTeamRoles(team)
+	m.persist()
+
+	slog.Info("Team member added", "team_id", teamID, "member_id", memberID)
+
+	return nil
+}
+
+// RemoveTeamMember removes a member from a team
+func (m *Manager) RemoveTeamMember(teamID, memberID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	team, exists := m.teams[teamID]
+	if !exists {
+		return fmt.Errorf("team %s does not exist", teamID)
+	}
+
+	index := slices.Index(team.MemberIDs, memberID)
+	if index < 0 {
+		return fmt.Errorf("member %s is not in team %s", memberID, teamID)
+	}
+
+	team.MemberIDs = slices.Delete(team.MemberIDs, index, index+1)
+	team.UpdatedAt = time.Now()
+	m.unlinkTeamMember(team.LeadID, memberID)
+	m.refreshTeamRoles(team)
+	m.persist()
+
+	slog.Info("Team member removed", "team_id", teamID, "member_id", memberID)
+
+	return nil
+}
+
+// DissolveTeam removes a team and clears the reporting lines it created
+func (m *Manager) DissolveTeam(teamID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	team, exists := m.teams[teamID]
+	if !exists {
+		return fmt.Errorf("team %s does not exist", teamID)
+	}
+
+	for _, memberID := range team.MemberIDs {
+		m.unlinkTeamMember(team.LeadID, memberID)
+	}
+	delete(m.teams, teamID)
+	m.persist()
+
+	slog.Info("Team dissolved", "team_id", teamID)
+
+	return nil
+}
+
+// validateTeamLead checks the team lead exists, holds a lead role and belongs
+// to the team's department
+func (m *Manager) validateTeamLead(team *Team) (*Member, error) {
+	lead, exists := m.members[team.LeadID]
+	if !exists {
+		return nil, fmt.Errorf("team lead %s does not exist", team.LeadID)
+	}
+	if !m.config.isLeadRole(lead.Role) {
+		return nil, fmt.Errorf("team lead %s has non-lead role %s", lead.ID, lead.Role)
+	}
+	if lead.DepartmentID != team.DepartmentID {
+		return nil, fmt.Errorf("team lead %s belongs to department %s, not %s", lead.ID, lead.DepartmentID, team.DepartmentID)
+	}
+	return lead, nil
+}
+
+// validateTeamMember checks a member exists and belongs to the team's department
+func (m *Manager) validateTeamMember(team *Team, memberID string) (*Member, error) {
+	member, exists := m.members[memberID]
+	if !exists {
+		return nil, fmt.Errorf("member %s does not exist", memberID)
+	}
+	if member.DepartmentID != team.DepartmentID {
+		return nil, fmt.Errorf("member %s belongs to department %s, not %s", memberID, member.DepartmentID, team.DepartmentID)
+	}
+	return member, nil
+}
+
+// linkTeamMember makes member report to lead
+func (m *Manager) linkTeamMember(lead, member *Member) {
+	if lead.ID == member.ID {
+		return
+	}
+	member.ReportsTo = lead.ID
+	if !slices.Contains(lead.TeamMembers, member.ID) {
+		lead.TeamMembers = append(lead.TeamMembers, member.ID)
+	}
+}
+
+// unlinkTeamMember removes the reporting line between a lead and a member
+func (m *Manager) unlinkTeamMember(leadID, memberID string) {
+	if member, exists := m.members[memberID]; exists && member.ReportsTo == leadID {
+		member.ReportsTo = ""
+	}
+	if lead, exists := m.members[leadID]; exists {
+		lead.TeamMembers = slices.DeleteFunc(lead.TeamMembers, func(id string) bool {
+			return id == memberID
+		})
+	}
+}
+
+// refreshTeamRoles recomputes the distinct roles present in a team
+func (m *Manager) refreshTeamRoles(team *Team) {
+	roles := []MemberRole{team.LeadRole}
+	for _, memberID := range team.MemberIDs {
+		if member, exists := m.members[memberID]; exists && !slices.Contains(roles, member.Role) {
+			roles = append(roles, member.Role)
+		}
+	}
+	team.Roles = roles
+}
+
+// finishSubtasks ends the unfinished team subtasks of a task along with it:
+// they complete or fail with the task, or are cancelled with it, which frees
+// the capacity they held. The caller must hold the manager lock.
+func (m *Manager) finishSubtasks(ctx context.Context, task *Task) {
+	for _, id := range task.Subtasks {
+		subtask, exists := m.tasks[id]
+		if !exists || isTaskDone(subtask.Status) {
+			continue
+		}
+		if task.Status == TaskStatusCancelled {
+			m.cancelTask(ctx, subtask, fmt.Sprintf("parent task %s was cancelled", task.ID))
+			continue
+		}
+		if err := m.updateTaskStatus(ctx, id, task.Status, nil); err != nil {
+			slog.Warn("Failed to finish subtask", "task_id", id, "parent_task", task.ID, "error", err)
+		}
+	}
+}
+
+// dropSubtasks removes the team subtasks of a task and frees the capacity
+// they held, so the task can be assigned afresh. The caller must hold the
+// manager lock.
+func (m *Manager) dropSubtasks(task *Task) {
+	for _, id := range task.Subtasks {
+		subtask, exists := m.tasks[id]
+		if !exists {
+			continue
+		}
+		if subtask.AssignedMember != "" {
+			m.releaseTask(subtask.AssignedMember, id)
+		}
+		delete(m.tasks, id)
+		m.unindexTask(subtask)
+		m.taskEvents.Publish(pubsub.DeletedEvent, subtask)
+	}
+
+	task.Subtasks = nil
+	task.AssignedTeam = ""
+	delete(task.Metadata, metadataCoordinator)
+}
@@ -0,0 +1,65 @@
+package department
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerTeamLifecycle(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	lead := registerTestMember(t, m, "lead-1", "dept-dev", RoleLeadDev, 2)
+	dev1 := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+	dev2 := registerTestMember(t, m, "dev-2", "dept-dev", RoleDeveloper, 2)
+
+	team := &Team{ID: "team-1", Name: "Core", DepartmentID: "dept-dev", LeadID: lead.ID, MemberIDs: []string{dev1.ID}}
+	require.NoError(t, m.CreateTeam(ctx, team))
+	require.Equal(t, RoleLeadDev, team.LeadRole)
+	require.Equal(t, lead.ID, dev1.ReportsTo)
+	require.Equal(t, []string{dev1.ID}, lead.TeamMembers)
+
+	got, err := m.GetTeam(team.ID)
+	require.NoError(t, err)
+	require.Equal(t, team, got)
+	require.Len(t, m.ListTeams("dept-dev"), 1)
+	require.Empty(t, m.ListTeams("dept-qa"))
+
+	require.NoError(t, m.AddTeamMember(team.ID, dev2.ID))
+	require.Equal(t, lead.ID, dev2.ReportsTo)
+	require.Equal(t, []string{dev1.ID, dev2.ID}, lead.TeamMembers)
+	require.ErrorContains(t, m.AddTeamMember(team.ID, dev2.ID), "already in team")
+
+	require.NoError(t, m.RemoveTeamMember(team.ID, dev1.ID))
+	require.Empty(t, dev1.ReportsTo)
+	require.Equal(t, []string{dev2.ID}, team.MemberIDs)
+	require.Equal(t, []string{dev2.ID}, lead.TeamMembers)
+
+	require.NoError(t, m.DissolveTeam(team.ID))
+	require.Empty(t, dev2.ReportsTo)
+	require.Empty(t, lead.TeamMembers)
+	_, err = m.GetTeam(team.ID)
+	require.Error(t, err)
+}
+
+func TestManagerCreateTeamValidation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	lead := registerTestMember(t, m, "lead-1", "dept-dev", RoleLeadDev, 2)
+	dev := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+	qa := registerTestMember(t, m, "qa-1", "dept-qa", RoleQA, 2)
+
+	err := m.CreateTeam(ctx, &Team{DepartmentID: "dept-dev", LeadID: dev.ID})
+	require.ErrorContains(t, err, "non-lead role developer")
+
+	err = m.CreateTeam(ctx, &Team{DepartmentID: "dept-dev", LeadID: lead.ID, MemberIDs: []string{qa.ID}})
+	require.ErrorContains(t, err, "belongs to department dept-qa")
+
+	require.Empty(t, m.ListTeams(""))
+	require.Empty(t, qa.ReportsTo)
+}
@@ -0,0 +1,135 @@
+package department
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// maxRoutingTraces bounds how many tasks keep a routing trace; the oldest
+// traces are dropped first
+const maxRoutingTraces = 1000
+
+// maxTracedRejections bounds the rejected members recorded per trace, in
+// member ID order
+const maxTracedRejections = 50
+
+// RoutingTrace records what the router saw the last time it routed a task:
+// the members it could pick from with their scores, and why the other
+// members of the department were passed over. Traces are only kept with
+// TaskRoutingConfig.TraceRouting set.
+type RoutingTrace struct {
+	TaskID       string          `json:"task_id"`
+	DepartmentID string          `json:"department_id"`
+	Strategy     RoutingStrategy `json:"strategy"`
+	// CandidateCount is how many members were suitable for the task
+	CandidateCount int `json:"candidate_count"`
+	// Candidates are the suitable members with the score the strategy gave
+	// them, highest first. Round-robin does not score candidates.
+	Candidates []RoutingCandidate `json:"candidates,omitempty"`
+	// Rejected are members of the department that were not suitable, up to
+	// maxTracedRejections of them
+	Rejected []RejectedCandidate `json:"rejected,omitempty"`
+	// RejectedCount is how many members were rejected, including those
+	// left out of Rejected
+	RejectedCount  int       `json:"rejected_count"`
+	SelectedMember string    `json:"selected_member,omitempty"`
+	Fallback       bool      `json:"fallback,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	TracedAt       time.Time `json:"traced_at"`
+}
+
+// RejectedCandidate is a member the router did not consider for a task
+type RejectedCandidate struct {
+	MemberID string `json:"member_id"`
+	Reason   string `json:"reason"`
+}
+
+// GetRoutingTrace returns the trace of the last time a task was routed
+func (m *Manager) GetRoutingTrace(taskID string) (*RoutingTrace, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.config.TaskRouting.TraceRouting {
+		return nil, fmt.Errorf("routing traces are disabled")
+	}
+	trace, exists := m.taskRouter.traces[taskID]
+	if !exists {
+		return nil, fmt.Errorf("no routing trace for task %s", taskID)
+	}
+
+	clone := *trace
+	clone.Candidates = slices.Clone(trace.Candidates)
+	clone.Rejected = slices.Clone(trace.Rejected)
+	return &clone, nil
+}
+
+// startTrace begins a trace for routing task, or returns nil when tracing
+// is disabled
+func (tr *TaskRouter) startTrace(task *Task) *RoutingTrace {
+	if !tr.config.TraceRouting {
+		return nil
+	}
+	return &RoutingTrace{
+		TaskID:   task.ID,
+		Strategy: tr.strategy(),
+		TracedAt: time.Now(),
+	}
+}
+
+// traceRejections records why each member of the task's department that is
+// not among candidates was passed over
+func (tr *TaskRouter) traceRejections(trace *RoutingTrace, task *Task, candidates []*Member, exclude map[string]bool) {
+	if trace == nil {
+		return
+	}
+
+	members := tr.manager.listMembers(task.DepartmentID)
+	slices.SortFunc(members, func(a, b *Member) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	trace.CandidateCount = len(candidates)
+	for _, member := range members {
+		if slices.Contains(candidates, member) {
+			continue
+		}
+		reason := "excluded from this routing attempt"
+		if !exclude[member.ID] {
+			reason = tr.unsuitableReason(member, task)
+		}
+		trace.RejectedCount++
+		if len(trace.Rejected) < maxTracedRejections {
+			trace.Rejected = append(trace.Rejected, RejectedCandidate{MemberID: member.ID, Reason: reason})
+		}
+	}
+}
+
+// finishTrace completes a trace with the routing outcome and stores it,
+// replacing the task's previous trace. The caller must hold the manager
+// lock.
+func (tr *TaskRouter) finishTrace(trace *RoutingTrace, task *Task, decision *RoutingDecision, err error) {
+	if trace == nil {
+		return
+	}
+
+	trace.DepartmentID = task.DepartmentID
+	trace.SelectedMember = task.AssignedMember
+	trace.Fallback = decision.Fallback
+	if trace.Candidates == nil {
+		trace.Candidates = decision.Candidates
+	}
+	if err != nil {
+		trace.Error = err.Error()
+	}
+
+	if _, exists := tr.traces[task.ID]; !exists {
+		tr.traceOrder = append(tr.traceOrder, task.ID)
+	}
+	tr.traces[task.ID] = trace
+	for len(tr.traceOrder) > maxRoutingTraces {
+		delete(tr.traces, tr.traceOrder[0])
+		tr.traceOrder = tr.traceOrder[1:]
+	}
+}
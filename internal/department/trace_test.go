@@ -0,0 +1,95 @@
+package department
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerRoutingTrace(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	m, err := NewManager(ctx, &DepartmentConfig{
+		Enabled:     true,
+		TaskRouting: TaskRoutingConfig{Strategy: RoutingSkillBased, TraceRouting: true},
+	})
+	require.NoError(t, err)
+
+	for _, member := range []*Member{
+		{ID: "dev-1", Specializations: []string{"go"}},
+		{ID: "dev-2", Specializations: []string{"go", "postgres"}},
+		{ID: "dev-3", Specializations: []string{"python"}},
+		{ID: "dev-4", Specializations: []string{"go"}, Status: MemberStatusOffline},
+	} {
+		member.Role = RoleDeveloper
+		member.DepartmentID = "dept-dev"
+		member.MaxConcurrent = 2
+		require.NoError(t, m.RegisterMember(ctx, member))
+	}
+	require.NoError(t, m.UpdateMemberStatus(ctx, "dev-4", MemberStatusOffline))
+
+	task, err := m.CreateTask(ctx, &Task{ID: "task-1", DepartmentID: "dept-dev", RequiredSkills: []string{"go"}})
+	require.NoError(t, err)
+
+	trace, err := m.GetRoutingTrace(task.ID)
+	require.NoError(t, err)
+	require.Equal(t, RoutingSkillBased, trace.Strategy)
+	require.Equal(t, "dept-dev", trace.DepartmentID)
+	require.Equal(t, task.AssignedMember, trace.SelectedMember)
+	require.Equal(t, 2, trace.CandidateCount)
+
+	// Skill-based routing records each candidate's skill score
+	require.Len(t, trace.Candidates, 2)
+	for _, candidate := range trace.Candidates {
+		require.Contains(t, []string{"dev-1", "dev-2"}, candidate.MemberID)
+		require.Positive(t, candidate.Score)
+	}
+
+	require.Equal(t, 2, trace.RejectedCount)
+	require.Equal(t, []RejectedCandidate{
+		{MemberID: "dev-3", Reason: `member lacks required skill "go"`},
+		{MemberID: "dev-4", Reason: "member is offline"},
+	}, trace.Rejected)
+
+	// Failed routing is traced as well
+	_, err = m.CreateTask(ctx, &Task{ID: "task-2", DepartmentID: "dept-dev", RequiredSkills: []string{"rust"}})
+	require.NoError(t, err)
+	trace, err = m.GetRoutingTrace("task-2")
+	require.NoError(t, err)
+	require.Zero(t, trace.CandidateCount)
+	require.Equal(t, 4, trace.RejectedCount)
+	require.Contains(t, trace.Error, "no suitable members")
+
+	_, err = m.GetRoutingTrace("missing")
+	require.ErrorContains(t, err, "no routing trace for task missing")
+}
+
+func TestManagerRoutingTraceDisabled(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+	task, err := m.CreateTask(t.Context(), &Task{DepartmentID: "dept-dev"})
+	require.NoError(t, err)
+
+	_, err = m.GetRoutingTrace(task.ID)
+	require.ErrorContains(t, err, "routing traces are disabled")
+	require.Empty(t, m.taskRouter.traces)
+}
+
+func TestTaskRouterBoundsRoutingTraces(t *testing.T) {
+	t.Parallel()
+
+	tr := NewTaskRouter(TaskRoutingConfig{TraceRouting: true}, newTestManager(t))
+	for i := range maxRoutingTraces + 10 {
+		task := &Task{ID: fmt.Sprintf("task-%d", i)}
+		tr.finishTrace(tr.startTrace(task), task, &RoutingDecision{}, nil)
+	}
+
+	require.Len(t, tr.traces, maxRoutingTraces)
+	require.Len(t, tr.traceOrder, maxRoutingTraces)
+	require.NotContains(t, tr.traces, "task-9")
+	require.Contains(t, tr.traces, "task-10")
+}
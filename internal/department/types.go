@@ -1,9 +1,33 @@
 package department
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 )
 
+// ErrTaskCancelled is returned when waiting on a task that was cancelled
+var ErrTaskCancelled = errors.New("task cancelled")
+
+// ErrPromptTooLarge is returned when a task's prompt is estimated to exceed
+// the configured token limit
+var ErrPromptTooLarge = errors.New("prompt exceeds the token limit")
+
+// ErrTaskTypeNotPermitted is returned when routing a task whose type no
+// available member's role is permitted to handle
+var ErrTaskTypeNotPermitted = errors.New("task type not permitted for any available role")
+
+// ErrQueueFull is returned when creating a task in a department whose queue
+// is at its MaxQueuedTasks limit. Callers may retry after backing off.
+var ErrQueueFull = errors.New("department task queue is full")
+
+// ErrTaskAlreadyAssigned is returned when manually assigning a task that is
+// already assigned to another member
+var ErrTaskAlreadyAssigned = errors.New("task is already assigned")
+
 // DepartmentType represents different types of departments in the IT organization
 type DepartmentType string
 
@@ -32,6 +56,12 @@ const (
 	RoleSecurity    MemberRole = "security"     // Security Engineer
 )
 
+// memberRoles lists every member role
+var memberRoles = []MemberRole{
+	RoleBA, RolePM, RolePO, RoleLeadTechnical, RoleLeadBA, RoleLeadDev,
+	RoleLeadTest, RoleDeveloper, RoleDevOps, RoleQA, RoleSecurity,
+}
+
 // MemberStatus represents the current status of a department member
 type MemberStatus string
 
@@ -40,8 +70,14 @@ const (
 	MemberStatusBusy       MemberStatus = "busy"
 	MemberStatusOffline    MemberStatus = "offline"
 	MemberStatusUnhealthy  MemberStatus = "unhealthy"
+	MemberStatusDraining   MemberStatus = "draining"
 )
 
+// memberStatuses lists every member status
+var memberStatuses = []MemberStatus{
+	MemberStatusOnline, MemberStatusBusy, MemberStatusOffline, MemberStatusUnhealthy, MemberStatusDraining,
+}
+
 // TaskStatus represents the status of a task in the workflow
 type TaskStatus string
 
@@ -52,8 +88,15 @@ const (
 	TaskStatusCompleted  TaskStatus = "completed"
 	TaskStatusFailed     TaskStatus = "failed"
 	TaskStatusBlocked    TaskStatus = "blocked"
+	TaskStatusCancelled  TaskStatus = "cancelled"
 )
 
+// taskStatuses lists every task status
+var taskStatuses = []TaskStatus{
+	TaskStatusQueued, TaskStatusAssigned, TaskStatusInProgress, TaskStatusCompleted,
+	TaskStatusFailed, TaskStatusBlocked, TaskStatusCancelled,
+}
+
 // Priority represents task priority levels
 type Priority string
 
@@ -64,6 +107,53 @@ const (
 	PriorityCritical Priority = "critical"
 )
 
+// Rank orders priorities from low (1) to critical (4). Unknown priorities
+// rank lowest.
+func (p Priority) Rank() int {
+	switch p {
+	case PriorityLow:
+		return 1
+	case PriorityMedium:
+		return 2
+	case PriorityHigh:
+		return 3
+	case PriorityCritical:
+		return 4
+	}
+	return 0
+}
+
+// RoutingStrategy selects how the task router picks among suitable members
+type RoutingStrategy string
+
+const (
+	RoutingRoundRobin RoutingStrategy = "round-robin"
+	RoutingLoadBased  RoutingStrategy = "load-based"
+	RoutingSkillBased RoutingStrategy = "skill-based"
+	RoutingRoleBased  RoutingStrategy = "role-based"
+	RoutingTeamBased  RoutingStrategy = "team-based"
+	RoutingCostBased  RoutingStrategy = "cost-based"
+)
+
+// IsValid reports whether the strategy is one the router knows about
+func (s RoutingStrategy) IsValid() bool {
+	switch s {
+	case RoutingRoundRobin, RoutingLoadBased, RoutingSkillBased, RoutingRoleBased, RoutingTeamBased, RoutingCostBased:
+		return true
+	}
+	return false
+}
+
+// DepartmentTieBreak selects among departments that match a task equally well
+type DepartmentTieBreak string
+
+const (
+	// TieBreakLeastLoaded prefers the department with the most spare capacity
+	TieBreakLeastLoaded DepartmentTieBreak = "least-loaded"
+	// TieBreakByID prefers the department whose ID sorts first
+	TieBreakByID DepartmentTieBreak = "by-id"
+)
+
 // Department represents an IT department with specialized capabilities
 type Department struct {
 	ID          string            `json:"id"`
@@ -74,6 +164,20 @@ type Department struct {
 	MaxMembers  int               `json:"max_members"`
 	MinMembers  int               `json:"min_members"`
 	AutoScale   bool              `json:"auto_scale"`
+	// Disabled departments accept no new tasks or members
+	Disabled    bool              `json:"disabled,omitempty"`
+	// MemberNameTemplate names auto-scaled members. Supports the {department},
+	// {role} and {index} placeholders, e.g. "dev-svc-{role}-{index}".
+	MemberNameTemplate string    `json:"member_name_template,omitempty"`
+	// BaselineSkills are required of every task routed to the department
+	BaselineSkills []string `json:"baseline_skills,omitempty"`
+	// ScaleToZero lets the auto-scaler remove every member, leads included,
+	// when MinMembers is 0. The next task cold-starts a member through the
+	// member launcher and waits for it to pass a health check.
+	ScaleToZero bool `json:"scale_to_zero,omitempty"`
+	// MaxQueuedTasks caps the department's queued tasks, overriding
+	// DepartmentConfig.MaxQueuedTasks when set
+	MaxQueuedTasks int `json:"max_queued_tasks,omitempty"`
 	CreatedAt   time.Time         `json:"created_at"`
 	UpdatedAt   time.Time         `json:"updated_at"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
@@ -90,10 +194,16 @@ type Member struct {
 	Specializations []string               `json:"specializations"`
 	CurrentTasks    []string               `json:"current_tasks"`
 	MaxConcurrent   int                    `json:"max_concurrent"`
+	// CapacityUnits is the total task weight the member can hold at once.
+	// Zero falls back to MaxConcurrent.
+	CapacityUnits float64 `json:"capacity_units,omitempty"`
 	LastSeen        time.Time              `json:"last_seen"`
 	JoinedAt        time.Time              `json:"joined_at"`
 	Endpoint        string                 `json:"endpoint"`
 	AuthMethod      string                 `json:"auth_method"`
+	// AuthToken is the credential sent with health probes when AuthMethod
+	// is set. It is never serialized, so members supply it on registration.
+	AuthToken string `json:"-"`
 	HealthScore     float64                `json:"health_score"`
 	Performance     map[string]float64     `json:"performance"`
 	Capabilities    map[string]interface{} `json:"capabilities"`
@@ -101,6 +211,35 @@ type Member struct {
 	ReportsTo       string                 `json:"reports_to,omitempty"`
 	TeamMembers     []string               `json:"team_members,omitempty"`
 	Metadata        map[string]string      `json:"metadata,omitempty"`
+	// CapabilityVersion increases whenever the member's specializations or
+	// capabilities change, for example after its agent is upgraded
+	CapabilityVersion int `json:"capability_version,omitempty"`
+	// HealthCheck overrides the role-level health criteria for this member.
+	// Only non-zero fields take effect.
+	HealthCheck *HealthCheck `json:"health_check,omitempty"`
+	// HealthCheckType selects how the member is probed. Defaults to HTTP.
+	// Exec checks run the command configured for the member's role in
+	// HealthCheckConfig.Commands.
+	HealthCheckType HealthCheckType `json:"health_check_type,omitempty"`
+	// CostPerTask is the estimated cost of a task of weight 1 on this
+	// member, for example from its model tier. Cost-based routing prefers
+	// cheaper members.
+	CostPerTask float64 `json:"cost_per_task,omitempty"`
+}
+
+// MemberPatch holds changes to a registered member. Zero values leave a
+// field unchanged.
+type MemberPatch struct {
+	Name            string                 `json:"name,omitempty"`
+	Role            MemberRole             `json:"role,omitempty"`
+	Specializations []string               `json:"specializations,omitempty"`
+	Capabilities    map[string]interface{} `json:"capabilities,omitempty"`
+	MaxConcurrent   int                    `json:"max_concurrent,omitempty"`
+	CapacityUnits   float64                `json:"capacity_units,omitempty"`
+	Metadata        map[string]string      `json:"metadata,omitempty"`
+	// CapabilityVersion replaces the member's version when newer
+	CapabilityVersion int     `json:"capability_version,omitempty"`
+	CostPerTask       float64 `json:"cost_per_task,omitempty"`
 }
 
 // Task represents a work item in the department workflow
@@ -113,7 +252,11 @@ type Task struct {
 	Status          TaskStatus             `json:"status"`
 	DepartmentID    string                 `json:"department_id"`
 	AssignedMember  string                 `json:"assigned_member,omitempty"`
+	AssignedTeam    string                 `json:"assigned_team,omitempty"`
 	RequestedBy     string                 `json:"requested_by"`
+	// SessionID is the session the task was requested from, which decides
+	// whether it may use a reserved department
+	SessionID       string                 `json:"session_id,omitempty"`
 	CreatedAt       time.Time              `json:"created_at"`
 	UpdatedAt       time.Time              `json:"updated_at"`
 	StartedAt       *time.Time             `json:"started_at,omitempty"`
@@ -127,7 +270,192 @@ type Task struct {
 	Results         map[string]interface{} `json:"results,omitempty"`
 	AssignedRole    MemberRole             `json:"assigned_role,omitempty"`
 	RequiredSkills  []string               `json:"required_skills,omitempty"`
+	RequiredRoles   []MemberRole           `json:"required_roles,omitempty"`
 	Metadata        map[string]string      `json:"metadata,omitempty"`
+	// Weight is how much of a member's capacity the task consumes. Zero
+	// counts as 1.
+	Weight float64 `json:"weight,omitempty"`
+	// RoutingDecision records how the task was last placed
+	RoutingDecision *RoutingDecision `json:"routing_decision,omitempty"`
+	// AssignedAt is when the task was last assigned to a member
+	AssignedAt *time.Time `json:"assigned_at,omitempty"`
+	// Timeout bounds how long the task may wait and run before it is failed.
+	// Zero uses the configured default.
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// Retries counts how often the task was taken off a member and requeued
+	Retries int `json:"retries,omitempty"`
+	// QueuedDuration is the total time the task spent waiting in the queue
+	// across all of its assignments
+	QueuedDuration time.Duration `json:"queued_duration,omitempty"`
+	// QueuedAt is when the task last entered the queue. It is nil while the
+	// task is not queued.
+	QueuedAt *time.Time `json:"queued_at,omitempty"`
+	// NoReassign pins the task to its member once it has started, for work
+	// with side effects that must not run twice. If the member is lost the
+	// task fails with reason member_lost instead of moving.
+	NoReassign bool `json:"no_reassign,omitempty"`
+	// Overdue is set once the task is found unfinished past its DueDate
+	Overdue bool `json:"overdue,omitempty"`
+	// InheritedPriority is the priority of the most urgent unfinished task
+	// waiting on this one, when that is above the task's own priority. It is
+	// maintained by the manager and cleared once nothing urgent waits.
+	InheritedPriority Priority `json:"inherited_priority,omitempty"`
+	// AffinityKey groups related tasks, such as follow-ups in one session,
+	// so they are routed to the same member while TaskRoutingConfig's
+	// AffinityTTL allows
+	AffinityKey string `json:"affinity_key,omitempty"`
+	// EstimatedCost accumulates the cost of every member the task was
+	// assigned to, from their CostPerTask
+	EstimatedCost float64 `json:"estimated_cost,omitempty"`
+	// RetryPolicy lets a task whose attempt failed be tried again, on
+	// another member when possible. Nil never retries.
+	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty"`
+	// Attempts counts how often the task was started
+	Attempts int `json:"attempts,omitempty"`
+	// Progress is the last percentage reported for the task, from 0 to 100
+	Progress float64 `json:"progress,omitempty"`
+	// Subtasks are the per-skill subtasks a team assignment split the task
+	// into. They finish along with the task.
+	Subtasks []string `json:"subtasks,omitempty"`
+}
+
+// pinned reports whether the task has started and must stay on its member
+func (t *Task) pinned() bool {
+	return t.NoReassign && t.StartedAt != nil
+}
+
+// clone returns a deep copy of the department that shares no slices or maps
+// with it
+func (d *Department) clone() *Department {
+	c := *d
+	c.Capabilities = slices.Clone(d.Capabilities)
+	c.BaselineSkills = slices.Clone(d.BaselineSkills)
+	c.Metadata = maps.Clone(d.Metadata)
+	return &c
+}
+
+// clone returns a deep copy of the member that shares no slices or maps with
+// it. Capability values are copied shallowly.
+func (m *Member) clone() *Member {
+	c := *m
+	c.Specializations = slices.Clone(m.Specializations)
+	c.CurrentTasks = slices.Clone(m.CurrentTasks)
+	c.TeamMembers = slices.Clone(m.TeamMembers)
+	c.Performance = maps.Clone(m.Performance)
+	c.Capabilities = maps.Clone(m.Capabilities)
+	c.Metadata = maps.Clone(m.Metadata)
+	c.HealthCheck = clonePtr(m.HealthCheck)
+	return &c
+}
+
+// clone returns a deep copy of the task that shares no slices, maps or
+// pointers with it. Result values and attachment contents are copied
+// shallowly.
+func (t *Task) clone() *Task {
+	c := *t
+	c.StartedAt = clonePtr(t.StartedAt)
+	c.CompletedAt = clonePtr(t.CompletedAt)
+	c.DueDate = clonePtr(t.DueDate)
+	c.AssignedAt = clonePtr(t.AssignedAt)
+	c.QueuedAt = clonePtr(t.QueuedAt)
+	c.EstimatedHours = clonePtr(t.EstimatedHours)
+	c.ActualHours = clonePtr(t.ActualHours)
+	c.RetryPolicy = clonePtr(t.RetryPolicy)
+	c.Tags = slices.Clone(t.Tags)
+	c.Dependencies = slices.Clone(t.Dependencies)
+	c.Attachments = slices.Clone(t.Attachments)
+	c.RequiredSkills = slices.Clone(t.RequiredSkills)
+	c.RequiredRoles = slices.Clone(t.RequiredRoles)
+	c.Subtasks = slices.Clone(t.Subtasks)
+	c.Results = maps.Clone(t.Results)
+	c.Metadata = maps.Clone(t.Metadata)
+	if t.RoutingDecision != nil {
+		decision := *t.RoutingDecision
+		decision.Candidates = slices.Clone(t.RoutingDecision.Candidates)
+		c.RoutingDecision = &decision
+	}
+	return &c
+}
+
+// clone returns a deep copy of the team that shares no slices with it
+func (t *Team) clone() *Team {
+	c := *t
+	c.MemberIDs = slices.Clone(t.MemberIDs)
+	c.Roles = slices.Clone(t.Roles)
+	return &c
+}
+
+// clone returns a deep copy of the workflow that shares no slices or maps
+// with it. Metadata values are copied shallowly.
+func (w *Workflow) clone() *Workflow {
+	c := *w
+	c.Steps = slices.Clone(w.Steps)
+	for i := range c.Steps {
+		c.Steps[i].Dependencies = slices.Clone(w.Steps[i].Dependencies)
+		c.Steps[i].Tools = slices.Clone(w.Steps[i].Tools)
+	}
+	c.RequiredRoles = slices.Clone(w.RequiredRoles)
+	c.OptionalRoles = slices.Clone(w.OptionalRoles)
+	c.Metadata = maps.Clone(w.Metadata)
+	return &c
+}
+
+// clone returns a deep copy of the workflow run that shares no maps or
+// pointers with it
+func (r *WorkflowRun) clone() *WorkflowRun {
+	c := *r
+	c.StepTasks = maps.Clone(r.StepTasks)
+	c.CompletedAt = clonePtr(r.CompletedAt)
+	return &c
+}
+
+// clonePtr returns a pointer to a copy of *p, or nil if p is nil
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	return &c
+}
+
+// TaskLifecycleSummary is a single record of a task's life, published when it
+// completes or fails
+type TaskLifecycleSummary struct {
+	TaskID         string     `json:"task_id"`
+	DepartmentID   string     `json:"department_id"`
+	AssignedMember string     `json:"assigned_member,omitempty"`
+	FinalStatus    TaskStatus `json:"final_status"`
+	Retries        int        `json:"retries"`
+	// QueuedDuration is the total time spent waiting for a member
+	QueuedDuration time.Duration `json:"queued_duration"`
+	// ExecutionDuration runs from the first start to completion, zero if
+	// the task never started
+	ExecutionDuration time.Duration `json:"execution_duration"`
+	// TotalDuration runs from creation to completion
+	TotalDuration time.Duration `json:"total_duration"`
+	CompletedAt   time.Time     `json:"completed_at"`
+}
+
+// RoutingDecision records how a task ended up with its department and member
+type RoutingDecision struct {
+	// Strategy is the routing strategy used, empty for manual assignments
+	Strategy         RoutingStrategy    `json:"strategy,omitempty"`
+	DepartmentID     string             `json:"department_id"`
+	DepartmentReason string             `json:"department_reason"`
+	MemberID         string             `json:"member_id"`
+	MemberReason     string             `json:"member_reason"`
+	TeamID           string             `json:"team_id,omitempty"`
+	Fallback         bool               `json:"fallback,omitempty"`
+	PreemptedTaskID  string             `json:"preempted_task_id,omitempty"`
+	Candidates       []RoutingCandidate `json:"candidates,omitempty"`
+	DecidedAt        time.Time          `json:"decided_at"`
+}
+
+// RoutingCandidate is a member the router considered, with the score the
+// strategy gave it
+type RoutingCandidate struct {
+	MemberID string  `json:"member_id"`
+	Score    float64 `json:"score"`
 }
 
 // TaskAttachment represents files or data attached to tasks
@@ -136,7 +464,14 @@ type TaskAttachment struct {
 	Name        string    `json:"name"`
 	Type        string    `json:"type"`
 	Size        int64     `json:"size"`
+	// Path is the file the attachment was read from, if any
+	Path        string    `json:"path,omitempty"`
+	// URL refers to content kept outside the task, such as content spilled
+	// to the blob store, when Content is empty
 	URL         string    `json:"url,omitempty"`
+	// Spilled reports that the content was moved to the blob store at URL,
+	// so the blob goes away along with the task
+	Spilled     bool      `json:"spilled,omitempty"`
 	Content     []byte    `json:"content,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 }
@@ -151,6 +486,31 @@ type DepartmentConfig struct {
 	Notifications  NotificationConfig      `json:"notifications,omitempty"`
 	Reporting      ReportingConfig         `json:"reporting,omitempty"`
 	Roles          RoleConfig              `json:"roles,omitempty"`
+	// DefaultTaskTimeout applies to tasks without their own Timeout
+	DefaultTaskTimeout time.Duration `json:"default_task_timeout,omitempty"`
+	// DefaultRetryPolicy applies to requests the coordinator runs as tasks.
+	// Nil never retries them.
+	DefaultRetryPolicy *RetryPolicy `json:"default_retry_policy,omitempty"`
+	// CapActiveMembersOnly counts only online and busy members toward a
+	// department's MaxMembers, so offline or unhealthy members don't block
+	// new registrations. Idle inactive members are removed to make room.
+	CapActiveMembersOnly bool `json:"cap_active_members_only,omitempty"`
+	// MaxQueuedTasks caps the queued tasks of every department without its
+	// own limit. New tasks are rejected with ErrQueueFull beyond it. Zero
+	// means no limit.
+	MaxQueuedTasks int `json:"max_queued_tasks,omitempty"`
+	// MemberHeartbeatTimeout is how long a member may go unseen before it is
+	// marked offline and its tasks are moved to other members. Members are
+	// seen on heartbeats, passed health checks and task activity. Zero never
+	// expires members.
+	MemberHeartbeatTimeout time.Duration `json:"member_heartbeat_timeout,omitempty"`
+	// AttachmentSpillThreshold is the size in bytes above which attachment
+	// content is moved to the blob store and referenced by URL. Zero keeps
+	// all content in memory.
+	AttachmentSpillThreshold int64 `json:"attachment_spill_threshold,omitempty"`
+	// AttachmentDir is where the default blob store writes spilled content.
+	// Empty uses a directory under the system temp dir.
+	AttachmentDir string `json:"attachment_dir,omitempty"`
 }
 
 // RoleConfig defines role-specific configurations and permissions
@@ -182,6 +542,72 @@ type AutoScalingConfig struct {
 	MaxMembersPerDept int           `json:"max_members_per_department"`
 	CooldownPeriod    time.Duration `json:"cooldown_period"`
 	RoleScaling       map[string]int `json:"role_scaling,omitempty"`
+	// UtilizationSmoothing is the EWMA weight (0, 1] given to the most recent
+	// utilization sample. Lower values favor long-running load over spikes;
+	// 1 disables smoothing. Defaults to 0.3 when unset.
+	UtilizationSmoothing float64 `json:"utilization_smoothing,omitempty"`
+	// QueueWaitP95Target scales a department up when the 95th percentile
+	// of its queue wait exceeds it, whatever the utilization. Zero disables it.
+	QueueWaitP95Target time.Duration `json:"queue_wait_p95_target,omitempty"`
+	// CapacityPerMember overrides, by role, how many concurrent tasks a
+	// member counts for when computing department utilization. Roles
+	// without an entry use each member's MaxConcurrent.
+	CapacityPerMember map[string]int `json:"capacity_per_member,omitempty"`
+	// MaxQueueWait scales a department up when its oldest queued task has
+	// waited longer than this, even if utilization looks low. Zero disables it.
+	MaxQueueWait time.Duration `json:"max_queue_wait,omitempty"`
+	// ScalingHistorySize is how many recent scaling events are kept.
+	// Defaults to 100.
+	ScalingHistorySize int `json:"scaling_history_size,omitempty"`
+	// ScaleUpCooldown and ScaleDownCooldown are how long after any scaling
+	// action a department must wait before scaling up or down again. An
+	// action reversing the last one waits for the longer of the two. Zero
+	// falls back to CooldownPeriod.
+	ScaleUpCooldown   time.Duration `json:"scale_up_cooldown,omitempty"`
+	ScaleDownCooldown time.Duration `json:"scale_down_cooldown,omitempty"`
+	// DrainTimeout is how long a busy member picked for scale-down may keep
+	// working on its tasks before the rest are reassigned and it is removed.
+	// Defaults to 10 minutes.
+	DrainTimeout time.Duration `json:"drain_timeout,omitempty"`
+	// ScheduledRules override department member bounds during recurring
+	// time windows, such as business hours
+	ScheduledRules []ScheduleRule `json:"scheduled_rules,omitempty"`
+	// Timezone is the IANA time zone the scheduled windows are in, for
+	// example "Europe/Berlin". Defaults to UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// ColdStartTimeout is how long a member cold-started for a department
+	// scaled to zero may take to pass its first health check. Defaults to
+	// 2 minutes.
+	ColdStartTimeout time.Duration `json:"cold_start_timeout,omitempty"`
+	// MaxConcurrentLaunches caps how many members may be starting at once
+	// across all departments, cold starts included. Zero means no limit.
+	MaxConcurrentLaunches int `json:"max_concurrent_launches,omitempty"`
+	// MaxScaleUpPerTick caps how many members one check cycle adds across
+	// all departments. Departments over the cap scale up on the next cycle.
+	// Zero means no limit.
+	MaxScaleUpPerTick int `json:"max_scale_up_per_tick,omitempty"`
+}
+
+// cooldown returns the cooldown that applies before the given scaling action
+func (c AutoScalingConfig) cooldown(action string) time.Duration {
+	switch {
+	case action == scaleUp && c.ScaleUpCooldown > 0:
+		return c.ScaleUpCooldown
+	case action == scaleDown && c.ScaleDownCooldown > 0:
+		return c.ScaleDownCooldown
+	}
+	return c.CooldownPeriod
+}
+
+// cooldownAfter returns the cooldown that applies before the given scaling
+// action when lastAction was the department's last one. Reversing direction
+// waits for the longer of the two cooldowns so departments do not flap.
+func (c AutoScalingConfig) cooldownAfter(lastAction, action string) time.Duration {
+	cooldown := c.cooldown(action)
+	if lastAction != "" && lastAction != action {
+		cooldown = max(cooldown, c.cooldown(lastAction))
+	}
+	return cooldown
 }
 
 // HealthCheckConfig defines health monitoring for members
@@ -190,8 +616,36 @@ type HealthCheckConfig struct {
 	CheckInterval     time.Duration `json:"check_interval"`
 	Timeout           time.Duration `json:"timeout"`
 	UnhealthyThreshold int          `json:"unhealthy_threshold"`
+	// HealthyThreshold is how many consecutive successful checks an
+	// unhealthy member needs before it is considered healthy again.
+	// Defaults to 1.
+	HealthyThreshold int `json:"healthy_threshold,omitempty"`
 	RetryCount        int           `json:"retry_count"`
+	// MaxConcurrentChecks caps how many members are probed at once.
+	// Defaults to four per CPU.
+	MaxConcurrentChecks int `json:"max_concurrent_checks,omitempty"`
 	RoleSpecificChecks map[string]HealthCheck `json:"role_specific_checks,omitempty"`
+	// Commands are the command and arguments exec health checks run for
+	// members of each role. They come from operator config only, never from
+	// a member's registration.
+	Commands map[string][]string `json:"commands,omitempty"`
+	// KeepAlive is the TCP keep-alive period for probe connections.
+	// Defaults to 30s; negative disables TCP keep-alives.
+	KeepAlive time.Duration `json:"keep_alive,omitempty"`
+	// MaxIdleConnsPerHost is how many idle probe connections are kept open
+	// per member endpoint for reuse. Defaults to 2.
+	MaxIdleConnsPerHost int `json:"max_idle_conns_per_host,omitempty"`
+	// IdleConnTimeout is how long an idle probe connection is kept open.
+	// Defaults to 90s.
+	IdleConnTimeout time.Duration `json:"idle_conn_timeout,omitempty"`
+	// ReassignOnUnhealthy moves a member's tasks to other members as soon as
+	// it is marked unhealthy, instead of leaving them to time out
+	ReassignOnUnhealthy bool `json:"reassign_on_unhealthy,omitempty"`
+	// DegradedThreshold and CriticalThreshold are the shares of unhealthy
+	// members at which a department reports as degraded or critical.
+	// Default to 0.25 and 0.75.
+	DegradedThreshold float64 `json:"degraded_threshold,omitempty"`
+	CriticalThreshold float64 `json:"critical_threshold,omitempty"`
 }
 
 // HealthCheck defines role-specific health check parameters
@@ -203,7 +657,7 @@ type HealthCheck struct {
 
 // TaskRoutingConfig defines how tasks are routed to departments and members
 type TaskRoutingConfig struct {
-	Strategy           string                 `json:"strategy"` // round-robin, load-based, skill-based, role-based
+	Strategy           RoutingStrategy        `json:"strategy"` // round-robin, load-based, skill-based, role-based, team-based, cost-based
 	DepartmentRules    map[string][]string    `json:"department_rules,omitempty"`
 	RoleRules          map[string][]string    `json:"role_rules,omitempty"`
 	MemberRules        map[string][]string    `json:"member_rules,omitempty"`
@@ -211,6 +665,141 @@ type TaskRoutingConfig struct {
 	DefaultRole        string                 `json:"default_role"`
 	FallbackEnabled    bool                   `json:"fallback_enabled"`
 	RoutingMetadata    map[string]interface{} `json:"routing_metadata,omitempty"`
+	// MaxQueueWait is how long a task of each priority may stay queued before
+	// a QueueWaitExceededEvent is published. Priorities without an entry are
+	// never flagged.
+	MaxQueueWait map[Priority]time.Duration `json:"max_queue_wait,omitempty"`
+	// ScaleOnQueueWait asks the auto-scaler to add a member to the task's
+	// department when a queue wait threshold is exceeded.
+	ScaleOnQueueWait bool `json:"scale_on_queue_wait,omitempty"`
+	// AckTimeout is how long a task may stay assigned without its member
+	// moving it to in_progress before it is reclaimed and routed elsewhere.
+	// Zero disables the timeout.
+	AckTimeout time.Duration `json:"ack_timeout,omitempty"`
+	// PreemptionEnabled lets a critical task that finds no free member take
+	// the slot of the lowest-priority in-progress task, which is requeued.
+	PreemptionEnabled bool `json:"preemption_enabled,omitempty"`
+	// DepartmentTieBreak picks among departments whose rules match a task
+	// equally well. Defaults to least-loaded.
+	DepartmentTieBreak DepartmentTieBreak `json:"department_tie_break,omitempty"`
+	// RetryInterval is how often queued tasks that could not be routed are
+	// routed again, without waiting for a capacity change. Zero disables it.
+	RetryInterval time.Duration `json:"retry_interval,omitempty"`
+	// MaxRetries caps how many times a queued task is retried. Zero retries
+	// until the task is routed.
+	MaxRetries int `json:"max_retries,omitempty"`
+	// MaxPromptTokens rejects tasks whose description is estimated to be
+	// longer than this many tokens before they are routed, rather than
+	// failing once a member runs them. Zero disables the check.
+	MaxPromptTokens int `json:"max_prompt_tokens,omitempty"`
+	// AssignmentLogSampling logs only one in every N routine assignments at
+	// info level and the rest at debug, for high-throughput deployments.
+	// Fallback, preemption and reassignment are always logged. Zero or one
+	// logs every assignment.
+	AssignmentLogSampling int `json:"assignment_log_sampling,omitempty"`
+	// MaxBlockedTime is how long a task may stay blocked on its dependencies
+	// before a warning is logged. Zero disables the warning.
+	MaxBlockedTime time.Duration `json:"max_blocked_time,omitempty"`
+	// OverdueCheckInterval is how often tasks are checked against their due
+	// dates and flagged as overdue. Zero disables the check.
+	OverdueCheckInterval time.Duration `json:"overdue_check_interval,omitempty"`
+	// EscalateOverdueCritical bumps overdue critical tasks the next time
+	// they are routed, for example after being reassigned: they go ahead of
+	// every other queued task and may preempt lower-priority work as if
+	// PreemptionEnabled were set
+	EscalateOverdueCritical bool `json:"escalate_overdue_critical,omitempty"`
+	// SkillAliases maps a canonical skill to other names for it, so a task
+	// needing "k8s" matches a member specializing in "kubernetes". Entries
+	// extend the built-in aliases and win where they overlap.
+	SkillAliases map[string][]string `json:"skill_aliases,omitempty"`
+	// AffinityTTL enables sticky routing: a task with an AffinityKey goes to
+	// the member that last handled one with the same key, if that member is
+	// available and has capacity, until this long after that assignment.
+	// Zero disables it.
+	AffinityTTL time.Duration `json:"affinity_ttl,omitempty"`
+	// FallbackStrategy picks the member fallback routing assigns a task to
+	// among the closest departments' available members: load-based or
+	// skill-based. Defaults to load-based.
+	FallbackStrategy RoutingStrategy `json:"fallback_strategy,omitempty"`
+	// ExcludeBusy skips members marked busy even when they appear to have
+	// capacity left, for example during a reassignment, so tasks only go to
+	// members that can start at once. This keeps latency low at the cost of
+	// throughput: while every member of a department is busy its tasks stay
+	// queued, or fall back to other departments, rather than waiting on a
+	// member that is already at work.
+	ExcludeBusy bool `json:"exclude_busy,omitempty"`
+	// CostLoadBlend weighs load against cost in cost-based routing, from 0,
+	// which picks the cheapest member whatever its load, to 1, which ignores
+	// cost like load-based routing
+	CostLoadBlend float64 `json:"cost_load_blend,omitempty"`
+	// CrossDepartmentEnabled lets a task that no member of its department
+	// can take go to a member of another department whose specializations
+	// cover every skill the task requires, before fallback routing is tried.
+	// Members of the task's own department are always preferred.
+	CrossDepartmentEnabled bool `json:"cross_department_enabled,omitempty"`
+	// TraceRouting keeps a RoutingTrace of the last routing of each task,
+	// with every candidate's score and why other members were passed over,
+	// for tuning routing. It costs a pass over the department's members per
+	// routing, so it is off by default.
+	TraceRouting bool `json:"trace_routing,omitempty"`
+}
+
+// Validate checks the routing configuration for unknown values. An empty
+// strategy is allowed and falls back to load-based routing.
+func (c TaskRoutingConfig) Validate() error {
+	if c.Strategy != "" && !c.Strategy.IsValid() {
+		return fmt.Errorf("unknown routing strategy %q", c.Strategy)
+	}
+	switch c.FallbackStrategy {
+	case "", RoutingLoadBased, RoutingSkillBased:
+	default:
+		return fmt.Errorf("unsupported fallback strategy %q", c.FallbackStrategy)
+	}
+	switch c.DepartmentTieBreak {
+	case "", TieBreakLeastLoaded, TieBreakByID:
+	default:
+		return fmt.Errorf("unknown department tie break %q", c.DepartmentTieBreak)
+	}
+	if c.AckTimeout < 0 {
+		return fmt.Errorf("ack timeout must not be negative")
+	}
+	if c.RetryInterval < 0 {
+		return fmt.Errorf("retry interval must not be negative")
+	}
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("max retries must not be negative")
+	}
+	if c.MaxPromptTokens < 0 {
+		return fmt.Errorf("max prompt tokens must not be negative")
+	}
+	if c.AssignmentLogSampling < 0 {
+		return fmt.Errorf("assignment log sampling must not be negative")
+	}
+	if c.OverdueCheckInterval < 0 {
+		return fmt.Errorf("overdue check interval must not be negative")
+	}
+	if c.MaxBlockedTime < 0 {
+		return fmt.Errorf("max blocked time must not be negative")
+	}
+	if c.CostLoadBlend < 0 || c.CostLoadBlend > 1 {
+		return fmt.Errorf("cost load blend must be between 0 and 1")
+	}
+	if c.AffinityTTL < 0 {
+		return fmt.Errorf("affinity ttl must not be negative")
+	}
+	for priority, wait := range c.MaxQueueWait {
+		if wait <= 0 {
+			return fmt.Errorf("max queue wait for priority %q must be positive", priority)
+		}
+	}
+	for skill, aliases := range c.SkillAliases {
+		if strings.TrimSpace(skill) == "" || slices.ContainsFunc(aliases, func(alias string) bool {
+			return strings.TrimSpace(alias) == ""
+		}) {
+			return fmt.Errorf("skill aliases must not be empty")
+		}
+	}
+	return nil
 }
 
 // NotificationConfig defines event-driven notifications
@@ -220,6 +809,8 @@ type NotificationConfig struct {
 	Channels    []string `json:"channels"`
 	Webhooks    []string `json:"webhooks,omitempty"`
 	Emails      []string `json:"emails,omitempty"`
+	// RateLimit is how many notifications may be sent per minute, in bursts
+	// of up to as many. Zero means no limit.
 	RateLimit   int      `json:"rate_limit,omitempty"`
 	RoleNotifications map[string][]string `json:"role_notifications,omitempty"`
 }
@@ -231,7 +822,11 @@ type ReportingConfig struct {
 	Metrics        []string      `json:"metrics"`
 	Dashboards     []string      `json:"dashboards,omitempty"`
 	ExportFormats  []string      `json:"export_formats,omitempty"`
-	RoleReports    []string      `json:"role_reports,omitempty"`
+	// RoleReports lists the member roles whose metrics are also reported
+	// on their own
+	RoleReports []string `json:"role_reports,omitempty"`
+	// ReportDir is where periodic reports are written, one file per format
+	ReportDir string `json:"report_dir,omitempty"`
 }
 
 // DepartmentStats represents statistics for a department
@@ -244,7 +839,111 @@ type DepartmentStats struct {
 	CompletedTasks  int               `json:"completed_tasks"`
 	FailedTasks     int               `json:"failed_tasks"`
 	AverageResponse float64           `json:"average_response"`
-	LastUpdated     time.Time         `json:"last_updated"`
+	// EstimatedCost is the accumulated estimated cost of the department's
+	// tasks
+	EstimatedCost float64 `json:"estimated_cost,omitempty"`
+	// QueueWaitP50 and QueueWaitP95 are the median and 95th percentile of
+	// how long the department's last maxQueueWaitSamples assigned tasks
+	// waited in the queue
+	QueueWaitP50 time.Duration `json:"queue_wait_p50,omitempty"`
+	QueueWaitP95 time.Duration `json:"queue_wait_p95,omitempty"`
+	LastUpdated  time.Time     `json:"last_updated"`
+}
+
+// DepartmentStatusReport is a point-in-time view of all departments, members
+// and tasks held by the manager
+type DepartmentStatusReport struct {
+	Departments map[string]DepartmentStatus `json:"departments"`
+	Members     MemberStatusSummary         `json:"members"`
+	Tasks       TaskStatusSummary           `json:"tasks"`
+	GeneratedAt time.Time                   `json:"generated_at"`
+}
+
+// DepartmentStatus describes a single department in a DepartmentStatusReport
+type DepartmentStatus struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Type      DepartmentType  `json:"type"`
+	AutoScale bool            `json:"auto_scale"`
+	Disabled  bool            `json:"disabled,omitempty"`
+	Stats     DepartmentStats `json:"stats"`
+	// Health rolls up member health checks, nil when health checking is
+	// disabled
+	Health *DepartmentHealth `json:"health,omitempty"`
+}
+
+// DepartmentHealthState is the overall health of a department
+type DepartmentHealthState string
+
+const (
+	DepartmentHealthy  DepartmentHealthState = "healthy"
+	DepartmentDegraded DepartmentHealthState = "degraded"
+	DepartmentCritical DepartmentHealthState = "critical"
+)
+
+// DepartmentHealth counts a department's checked members by health
+type DepartmentHealth struct {
+	HealthyMembers   int                   `json:"healthy_members"`
+	UnhealthyMembers int                   `json:"unhealthy_members"`
+	State            DepartmentHealthState `json:"state"`
+}
+
+// MemberStatusSummary counts members by status
+type MemberStatusSummary struct {
+	Total     int `json:"total"`
+	Online    int `json:"online"`
+	Busy      int `json:"busy"`
+	Offline   int `json:"offline"`
+	Unhealthy int `json:"unhealthy"`
+	Draining  int `json:"draining"`
+}
+
+// TaskStatusSummary counts tasks by status
+type TaskStatusSummary struct {
+	Total     int `json:"total"`
+	Queued    int `json:"queued"`
+	Blocked   int `json:"blocked"`
+	Assigned  int `json:"assigned"`
+	Active    int `json:"active"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Cancelled int `json:"cancelled"`
+}
+
+// DepartmentSnapshot is a consistent view of one department, its members
+// and their health, its tasks and its scaling state
+type DepartmentSnapshot struct {
+	Department Department        `json:"department"`
+	Members    []MemberSnapshot  `json:"members"`
+	QueueDepth int               `json:"queue_depth"`
+	Tasks      TaskStatusSummary `json:"tasks"`
+	// Health rolls up member health checks, nil when health checking is
+	// disabled
+	Health *DepartmentHealth `json:"health,omitempty"`
+	// Scaling is nil when auto-scaling is disabled
+	Scaling     *DepartmentScalingState `json:"scaling,omitempty"`
+	GeneratedAt time.Time               `json:"generated_at"`
+}
+
+// MemberSnapshot is a member with its latest health check, nil when the
+// member has not been checked
+type MemberSnapshot struct {
+	Member Member        `json:"member"`
+	Health *MemberHealth `json:"health,omitempty"`
+}
+
+// DepartmentScalingState is the auto-scaler's state for one department
+type DepartmentScalingState struct {
+	LastScaleTime   time.Time `json:"last_scale_time,omitempty"`
+	LastScaleAction string    `json:"last_scale_action,omitempty"`
+	// The cooldowns end at these times, zero when not cooling down
+	ScaleUpCooldownUntil   time.Time `json:"scale_up_cooldown_until,omitempty"`
+	ScaleDownCooldownUntil time.Time `json:"scale_down_cooldown_until,omitempty"`
+	// Utilization is the smoothed utilization the scaler last saw
+	Utilization float64 `json:"utilization"`
+	// DrainingMembers are waiting for their tasks to finish before removal
+	DrainingMembers []string `json:"draining_members,omitempty"`
+	ColdStarting    bool     `json:"cold_starting,omitempty"`
 }
 
 // MemberStats represents performance statistics for a member
@@ -259,6 +958,10 @@ type MemberStats struct {
 	CurrentLoad     int       `json:"current_load"`
 	TeamTasks       int       `json:"team_tasks,omitempty"`
 	LeadershipTasks int       `json:"leadership_tasks,omitempty"`
+	// TimedTasks counts the completed tasks folded into AverageTime
+	TimedTasks int `json:"timed_tasks,omitempty"`
+	// MissedAcks counts tasks reclaimed because the member never started them
+	MissedAcks int `json:"missed_acks,omitempty"`
 	LastUpdated     time.Time `json:"last_updated"`
 }
 
@@ -286,6 +989,20 @@ type Workflow struct {
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// WorkflowRun tracks the execution of a workflow for a parent task. Each step
+// runs as its own subtask.
+type WorkflowRun struct {
+	ID          string            `json:"id"`
+	WorkflowID  string            `json:"workflow_id"`
+	TaskID      string            `json:"task_id"`
+	Status      TaskStatus        `json:"status"`
+	StepTasks   map[string]string `json:"step_tasks"` // step ID -> subtask ID
+	Error       string            `json:"error,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty"`
+}
+
 // WorkflowStep represents a step in a workflow
 type WorkflowStep struct {
 	ID          string      `json:"id"`
@@ -296,4 +1013,4 @@ type WorkflowStep struct {
 	Dependencies []string   `json:"dependencies,omitempty"`
 	EstimatedTime float64   `json:"estimated_time,omitempty"`
 	Tools       []string    `json:"tools,omitempty"`
-}
\ No newline at end of file
+}
\ No newline at end of file
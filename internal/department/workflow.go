@@ -0,0 +1,483 @@
+package department
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/eliasbui/ccl-magic/internal/pubsub"
+)
+
+// Task metadata keys linking step subtasks back to their workflow run
+const (
+	metadataWorkflowRun  = "workflow_run"
+	metadataWorkflowStep = "workflow_step"
+)
+
+// RegisterWorkflow adds or replaces a workflow definition. Step IDs must be
+// unique and dependencies must refer to other steps without forming a cycle.
+func (m *Manager) RegisterWorkflow(workflow *Workflow) error {
+	if err := validateWorkflow(workflow); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.workflows[workflow.ID] = workflow
+	m.persist()
+
+	return nil
+}
+
+// validateWorkflow checks a workflow's ID and the dependencies between its
+// steps
+func validateWorkflow(workflow *Workflow) error {
+	if workflow.ID == "" {
+		return fmt.Errorf("workflow ID is required")
+	}
+	if len(workflow.Steps) == 0 {
+		return fmt.Errorf("workflow %s has no steps", workflow.ID)
+	}
+
+	steps := make(map[string]WorkflowStep, len(workflow.Steps))
+	for _, step := range workflow.Steps {
+		if _, exists := steps[step.ID]; exists {
+			return fmt.Errorf("workflow %s has duplicate step %s", workflow.ID, step.ID)
+		}
+		steps[step.ID] = step
+	}
+	for _, step := range workflow.Steps {
+		for _, dep := range step.Dependencies {
+			if _, exists := steps[dep]; !exists {
+				return fmt.Errorf("step %s depends on unknown step %s", step.ID, dep)
+			}
+		}
+	}
+	if cycle := findStepCycle(workflow.Steps); cycle != "" {
+		return fmt.Errorf("workflow %s has a dependency cycle at step %s", workflow.ID, cycle)
+	}
+	return nil
+}
+
+// WorkflowForTaskType returns the workflow registered for a task type, if any
+func (m *Manager) WorkflowForTaskType(taskType string) (*Workflow, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, workflow := range m.workflows {
+		if workflow.TaskType == taskType {
+			return workflow, true
+		}
+	}
+	return nil, false
+}
+
+// StartWorkflow expands a workflow into one subtask per step for the given
+// parent task. Steps without dependencies are routed immediately; the rest
+// stay blocked until the steps they depend on finish.
+func (m *Manager) StartWorkflow(ctx context.Context, workflowID string, task *Task) (*WorkflowRun, error) {
+	if err := m.CheckPromptSize(task.Description); err != nil {
+		return nil, err
+	}
+	if task.ID == "" {
+		task.ID = generateTaskID()
+	}
+
+	// Check the run can start before spilling, so a rejected workflow leaves
+	// no blobs behind. Spilling does I/O, so it runs without the lock.
+	m.mu.RLock()
+	_, _, _, err := m.planWorkflowRun(workflowID, task, time.Now())
+	m.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := m.spillAttachments(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Plan again since the state may have changed while spilling
+	now := time.Now()
+	run, workflow, subtasks, err := m.planWorkflowRun(workflowID, task, now)
+	if err != nil {
+		go m.deleteAttachments(context.WithoutCancel(ctx), attachments)
+		return nil, err
+	}
+	task.Attachments = attachments
+
+	// Track the parent task; the steps do the actual work
+	if _, exists := m.tasks[task.ID]; !exists {
+		task.CreatedAt = now
+		m.tasks[task.ID] = task
+	}
+	if task.AssignedMember != "" {
+		m.releaseTask(task.AssignedMember, task.ID)
+		task.AssignedMember = ""
+	}
+	task.Status = TaskStatusInProgress
+	task.StartedAt = &now
+	task.UpdatedAt = now
+	m.indexTask(task)
+
+	var ready, blocked []*Task
+	for _, subtask := range subtasks {
+		if subtask.Status == TaskStatusQueued {
+			ready = append(ready, subtask)
+		} else {
+			blocked = append(blocked, subtask)
+		}
+		m.tasks[subtask.ID] = subtask
+		m.indexTask(subtask)
+	}
+
+	m.workflowRuns[run.ID] = run
+	m.inheritPriorities()
+
+	for _, subtask := range ready {
+		m.routeStep(ctx, subtask)
+	}
+	for _, subtask := range blocked {
+		m.updateBlockedOn(subtask)
+		m.taskEvents.Publish(pubsub.UpdatedEvent, subtask)
+	}
+
+	m.persist()
+	m.taskEvents.Publish(pubsub.UpdatedEvent, task)
+
+	slog.Info("Workflow started",
+		"workflow_id", workflow.ID,
+		"run_id", run.ID,
+		"task_id", task.ID,
+		"steps", len(workflow.Steps))
+
+	return run, nil
+}
+
+// planWorkflowRun checks that a workflow can start for the parent task and
+// builds its run and step subtasks without touching any state, so a step
+// that cannot be placed leaves neither the parent nor earlier steps behind.
+// The caller must hold the manager lock, for reading at least.
+func (m *Manager) planWorkflowRun(workflowID string, task *Task, now time.Time) (*WorkflowRun, *Workflow, []*Task, error) {
+	if m.shuttingDown {
+		return nil, nil, nil, fmt.Errorf("department manager is shutting down")
+	}
+
+	workflow, exists := m.workflows[workflowID]
+	if !exists {
+		return nil, nil, nil, fmt.Errorf("workflow %s does not exist", workflowID)
+	}
+
+	run := &WorkflowRun{
+		ID:         fmt.Sprintf("run-%s", task.ID),
+		WorkflowID: workflow.ID,
+		TaskID:     task.ID,
+		Status:     TaskStatusInProgress,
+		StepTasks:  make(map[string]string, len(workflow.Steps)),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if _, exists := m.workflowRuns[run.ID]; exists {
+		return nil, nil, nil, fmt.Errorf("workflow run %s already exists", run.ID)
+	}
+
+	for _, step := range workflow.Steps {
+		run.StepTasks[step.ID] = fmt.Sprintf("%s-%s", task.ID, step.ID)
+	}
+
+	subtasks := make([]*Task, 0, len(workflow.Steps))
+	for _, step := range workflow.Steps {
+		subtask := &Task{
+			ID:           run.StepTasks[step.ID],
+			Title:        step.Name,
+			Description:  step.Description,
+			Type:         task.Type,
+			Priority:     task.Priority,
+			Status:       TaskStatusBlocked,
+			DepartmentID: m.departmentForRole(step.AssignedRole, task.DepartmentID),
+			RequestedBy:  task.RequestedBy,
+			SessionID:    task.SessionID,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+			AssignedRole: step.AssignedRole,
+			Metadata: map[string]string{
+				metadataWorkflowRun:  run.ID,
+				metadataWorkflowStep: step.ID,
+			},
+		}
+		if subtask.DepartmentID == "" {
+			return nil, nil, nil, fmt.Errorf("cannot determine department for workflow step %s", step.ID)
+		}
+		for _, dep := range step.Dependencies {
+			subtask.Dependencies = append(subtask.Dependencies, run.StepTasks[dep])
+		}
+		if len(step.Dependencies) == 0 {
+			subtask.Status = TaskStatusQueued
+		}
+		subtasks = append(subtasks, subtask)
+	}
+
+	return run, workflow, subtasks, nil
+}
+
+// GetWorkflowRun returns a workflow run by ID
+func (m *Manager) GetWorkflowRun(runID string) (*WorkflowRun, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	run, exists := m.workflowRuns[runID]
+	if !exists {
+		return nil, fmt.Errorf("workflow run %s does not exist", runID)
+	}
+	return run, nil
+}
+
+// AbortWorkflowRun fails an unfinished run with the given cause. Every step
+// that has not finished is cancelled, including ones a member is still
+// working on, so the members' capacity is released along with the run.
+func (m *Manager) AbortWorkflowRun(ctx context.Context, runID string, cause error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	run, exists := m.workflowRuns[runID]
+	if !exists {
+		return fmt.Errorf("workflow run %s does not exist", runID)
+	}
+	if run.Status != TaskStatusInProgress {
+		return fmt.Errorf("workflow run %s is already %s", runID, run.Status)
+	}
+	workflow, exists := m.workflows[run.WorkflowID]
+	if !exists {
+		return fmt.Errorf("workflow %s does not exist", run.WorkflowID)
+	}
+
+	now := time.Now()
+	for _, step := range workflow.Steps {
+		subtask := m.tasks[run.StepTasks[step.ID]]
+		if subtask == nil || isTaskDone(subtask.Status) {
+			continue
+		}
+		if subtask.AssignedMember != "" {
+			m.releaseTask(subtask.AssignedMember, subtask.ID)
+		}
+		subtask.Status = TaskStatusCancelled
+		subtask.UpdatedAt = now
+		if subtask.Results == nil {
+			subtask.Results = make(map[string]interface{})
+		}
+		subtask.Results["cancel_reason"] = cause.Error()
+		m.indexTask(subtask)
+		m.taskEvents.Publish(pubsub.UpdatedEvent, subtask)
+	}
+
+	m.finishWorkflowRun(ctx, run, workflow, cause)
+	return nil
+}
+
+// advanceWorkflow reacts to a step subtask finishing: it unblocks steps whose
+// dependencies are now done and finishes the run once every step is done or a
+// required step failed. The caller must hold the manager lock.
+func (m *Manager) advanceWorkflow(ctx context.Context, task *Task) {
+	run, exists := m.workflowRuns[task.Metadata[metadataWorkflowRun]]
+	if !exists || run.Status != TaskStatusInProgress {
+		return
+	}
+	workflow, exists := m.workflows[run.WorkflowID]
+	if !exists {
+		return
+	}
+
+	run.UpdatedAt = time.Now()
+
+	// A failed required step fails the whole run
+	if task.Status != TaskStatusCompleted {
+		if step, ok := workflowStep(workflow, task.Metadata[metadataWorkflowStep]); ok && step.Required {
+			m.finishWorkflowRun(ctx, run, workflow,
+				fmt.Errorf("required step %s %s", step.ID, task.Status))
+			return
+		}
+	}
+
+	finished := 0
+	for _, step := range workflow.Steps {
+		subtask := m.tasks[run.StepTasks[step.ID]]
+		if subtask == nil {
+			continue
+		}
+		if isTaskDone(subtask.Status) {
+			finished++
+			continue
+		}
+		if subtask.Status != TaskStatusBlocked {
+			continue
+		}
+
+		ready := true
+		for _, dep := range subtask.Dependencies {
+			if depTask := m.tasks[dep]; depTask == nil || !isTaskDone(depTask.Status) {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			subtask.Status = TaskStatusQueued
+			subtask.UpdatedAt = time.Now()
+			m.indexTask(subtask)
+			delete(subtask.Results, resultBlockedOn)
+			m.routeStep(ctx, subtask)
+		} else if m.updateBlockedOn(subtask) {
+			m.taskEvents.Publish(pubsub.UpdatedEvent, subtask)
+		}
+	}
+
+	if finished == len(workflow.Steps) {
+		m.finishWorkflowRun(ctx, run, workflow, nil)
+		return
+	}
+
+	m.persist()
+}
+
+// finishWorkflowRun completes or fails a run and its parent task, aggregating
+// the step results into the parent task's results
+func (m *Manager) finishWorkflowRun(ctx context.Context, run *WorkflowRun, workflow *Workflow, runErr error) {
+	now := time.Now()
+	run.CompletedAt = &now
+	run.UpdatedAt = now
+
+	var responses []string
+	stepResults := make(map[string]interface{}, len(workflow.Steps))
+	for _, step := range workflow.Steps {
+		subtask := m.tasks[run.StepTasks[step.ID]]
+		if subtask == nil {
+			continue
+		}
+
+		// Steps that never started are cancelled along with a failed run
+		if runErr != nil && (subtask.Status == TaskStatusBlocked || subtask.Status == TaskStatusQueued || subtask.Status == TaskStatusAssigned) {
+			if subtask.AssignedMember != "" {
+				m.releaseTask(subtask.AssignedMember, subtask.ID)
+			}
+			subtask.Status = TaskStatusCancelled
+			subtask.UpdatedAt = now
+			m.indexTask(subtask)
+			m.taskEvents.Publish(pubsub.UpdatedEvent, subtask)
+		}
+
+		stepResults[step.ID] = subtask.Results
+		if response, ok := subtask.Results["response"].(string); ok && response != "" {
+			responses = append(responses, fmt.Sprintf("## %s\n\n%s", step.Name, response))
+		}
+	}
+
+	result := map[string]interface{}{
+		"workflow_run": run.ID,
+		"steps":        stepResults,
+		"response":     strings.Join(responses, "\n\n"),
+	}
+
+	status := TaskStatusCompleted
+	if runErr != nil {
+		status = TaskStatusFailed
+		run.Error = runErr.Error()
+		result["error"] = runErr.Error()
+	}
+	run.Status = status
+
+	if err := m.updateTaskStatus(ctx, run.TaskID, status, result); err != nil {
+		slog.Warn("Failed to update workflow parent task", "run_id", run.ID, "error", err)
+	}
+
+	slog.Info("Workflow finished",
+		"workflow_id", workflow.ID,
+		"run_id", run.ID,
+		"status", string(status))
+}
+
+// routeStep routes a step subtask that has become ready to run
+func (m *Manager) routeStep(ctx context.Context, subtask *Task) {
+	if err := m.taskRouter.routeTask(ctx, subtask); err != nil {
+		slog.Warn("Failed to route workflow step", "task_id", subtask.ID, "error", err)
+	}
+	m.taskEvents.Publish(pubsub.UpdatedEvent, subtask)
+}
+
+// departmentForRole picks the department a step for the given role should run
+// in, preferring the given department when it has a member with that role
+func (m *Manager) departmentForRole(role MemberRole, preferred string) string {
+	fallback := ""
+	for _, member := range m.members {
+		if member.Role != role {
+			continue
+		}
+		if member.DepartmentID == preferred {
+			return preferred
+		}
+		if fallback == "" {
+			fallback = member.DepartmentID
+		}
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return preferred
+}
+
+func workflowStep(workflow *Workflow, stepID string) (WorkflowStep, bool) {
+	for _, step := range workflow.Steps {
+		if step.ID == stepID {
+			return step, true
+		}
+	}
+	return WorkflowStep{}, false
+}
+
+// isTaskDone reports whether a task has reached a terminal status
+func isTaskDone(status TaskStatus) bool {
+	return status == TaskStatusCompleted || status == TaskStatusFailed || status == TaskStatusCancelled
+}
+
+// findStepCycle returns the ID of a step that is part of a dependency cycle,
+// or an empty string if there is none
+func findStepCycle(steps []WorkflowStep) string {
+	deps := make(map[string][]string, len(steps))
+	for _, step := range steps {
+		deps[step.ID] = step.Dependencies
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(steps))
+
+	var visit func(id string) string
+	visit = func(id string) string {
+		switch state[id] {
+		case visiting:
+			return id
+		case visited:
+			return ""
+		}
+		state[id] = visiting
+		for _, dep := range deps[id] {
+			if cycle := visit(dep); cycle != "" {
+				return cycle
+			}
+		}
+		state[id] = visited
+		return ""
+	}
+
+	for _, step := range steps {
+		if cycle := visit(step.ID); cycle != "" {
+			return cycle
+		}
+	}
+	return ""
+}
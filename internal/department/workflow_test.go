@@ -0,0 +1,204 @@
+package department
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func registerTestWorkflow(t *testing.T, m *Manager) {
+	t.Helper()
+
+	require.NoError(t, m.RegisterWorkflow(&Workflow{
+		ID:       "feature-flow",
+		Name:     "Feature",
+		TaskType: "feature",
+		Steps: []WorkflowStep{
+			{ID: "design", Name: "Design", AssignedRole: RoleLeadDev, Required: true},
+			{ID: "implement", Name: "Implement", AssignedRole: RoleDeveloper, Required: true, Dependencies: []string{"design"}},
+			{ID: "test", Name: "Test", AssignedRole: RoleQA, Required: true, Dependencies: []string{"implement"}},
+		},
+	}))
+}
+
+func TestManagerWorkflowRunsStepsInOrder(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestWorkflow(t, m)
+	registerTestMember(t, m, "lead-1", "dept-dev", RoleLeadDev, 2)
+	registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 2)
+	registerTestMember(t, m, "qa-1", "dept-qa", RoleQA, 2)
+
+	workflow, ok := m.WorkflowForTaskType("feature")
+	require.True(t, ok)
+
+	parent := &Task{ID: "feature-1", Type: "feature", DepartmentID: "dept-dev"}
+	run, err := m.StartWorkflow(ctx, workflow.ID, parent)
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusInProgress, parent.Status)
+
+	step := func(id string) *Task {
+		task, err := m.GetTask(run.StepTasks[id])
+		require.NoError(t, err)
+		return task
+	}
+
+	require.Equal(t, TaskStatusAssigned, step("design").Status)
+	require.Equal(t, "lead-1", step("design").AssignedMember)
+	require.Equal(t, TaskStatusBlocked, step("implement").Status)
+	require.Equal(t, TaskStatusBlocked, step("test").Status)
+
+	require.NoError(t, m.UpdateTaskStatus(ctx, step("design").ID, TaskStatusCompleted, map[string]interface{}{"response": "designed"}))
+	require.Equal(t, "dev-1", step("implement").AssignedMember)
+	require.Equal(t, TaskStatusBlocked, step("test").Status)
+
+	require.NoError(t, m.UpdateTaskStatus(ctx, step("implement").ID, TaskStatusCompleted, map[string]interface{}{"response": "implemented"}))
+	require.Equal(t, "qa-1", step("test").AssignedMember)
+	require.Equal(t, "dept-qa", step("test").DepartmentID)
+
+	require.NoError(t, m.UpdateTaskStatus(ctx, step("test").ID, TaskStatusCompleted, map[string]interface{}{"response": "tested"}))
+
+	run, err = m.GetWorkflowRun(run.ID)
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusCompleted, run.Status)
+	require.Equal(t, TaskStatusCompleted, parent.Status)
+	require.Equal(t, "## Design\n\ndesigned\n\n## Implement\n\nimplemented\n\n## Test\n\ntested", parent.Results["response"])
+}
+
+func TestManagerWorkflowFailsOnRequiredStep(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestWorkflow(t, m)
+	registerTestMember(t, m, "lead-1", "dept-dev", RoleLeadDev, 2)
+
+	parent := &Task{ID: "feature-1", Type: "feature", DepartmentID: "dept-dev"}
+	run, err := m.StartWorkflow(ctx, "feature-flow", parent)
+	require.NoError(t, err)
+
+	require.NoError(t, m.UpdateTaskStatus(ctx, run.StepTasks["design"], TaskStatusFailed, map[string]interface{}{"error": "boom"}))
+
+	require.Equal(t, TaskStatusFailed, run.Status)
+	require.Equal(t, "required step design failed", run.Error)
+	require.Equal(t, TaskStatusFailed, parent.Status)
+
+	implement, err := m.GetTask(run.StepTasks["implement"])
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusCancelled, implement.Status)
+}
+
+func TestManagerAbortWorkflowRunReleasesMembers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestWorkflow(t, m)
+	registerTestMember(t, m, "lead-1", "dept-dev", RoleLeadDev, 2)
+
+	parent := &Task{ID: "feature-1", Type: "feature", DepartmentID: "dept-dev"}
+	run, err := m.StartWorkflow(ctx, "feature-flow", parent)
+	require.NoError(t, err)
+	require.NoError(t, m.UpdateTaskStatus(ctx, run.StepTasks["design"], TaskStatusInProgress, nil))
+
+	lead, err := m.GetMember("lead-1")
+	require.NoError(t, err)
+	require.Len(t, lead.CurrentTasks, 1)
+
+	require.NoError(t, m.AbortWorkflowRun(ctx, run.ID, errors.New("timed out")))
+
+	require.Equal(t, TaskStatusFailed, run.Status)
+	require.Equal(t, "timed out", run.Error)
+	require.Equal(t, TaskStatusFailed, parent.Status)
+	for _, stepID := range []string{"design", "implement", "test"} {
+		step, err := m.GetTask(run.StepTasks[stepID])
+		require.NoError(t, err)
+		require.Equal(t, TaskStatusCancelled, step.Status, stepID)
+	}
+
+	lead, err = m.GetMember("lead-1")
+	require.NoError(t, err)
+	require.Empty(t, lead.CurrentTasks)
+
+	require.Error(t, m.AbortWorkflowRun(ctx, run.ID, errors.New("again")))
+}
+
+func TestManagerWorkflowUnplaceableStepLeavesNoTasks(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	registerTestWorkflow(t, m)
+	registerTestMember(t, m, "lead-1", "dept-dev", RoleLeadDev, 2)
+
+	// Without a department or developers the implement step has nowhere to go
+	parent := &Task{ID: "feature-1", Type: "feature"}
+	_, err := m.StartWorkflow(ctx, "feature-flow", parent)
+	require.ErrorContains(t, err, "cannot determine department for workflow step implement")
+
+	require.Empty(t, parent.Status)
+	_, err = m.GetTask("feature-1")
+	require.Error(t, err)
+	_, err = m.GetTask("feature-1-design")
+	require.Error(t, err)
+
+	lead, err := m.GetMember("lead-1")
+	require.NoError(t, err)
+	require.Empty(t, lead.CurrentTasks)
+}
+
+func TestManagerWorkflowNextStepCompetesForFreedCapacity(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := newTestManager(t)
+	require.NoError(t, m.RegisterWorkflow(&Workflow{
+		ID:       "hotfix-flow",
+		TaskType: "hotfix",
+		Steps: []WorkflowStep{
+			{ID: "patch", Name: "Patch", AssignedRole: RoleDeveloper, Required: true},
+			{ID: "verify", Name: "Verify", AssignedRole: RoleDeveloper, Required: true, Dependencies: []string{"patch"}},
+		},
+	}))
+	dev := registerTestMember(t, m, "dev-1", "dept-dev", RoleDeveloper, 1)
+
+	run, err := m.StartWorkflow(ctx, "hotfix-flow", &Task{ID: "hotfix-1", Type: "hotfix", DepartmentID: "dept-dev", Priority: PriorityCritical})
+	require.NoError(t, err)
+	chore, err := m.CreateTask(ctx, &Task{ID: "chore", DepartmentID: "dept-dev", Priority: PriorityLow})
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, chore.Status)
+
+	// The unblocked critical step gets the freed slot ahead of the older
+	// low priority task
+	require.NoError(t, m.UpdateTaskStatus(ctx, run.StepTasks["patch"], TaskStatusCompleted, nil))
+	verify, err := m.GetTask(run.StepTasks["verify"])
+	require.NoError(t, err)
+	require.Equal(t, dev.ID, verify.AssignedMember)
+	chore, err = m.GetTask(chore.ID)
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusQueued, chore.Status)
+}
+
+func TestManagerRegisterWorkflowValidation(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t)
+
+	err := m.RegisterWorkflow(&Workflow{ID: "cyclic", Steps: []WorkflowStep{
+		{ID: "a", Dependencies: []string{"b"}},
+		{ID: "b", Dependencies: []string{"a"}},
+	}})
+	require.ErrorContains(t, err, "dependency cycle")
+
+	err = m.RegisterWorkflow(&Workflow{ID: "unknown", Steps: []WorkflowStep{
+		{ID: "a", Dependencies: []string{"missing"}},
+	}})
+	require.ErrorContains(t, err, "unknown step missing")
+
+	_, err = m.StartWorkflow(context.Background(), "cyclic", &Task{})
+	require.ErrorContains(t, err, "workflow cyclic does not exist")
+}